@@ -21,6 +21,7 @@ import (
 	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/prometheus/common/promslog"
 	promslogflag "github.com/prometheus/common/promslog/flag"
@@ -28,8 +29,9 @@ import (
 	"github.com/urfave/cli/v2"
 	"golang.org/x/sync/semaphore"
 
-	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 const (
@@ -54,17 +56,34 @@ const (
 )
 
 var (
-	addr                  string
-	configFile            string
-	logLevel              string
-	logFormat             string
-	fips                  bool
-	cloudwatchConcurrency config.CloudWatchConcurrencyConfig
-	tagConcurrency        int
-	scrapingInterval      int
-	metricsPerQuery       int
-	labelsSnakeCase       bool
-	profilingEnabled      bool
+	addr                    string
+	configFile              string
+	logLevel                string
+	logFormat               string
+	fips                    bool
+	cloudwatchConcurrency   config.CloudWatchConcurrencyConfig
+	cloudwatchMaxBackoff    time.Duration
+	tagConcurrency          int
+	scrapingInterval        int
+	metricsPerQuery         int
+	buildMetricsConcurrency int
+	labelsSnakeCase         bool
+	logDuplicateMetrics     bool
+	clampFutureTimestamps   bool
+	customTagsLabelPrefix   string
+	arnLabelName            string
+	maxSeries               int
+	seriesLimitAction       string
+	invalidLabelNameAction  string
+	accountAliasSource      string
+	discoveryRetries        int
+	discoveryRetryBackoff   time.Duration
+	accountConcurrency      int
+	jobConcurrency          int
+	namespaceJobShare       float64
+	profilingEnabled        bool
+	metricsFile             string
+	metricsMetadataFile     string
 
 	logger *slog.Logger
 )
@@ -169,6 +188,42 @@ func NewYACEApp() *cli.App {
 			Usage:       "Maximum number of concurrent requests to GetMetricStatistics CloudWatch API. Used if the -cloudwatch-concurrency.per-api-limit-enabled concurrency limiter is enabled.",
 			Destination: &cloudwatchConcurrency.GetMetricStatistics,
 		},
+		&cli.DurationFlag{
+			Name:        "cloudwatch-max-backoff",
+			Value:       config.DefaultCloudwatchMaxBackoff,
+			Usage:       "Maximum delay between the retries of a throttled or failed CloudWatch API request. The retries are delayed by an exponential backoff with jitter.",
+			Destination: &cloudwatchMaxBackoff,
+		},
+		&cli.IntFlag{
+			Name:        "discovery-retries",
+			Value:       config.DefaultDiscoveryRetries,
+			Usage:       "Number of times a failed resource discovery is retried, e.g. when the tagging API is throttled.",
+			Destination: &discoveryRetries,
+		},
+		&cli.DurationFlag{
+			Name:        "discovery-retry-backoff",
+			Value:       config.DefaultDiscoveryRetryBackoff,
+			Usage:       "Delay before the first retry of a failed resource discovery, doubled before every following retry.",
+			Destination: &discoveryRetryBackoff,
+		},
+		&cli.IntFlag{
+			Name:        "account-concurrency",
+			Value:       config.DefaultAccountConcurrency,
+			Usage:       "Maximum number of concurrent account and alias lookups, one per job, role and region. 0 doesn't bound them.",
+			Destination: &accountConcurrency,
+		},
+		&cli.IntFlag{
+			Name:        "job-concurrency",
+			Value:       config.DefaultJobConcurrency,
+			Usage:       "Maximum number of concurrent jobs, one per job, role and region. 0 doesn't bound them.",
+			Destination: &jobConcurrency,
+		},
+		&cli.Float64Flag{
+			Name:        "job-concurrency.namespace-share",
+			Value:       config.DefaultNamespaceJobShare,
+			Usage:       "Share of -job-concurrency the jobs of a single namespace can take, between 0 and 1.",
+			Destination: &namespaceJobShare,
+		},
 		&cli.IntFlag{
 			Name:        "tag-concurrency",
 			Value:       config.DefaultTaggingAPIConcurrency,
@@ -189,12 +244,78 @@ func NewYACEApp() *cli.App {
 			Destination: &metricsPerQuery,
 			EnvVars:     []string{"metrics-per-query"},
 		},
+		&cli.IntFlag{
+			Name:        "build-metrics-concurrency",
+			Value:       config.DefaultBuildMetricsConcurrency,
+			Usage:       "Number of workers converting the scraped CloudWatch data to Prometheus metrics",
+			Destination: &buildMetricsConcurrency,
+		},
 		&cli.BoolFlag{
 			Name:        "labels-snake-case",
 			Value:       config.DefaultLabelsSnakeCase,
 			Usage:       "Whether labels should be output in snake case instead of camel case",
 			Destination: &labelsSnakeCase,
 		},
+		&cli.BoolFlag{
+			Name:        "log-duplicate-metrics",
+			Value:       false,
+			Usage:       "Log the labels of the duplicate series dropped from the exported metrics at debug level",
+			Destination: &logDuplicateMetrics,
+		},
+		&cli.BoolFlag{
+			Name:        "clamp-future-timestamps",
+			Value:       false,
+			Usage:       "Export the CloudWatch timestamps which are in the future with the current time instead",
+			Destination: &clampFutureTimestamps,
+		},
+		&cli.StringFlag{
+			Name:        "custom-tags-label-prefix",
+			Value:       config.DefaultCustomTagsLabelPrefix,
+			Usage:       "Prefix of the label names of custom tags. Can be empty.",
+			Destination: &customTagsLabelPrefix,
+		},
+		&cli.StringFlag{
+			Name:        "arn-label-name",
+			Value:       config.DefaultARNLabelName,
+			Usage:       "Name of the label holding the ARN of the resource of the info and data metrics.",
+			Destination: &arnLabelName,
+		},
+		&cli.IntFlag{
+			Name:        "max-series",
+			Value:       config.DefaultMaxSeries,
+			Usage:       "Maximum number of series exported by a single scrape. 0 means no limit.",
+			Destination: &maxSeries,
+		},
+		&cli.StringFlag{
+			Name:        "max-series.action",
+			Value:       string(config.DefaultSeriesLimitAction),
+			Usage:       "What to do when a scrape exceeds -max-series. One of: [truncate, fail]",
+			Destination: &seriesLimitAction,
+		},
+		&cli.StringFlag{
+			Name:        "invalid-label-name-action",
+			Value:       string(config.DefaultInvalidLabelNameAction),
+			Usage:       "What to do with the dimensions and tags whose name isn't a valid label name. One of: [skip, sanitize, fail]",
+			Destination: &invalidLabelNameAction,
+		},
+		&cli.StringFlag{
+			Name:        "account-alias-source",
+			Value:       string(config.DefaultAccountAliasSource),
+			Usage:       "API the account_alias label is resolved with. One of: [iam, organizations]",
+			Destination: &accountAliasSource,
+		},
+		&cli.StringFlag{
+			Name:        "metrics-file",
+			Value:       "",
+			Usage:       "Path of a file to write the scraped metrics to, in Prometheus text format, after each scrape",
+			Destination: &metricsFile,
+		},
+		&cli.StringFlag{
+			Name:        "metrics-metadata-file",
+			Value:       "",
+			Usage:       "Path of a YAML file with help texts and units of exported metrics",
+			Destination: &metricsMetadataFile,
+		},
 		&cli.BoolFlag{
 			Name:        "profiling.enabled",
 			Value:       false,
@@ -258,26 +379,42 @@ func startScraper(c *cli.Context) error {
 	cfg := config.DefaultConfig()
 	cfg.ScrapeConfigFile = configFile
 	cfg.MetricsPerQuery = metricsPerQuery
+	cfg.BuildMetricsConcurrency = buildMetricsConcurrency
 	cfg.LabelsSnakeCase = labelsSnakeCase
+	cfg.LogDuplicateMetrics = logDuplicateMetrics
+	cfg.ClampFutureTimestamps = clampFutureTimestamps
+	cfg.CustomTagsLabelPrefix = customTagsLabelPrefix
+	cfg.ARNLabelName = arnLabelName
+	cfg.MetricsMetadataFile = metricsMetadataFile
 	cfg.TaggingAPIConcurrency = tagConcurrency
 	cfg.FeatureFlags = c.StringSlice(enableFeatureFlag)
 	cfg.FIPSEnabled = fips
 	cfg.CloudwatchConcurrency = cloudwatchConcurrency
+	cfg.CloudwatchMaxBackoff = cloudwatchMaxBackoff
+	cfg.MaxSeries = maxSeries
+	cfg.SeriesLimitAction = config.SeriesLimitAction(seriesLimitAction)
+	cfg.InvalidLabelNameAction = promutil.InvalidLabelNameAction(invalidLabelNameAction)
+	cfg.AccountAliasSource = account.AliasSource(accountAliasSource)
+	cfg.DiscoveryRetries = discoveryRetries
+	cfg.DiscoveryRetryBackoff = discoveryRetryBackoff
+	cfg.AccountConcurrency = accountConcurrency
+	cfg.JobConcurrency = jobConcurrency
+	cfg.NamespaceJobShare = namespaceJobShare
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid runtime scrape configuration: %w", err)
 	}
 
-	scrapeCfg := config.ScrapeConf{}
-	jobsCfg, err := scrapeCfg.Load(cfg.ScrapeConfigFile, logger)
-	if err != nil {
-		return fmt.Errorf("couldn't read %s: %w", cfg.ScrapeConfigFile, err)
+	if cfg.MetricsMetadataFile != "" {
+		if _, err := promutil.LoadMetricsMetadata(cfg.MetricsMetadataFile); err != nil {
+			return fmt.Errorf("couldn't read %s: %w", cfg.MetricsMetadataFile, err)
+		}
 	}
 
 	s := NewScraper(cfg)
 
-	cachingFactory, err := clients.NewFactory(logger, s.scrapeMetrics, jobsCfg, cfg.FIPSEnabled)
+	jobsCfg, cachingFactory, err := s.loadConfig(logger)
 	if err != nil {
-		return fmt.Errorf("failed to construct aws sdk v2 client cache: %w", err)
+		return err
 	}
 
 	ctx, cancelRunningScrape := context.WithCancel(context.Background())
@@ -315,18 +452,10 @@ func startScraper(c *cli.Context) error {
 			return
 		}
 
-		logger.Info("Parsing config")
-		newCfg := config.ScrapeConf{}
-		newJobsCfg, err := newCfg.Load(cfg.ScrapeConfigFile, logger)
-		if err != nil {
-			logger.Error("Couldn't read config file", "err", err, "path", cfg.ScrapeConfigFile)
-			return
-		}
-
-		logger.Info("Reset clients cache")
-		cache, err := clients.NewFactory(logger, s.scrapeMetrics, newJobsCfg, cfg.FIPSEnabled)
+		logger.Info("Parsing config and resetting clients cache")
+		newJobsCfg, cache, err := s.loadConfig(logger)
 		if err != nil {
-			logger.Error("Failed to construct aws sdk v2 client cache", "err", err, "path", cfg.ScrapeConfigFile)
+			logger.Error("Couldn't reload config", "err", err, "path", cfg.ScrapeConfigFile)
 			return
 		}
 
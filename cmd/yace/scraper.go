@@ -14,6 +14,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"sync/atomic"
@@ -37,6 +38,9 @@ type Scraper struct {
 	resultReg     atomic.Pointer[prometheus.Registry]
 	scrapeMetrics *promutil.ScrapeMetrics
 	config        config.Config
+
+	configReloadSuccess          prometheus.Gauge
+	configReloadSuccessTimestamp prometheus.Gauge
 }
 
 type cachingFactory interface {
@@ -52,11 +56,52 @@ func NewScraper(cfg config.Config) *Scraper {
 		stableReg:     stableReg,
 		scrapeMetrics: promutil.NewScrapeMetrics(stableReg),
 		config:        cfg,
+		configReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "yace_config_last_reload_success",
+			Help: "Whether the last configuration (re)load attempt succeeded.",
+		}),
+		configReloadSuccessTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "yace_config_last_reload_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration (re)load.",
+		}),
 	}
+	stableReg.MustRegister(s.configReloadSuccess, s.configReloadSuccessTimestamp)
 	s.resultReg.Store(prometheus.NewRegistry())
 	return s
 }
 
+// loadConfig reads the scrape configuration file and builds a client factory
+// for it, recording the outcome in the config reload metrics.
+func (s *Scraper) loadConfig(logger *slog.Logger) (model.JobsConfig, *clients.CachingFactory, error) {
+	jobsCfg, cache, err := s.doLoadConfig(logger)
+	if err != nil {
+		s.configReloadSuccess.Set(0)
+		return model.JobsConfig{}, nil, err
+	}
+
+	s.configReloadSuccess.Set(1)
+	s.configReloadSuccessTimestamp.SetToCurrentTime()
+	return jobsCfg, cache, nil
+}
+
+func (s *Scraper) doLoadConfig(logger *slog.Logger) (model.JobsConfig, *clients.CachingFactory, error) {
+	scrapeCfg := config.ScrapeConf{}
+	jobsCfg, err := scrapeCfg.Load(s.config.ScrapeConfigFile, logger)
+	if err != nil {
+		return model.JobsConfig{}, nil, fmt.Errorf("couldn't read %s: %w", s.config.ScrapeConfigFile, err)
+	}
+
+	cache, err := clients.NewFactory(logger, s.scrapeMetrics, jobsCfg, s.config.FIPSEnabled,
+		clients.WithCloudwatchMaxBackoff(s.config.CloudwatchMaxBackoff),
+		clients.WithAccountAliasSource(s.config.AccountAliasSource),
+	)
+	if err != nil {
+		return model.JobsConfig{}, nil, fmt.Errorf("failed to construct aws sdk v2 client cache: %w", err)
+	}
+
+	return jobsCfg, cache, nil
+}
+
 func (s *Scraper) makeHandler() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		gatherers := prometheus.Gatherers{s.stableReg, s.resultReg.Load()}
@@ -114,6 +159,12 @@ func (s *Scraper) scrape(ctx context.Context, logger *slog.Logger, scraper *yace
 		return
 	}
 
+	if metricsFile != "" {
+		if err := promutil.WriteMetricsFile(metricsFile, metrics); err != nil {
+			logger.Error("error writing metrics file", "err", err, "path", metricsFile)
+		}
+	}
+
 	newResultReg := prometheus.NewRegistry()
 	newResultReg.MustRegister(promutil.NewPrometheusCollector(metrics))
 	s.resultReg.Store(newResultReg)
@@ -0,0 +1,62 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+)
+
+func TestScraper_LoadConfigRecordsReloadStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	cfg := config.DefaultConfig()
+	cfg.ScrapeConfigFile = path
+	s := NewScraper(cfg)
+	logger := promslog.NewNopLogger()
+
+	require.NoError(t, os.WriteFile(path, []byte(`apiVersion: v1alpha1
+static:
+  - namespace: AWS/AutoScaling
+    name: must_be_set
+    regions:
+      - eu-west-1
+    dimensions:
+      - name: AutoScalingGroupName
+        value: my-group
+    metrics:
+      - name: GroupInServiceInstances
+        statistics: [Minimum]
+        period: 60
+        length: 300
+`), 0o600))
+
+	_, _, err := s.loadConfig(logger)
+	require.NoError(t, err)
+	require.Equal(t, float64(1), testutil.ToFloat64(s.configReloadSuccess))
+	lastSuccess := testutil.ToFloat64(s.configReloadSuccessTimestamp)
+	require.Positive(t, lastSuccess)
+
+	require.NoError(t, os.WriteFile(path, []byte("apiVersion: v1alpha1\ndiscovery:\n  jobs:\n    - type: AWS/Invalid\n"), 0o600))
+
+	_, _, err = s.loadConfig(logger)
+	require.Error(t, err)
+	require.Equal(t, float64(0), testutil.ToFloat64(s.configReloadSuccess))
+	require.Equal(t, lastSuccess, testutil.ToFloat64(s.configReloadSuccessTimestamp))
+}
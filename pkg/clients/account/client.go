@@ -16,11 +16,25 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
+// AliasSource is the API the account alias is resolved with.
+type AliasSource string
+
+const (
+	// AliasSourceIAM uses the IAM account alias.
+	AliasSourceIAM AliasSource = "iam"
+	// AliasSourceOrganizations uses the name of the account in AWS Organizations, falling back to
+	// the IAM account alias when the name can't be resolved.
+	AliasSourceOrganizations AliasSource = "organizations"
+)
+
 type Client interface {
 	// GetAccount returns the AWS account ID for the configured authenticated client.
 	GetAccount(ctx context.Context) (string, error)
@@ -33,17 +47,91 @@ type client struct {
 	logger             *slog.Logger
 	getCallerIdentity  func(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
 	listAccountAliases func(ctx context.Context, params *iam.ListAccountAliasesInput, optFns ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error)
+	// organizationsNames is only set when the alias is resolved with AWS Organizations.
+	organizationsNames *OrganizationsNames
+
+	// accountID caches the result of GetAccount, so that resolving the alias doesn't call STS again.
+	accountIDMu sync.Mutex
+	accountID   string
+}
+
+// Option configures the account client.
+type Option func(*client)
+
+// WithOrganizationsNames resolves the account alias with the name of the account in AWS Organizations.
+// The IAM account alias is used when the name can't be resolved, e.g. because the account isn't part of
+// an organization.
+func WithOrganizationsNames(names *OrganizationsNames) Option {
+	return func(c *client) {
+		c.organizationsNames = names
+	}
+}
+
+// OrganizationsNames resolves the names of accounts in AWS Organizations. It's meant to be shared by the account
+// clients of all the roles, with the credentials of the exporter: only the management account and the delegated
+// administrators of the organization are allowed to describe its accounts, not the member accounts the roles
+// usually belong to. The outcome of every lookup is cached by account ID for the lifetime of the exporter, so an
+// account is only described once.
+type OrganizationsNames struct {
+	logger          *slog.Logger
+	describeAccount func(ctx context.Context, params *organizations.DescribeAccountInput, optFns ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error)
+
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// NewOrganizationsNames returns an OrganizationsNames describing the accounts with organizationsClient, which
+// requires the organizations:DescribeAccount permission.
+func NewOrganizationsNames(logger *slog.Logger, organizationsClient *organizations.Client) *OrganizationsNames {
+	return &OrganizationsNames{
+		logger:          logger,
+		describeAccount: organizationsClient.DescribeAccount,
+		names:           map[string]string{},
+	}
+}
+
+// Name returns the name of the account in AWS Organizations, or an empty string when it can't be resolved.
+func (n *OrganizationsNames) Name(ctx context.Context, accountID string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if name, ok := n.names[accountID]; ok {
+		return name
+	}
+
+	name := ""
+	result, err := n.describeAccount(ctx, &organizations.DescribeAccountInput{AccountId: aws.String(accountID)})
+	switch {
+	case err != nil && ctx.Err() != nil:
+		// The scrape was canceled, the account can be described by the next one
+		return ""
+	case err != nil:
+		n.logger.Warn("Couldn't describe the account with AWS Organizations, the IAM account alias is used instead", "account", accountID, "err", err)
+	case result.Account != nil:
+		name = aws.ToString(result.Account.Name)
+	}
+	n.names[accountID] = name
+	return name
 }
 
-func NewClient(logger *slog.Logger, stsClient *sts.Client, iamClient *iam.Client) Client {
-	return &client{
+func NewClient(logger *slog.Logger, stsClient *sts.Client, iamClient *iam.Client, opts ...Option) Client {
+	c := &client{
 		logger:             logger,
 		getCallerIdentity:  stsClient.GetCallerIdentity,
 		listAccountAliases: iamClient.ListAccountAliases,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c client) GetAccount(ctx context.Context) (string, error) {
+func (c *client) GetAccount(ctx context.Context) (string, error) {
+	c.accountIDMu.Lock()
+	defer c.accountIDMu.Unlock()
+	if c.accountID != "" {
+		return c.accountID, nil
+	}
+
 	result, err := c.getCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		return "", err
@@ -51,10 +139,21 @@ func (c client) GetAccount(ctx context.Context) (string, error) {
 	if result.Account == nil {
 		return "", errors.New("aws sts GetCallerIdentity returned no account")
 	}
-	return *result.Account, nil
+	c.accountID = *result.Account
+	return c.accountID, nil
 }
 
-func (c client) GetAccountAlias(ctx context.Context) (string, error) {
+func (c *client) GetAccountAlias(ctx context.Context) (string, error) {
+	if c.organizationsNames != nil {
+		accountID, err := c.GetAccount(ctx)
+		if err != nil {
+			return "", err
+		}
+		if name := c.organizationsNames.Name(ctx, accountID); name != "" {
+			return name, nil
+		}
+	}
+
 	acctAliasOut, err := c.listAccountAliases(ctx, &iam.ListAccountAliasesInput{})
 	if err != nil {
 		return "", err
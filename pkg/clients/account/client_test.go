@@ -0,0 +1,168 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_GetAccountAlias(t *testing.T) {
+	getCallerIdentity := func(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+		return &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")}, nil
+	}
+	listAccountAliases := func(_ context.Context, _ *iam.ListAccountAliasesInput, _ ...func(*iam.Options)) (*iam.ListAccountAliasesOutput, error) {
+		return &iam.ListAccountAliasesOutput{AccountAliases: []string{"iam-alias"}}, nil
+	}
+	describeAccount := func(name string, err error) func(context.Context, *organizations.DescribeAccountInput, ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error) {
+		return func(_ context.Context, params *organizations.DescribeAccountInput, _ ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &organizations.DescribeAccountOutput{Account: &types.Account{
+				Id:   params.AccountId,
+				Name: aws.String(name),
+			}}, nil
+		}
+	}
+
+	tests := []struct {
+		name            string
+		describeAccount func(context.Context, *organizations.DescribeAccountInput, ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error)
+		expected        string
+	}{
+		{
+			name:     "iam",
+			expected: "iam-alias",
+		},
+		{
+			name:            "organizations",
+			describeAccount: describeAccount("org-account-name", nil),
+			expected:        "org-account-name",
+		},
+		{
+			name:            "organizations without a name falls back to iam",
+			describeAccount: describeAccount("", nil),
+			expected:        "iam-alias",
+		},
+		{
+			name:            "organizations error falls back to iam",
+			describeAccount: describeAccount("", errors.New("AWSOrganizationsNotInUseException")),
+			expected:        "iam-alias",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &client{
+				logger:             promslog.NewNopLogger(),
+				getCallerIdentity:  getCallerIdentity,
+				listAccountAliases: listAccountAliases,
+			}
+			if tc.describeAccount != nil {
+				c.organizationsNames = &OrganizationsNames{
+					logger:          promslog.NewNopLogger(),
+					describeAccount: tc.describeAccount,
+					names:           map[string]string{},
+				}
+			}
+
+			alias, err := c.GetAccountAlias(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, alias)
+		})
+	}
+}
+
+func TestClient_GetAccountAlias_DescribesTheCallerAccount(t *testing.T) {
+	var describedAccountID string
+	callerIdentityCalls := 0
+	c := &client{
+		logger: promslog.NewNopLogger(),
+		getCallerIdentity: func(_ context.Context, _ *sts.GetCallerIdentityInput, _ ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+			callerIdentityCalls++
+			return &sts.GetCallerIdentityOutput{Account: aws.String("123456789012")}, nil
+		},
+		organizationsNames: &OrganizationsNames{
+			logger: promslog.NewNopLogger(),
+			describeAccount: func(_ context.Context, params *organizations.DescribeAccountInput, _ ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error) {
+				describedAccountID = aws.ToString(params.AccountId)
+				return &organizations.DescribeAccountOutput{Account: &types.Account{Name: aws.String("org-account-name")}}, nil
+			},
+			names: map[string]string{},
+		},
+	}
+
+	accountID, err := c.GetAccount(context.Background())
+	require.NoError(t, err)
+	alias, err := c.GetAccountAlias(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "org-account-name", alias)
+	assert.Equal(t, accountID, describedAccountID)
+	// The account ID is reused to resolve the alias
+	assert.Equal(t, 1, callerIdentityCalls)
+}
+
+func TestOrganizationsNames_CachesByAccountID(t *testing.T) {
+	describeCalls := map[string]int{}
+	names := &OrganizationsNames{
+		logger: promslog.NewNopLogger(),
+		describeAccount: func(_ context.Context, params *organizations.DescribeAccountInput, _ ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error) {
+			accountID := aws.ToString(params.AccountId)
+			describeCalls[accountID]++
+			if accountID == "210987654321" {
+				return nil, errors.New("AccessDeniedException")
+			}
+			return &organizations.DescribeAccountOutput{Account: &types.Account{Name: aws.String("name-" + accountID)}}, nil
+		},
+		names: map[string]string{},
+	}
+
+	for range 3 {
+		assert.Equal(t, "name-123456789012", names.Name(context.Background(), "123456789012"))
+		assert.Empty(t, names.Name(context.Background(), "210987654321"))
+	}
+	// Failed lookups are cached as well, so that they aren't retried on every scrape
+	assert.Equal(t, map[string]int{"123456789012": 1, "210987654321": 1}, describeCalls)
+}
+
+func TestOrganizationsNames_DoesNotCacheCanceledLookups(t *testing.T) {
+	describeCalls := 0
+	names := &OrganizationsNames{
+		logger: promslog.NewNopLogger(),
+		describeAccount: func(ctx context.Context, _ *organizations.DescribeAccountInput, _ ...func(*organizations.Options)) (*organizations.DescribeAccountOutput, error) {
+			describeCalls++
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return &organizations.DescribeAccountOutput{Account: &types.Account{Name: aws.String("org-account-name")}}, nil
+		},
+		names: map[string]string{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, names.Name(ctx, "123456789012"))
+	assert.Equal(t, "org-account-name", names.Name(context.Background(), "123456789012"))
+	assert.Equal(t, 2, describeCalls)
+}
@@ -29,8 +29,9 @@ type Client interface {
 	// ListMetrics returns the list of metrics and dimensions for a given namespace
 	// and metric name. Results pagination is handled automatically; the caller
 	// must provide a non-nil handler func that will be invoked for each page of
-	// results.
-	ListMetrics(ctx context.Context, namespace string, metric *model.MetricConfig, recentlyActiveOnly bool, fn func(page []*model.Metric)) error
+	// results. When includeLinkedAccounts is set, the metrics of the accounts linked to
+	// the monitoring account are listed as well, with their source account.
+	ListMetrics(ctx context.Context, namespace string, metric *model.MetricConfig, recentlyActiveOnly bool, includeLinkedAccounts bool, fn func(page []*model.Metric)) error
 
 	// GetMetricData returns the output of the GetMetricData CloudWatch API.
 	// Results pagination is handled automatically.
@@ -87,30 +88,92 @@ var (
 	_ aws_cloudwatch.GetMetricDataAPIClient = cloudwatchClientAdapter{}
 )
 
+// RecentlyActiveWindow is how recently the metrics listed with recentlyActiveOnly must have had data.
+type RecentlyActiveWindow string
+
+// RecentlyActive3h lists the metrics with data in the last three hours, the only window supported by the CloudWatch API.
+const RecentlyActive3h = RecentlyActiveWindow(types.RecentlyActivePt3h)
+
 type client struct {
-	logger        *slog.Logger
-	scrapeMetrics *promutil.ScrapeMetrics
-	cloudwatchAPI cloudwatchClientAdapter
+	logger               *slog.Logger
+	scrapeMetrics        *promutil.ScrapeMetrics
+	cloudwatchAPI        cloudwatchClientAdapter
+	recentlyActiveWindow RecentlyActiveWindow
+}
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*client)
+
+// WithListMetricsEndpoint sends the ListMetrics requests to endpoint instead of the endpoint of the CloudWatch API client.
+// An empty endpoint is ignored.
+func WithListMetricsEndpoint(endpoint string) ClientOption {
+	return func(c *client) {
+		if endpoint != "" {
+			c.cloudwatchAPI.listMetrics = withBaseEndpoint(c.cloudwatchAPI.listMetrics, endpoint)
+		}
+	}
+}
+
+// WithRecentlyActiveWindow sets the window of the ListMetrics requests with recentlyActiveOnly, instead of
+// RecentlyActive3h. An empty window is ignored.
+func WithRecentlyActiveWindow(window RecentlyActiveWindow) ClientOption {
+	return func(c *client) {
+		if window != "" {
+			c.recentlyActiveWindow = window
+		}
+	}
+}
+
+// WithDataEndpoint sends the GetMetricData and GetMetricStatistics requests to endpoint instead of the endpoint of
+// the CloudWatch API client. An empty endpoint is ignored.
+func WithDataEndpoint(endpoint string) ClientOption {
+	return func(c *client) {
+		if endpoint != "" {
+			c.cloudwatchAPI.getMetricData = withBaseEndpoint(c.cloudwatchAPI.getMetricData, endpoint)
+			c.cloudwatchAPI.getMetricStatistics = withBaseEndpoint(c.cloudwatchAPI.getMetricStatistics, endpoint)
+		}
+	}
 }
 
-func NewClient(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, cloudwatchAPI *aws_cloudwatch.Client) Client {
+// withBaseEndpoint overrides the endpoint of every call to an operation of the CloudWatch API client.
+func withBaseEndpoint[In, Out any](
+	operation func(context.Context, *In, ...func(*aws_cloudwatch.Options)) (*Out, error),
+	endpoint string,
+) func(context.Context, *In, ...func(*aws_cloudwatch.Options)) (*Out, error) {
+	return func(ctx context.Context, params *In, optFns ...func(*aws_cloudwatch.Options)) (*Out, error) {
+		optFns = append(optFns, func(options *aws_cloudwatch.Options) {
+			options.BaseEndpoint = aws.String(endpoint)
+		})
+		return operation(ctx, params, optFns...)
+	}
+}
+
+func NewClient(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, cloudwatchAPI *aws_cloudwatch.Client, opts ...ClientOption) Client {
 	if scrapeMetrics == nil {
 		scrapeMetrics = promutil.Discard
 	}
-	return &client{
-		logger:        logger,
-		scrapeMetrics: scrapeMetrics,
-		cloudwatchAPI: newCloudwatchClientAdapter(cloudwatchAPI),
+	c := &client{
+		logger:               logger,
+		scrapeMetrics:        scrapeMetrics,
+		cloudwatchAPI:        newCloudwatchClientAdapter(cloudwatchAPI),
+		recentlyActiveWindow: RecentlyActive3h,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-func (c client) ListMetrics(ctx context.Context, namespace string, metric *model.MetricConfig, recentlyActiveOnly bool, fn func(page []*model.Metric)) error {
+func (c client) ListMetrics(ctx context.Context, namespace string, metric *model.MetricConfig, recentlyActiveOnly bool, includeLinkedAccounts bool, fn func(page []*model.Metric)) error {
 	filter := &aws_cloudwatch.ListMetricsInput{
 		MetricName: aws.String(metric.Name),
 		Namespace:  aws.String(namespace),
 	}
 	if recentlyActiveOnly {
-		filter.RecentlyActive = types.RecentlyActivePt3h
+		filter.RecentlyActive = types.RecentlyActive(c.recentlyActiveWindow)
+	}
+	if includeLinkedAccounts {
+		filter.IncludeLinkedAccounts = aws.Bool(true)
 	}
 
 	c.logger.Debug("ListMetrics", "input", filter)
@@ -119,8 +182,12 @@ func (c client) ListMetrics(ctx context.Context, namespace string, metric *model
 		options.StopOnDuplicateToken = true
 	})
 
+	pages := 0
+	defer func() { c.scrapeMetrics.APIPagesHistogram.Observe(float64(pages), "ListMetrics") }()
+
 	for paginator.HasMorePages() {
 		c.scrapeMetrics.CloudwatchAPICounter.Inc("ListMetrics")
+		pages++
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
 			c.scrapeMetrics.CloudwatchAPIErrorCounter.Inc("ListMetrics")
@@ -137,14 +204,19 @@ func (c client) ListMetrics(ctx context.Context, namespace string, metric *model
 	return nil
 }
 
+// toModelMetric maps a ListMetrics page. When linked accounts are included, OwningAccounts
+// holds the source account of every metric, in the same order.
 func toModelMetric(page *aws_cloudwatch.ListMetricsOutput) []*model.Metric {
 	modelMetrics := make([]*model.Metric, 0, len(page.Metrics))
-	for _, cloudwatchMetric := range page.Metrics {
+	for i, cloudwatchMetric := range page.Metrics {
 		modelMetric := &model.Metric{
 			MetricName: *cloudwatchMetric.MetricName,
 			Namespace:  *cloudwatchMetric.Namespace,
 			Dimensions: toModelDimensions(cloudwatchMetric.Dimensions),
 		}
+		if i < len(page.OwningAccounts) {
+			modelMetric.SourceAccountID = page.OwningAccounts[i]
+		}
 		modelMetrics = append(modelMetrics, modelMetric)
 	}
 	return modelMetrics
@@ -165,7 +237,17 @@ func toModelDimensions(dimensions []types.Dimension) []model.Dimension {
 func (c client) GetMetricData(ctx context.Context, getMetricData []*model.CloudwatchData, namespace string, startTime time.Time, endTime time.Time) []MetricDataResult {
 	metricDataQueries := make([]types.MetricDataQuery, 0, len(getMetricData))
 	exportAllDataPoints := false
+	keepLastN := 0
 	for _, data := range getMetricData {
+		if data.GetMetricDataProcessingParams.Expression != "" {
+			metricDataQueries = append(metricDataQueries, types.MetricDataQuery{
+				Id:         &data.GetMetricDataProcessingParams.QueryID,
+				Expression: &data.GetMetricDataProcessingParams.Expression,
+				Period:     aws.Int32(int32(data.GetMetricDataProcessingParams.Period)),
+				ReturnData: aws.Bool(true),
+			})
+			continue
+		}
 		metricStat := &types.MetricStat{
 			Metric: &types.Metric{
 				Dimensions: toCloudWatchDimensions(data.Dimensions),
@@ -175,12 +257,17 @@ func (c client) GetMetricData(ctx context.Context, getMetricData []*model.Cloudw
 			Period: aws.Int32(int32(data.GetMetricDataProcessingParams.Period)),
 			Stat:   &data.GetMetricDataProcessingParams.Statistic,
 		}
-		metricDataQueries = append(metricDataQueries, types.MetricDataQuery{
+		query := types.MetricDataQuery{
 			Id:         &data.GetMetricDataProcessingParams.QueryID,
 			MetricStat: metricStat,
 			ReturnData: aws.Bool(true),
-		})
+		}
+		if data.SourceAccountID != "" {
+			query.AccountId = aws.String(data.SourceAccountID)
+		}
+		metricDataQueries = append(metricDataQueries, query)
 		exportAllDataPoints = exportAllDataPoints || data.MetricMigrationParams.ExportAllDataPoints
+		keepLastN = max(keepLastN, data.MetricMigrationParams.KeepLastN)
 	}
 
 	input := &aws_cloudwatch.GetMetricDataInput{
@@ -191,14 +278,19 @@ func (c client) GetMetricData(ctx context.Context, getMetricData []*model.Cloudw
 	}
 	var resp aws_cloudwatch.GetMetricDataOutput
 	c.scrapeMetrics.CloudwatchGetMetricDataAPIMetricsCounter.Add(float64(len(input.MetricDataQueries)))
+	c.scrapeMetrics.CloudwatchGetMetricDataNamespaceCounter.Add(float64(len(input.MetricDataQueries)), namespace)
 	c.logger.Debug("GetMetricData", "input", input)
 
 	paginator := aws_cloudwatch.NewGetMetricDataPaginator(c.cloudwatchAPI, input, func(options *aws_cloudwatch.GetMetricDataPaginatorOptions) {
 		options.StopOnDuplicateToken = true
 	})
+	pages := 0
+	defer func() { c.scrapeMetrics.APIPagesHistogram.Observe(float64(pages), "GetMetricData") }()
+
 	for paginator.HasMorePages() {
 		c.scrapeMetrics.CloudwatchAPICounter.Inc("GetMetricData")
 		c.scrapeMetrics.CloudwatchGetMetricDataAPICounter.Inc()
+		pages++
 
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
@@ -211,10 +303,14 @@ func (c client) GetMetricData(ctx context.Context, getMetricData []*model.Cloudw
 
 	c.logger.Debug("GetMetricData", "output", resp)
 
-	return toMetricDataResult(resp, exportAllDataPoints)
+	return toMetricDataResult(resp, exportAllDataPoints, keepLastN)
 }
 
-func toMetricDataResult(resp aws_cloudwatch.GetMetricDataOutput, exportAllDataPoints bool) []MetricDataResult {
+// toMetricDataResult maps the GetMetricData output, keeping only the most recent keepLastN
+// data points of every result (at least one) unless exportAllDataPoints is set. Data points
+// are requested with ScanBy TimestampDescending, so the most recent ones come first.
+func toMetricDataResult(resp aws_cloudwatch.GetMetricDataOutput, exportAllDataPoints bool, keepLastN int) []MetricDataResult {
+	maxDataPoints := max(keepLastN, 1)
 	output := make([]MetricDataResult, 0, len(resp.MetricDataResults))
 	for _, metricDataResult := range resp.MetricDataResults {
 		mappedResult := MetricDataResult{
@@ -227,7 +323,7 @@ func toMetricDataResult(resp aws_cloudwatch.GetMetricDataOutput, exportAllDataPo
 				Timestamp: metricDataResult.Timestamps[i],
 			})
 
-			if !exportAllDataPoints {
+			if !exportAllDataPoints && len(mappedResult.DataPoints) >= maxDataPoints {
 				break
 			}
 		}
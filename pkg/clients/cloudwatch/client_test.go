@@ -13,14 +13,24 @@
 package cloudwatch
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_cloudwatch "github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
-
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 func Test_toMetricDataResult(t *testing.T) {
@@ -29,6 +39,7 @@ func Test_toMetricDataResult(t *testing.T) {
 	type testCase struct {
 		name                      string
 		exportAllDataPoints       bool
+		keepLastN                 int
 		getMetricDataOutput       aws_cloudwatch.GetMetricDataOutput
 		expectedMetricDataResults []MetricDataResult
 	}
@@ -125,12 +136,311 @@ func Test_toMetricDataResult(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "keep last two data points",
+			keepLastN: 2,
+			getMetricDataOutput: aws_cloudwatch.GetMetricDataOutput{
+				MetricDataResults: []types.MetricDataResult{
+					{
+						Id:         aws.String("metric-1"),
+						Values:     []float64{1.0, 2.0, 3.0},
+						Timestamps: []time.Time{ts.Add(10 * time.Minute), ts.Add(5 * time.Minute), ts},
+					},
+					{
+						Id:         aws.String("metric-2"),
+						Values:     []float64{2.0},
+						Timestamps: []time.Time{ts},
+					},
+				},
+			},
+			expectedMetricDataResults: []MetricDataResult{
+				{
+					ID: "metric-1", DataPoints: []DataPoint{
+						{Value: aws.Float64(1.0), Timestamp: ts.Add(10 * time.Minute)},
+						{Value: aws.Float64(2.0), Timestamp: ts.Add(5 * time.Minute)},
+					},
+				},
+				{
+					ID: "metric-2", DataPoints: []DataPoint{
+						{Value: aws.Float64(2.0), Timestamp: ts},
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			metricDataResults := toMetricDataResult(tc.getMetricDataOutput, tc.exportAllDataPoints)
+			metricDataResults := toMetricDataResult(tc.getMetricDataOutput, tc.exportAllDataPoints, tc.keepLastN)
 			require.Equal(t, tc.expectedMetricDataResults, metricDataResults)
 		})
 	}
 }
+
+func TestGetMetricData_CountsRequestedMetricsByNamespace(t *testing.T) {
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: scrapeMetrics,
+		cloudwatchAPI: cloudwatchClientAdapter{
+			getMetricData: func(context.Context, *aws_cloudwatch.GetMetricDataInput, ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.GetMetricDataOutput, error) {
+				return &aws_cloudwatch.GetMetricDataOutput{}, nil
+			},
+		},
+	}
+
+	newQueries := func(n int) []*model.CloudwatchData {
+		queries := make([]*model.CloudwatchData, 0, n)
+		for i := 0; i < n; i++ {
+			queries = append(queries, &model.CloudwatchData{
+				MetricName:                    "CPUUtilization",
+				GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{QueryID: "id_0", Period: 60, Statistic: "Average"},
+			})
+		}
+		return queries
+	}
+
+	now := time.Now()
+	c.GetMetricData(context.Background(), newQueries(3), "AWS/EC2", now.Add(-5*time.Minute), now)
+	c.GetMetricData(context.Background(), newQueries(2), "AWS/RDS", now.Add(-5*time.Minute), now)
+	c.GetMetricData(context.Background(), newQueries(4), "AWS/EC2", now.Add(-5*time.Minute), now)
+
+	byNamespace := scrapeMetrics.CloudwatchGetMetricDataNamespaceCounter.Raw()
+	require.Equal(t, float64(7), testutil.ToFloat64(byNamespace.WithLabelValues("AWS/EC2")))
+	require.Equal(t, float64(2), testutil.ToFloat64(byNamespace.WithLabelValues("AWS/RDS")))
+	require.Equal(t, float64(9), testutil.ToFloat64(scrapeMetrics.CloudwatchGetMetricDataAPIMetricsCounter.Raw()))
+}
+
+func TestListMetrics_IncludeLinkedAccounts(t *testing.T) {
+	var inputs []*aws_cloudwatch.ListMetricsInput
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: promutil.Discard,
+		cloudwatchAPI: cloudwatchClientAdapter{
+			listMetrics: func(_ context.Context, input *aws_cloudwatch.ListMetricsInput, _ ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.ListMetricsOutput, error) {
+				inputs = append(inputs, input)
+				output := &aws_cloudwatch.ListMetricsOutput{
+					Metrics: []types.Metric{
+						{MetricName: aws.String("Requests"), Namespace: aws.String("MyApp"), Dimensions: []types.Dimension{{Name: aws.String("Service"), Value: aws.String("api")}}},
+						{MetricName: aws.String("Requests"), Namespace: aws.String("MyApp"), Dimensions: []types.Dimension{{Name: aws.String("Service"), Value: aws.String("web")}}},
+					},
+				}
+				if aws.ToBool(input.IncludeLinkedAccounts) {
+					output.OwningAccounts = []string{"111111111111", "222222222222"}
+				}
+				return output, nil
+			},
+		},
+	}
+
+	var metrics []*model.Metric
+	err := c.ListMetrics(context.Background(), "MyApp", &model.MetricConfig{Name: "Requests"}, false, true, func(page []*model.Metric) {
+		metrics = append(metrics, page...)
+	})
+	require.NoError(t, err)
+	require.True(t, aws.ToBool(inputs[0].IncludeLinkedAccounts))
+	require.Len(t, metrics, 2)
+	require.Equal(t, "111111111111", metrics[0].SourceAccountID)
+	require.Equal(t, "222222222222", metrics[1].SourceAccountID)
+
+	metrics = nil
+	err = c.ListMetrics(context.Background(), "MyApp", &model.MetricConfig{Name: "Requests"}, false, false, func(page []*model.Metric) {
+		metrics = append(metrics, page...)
+	})
+	require.NoError(t, err)
+	require.Nil(t, inputs[1].IncludeLinkedAccounts)
+	require.Len(t, metrics, 2)
+	require.Empty(t, metrics[0].SourceAccountID)
+}
+
+func TestListMetrics_RecentlyActiveWindow(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		recentlyActiveOnly bool
+		opts               []ClientOption
+		expected           types.RecentlyActive
+	}{
+		{
+			name:     "not recently active only",
+			expected: "",
+		},
+		{
+			name:               "default window",
+			recentlyActiveOnly: true,
+			expected:           types.RecentlyActivePt3h,
+		},
+		{
+			name:               "empty window is ignored",
+			recentlyActiveOnly: true,
+			opts:               []ClientOption{WithRecentlyActiveWindow("")},
+			expected:           types.RecentlyActivePt3h,
+		},
+		{
+			name:               "configured window",
+			recentlyActiveOnly: true,
+			opts:               []ClientOption{WithRecentlyActiveWindow("PT1H")},
+			expected:           types.RecentlyActive("PT1H"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(promslog.NewNopLogger(), nil, aws_cloudwatch.New(aws_cloudwatch.Options{}), tc.opts...).(*client)
+			var input *aws_cloudwatch.ListMetricsInput
+			c.cloudwatchAPI.listMetrics = func(_ context.Context, params *aws_cloudwatch.ListMetricsInput, _ ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.ListMetricsOutput, error) {
+				input = params
+				return &aws_cloudwatch.ListMetricsOutput{}, nil
+			}
+
+			err := c.ListMetrics(context.Background(), "AWS/EC2", &model.MetricConfig{Name: "CPUUtilization"}, tc.recentlyActiveOnly, false, func([]*model.Metric) {})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, input.RecentlyActive)
+		})
+	}
+}
+
+func TestGetMetricData_QueriesSourceAccount(t *testing.T) {
+	var input *aws_cloudwatch.GetMetricDataInput
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: promutil.Discard,
+		cloudwatchAPI: cloudwatchClientAdapter{
+			getMetricData: func(_ context.Context, params *aws_cloudwatch.GetMetricDataInput, _ ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.GetMetricDataOutput, error) {
+				input = params
+				return &aws_cloudwatch.GetMetricDataOutput{}, nil
+			},
+		},
+	}
+
+	queries := []*model.CloudwatchData{
+		{
+			MetricName:                    "Requests",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{QueryID: "id_0", Period: 60, Statistic: "Sum"},
+		},
+		{
+			MetricName:                    "Requests",
+			SourceAccountID:               "222222222222",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{QueryID: "id_1", Period: 60, Statistic: "Sum"},
+		},
+	}
+
+	now := time.Now()
+	c.GetMetricData(context.Background(), queries, "MyApp", now.Add(-5*time.Minute), now)
+	require.Len(t, input.MetricDataQueries, 2)
+	require.Nil(t, input.MetricDataQueries[0].AccountId)
+	require.Equal(t, "222222222222", aws.ToString(input.MetricDataQueries[1].AccountId))
+}
+
+func TestGetMetricData_QueriesExpression(t *testing.T) {
+	var input *aws_cloudwatch.GetMetricDataInput
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: promutil.Discard,
+		cloudwatchAPI: cloudwatchClientAdapter{
+			getMetricData: func(_ context.Context, params *aws_cloudwatch.GetMetricDataInput, _ ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.GetMetricDataOutput, error) {
+				input = params
+				return &aws_cloudwatch.GetMetricDataOutput{}, nil
+			},
+		},
+	}
+
+	queries := []*model.CloudwatchData{
+		{
+			MetricName:                    "Requests",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{QueryID: "id_0", Period: 60, Statistic: "Sum"},
+		},
+		{
+			MetricName: "error_rate",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{
+				QueryID:    "id_1",
+				Period:     300,
+				Expression: "SELECT AVG(ErrorRate) FROM SCHEMA(MyApp, Service)",
+			},
+		},
+	}
+
+	now := time.Now()
+	c.GetMetricData(context.Background(), queries, "MyApp", now.Add(-5*time.Minute), now)
+	require.Len(t, input.MetricDataQueries, 2)
+	require.NotNil(t, input.MetricDataQueries[0].MetricStat)
+	require.Nil(t, input.MetricDataQueries[0].Expression)
+
+	expression := input.MetricDataQueries[1]
+	require.Nil(t, expression.MetricStat)
+	require.Equal(t, "id_1", aws.ToString(expression.Id))
+	require.Equal(t, "SELECT AVG(ErrorRate) FROM SCHEMA(MyApp, Service)", aws.ToString(expression.Expression))
+	require.Equal(t, int32(300), aws.ToInt32(expression.Period))
+	require.True(t, aws.ToBool(expression.ReturnData))
+}
+
+func TestNewClient_OperationEndpoints(t *testing.T) {
+	newServer := func(requests *atomic.Int32) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		t.Cleanup(server.Close)
+		return server
+	}
+	var defaultRequests, listRequests, dataRequests atomic.Int32
+	defaultServer := newServer(&defaultRequests)
+	listServer := newServer(&listRequests)
+	dataServer := newServer(&dataRequests)
+
+	cloudwatchAPI := aws_cloudwatch.New(aws_cloudwatch.Options{
+		Region:           "us-east-1",
+		Credentials:      aws.AnonymousCredentials{},
+		BaseEndpoint:     aws.String(defaultServer.URL),
+		RetryMaxAttempts: 1,
+	})
+	metric := &model.MetricConfig{Name: "Requests", Statistics: []string{"Sum"}, Period: 60, Length: 60}
+	queries := []*model.CloudwatchData{{
+		MetricName:                    "Requests",
+		GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{QueryID: "id_0", Period: 60, Statistic: "Sum"},
+	}}
+	call := func(c Client) {
+		now := time.Now()
+		_ = c.ListMetrics(context.Background(), "MyApp", metric, false, false, func([]*model.Metric) {})
+		c.GetMetricData(context.Background(), queries, "MyApp", now.Add(-5*time.Minute), now)
+		c.GetMetricStatistics(context.Background(), promslog.NewNopLogger(), nil, "MyApp", metric)
+	}
+
+	call(NewClient(promslog.NewNopLogger(), promutil.Discard, cloudwatchAPI))
+	require.Equal(t, int32(3), defaultRequests.Load())
+
+	call(NewClient(promslog.NewNopLogger(), promutil.Discard, cloudwatchAPI,
+		WithListMetricsEndpoint(listServer.URL),
+		WithDataEndpoint(dataServer.URL),
+	))
+	require.Equal(t, int32(3), defaultRequests.Load())
+	require.Equal(t, int32(1), listRequests.Load())
+	require.Equal(t, int32(2), dataRequests.Load())
+
+	// Empty endpoints keep the endpoint of the CloudWatch API client.
+	call(NewClient(promslog.NewNopLogger(), promutil.Discard, cloudwatchAPI, WithListMetricsEndpoint(""), WithDataEndpoint("")))
+	require.Equal(t, int32(6), defaultRequests.Load())
+}
+
+func TestListMetrics_ObservesPages(t *testing.T) {
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	tokens := []*string{aws.String("page-2"), aws.String("page-3"), nil}
+	calls := 0
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: scrapeMetrics,
+		cloudwatchAPI: cloudwatchClientAdapter{
+			listMetrics: func(context.Context, *aws_cloudwatch.ListMetricsInput, ...func(*aws_cloudwatch.Options)) (*aws_cloudwatch.ListMetricsOutput, error) {
+				output := &aws_cloudwatch.ListMetricsOutput{NextToken: tokens[calls]}
+				calls++
+				return output, nil
+			},
+		},
+	}
+
+	err := c.ListMetrics(context.Background(), "MyApp", &model.MetricConfig{Name: "Requests"}, false, false, func([]*model.Metric) {})
+	require.NoError(t, err)
+	require.Equal(t, 3, calls)
+
+	var m dto.Metric
+	require.NoError(t, scrapeMetrics.APIPagesHistogram.Raw().WithLabelValues("ListMetrics").(prometheus.Metric).Write(&m))
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	require.Equal(t, float64(3), m.GetHistogram().GetSampleSum())
+}
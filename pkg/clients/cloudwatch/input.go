@@ -67,12 +67,21 @@ func createGetMetricStatisticsInput(logger *slog.Logger, dimensions []model.Dime
 		ExtendedStatistics: extendedStatistics,
 	}
 
+	// Percentiles are requested as extended statistics, so either list might be empty
+	statisticsArgs := ""
+	if len(statistics) > 0 {
+		statisticsArgs += " --statistics " + string(statistics[0])
+	}
+	if len(extendedStatistics) > 0 {
+		statisticsArgs += " --extended-statistics " + strings.Join(extendedStatistics, " ")
+	}
+
 	logger.Debug("CLI helper - " +
 		"aws cloudwatch get-metric-statistics" +
 		" --metric-name " + metric.Name +
 		" --dimensions " + dimensionsToCliString(dimensions) +
 		" --namespace " + *namespace +
-		" --statistics " + string(statistics[0]) +
+		statisticsArgs +
 		" --period " + strconv.FormatInt(period, 10) +
 		" --start-time " + startTime.Format(time.RFC3339) +
 		" --end-time " + endTime.Format(time.RFC3339))
@@ -0,0 +1,64 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cloudwatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func Test_createGetMetricStatisticsInput(t *testing.T) {
+	dimensions := []model.Dimension{{Name: "AutoScalingGroupName", Value: "my-group"}}
+
+	for _, tc := range []struct {
+		name                       string
+		statistics                 []string
+		expectedStatistics         []types.Statistic
+		expectedExtendedStatistics []string
+	}{
+		{
+			name:               "statistics",
+			statistics:         []string{"Average", "Maximum"},
+			expectedStatistics: []types.Statistic{types.StatisticAverage, types.StatisticMaximum},
+		},
+		{
+			name:                       "percentiles only",
+			statistics:                 []string{"p95", "p99.9"},
+			expectedExtendedStatistics: []string{"p95", "p99.9"},
+		},
+		{
+			name:                       "statistics and percentiles",
+			statistics:                 []string{"p99", "Sum"},
+			expectedStatistics:         []types.Statistic{types.StatisticSum},
+			expectedExtendedStatistics: []string{"p99"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			metric := &model.MetricConfig{Name: "GroupInServiceInstances", Statistics: tc.statistics, Period: 120, Length: 600}
+
+			input := createGetMetricStatisticsInput(promslog.NewNopLogger(), dimensions, aws.String("AWS/AutoScaling"), metric)
+
+			require.Equal(t, tc.expectedStatistics, input.Statistics)
+			require.Equal(t, tc.expectedExtendedStatistics, input.ExtendedStatistics)
+			require.Equal(t, int32(120), aws.ToInt32(input.Period))
+			require.Equal(t, 600*time.Second, input.EndTime.Sub(*input.StartTime).Round(time.Second))
+		})
+	}
+}
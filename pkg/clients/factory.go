@@ -17,12 +17,15 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/amp"
 	"github.com/aws/aws-sdk-go-v2/service/apigateway"
@@ -32,6 +35,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
 	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
 	"github.com/aws/aws-sdk-go-v2/service/shield"
 	"github.com/aws/aws-sdk-go-v2/service/storagegateway"
@@ -42,6 +46,8 @@ import (
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
 	cloudwatch_client "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	emconfig "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
@@ -59,13 +65,23 @@ type awsRegion = string
 type CachingFactory struct {
 	logger              *slog.Logger
 	scrapeMetrics       *promutil.ScrapeMetrics
-	stsOptions          func(*sts.Options)
+	stsOptions          map[bool]func(*sts.Options) // by FIPS endpoint use
 	clients             map[model.Role]map[awsRegion]*cachedClients
 	mu                  sync.Mutex
 	refreshed           *atomic.Bool
 	cleared             *atomic.Bool
 	fipsEnabled         bool
 	endpointURLOverride string
+	endpoints           map[string]string
+	// cloudwatchMaxBackoff caps the jittered exponential backoff between CloudWatch API retries.
+	cloudwatchMaxBackoff time.Duration
+	accountAliasSource   account.AliasSource
+	// organizationsNames is shared by the account clients of all the roles when the account alias is resolved
+	// with AWS Organizations, nil otherwise.
+	organizationsNames *account.OrganizationsNames
+
+	taggingLimitersMu sync.Mutex
+	taggingLimiters   map[model.Role]*tagging.Limiter
 }
 
 type cachedClients struct {
@@ -80,7 +96,31 @@ type cachedClients struct {
 // Ensure the struct properly implements the interface
 var _ Factory = &CachingFactory{}
 
-func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobsCfg model.JobsConfig, fips bool) (*CachingFactory, error) {
+// Enhanced metrics build their AWS clients from the factory's regional configs.
+var _ emconfig.RegionalConfigProvider = &CachingFactory{}
+
+// Option configures a CachingFactory.
+type Option func(*CachingFactory)
+
+// WithCloudwatchMaxBackoff sets the maximum delay between the retries of a CloudWatch API call, which
+// defaults to config.DefaultCloudwatchMaxBackoff. The delays are jittered, so that the clients of many
+// regions and roles throttled at the same time don't retry in lockstep. Non-positive values are ignored.
+func WithCloudwatchMaxBackoff(maxBackoff time.Duration) Option {
+	return func(c *CachingFactory) {
+		if maxBackoff > 0 {
+			c.cloudwatchMaxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithAccountAliasSource sets the API the account alias is resolved with, which defaults to the IAM account alias.
+func WithAccountAliasSource(source account.AliasSource) Option {
+	return func(c *CachingFactory) {
+		c.accountAliasSource = source
+	}
+}
+
+func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobsCfg model.JobsConfig, fips bool, opts ...Option) (*CachingFactory, error) {
 	if scrapeMetrics == nil {
 		scrapeMetrics = promutil.Discard
 	}
@@ -109,7 +149,15 @@ func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobs
 		return nil, fmt.Errorf("failed to load default aws config: %w", err)
 	}
 
-	stsOptions := createStsOptions(jobsCfg.StsRegion, logger.Enabled(context.Background(), slog.LevelDebug), endpointURLOverride, fips)
+	stsEndpoint := endpointURLOverride
+	if endpoint, ok := jobsCfg.Endpoints["sts"]; ok {
+		stsEndpoint = endpoint
+	}
+	isDebugLoggingEnabled := logger.Enabled(context.Background(), slog.LevelDebug)
+	stsOptions := map[bool]func(*sts.Options){
+		false: createStsOptions(jobsCfg.StsRegion, isDebugLoggingEnabled, stsEndpoint, false),
+		true:  createStsOptions(jobsCfg.StsRegion, isDebugLoggingEnabled, stsEndpoint, true),
+	}
 	cache := map[model.Role]map[awsRegion]*cachedClients{}
 	for _, discoveryJob := range jobsCfg.DiscoveryJobs {
 		for _, role := range discoveryJob.Roles {
@@ -117,7 +165,7 @@ func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobs
 				cache[role] = map[awsRegion]*cachedClients{}
 			}
 			for _, region := range discoveryJob.Regions {
-				regionConfig := awsConfigForRegion(role, &c, region, stsOptions)
+				regionConfig := awsConfigForRegion(role, &c, region, stsOptions[useFIPS(fips, role)])
 				cache[role][region] = &cachedClients{
 					awsConfig:  regionConfig,
 					onlyStatic: false,
@@ -134,7 +182,7 @@ func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobs
 			for _, region := range staticJob.Regions {
 				// Discovery job client definitions have precedence
 				if _, exists := cache[role][region]; !exists {
-					regionConfig := awsConfigForRegion(role, &c, region, stsOptions)
+					regionConfig := awsConfigForRegion(role, &c, region, stsOptions[useFIPS(fips, role)])
 					cache[role][region] = &cachedClients{
 						awsConfig:  regionConfig,
 						onlyStatic: true,
@@ -152,7 +200,7 @@ func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobs
 			for _, region := range customNamespaceJob.Regions {
 				// Discovery job client definitions have precedence
 				if _, exists := cache[role][region]; !exists {
-					regionConfig := awsConfigForRegion(role, &c, region, stsOptions)
+					regionConfig := awsConfigForRegion(role, &c, region, stsOptions[useFIPS(fips, role)])
 					cache[role][region] = &cachedClients{
 						awsConfig:  regionConfig,
 						onlyStatic: true,
@@ -162,16 +210,36 @@ func NewFactory(logger *slog.Logger, scrapeMetrics *promutil.ScrapeMetrics, jobs
 		}
 	}
 
-	return &CachingFactory{
-		logger:              logger,
-		scrapeMetrics:       scrapeMetrics,
-		clients:             cache,
-		fipsEnabled:         fips,
-		stsOptions:          stsOptions,
-		endpointURLOverride: endpointURLOverride,
-		cleared:             atomic.NewBool(false),
-		refreshed:           atomic.NewBool(false),
-	}, nil
+	// Lets dashboards confirm which AWS SDK the exporter runs on.
+	scrapeMetrics.ClientSDKVersionGauge.Set(1, "v2")
+
+	factory := &CachingFactory{
+		logger:               logger,
+		scrapeMetrics:        scrapeMetrics,
+		clients:              cache,
+		fipsEnabled:          fips,
+		stsOptions:           stsOptions,
+		endpointURLOverride:  endpointURLOverride,
+		endpoints:            jobsCfg.Endpoints,
+		cloudwatchMaxBackoff: config.DefaultCloudwatchMaxBackoff,
+		taggingLimiters:      map[model.Role]*tagging.Limiter{},
+		cleared:              atomic.NewBool(false),
+		refreshed:            atomic.NewBool(false),
+	}
+	for _, opt := range opts {
+		opt(factory)
+	}
+	if factory.accountAliasSource == account.AliasSourceOrganizations {
+		// The accounts are described with the credentials of the exporter rather than the ones of the roles,
+		// as only the management account and the delegated administrators of an organization can describe them.
+		organizationsConfig := c.Copy()
+		if organizationsConfig.Region == "" {
+			// AWS Organizations is a global service, the endpoint of the partition is resolved from any of its regions
+			organizationsConfig.Region = config.DefaultGlobalServiceRegion
+		}
+		factory.organizationsNames = account.NewOrganizationsNames(logger, factory.createOrganizationsClient(&organizationsConfig, fips))
+	}
+	return factory, nil
 }
 
 func (c *CachingFactory) GetCloudwatchClient(region string, role model.Role, concurrency cloudwatch_client.ConcurrencyConfig) cloudwatch_client.Client {
@@ -181,7 +249,10 @@ func (c *CachingFactory) GetCloudwatchClient(region string, role model.Role, con
 		defer c.mu.Unlock()
 	}
 
-	client := cloudwatch_client.NewClient(c.logger, c.scrapeMetrics, c.createCloudwatchClient(c.clients[role][region].awsConfig))
+	client := cloudwatch_client.NewClient(c.logger, c.scrapeMetrics, c.createCloudwatchClient(c.clients[role][region].awsConfig, c.useFIPS(role)),
+		cloudwatch_client.WithListMetricsEndpoint(c.operationEndpoint("cloudwatch.list", region)),
+		cloudwatch_client.WithDataEndpoint(c.operationEndpoint("cloudwatch.data", region)),
+	)
 	return cloudwatch_client.NewLimitedConcurrencyClient(client, concurrency.NewLimiter())
 }
 
@@ -196,15 +267,40 @@ func (c *CachingFactory) GetTaggingClient(region string, role model.Role, concur
 		c.scrapeMetrics,
 		c.createTaggingClient(c.clients[role][region].awsConfig),
 		c.createAutoScalingClient(c.clients[role][region].awsConfig),
-		c.createAPIGatewayClient(c.clients[role][region].awsConfig),
-		c.createAPIGatewayV2Client(c.clients[role][region].awsConfig),
-		c.createEC2Client(c.clients[role][region].awsConfig),
-		c.createDMSClient(c.clients[role][region].awsConfig),
+		c.createAPIGatewayClient(c.clients[role][region].awsConfig, c.useFIPS(role)),
+		c.createAPIGatewayV2Client(c.clients[role][region].awsConfig, c.useFIPS(role)),
+		c.createEC2Client(c.clients[role][region].awsConfig, c.useFIPS(role)),
+		c.createDMSClient(c.clients[role][region].awsConfig, c.useFIPS(role)),
 		c.createPrometheusClient(c.clients[role][region].awsConfig),
-		c.createStorageGatewayClient(c.clients[role][region].awsConfig),
-		c.createShieldClient(c.clients[role][region].awsConfig),
+		c.createStorageGatewayClient(c.clients[role][region].awsConfig, c.useFIPS(role)),
+		c.createShieldClient(c.clients[role][region].awsConfig, c.useFIPS(role)),
 	)
-	return tagging.NewLimitedConcurrencyClient(client, concurrencyLimit)
+	return tagging.NewLimitedClient(client, c.taggingLimiter(role, concurrencyLimit))
+}
+
+// taggingLimiter returns the limiter for the tagging clients of role. Roles configuring their
+// own tagging API concurrency or rate limit share a single limiter across all their jobs and
+// regions. Other roles get a new limiter bounded by concurrencyLimit for every client.
+func (c *CachingFactory) taggingLimiter(role model.Role, concurrencyLimit int) *tagging.Limiter {
+	if role.TaggingAPIConcurrency == 0 && role.TaggingAPIRateLimit == 0 {
+		return tagging.NewLimiter(c.scrapeMetrics, concurrencyLimit, 0)
+	}
+
+	// The tagging API has no FIPS endpoints, so FIPS and non-FIPS jobs of a role share the limiter.
+	role.UseFIPSEndpoint = aws.FIPSEndpointStateUnset
+	c.taggingLimitersMu.Lock()
+	defer c.taggingLimitersMu.Unlock()
+	if limiter, ok := c.taggingLimiters[role]; ok {
+		return limiter
+	}
+
+	maxConcurrency := role.TaggingAPIConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = concurrencyLimit
+	}
+	limiter := tagging.NewLimiter(c.scrapeMetrics, maxConcurrency, role.TaggingAPIRateLimit)
+	c.taggingLimiters[role] = limiter
+	return limiter
 }
 
 func (c *CachingFactory) GetAccountClient(region string, role model.Role) account.Client {
@@ -217,9 +313,7 @@ func (c *CachingFactory) GetAccountClient(region string, role model.Role) accoun
 		return client
 	}
 
-	stsClient := c.createStsClient(c.clients[role][region].awsConfig)
-	iamClient := c.createIAMClient(c.clients[role][region].awsConfig)
-	c.clients[role][region].account = account.NewClient(c.logger, stsClient, iamClient)
+	c.clients[role][region].account = c.createAccountClient(c.clients[role][region].awsConfig, c.useFIPS(role))
 	return c.clients[role][region].account
 }
 
@@ -234,13 +328,13 @@ func (c *CachingFactory) Refresh() {
 		return
 	}
 
-	for _, regionClients := range c.clients {
+	for role, regionClients := range c.clients {
 		for _, cache := range regionClients {
 			if cache.onlyStatic {
 				continue
 			}
 
-			cache.account = account.NewClient(c.logger, c.createStsClient(cache.awsConfig), c.createIAMClient(cache.awsConfig))
+			cache.account = c.createAccountClient(cache.awsConfig, c.useFIPS(role))
 		}
 	}
 
@@ -275,22 +369,23 @@ func (c *CachingFactory) GetAWSRegionalConfig(region string, role model.Role) *a
 	return c.clients[role][region].awsConfig
 }
 
-func (c *CachingFactory) createCloudwatchClient(regionConfig *aws.Config) *cloudwatch.Client {
+func (c *CachingFactory) createCloudwatchClient(regionConfig *aws.Config, fips bool) *cloudwatch.Client {
 	return cloudwatch.NewFromConfig(*regionConfig, func(options *cloudwatch.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("cloudwatch", regionConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
 
-		// Setting an explicit retryer will override the default settings on the config
+		// Setting an explicit retryer will override the default settings on the config.
+		// The standard retryer uses an exponential backoff with jitter, capped to MaxBackoff.
 		options.Retryer = retry.NewStandard(func(options *retry.StandardOptions) {
 			options.MaxAttempts = 5
-			options.MaxBackoff = 3 * time.Second
+			options.MaxBackoff = c.cloudwatchMaxBackoff
 		})
 
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
@@ -301,8 +396,8 @@ func (c *CachingFactory) createTaggingClient(regionConfig *aws.Config) *resource
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("tagging", regionConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
 		// The FIPS setting is ignored because FIPS is not available for resource groups tagging apis
 		// If enabled the SDK will try to use non-existent FIPS URLs, https://github.com/aws/aws-sdk-go-v2/issues/2138#issuecomment-1570791988
@@ -315,8 +410,8 @@ func (c *CachingFactory) createAutoScalingClient(assumedConfig *aws.Config) *aut
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("autoscaling", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
 		// The FIPS setting is ignored because FIPS is not available for EC2 autoscaling apis
 		// If enabled the SDK will try to use non-existent FIPS URLs, https://github.com/aws/aws-sdk-go-v2/issues/2138#issuecomment-1570791988
@@ -326,71 +421,71 @@ func (c *CachingFactory) createAutoScalingClient(assumedConfig *aws.Config) *aut
 	})
 }
 
-func (c *CachingFactory) createAPIGatewayClient(assumedConfig *aws.Config) *apigateway.Client {
+func (c *CachingFactory) createAPIGatewayClient(assumedConfig *aws.Config, fips bool) *apigateway.Client {
 	return apigateway.NewFromConfig(*assumedConfig, func(options *apigateway.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("apigateway", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
 }
 
-func (c *CachingFactory) createAPIGatewayV2Client(assumedConfig *aws.Config) *apigatewayv2.Client {
+func (c *CachingFactory) createAPIGatewayV2Client(assumedConfig *aws.Config, fips bool) *apigatewayv2.Client {
 	return apigatewayv2.NewFromConfig(*assumedConfig, func(options *apigatewayv2.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("apigatewayv2", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
 }
 
-func (c *CachingFactory) createEC2Client(assumedConfig *aws.Config) *ec2.Client {
+func (c *CachingFactory) createEC2Client(assumedConfig *aws.Config, fips bool) *ec2.Client {
 	return ec2.NewFromConfig(*assumedConfig, func(options *ec2.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("ec2", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
 }
 
-func (c *CachingFactory) createDMSClient(assumedConfig *aws.Config) *databasemigrationservice.Client {
+func (c *CachingFactory) createDMSClient(assumedConfig *aws.Config, fips bool) *databasemigrationservice.Client {
 	return databasemigrationservice.NewFromConfig(*assumedConfig, func(options *databasemigrationservice.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("dms", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
 }
 
-func (c *CachingFactory) createStorageGatewayClient(assumedConfig *aws.Config) *storagegateway.Client {
+func (c *CachingFactory) createStorageGatewayClient(assumedConfig *aws.Config, fips bool) *storagegateway.Client {
 	return storagegateway.NewFromConfig(*assumedConfig, func(options *storagegateway.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("storagegateway", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
@@ -401,8 +496,8 @@ func (c *CachingFactory) createPrometheusClient(assumedConfig *aws.Config) *amp.
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("amp", assumedConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
 		// The FIPS setting is ignored because FIPS is not available for amp apis
 		// If enabled the SDK will try to use non-existent FIPS URLs, https://github.com/aws/aws-sdk-go-v2/issues/2138#issuecomment-1570791988
@@ -410,23 +505,62 @@ func (c *CachingFactory) createPrometheusClient(assumedConfig *aws.Config) *amp.
 	})
 }
 
-func (c *CachingFactory) createStsClient(awsConfig *aws.Config) *sts.Client {
-	return sts.NewFromConfig(*awsConfig, c.stsOptions)
+func (c *CachingFactory) createAccountClient(awsConfig *aws.Config, fips bool) account.Client {
+	var opts []account.Option
+	if c.organizationsNames != nil {
+		opts = append(opts, account.WithOrganizationsNames(c.organizationsNames))
+	}
+	return account.NewClient(c.logger, c.createStsClient(awsConfig, fips), c.createIAMClient(awsConfig), opts...)
+}
+
+func (c *CachingFactory) createStsClient(awsConfig *aws.Config, fips bool) *sts.Client {
+	return sts.NewFromConfig(*awsConfig, c.stsOptions[fips])
 }
 
 func (c *CachingFactory) createIAMClient(awsConfig *aws.Config) *iam.Client {
-	return iam.NewFromConfig(*awsConfig)
+	return iam.NewFromConfig(*awsConfig, func(options *iam.Options) {
+		if endpoint := c.baseEndpoint("iam", awsConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+}
+
+func (c *CachingFactory) createOrganizationsClient(awsConfig *aws.Config, fips bool) *organizations.Client {
+	return organizations.NewFromConfig(*awsConfig, func(options *organizations.Options) {
+		if endpoint := c.baseEndpoint("organizations", awsConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
+		}
+		if fips {
+			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
+	})
+}
+
+// baseEndpoint returns the endpoint configured for service, falling back to AWS_ENDPOINT_URL.
+// A "{region}" placeholder in the endpoint is replaced with region.
+func (c *CachingFactory) baseEndpoint(service string, region string) string {
+	endpoint := c.endpointURLOverride
+	if override, ok := c.endpoints[service]; ok {
+		endpoint = override
+	}
+	return strings.ReplaceAll(endpoint, "{region}", region)
+}
+
+// operationEndpoint returns the endpoint configured for a subset of the operations of a service, or an empty
+// string when the operations use the endpoint of the service. A "{region}" placeholder in the endpoint is replaced with region.
+func (c *CachingFactory) operationEndpoint(operations string, region string) string {
+	return strings.ReplaceAll(c.endpoints[operations], "{region}", region)
 }
 
-func (c *CachingFactory) createShieldClient(awsConfig *aws.Config) *shield.Client {
+func (c *CachingFactory) createShieldClient(awsConfig *aws.Config, fips bool) *shield.Client {
 	return shield.NewFromConfig(*awsConfig, func(options *shield.Options) {
 		if c.logger != nil && c.logger.Enabled(context.Background(), slog.LevelDebug) {
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
-		if c.endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(c.endpointURLOverride)
+		if endpoint := c.baseEndpoint("shield", awsConfig.Region); endpoint != "" {
+			options.BaseEndpoint = aws.String(endpoint)
 		}
-		if c.fipsEnabled {
+		if fips {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
 		}
 	})
@@ -441,7 +575,7 @@ func createStsOptions(stsRegion string, isDebugLoggingEnabled bool, endpointURLO
 			options.ClientLogMode = aws.LogRequestWithBody | aws.LogResponseWithBody
 		}
 		if endpointURLOverride != "" {
-			options.BaseEndpoint = aws.String(endpointURLOverride)
+			options.BaseEndpoint = aws.String(strings.ReplaceAll(endpointURLOverride, "{region}", options.Region))
 		}
 		if fipsEnabled {
 			options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
@@ -449,8 +583,35 @@ func createStsOptions(stsRegion string, isDebugLoggingEnabled bool, endpointURLO
 	}
 }
 
+// useFIPS returns whether the clients of role use FIPS endpoints, which the jobs of the role can
+// enable or disable regardless of the global fips setting.
+func useFIPS(fips bool, role model.Role) bool {
+	switch role.UseFIPSEndpoint {
+	case aws.FIPSEndpointStateEnabled:
+		return true
+	case aws.FIPSEndpointStateDisabled:
+		return false
+	default:
+		return fips
+	}
+}
+
+func (c *CachingFactory) useFIPS(role model.Role) bool {
+	return useFIPS(c.fipsEnabled, role)
+}
+
 var defaultRole = model.Role{}
 
+// assumeRoleOptions returns the options assuming the RoleArn of the role in region. The external ID is only sent when
+// it's set, as STS rejects an empty one.
+func assumeRoleOptions(r model.Role, region awsRegion) func(*stscreds.AssumeRoleOptions) {
+	return func(options *stscreds.AssumeRoleOptions) {
+		if externalID := r.ExternalIDForRegion(region); externalID != "" {
+			options.ExternalID = aws.String(externalID)
+		}
+	}
+}
+
 func awsConfigForRegion(r model.Role, c *aws.Config, region awsRegion, stsOptions func(*sts.Options)) *aws.Config {
 	regionalConfig := c.Copy()
 	regionalConfig.Region = region
@@ -459,15 +620,81 @@ func awsConfigForRegion(r model.Role, c *aws.Config, region awsRegion, stsOption
 		return &regionalConfig
 	}
 
+	// Credentials minted by an external process act as the source credentials for the role.
+	// When no role ARN is configured they are used as-is, otherwise they are used to assume the role.
+	sourceConfig := c.Copy()
+	if r.CredentialProcess != "" {
+		sourceConfig.Credentials = aws.NewCredentialsCache(processcreds.NewProvider(r.CredentialProcess))
+	}
+
+	// With a source role the chain is source credentials -> SourceRoleArn -> RoleArn
+	if r.SourceRoleArn != "" && r.RoleArn != "" {
+		sourceSts := sts.NewFromConfig(sourceConfig, stsOptions)
+		sourceConfig.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sourceSts, r.SourceRoleArn))
+	}
+
+	if r.RoleArn == "" {
+		regionalConfig.Credentials = sourceConfig.Credentials
+		return &regionalConfig
+	}
+
 	// based on https://pkg.go.dev/github.com/aws/aws-sdk-go-v2/credentials/stscreds#hdr-Assume_Role
 	// found via https://github.com/aws/aws-sdk-go-v2/issues/1382
-	regionalSts := sts.NewFromConfig(*c, stsOptions)
-	credentials := stscreds.NewAssumeRoleProvider(regionalSts, r.RoleArn, func(options *stscreds.AssumeRoleOptions) {
-		if r.ExternalID != "" {
-			options.ExternalID = aws.String(r.ExternalID)
-		}
-	})
+	regionalSts := sts.NewFromConfig(sourceConfig, stsOptions)
+	credentials := stscreds.NewAssumeRoleProvider(regionalSts, r.RoleArn, assumeRoleOptions(r, region))
 	regionalConfig.Credentials = aws.NewCredentialsCache(credentials)
 
+	if r.UseCurrentCredentialsForSameAccount {
+		if roleArn, err := arn.Parse(r.RoleArn); err == nil {
+			regionalConfig.Credentials = aws.NewCredentialsCache(&sameAccountCredentialsProvider{
+				roleAccountID: roleArn.AccountID,
+				current:       sourceConfig.Credentials,
+				assumeRole:    credentials,
+				callerAccountID: func(ctx context.Context) (string, error) {
+					output, err := regionalSts.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+					if err != nil {
+						return "", err
+					}
+					return aws.ToString(output.Account), nil
+				},
+			})
+		}
+	}
+
 	return &regionalConfig
 }
+
+// sameAccountCredentialsProvider uses the current credentials as-is when they belong to the
+// same account as the role, and assumes the role otherwise. If the account of the current
+// credentials cannot be determined the role is assumed.
+type sameAccountCredentialsProvider struct {
+	roleAccountID   string
+	current         aws.CredentialsProvider
+	assumeRole      aws.CredentialsProvider
+	callerAccountID func(ctx context.Context) (string, error)
+
+	mu          sync.Mutex
+	sameAccount *bool
+}
+
+func (p *sameAccountCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	if p.isSameAccount(ctx) {
+		return p.current.Retrieve(ctx)
+	}
+	return p.assumeRole.Retrieve(ctx)
+}
+
+func (p *sameAccountCredentialsProvider) isSameAccount(ctx context.Context) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.sameAccount == nil {
+		accountID, err := p.callerAccountID(ctx)
+		if err != nil {
+			// Retry on the next retrieval rather than caching the failure.
+			return false
+		}
+		p.sameAccount = aws.Bool(accountID == p.roleAccountID)
+	}
+	return *p.sameAccount
+}
@@ -14,29 +14,47 @@ package clients
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 	"unsafe"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/amp"
 	"github.com/aws/aws-sdk-go-v2/service/apigateway"
 	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
 	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/databasemigrationservice"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/shield"
 	"github.com/aws/aws-sdk-go-v2/service/storagegateway"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
 	cloudwatch_client "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
@@ -156,6 +174,27 @@ func TestNewFactory_initializes_clients(t *testing.T) {
 	}
 }
 
+func TestNewFactory_SetsClientSDKVersion(t *testing.T) {
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+
+	_, err := NewFactory(promslog.NewNopLogger(), scrapeMetrics, jobsCfgWithDefaultRoleAndRegion1, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(scrapeMetrics.ClientSDKVersionGauge.Raw().WithLabelValues("v2")))
+	assert.Equal(t, 1, testutil.CollectAndCount(scrapeMetrics.ClientSDKVersionGauge.Raw()))
+}
+
+func TestNewFactory_OrganizationsAccountAliasSource(t *testing.T) {
+	output, err := NewFactory(promslog.NewNopLogger(), nil, jobsCfgWithDefaultRoleAndRegion1, false)
+	require.NoError(t, err)
+	assert.Nil(t, output.organizationsNames)
+
+	// The account names are resolved once for all the roles and regions, with the credentials of the exporter
+	output, err = NewFactory(promslog.NewNopLogger(), nil, jobsCfgWithDefaultRoleAndRegion1, false, WithAccountAliasSource(account.AliasSourceOrganizations))
+	require.NoError(t, err)
+	assert.NotNil(t, output.organizationsNames)
+}
+
 func TestNewFactory_respects_stsregion(t *testing.T) {
 	stsRegion := "custom-sts-region"
 	cfg := model.JobsConfig{
@@ -170,10 +209,212 @@ func TestNewFactory_respects_stsregion(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, output.clients, 1)
 	stsOptions := sts.Options{}
-	output.stsOptions(&stsOptions)
+	output.stsOptions[false](&stsOptions)
 	assert.Equal(t, stsRegion, stsOptions.Region)
 }
 
+func TestAwsConfigForRegion_CredentialProcess(t *testing.T) {
+	baseConfig := aws.Config{Region: "base-region"}
+	credentialProcess := `echo '{"Version": 1, "AccessKeyId": "process-access-key", "SecretAccessKey": "process-secret-key"}'`
+
+	t.Run("uses process credentials when no role arn is set", func(t *testing.T) {
+		role := model.Role{CredentialProcess: credentialProcess}
+
+		regionalConfig := awsConfigForRegion(role, &baseConfig, "region1", createStsOptions("", false, "", false))
+		require.Equal(t, "region1", regionalConfig.Region)
+
+		cache, ok := regionalConfig.Credentials.(*aws.CredentialsCache)
+		require.True(t, ok)
+		assert.True(t, cache.IsCredentialsProvider(&processcreds.Provider{}))
+
+		creds, err := regionalConfig.Credentials.Retrieve(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "process-access-key", creds.AccessKeyID)
+		assert.Equal(t, "process-secret-key", creds.SecretAccessKey)
+	})
+
+	t.Run("assumes role with process credentials as source", func(t *testing.T) {
+		role := model.Role{RoleArn: "role1", CredentialProcess: credentialProcess}
+
+		regionalConfig := awsConfigForRegion(role, &baseConfig, "region1", createStsOptions("", false, "", false))
+
+		cache, ok := regionalConfig.Credentials.(*aws.CredentialsCache)
+		require.True(t, ok)
+		assert.True(t, cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}))
+		assert.False(t, cache.IsCredentialsProvider(&processcreds.Provider{}))
+	})
+}
+
+func TestAwsConfigForRegion_SourceRoleArn(t *testing.T) {
+	var mu sync.Mutex
+	var assumed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		roleArn := r.PostForm.Get("RoleArn")
+		mu.Lock()
+		// The access key signing the request shows which credentials were used to assume the role
+		assumed = append(assumed, fmt.Sprintf("%s by %s", roleArn, strings.Split(strings.Split(r.Header.Get("Authorization"), "Credential=")[1], "/")[0]))
+		mu.Unlock()
+
+		fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+<Credentials><AccessKeyId>%s</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials>
+<AssumedRoleUser><Arn>%s</Arn><AssumedRoleId>id</AssumedRoleId></AssumedRoleUser>
+</AssumeRoleResult></AssumeRoleResponse>`, path.Base(roleArn), roleArn)
+	}))
+	defer server.Close()
+
+	baseConfig := aws.Config{
+		Region:      "base-region",
+		Credentials: credentials.NewStaticCredentialsProvider("base", "secret", ""),
+	}
+	role := model.Role{
+		SourceRoleArn: "arn:aws:iam::111111111111:role/Hub",
+		RoleArn:       "arn:aws:iam::222222222222:role/Prometheus",
+	}
+	regionalConfig := awsConfigForRegion(role, &baseConfig, "region1", createStsOptions("us-east-1", false, server.URL, false))
+
+	cache, ok := regionalConfig.Credentials.(*aws.CredentialsCache)
+	require.True(t, ok)
+	assert.True(t, cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}))
+
+	creds, err := regionalConfig.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Prometheus", creds.AccessKeyID)
+	assert.Equal(t, []string{
+		"arn:aws:iam::111111111111:role/Hub by base",
+		"arn:aws:iam::222222222222:role/Prometheus by Hub",
+	}, assumed)
+}
+
+func TestAssumeRoleOptions(t *testing.T) {
+	byRegion := model.NewRegionalExternalIDs(map[string]string{"eu-west-1": "external-eu", "us-east-1": "external-us"})
+	for _, tc := range []struct {
+		name               string
+		externalID         string
+		externalIDByRegion model.RegionalExternalIDs
+		region             string
+		want               *string
+	}{
+		{name: "empty external id", externalID: "", region: "us-east-1", want: nil},
+		{name: "external id", externalID: "external1", region: "us-east-1", want: aws.String("external1")},
+		{name: "external id of the region", externalID: "external1", externalIDByRegion: byRegion, region: "eu-west-1", want: aws.String("external-eu")},
+		{name: "external id of another region", externalID: "external1", externalIDByRegion: byRegion, region: "us-east-1", want: aws.String("external-us")},
+		{name: "falls back to the external id", externalID: "external1", externalIDByRegion: byRegion, region: "ap-south-1", want: aws.String("external1")},
+		{name: "no fallback", externalIDByRegion: byRegion, region: "ap-south-1", want: nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			options := stscreds.AssumeRoleOptions{}
+			role := model.Role{RoleArn: "arn:aws:iam::123456789012:role/Prometheus", ExternalID: tc.externalID, ExternalIDByRegion: tc.externalIDByRegion}
+			assumeRoleOptions(role, tc.region)(&options)
+			assert.Equal(t, tc.want, options.ExternalID)
+		})
+	}
+}
+
+func TestAwsConfigForRegion_ExternalID(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		externalID         string
+		externalIDByRegion model.RegionalExternalIDs
+		want               string
+	}{
+		{name: "empty external id", externalID: ""},
+		{name: "external id", externalID: "external1", want: "external1"},
+		{
+			name:               "external id of the region",
+			externalID:         "external1",
+			externalIDByRegion: model.NewRegionalExternalIDs(map[string]string{"region1": "external-region1", "region2": "external-region2"}),
+			want:               "external-region1",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var form url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				form = r.PostForm
+				fmt.Fprint(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+<Credentials><AccessKeyId>Prometheus</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials>
+<AssumedRoleUser><Arn>arn:aws:iam::123456789012:role/Prometheus</Arn><AssumedRoleId>id</AssumedRoleId></AssumedRoleUser>
+</AssumeRoleResult></AssumeRoleResponse>`)
+			}))
+			defer server.Close()
+
+			baseConfig := aws.Config{
+				Region:      "base-region",
+				Credentials: credentials.NewStaticCredentialsProvider("base", "secret", ""),
+			}
+			role := model.Role{RoleArn: "arn:aws:iam::123456789012:role/Prometheus", ExternalID: tc.externalID, ExternalIDByRegion: tc.externalIDByRegion}
+			regionalConfig := awsConfigForRegion(role, &baseConfig, "region1", createStsOptions("us-east-1", false, server.URL, false))
+
+			_, err := regionalConfig.Credentials.Retrieve(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, role.RoleArn, form.Get("RoleArn"))
+			if tc.want == "" {
+				assert.NotContains(t, form, "ExternalId")
+			} else {
+				assert.Equal(t, tc.want, form.Get("ExternalId"))
+			}
+		})
+	}
+}
+
+func TestAwsConfigForRegion_UseCurrentCredentialsForSameAccount(t *testing.T) {
+	baseConfig := aws.Config{Region: "base-region"}
+
+	role := model.Role{RoleArn: "arn:aws:iam::123456789012:role/Prometheus", UseCurrentCredentialsForSameAccount: true}
+	regionalConfig := awsConfigForRegion(role, &baseConfig, "region1", createStsOptions("", false, "", false))
+
+	cache, ok := regionalConfig.Credentials.(*aws.CredentialsCache)
+	require.True(t, ok)
+	assert.True(t, cache.IsCredentialsProvider(&sameAccountCredentialsProvider{}))
+}
+
+func TestSameAccountCredentialsProvider(t *testing.T) {
+	newProvider := func(callerAccountID string, callerErr error) (*sameAccountCredentialsProvider, *int) {
+		assumeRoleCalls := 0
+		return &sameAccountCredentialsProvider{
+			roleAccountID: "123456789012",
+			current: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+				return aws.Credentials{AccessKeyID: "current"}, nil
+			}),
+			assumeRole: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+				assumeRoleCalls++
+				return aws.Credentials{AccessKeyID: "assumed"}, nil
+			}),
+			callerAccountID: func(context.Context) (string, error) {
+				return callerAccountID, callerErr
+			},
+		}, &assumeRoleCalls
+	}
+
+	t.Run("skips assume role for the same account", func(t *testing.T) {
+		provider, assumeRoleCalls := newProvider("123456789012", nil)
+
+		creds, err := provider.Retrieve(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "current", creds.AccessKeyID)
+		assert.Equal(t, 0, *assumeRoleCalls)
+	})
+
+	t.Run("assumes role for another account", func(t *testing.T) {
+		provider, assumeRoleCalls := newProvider("210987654321", nil)
+
+		creds, err := provider.Retrieve(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "assumed", creds.AccessKeyID)
+		assert.Equal(t, 1, *assumeRoleCalls)
+	})
+
+	t.Run("assumes role when the caller account is unknown", func(t *testing.T) {
+		provider, assumeRoleCalls := newProvider("", errors.New("access denied"))
+
+		creds, err := provider.Retrieve(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "assumed", creds.AccessKeyID)
+		assert.Equal(t, 1, *assumeRoleCalls)
+	})
+}
+
 func TestCachingFactory_Clear(t *testing.T) {
 	cache := &CachingFactory{
 		logger: promslog.NewNopLogger(),
@@ -350,6 +591,42 @@ func TestCachingFactory_GetTaggingClient(t *testing.T) {
 
 		assert.NotNil(t, output.GetTaggingClient("region1", defaultRole, 1))
 	})
+
+	t.Run("roles with their own limits get distinct limiters", func(t *testing.T) {
+		account1 := model.Role{RoleArn: "arn:aws:iam::111111111111:role/yace", TaggingAPIConcurrency: 2, TaggingAPIRateLimit: 0.5}
+		account2 := model.Role{RoleArn: "arn:aws:iam::222222222222:role/yace", TaggingAPIRateLimit: 10}
+		jobsCfg := model.JobsConfig{
+			DiscoveryJobs: []model.DiscoveryJob{{
+				Roles:   []model.Role{account1, account2, defaultRole},
+				Regions: []string{"region1", "region2"},
+			}},
+		}
+
+		output, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfg, false)
+		require.NoError(t, err)
+		output.Refresh()
+
+		for _, role := range []model.Role{account1, account2, defaultRole} {
+			for _, region := range []string{"region1", "region2"} {
+				require.NotNil(t, output.GetTaggingClient(region, role, 5))
+			}
+		}
+
+		// Only roles with their own limits keep a limiter, shared by all their regions.
+		require.Len(t, output.taggingLimiters, 2)
+		limiter1, limiter2 := output.taggingLimiter(account1, 5), output.taggingLimiter(account2, 5)
+		assert.NotSame(t, limiter1, limiter2)
+		assert.Same(t, output.taggingLimiters[account1], limiter1)
+		assert.Equal(t, 2, limiter1.MaxConcurrency())
+		assert.Equal(t, 0.5, limiter1.RequestsPerSecond())
+		// The global concurrency limit applies when a role only sets a rate limit.
+		assert.Equal(t, 5, limiter2.MaxConcurrency())
+		assert.Equal(t, float64(10), limiter2.RequestsPerSecond())
+
+		defaultLimiter := output.taggingLimiter(defaultRole, 5)
+		assert.Equal(t, 5, defaultLimiter.MaxConcurrency())
+		assert.Zero(t, defaultLimiter.RequestsPerSecond())
+	})
 }
 
 func TestCachingFactory_createTaggingClient_DoesNotEnableFIPS(t *testing.T) {
@@ -369,7 +646,7 @@ func TestCachingFactory_createAPIGatewayClient_EnablesFIPS(t *testing.T) {
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, true)
 	require.NoError(t, err)
 
-	client := factory.createAPIGatewayClient(factory.clients[defaultRole]["region1"].awsConfig)
+	client := factory.createAPIGatewayClient(factory.clients[defaultRole]["region1"].awsConfig, factory.useFIPS(defaultRole))
 	require.NotNil(t, client)
 
 	options := getOptions[apigateway.Client, apigateway.Options](client)
@@ -382,7 +659,7 @@ func TestCachingFactory_createAPIGatewayV2Client_EnablesFIPS(t *testing.T) {
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, true)
 	require.NoError(t, err)
 
-	client := factory.createAPIGatewayV2Client(factory.clients[defaultRole]["region1"].awsConfig)
+	client := factory.createAPIGatewayV2Client(factory.clients[defaultRole]["region1"].awsConfig, factory.useFIPS(defaultRole))
 	require.NotNil(t, client)
 
 	options := getOptions[apigatewayv2.Client, apigatewayv2.Options](client)
@@ -408,7 +685,7 @@ func TestCachingFactory_createEC2Client_EnablesFIPS(t *testing.T) {
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, true)
 	require.NoError(t, err)
 
-	client := factory.createEC2Client(factory.clients[defaultRole]["region1"].awsConfig)
+	client := factory.createEC2Client(factory.clients[defaultRole]["region1"].awsConfig, factory.useFIPS(defaultRole))
 	require.NotNil(t, client)
 
 	options := getOptions[ec2.Client, ec2.Options](client)
@@ -421,7 +698,7 @@ func TestCachingFactory_createDMSClient_EnablesFIPS(t *testing.T) {
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, true)
 	require.NoError(t, err)
 
-	client := factory.createDMSClient(factory.clients[defaultRole]["region1"].awsConfig)
+	client := factory.createDMSClient(factory.clients[defaultRole]["region1"].awsConfig, factory.useFIPS(defaultRole))
 	require.NotNil(t, client)
 
 	options := getOptions[databasemigrationservice.Client, databasemigrationservice.Options](client)
@@ -434,7 +711,7 @@ func TestCachingFactory_createStorageGatewayClient_EnablesFIPS(t *testing.T) {
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, true)
 	require.NoError(t, err)
 
-	client := factory.createStorageGatewayClient(factory.clients[defaultRole]["region1"].awsConfig)
+	client := factory.createStorageGatewayClient(factory.clients[defaultRole]["region1"].awsConfig, factory.useFIPS(defaultRole))
 	require.NotNil(t, client)
 
 	options := getOptions[storagegateway.Client, storagegateway.Options](client)
@@ -456,6 +733,43 @@ func TestCachingFactory_createPrometheusClient_DoesNotEnableFIPS(t *testing.T) {
 	assert.Equal(t, options.EndpointOptions.UseFIPSEndpoint, aws.FIPSEndpointStateUnset)
 }
 
+func TestCachingFactory_JobLevelFIPS(t *testing.T) {
+	fipsRole := model.Role{UseFIPSEndpoint: aws.FIPSEndpointStateEnabled}
+	nonFIPSRole := model.Role{UseFIPSEndpoint: aws.FIPSEndpointStateDisabled}
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{Roles: []model.Role{fipsRole}, Regions: []string{"us-gov-west-1"}},
+			{Roles: []model.Role{nonFIPSRole}, Regions: []string{"us-gov-west-1"}},
+			{Roles: []model.Role{defaultRole}, Regions: []string{"us-gov-west-1"}},
+		},
+	}
+
+	for _, globalFIPS := range []bool{false, true} {
+		factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfg, globalFIPS)
+		require.NoError(t, err)
+		// The jobs get distinct clients for the same role ARN and region
+		require.Len(t, factory.clients, 3)
+
+		for role, expected := range map[model.Role]aws.FIPSEndpointState{
+			fipsRole:    aws.FIPSEndpointStateEnabled,
+			nonFIPSRole: aws.FIPSEndpointStateUnset,
+			defaultRole: map[bool]aws.FIPSEndpointState{false: aws.FIPSEndpointStateUnset, true: aws.FIPSEndpointStateEnabled}[globalFIPS],
+		} {
+			cfg := factory.clients[role]["us-gov-west-1"].awsConfig
+			fips := factory.useFIPS(role)
+			assert.Equal(t, expected, getOptions[ec2.Client, ec2.Options](factory.createEC2Client(cfg, fips)).EndpointOptions.UseFIPSEndpoint)
+			assert.Equal(t, expected, getOptions[cloudwatch.Client, cloudwatch.Options](factory.createCloudwatchClient(cfg, fips)).EndpointOptions.UseFIPSEndpoint)
+			assert.Equal(t, expected, getOptions[sts.Client, sts.Options](factory.createStsClient(cfg, fips)).EndpointOptions.UseFIPSEndpoint)
+		}
+
+		// The tagging API has no FIPS endpoints, the FIPS and non-FIPS jobs of a role share its limiter
+		limitedRole := model.Role{RoleArn: "arn:aws:iam::111111111111:role/yace", TaggingAPIRateLimit: 1}
+		fipsLimitedRole := limitedRole
+		fipsLimitedRole.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		assert.Same(t, factory.taggingLimiter(limitedRole, 5), factory.taggingLimiter(fipsLimitedRole, 5))
+	}
+}
+
 func TestRaceConditionRefreshClear(t *testing.T) {
 	// Create a factory with the test config
 	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), model.JobsConfig{}, false)
@@ -494,6 +808,101 @@ func TestRaceConditionRefreshClear(t *testing.T) {
 	}
 }
 
+func TestCachingFactory_UsesConfiguredEndpoints(t *testing.T) {
+	t.Setenv("AWS_ENDPOINT_URL", "https://fallback.example.com")
+
+	endpoints := make(map[string]string, len(model.EndpointServices))
+	for _, service := range model.EndpointServices {
+		if service == "shield" {
+			// shield falls back to AWS_ENDPOINT_URL
+			continue
+		}
+		endpoints[service] = fmt.Sprintf("https://vpce-1.%s.{region}.vpce.amazonaws.com", service)
+	}
+	jobsCfg := model.JobsConfig{
+		Endpoints:     endpoints,
+		DiscoveryJobs: jobsCfgWithDefaultRoleAndRegion1.DiscoveryJobs,
+	}
+
+	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfg, false)
+	require.NoError(t, err)
+	cfg := factory.clients[defaultRole]["region1"].awsConfig
+
+	expected := func(service string) string {
+		return fmt.Sprintf("https://vpce-1.%s.region1.vpce.amazonaws.com", service)
+	}
+
+	assert.Equal(t, expected("cloudwatch"), aws.ToString(getOptions[cloudwatch.Client, cloudwatch.Options](factory.createCloudwatchClient(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("tagging"), aws.ToString(getOptions[resourcegroupstaggingapi.Client, resourcegroupstaggingapi.Options](factory.createTaggingClient(cfg)).BaseEndpoint))
+	assert.Equal(t, expected("sts"), aws.ToString(getOptions[sts.Client, sts.Options](factory.createStsClient(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("iam"), aws.ToString(getOptions[iam.Client, iam.Options](factory.createIAMClient(cfg)).BaseEndpoint))
+	assert.Equal(t, expected("autoscaling"), aws.ToString(getOptions[autoscaling.Client, autoscaling.Options](factory.createAutoScalingClient(cfg)).BaseEndpoint))
+	assert.Equal(t, expected("apigateway"), aws.ToString(getOptions[apigateway.Client, apigateway.Options](factory.createAPIGatewayClient(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("apigatewayv2"), aws.ToString(getOptions[apigatewayv2.Client, apigatewayv2.Options](factory.createAPIGatewayV2Client(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("ec2"), aws.ToString(getOptions[ec2.Client, ec2.Options](factory.createEC2Client(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("dms"), aws.ToString(getOptions[databasemigrationservice.Client, databasemigrationservice.Options](factory.createDMSClient(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("storagegateway"), aws.ToString(getOptions[storagegateway.Client, storagegateway.Options](factory.createStorageGatewayClient(cfg, false)).BaseEndpoint))
+	assert.Equal(t, expected("amp"), aws.ToString(getOptions[amp.Client, amp.Options](factory.createPrometheusClient(cfg)).BaseEndpoint))
+	assert.Equal(t, "https://fallback.example.com", aws.ToString(getOptions[shield.Client, shield.Options](factory.createShieldClient(cfg, false)).BaseEndpoint))
+}
+
+func TestCachingFactory_operationEndpoint(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		Endpoints: map[string]string{
+			"cloudwatch":      "https://monitoring.example.com",
+			"cloudwatch.list": "https://list.{region}.example.com",
+		},
+		DiscoveryJobs: jobsCfgWithDefaultRoleAndRegion1.DiscoveryJobs,
+	}
+	factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfg, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://list.region1.example.com", factory.operationEndpoint("cloudwatch.list", "region1"))
+	// The data operations use the endpoint of the cloudwatch client.
+	assert.Empty(t, factory.operationEndpoint("cloudwatch.data", "region1"))
+}
+
+func TestCachingFactory_CloudwatchRetryer(t *testing.T) {
+	for _, tc := range []struct {
+		name               string
+		opts               []Option
+		expectedMaxBackoff time.Duration
+	}{
+		{
+			name:               "default",
+			expectedMaxBackoff: config.DefaultCloudwatchMaxBackoff,
+		},
+		{
+			name:               "configured max backoff",
+			opts:               []Option{WithCloudwatchMaxBackoff(10 * time.Second)},
+			expectedMaxBackoff: 10 * time.Second,
+		},
+		{
+			name:               "non-positive max backoff is ignored",
+			opts:               []Option{WithCloudwatchMaxBackoff(0)},
+			expectedMaxBackoff: config.DefaultCloudwatchMaxBackoff,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			factory, err := NewFactory(promslog.NewNopLogger(), promutil.NewScrapeMetrics(nil), jobsCfgWithDefaultRoleAndRegion1, false, tc.opts...)
+			require.NoError(t, err)
+
+			client := factory.createCloudwatchClient(factory.clients[defaultRole]["region1"].awsConfig, false)
+			// The retryer is wrapped by the client to apply the max attempts of the aws config
+			wrapped := reflect.ValueOf(getOptions[cloudwatch.Client, cloudwatch.Options](client).Retryer).Elem()
+			retryer, ok := wrapped.FieldByName("RetryerV2").Interface().(*retry.Standard)
+			require.True(t, ok)
+
+			retryerOptions := getOptions[retry.Standard, retry.StandardOptions](retryer)
+			assert.Equal(t, 5, retryerOptions.MaxAttempts)
+			assert.Equal(t, tc.expectedMaxBackoff, retryerOptions.MaxBackoff)
+			// The delays between the retries are jittered
+			backoff := reflect.ValueOf(retryer).Elem().FieldByName("backoff").Elem()
+			assert.Equal(t, reflect.TypeOf(&retry.ExponentialJitterBackoff{}), backoff.Type())
+		})
+	}
+}
+
 // getOptions uses reflection to pull the unexported options field off of any AWS Client
 // the options of the client carries around a lot of info about how the client will behave and is helpful for
 // testing lower level sdk configuration
@@ -517,7 +926,7 @@ func (t testClient) GetAccountAlias(_ context.Context) (string, error) {
 	return "", nil
 }
 
-func (t testClient) ListMetrics(_ context.Context, _ string, _ *model.MetricConfig, _ bool, _ func(page []*model.Metric)) error {
+func (t testClient) ListMetrics(_ context.Context, _ string, _ *model.MetricConfig, _ bool, _ bool, _ func(page []*model.Metric)) error {
 	return nil
 }
 
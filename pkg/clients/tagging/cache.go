@@ -0,0 +1,168 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tagging
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// ResourceCache shares the resources discovered by the jobs of a single scrape, so that jobs discovering the same
+// namespace with the same search tags, in the same region and with the same role, only call the tagging APIs once.
+// The first job to run a discovery does it, the others wait for its result. A ResourceCache must not be reused
+// across scrapes.
+//
+// Only the discoveries expected to be shared are buffered: the others are handed over page by page by the clients
+// supporting it, see Expect.
+type ResourceCache struct {
+	mu       sync.Mutex
+	entries  map[resourceCacheKey]*resourceCacheEntry
+	expected map[resourceCacheKey]int
+}
+
+type resourceCacheKey struct {
+	region    string
+	role      model.Role
+	namespace string
+	tagFilter uint64
+}
+
+func newResourceCacheKey(job model.DiscoveryJob, region string, role model.Role) resourceCacheKey {
+	return resourceCacheKey{
+		region:    region,
+		role:      role,
+		namespace: job.Namespace,
+		tagFilter: tagFilterHash(job.SearchTags),
+	}
+}
+
+type resourceCacheEntry struct {
+	done      chan struct{}
+	resources []*model.TaggedResource
+	err       error
+}
+
+func NewResourceCache() *ResourceCache {
+	return &ResourceCache{
+		entries:  map[resourceCacheKey]*resourceCacheEntry{},
+		expected: map[resourceCacheKey]int{},
+	}
+}
+
+// Expect records that a job of the scrape will discover its resources in region with role. It must be called for
+// every discovery before they start, so that the ones run by several jobs are known to be shared.
+func (c *ResourceCache) Expect(job model.DiscoveryJob, region string, role model.Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expected[newResourceCacheKey(job, region, role)]++
+}
+
+// shared returns whether several discoveries of the given key are expected.
+func (c *ResourceCache) shared(key resourceCacheKey) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.expected[key] > 1
+}
+
+// claim returns the entry of the given key, and whether the caller is the one responsible for resolving it.
+func (c *ResourceCache) claim(key resourceCacheKey) (*resourceCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		return entry, false
+	}
+	entry := &resourceCacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true
+}
+
+func (e *resourceCacheEntry) resolve(resources []*model.TaggedResource, err error) {
+	e.resources = resources
+	e.err = err
+	close(e.done)
+}
+
+// wait blocks until the entry is resolved and returns its result. The resources are shared with the other jobs
+// waiting on the entry, and must not be modified.
+func (e *resourceCacheEntry) wait(ctx context.Context) ([]*model.TaggedResource, error) {
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return slices.Clone(e.resources), e.err
+}
+
+// tagFilterHash hashes the search tags of a job. It doesn't depend on their order, as a resource must match all of
+// them.
+func tagFilterHash(searchTags []model.SearchTag) uint64 {
+	filters := make([]string, 0, len(searchTags))
+	for _, searchTag := range searchTags {
+		value := ""
+		if searchTag.Value != nil {
+			value = searchTag.Value.String()
+		}
+		filters = append(filters, searchTag.Key+"\x00"+value)
+	}
+	slices.Sort(filters)
+
+	h := fnv.New64a()
+	h.Write([]byte(strings.Join(filters, "\x00")))
+	return h.Sum64()
+}
+
+type cachingClient struct {
+	client Client
+	cache  *ResourceCache
+	role   model.Role
+}
+
+// NewCachingClient returns a client sharing the resources it discovers with the other clients using the same cache.
+// The role is the one the client was created for, as the resources it can see depend on it.
+func NewCachingClient(client Client, cache *ResourceCache, role model.Role) Client {
+	return &cachingClient{
+		client: client,
+		cache:  cache,
+		role:   role,
+	}
+}
+
+func (c *cachingClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	entry, owner := c.cache.claim(newResourceCacheKey(job, region, c.role))
+	if owner {
+		entry.resolve(c.client.GetResources(ctx, job, region))
+	}
+	return entry.wait(ctx)
+}
+
+// GetResourcePages hands the resources over page by page when their discovery isn't shared with other jobs, and
+// as a single page of the shared resources otherwise.
+func (c *cachingClient) GetResourcePages(ctx context.Context, job model.DiscoveryJob, region string, handler PageHandler) error {
+	if !c.cache.shared(newResourceCacheKey(job, region, c.role)) {
+		return GetResourcePages(ctx, c.client, job, region, handler)
+	}
+	resources, err := c.GetResources(ctx, job, region)
+	if err != nil {
+		return err
+	}
+	if len(resources) > 0 {
+		handler.HandlePage(resources)
+	}
+	return nil
+}
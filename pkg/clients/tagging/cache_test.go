@@ -0,0 +1,137 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tagging
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/grafana/regexp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestCachingClient_SharesIdenticalDiscoveries(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	client := clientFunc(func(_ context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return []*model.TaggedResource{{ARN: "arn:aws:sqs:" + region + ":123456789012:queue-1", Namespace: job.Namespace}}, nil
+	})
+	role := model.Role{RoleArn: "arn:aws:iam::123456789012:role/Prometheus"}
+	newJob := func(searchTags ...model.SearchTag) model.DiscoveryJob {
+		return model.DiscoveryJob{Namespace: "AWS/SQS", SearchTags: searchTags}
+	}
+	team := model.SearchTag{Key: "Team", Value: regexp.MustCompile("payments")}
+	env := model.SearchTag{Key: "Environment", Value: regexp.MustCompile("production")}
+
+	cache := NewResourceCache()
+	var wg sync.WaitGroup
+	for _, job := range []model.DiscoveryJob{newJob(team, env), newJob(env, team), newJob(team, env)} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resources, err := NewCachingClient(client, cache, role).GetResources(context.Background(), job, "us-east-1")
+			assert.NoError(t, err)
+			assert.Len(t, resources, 1)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, calls)
+
+	// Differing search tags, regions and roles discover their resources on their own
+	_, err := NewCachingClient(client, cache, role).GetResources(context.Background(), newJob(team), "us-east-1")
+	require.NoError(t, err)
+	_, err = NewCachingClient(client, cache, role).GetResources(context.Background(), newJob(team, model.SearchTag{Key: "Environment", Value: regexp.MustCompile("staging")}), "us-east-1")
+	require.NoError(t, err)
+	_, err = NewCachingClient(client, cache, role).GetResources(context.Background(), newJob(team, env), "eu-west-1")
+	require.NoError(t, err)
+	_, err = NewCachingClient(client, cache, model.Role{}).GetResources(context.Background(), newJob(team, env), "us-east-1")
+	require.NoError(t, err)
+	require.Equal(t, 5, calls)
+}
+
+func TestCachingClient_SharesErrors(t *testing.T) {
+	calls := 0
+	client := clientFunc(func(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+		calls++
+		return nil, ErrExpectedToFindResources
+	})
+
+	cache := NewResourceCache()
+	job := model.DiscoveryJob{Namespace: "AWS/SQS"}
+	for range 2 {
+		_, err := NewCachingClient(client, cache, model.Role{}).GetResources(context.Background(), job, "us-east-1")
+		require.ErrorIs(t, err, ErrExpectedToFindResources)
+	}
+	require.Equal(t, 1, calls)
+}
+
+// pagesClient is a PagedClient discovering the same pages on every call.
+type pagesClient struct {
+	mu    sync.Mutex
+	calls int
+	pages [][]*model.TaggedResource
+}
+
+func (c *pagesClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	var collector resourceCollector
+	if err := c.GetResourcePages(ctx, job, region, &collector); err != nil {
+		return nil, err
+	}
+	return collector.resources, nil
+}
+
+func (c *pagesClient) GetResourcePages(_ context.Context, _ model.DiscoveryJob, _ string, handler PageHandler) error {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+	for _, page := range c.pages {
+		handler.HandlePage(page)
+	}
+	return nil
+}
+
+func TestCachingClient_HandsUnsharedDiscoveriesOverPageByPage(t *testing.T) {
+	client := &pagesClient{pages: [][]*model.TaggedResource{
+		{{ARN: "arn:aws:sqs:us-east-1:123456789012:queue-1", Namespace: "AWS/SQS"}},
+		{{ARN: "arn:aws:sqs:us-east-1:123456789012:queue-2", Namespace: "AWS/SQS"}},
+	}}
+	sqs := model.DiscoveryJob{Namespace: "AWS/SQS"}
+
+	cache := NewResourceCache()
+	cache.Expect(sqs, "us-east-1", model.Role{})
+	var recorder pageRecorder
+	require.NoError(t, GetResourcePages(context.Background(), NewCachingClient(client, cache, model.Role{}), sqs, "us-east-1", &recorder))
+	require.Equal(t, client.pages, recorder.pages)
+
+	t.Run("shared", func(t *testing.T) {
+		client.calls = 0
+		cache := NewResourceCache()
+		cache.Expect(sqs, "us-east-1", model.Role{})
+		cache.Expect(sqs, "us-east-1", model.Role{})
+		for range 2 {
+			var recorder pageRecorder
+			require.NoError(t, GetResourcePages(context.Background(), NewCachingClient(client, cache, model.Role{}), sqs, "us-east-1", &recorder))
+			// The shared resources are handed over as a single page
+			require.Equal(t, [][]*model.TaggedResource{slices.Concat(client.pages...)}, recorder.pages)
+		}
+		require.Equal(t, 1, client.calls)
+	})
+}
@@ -54,6 +54,50 @@ type Client interface {
 	GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error)
 }
 
+// PageHandler receives the resources of a discovery page by page, as they're discovered.
+type PageHandler interface {
+	// HandlePage is called with every page of resources, in order.
+	HandlePage(page []*model.TaggedResource)
+	// Reset discards the pages handled so far, when the discovery starts over, e.g. when it's retried.
+	Reset()
+}
+
+// PagedClient is a Client which can hand the resources it discovers over page by page, so that they don't have to
+// be buffered until the discovery is done.
+type PagedClient interface {
+	Client
+	GetResourcePages(ctx context.Context, job model.DiscoveryJob, region string, handler PageHandler) error
+}
+
+// GetResourcePages hands the resources discovered by client over to handler page by page when client is a
+// PagedClient, and as a single page otherwise.
+func GetResourcePages(ctx context.Context, client Client, job model.DiscoveryJob, region string, handler PageHandler) error {
+	if paged, ok := client.(PagedClient); ok {
+		return paged.GetResourcePages(ctx, job, region, handler)
+	}
+	resources, err := client.GetResources(ctx, job, region)
+	if err != nil {
+		return err
+	}
+	if len(resources) > 0 {
+		handler.HandlePage(resources)
+	}
+	return nil
+}
+
+// resourceCollector buffers the pages it handles.
+type resourceCollector struct {
+	resources []*model.TaggedResource
+}
+
+func (c *resourceCollector) HandlePage(page []*model.TaggedResource) {
+	c.resources = append(c.resources, page...)
+}
+
+func (c *resourceCollector) Reset() {
+	c.resources = nil
+}
+
 var ErrExpectedToFindResources = errors.New("expected to discover resources but none were found")
 
 type client struct {
@@ -102,8 +146,30 @@ func NewClient(
 }
 
 func (c client) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	var collector resourceCollector
+	if err := c.GetResourcePages(ctx, job, region, &collector); err != nil {
+		return nil, err
+	}
+	return collector.resources, nil
+}
+
+// GetResourcePages hands every page of the tagging API over to handler as it arrives. The resources of the
+// namespaces with a ResourceFunc or a FilterFunc are handed over as a single page, as the FilterFunc needs all
+// of them.
+func (c client) GetResourcePages(ctx context.Context, job model.DiscoveryJob, region string, handler PageHandler) error {
 	svc := config.SupportedServices.GetService(job.Namespace)
+	ext, hasExt := ServiceFilters[svc.Namespace]
+	buffered := hasExt && (ext.ResourceFunc != nil || ext.FilterFunc != nil)
+	// resources holds the resources of the buffered namespaces only
 	var resources []*model.TaggedResource
+	discovered := 0
+	handlePage := func(page []*model.TaggedResource) {
+		for _, resource := range page {
+			resource.BackfillRegion()
+		}
+		discovered += len(page)
+		handler.HandlePage(page)
+	}
 	shouldHaveDiscoveredResources := false
 
 	if len(svc.ResourceFilters) > 0 {
@@ -136,13 +202,17 @@ func (c client) GetResources(ctx context.Context, job model.DiscoveryJob, region
 		paginator := resourcegroupstaggingapi.NewGetResourcesPaginator(c.taggingAPI, inputparams, func(options *resourcegroupstaggingapi.GetResourcesPaginatorOptions) {
 			options.StopOnDuplicateToken = true
 		})
+		pages := 0
 		for paginator.HasMorePages() {
 			c.scrapeMetrics.ResourceGroupTaggingAPICounter.Inc()
+			pages++
 			page, err := paginator.NextPage(ctx)
 			if err != nil {
-				return nil, err
+				c.scrapeMetrics.APIPagesHistogram.Observe(float64(pages), "GetResources")
+				return err
 			}
 
+			var pageResources []*model.TaggedResource
 			for _, resourceTagMapping := range page.ResourceTagMappingList {
 				resource := model.TaggedResource{
 					ARN:       *resourceTagMapping.ResourceARN,
@@ -156,22 +226,28 @@ func (c client) GetResources(ctx context.Context, job model.DiscoveryJob, region
 				}
 
 				if resource.FilterThroughTags(job.SearchTags) {
-					resources = append(resources, &resource)
+					pageResources = append(pageResources, &resource)
 				} else {
 					c.logger.Debug("Skipping resource because search tags do not match", "arn", resource.ARN)
 				}
 			}
+			if buffered {
+				resources = append(resources, pageResources...)
+			} else if len(pageResources) > 0 {
+				handlePage(pageResources)
+			}
 		}
 
-		c.logger.Debug("GetResourcesPages finished", "total", len(resources))
+		c.scrapeMetrics.APIPagesHistogram.Observe(float64(pages), "GetResources")
+		c.logger.Debug("GetResourcesPages finished", "total", discovered+len(resources))
 	}
 
-	if ext, ok := ServiceFilters[svc.Namespace]; ok {
+	if buffered {
 		if ext.ResourceFunc != nil {
 			shouldHaveDiscoveredResources = true
 			newResources, err := ext.ResourceFunc(ctx, c, job, region)
 			if err != nil {
-				return nil, fmt.Errorf("failed to apply ResourceFunc for %s, %w", svc.Namespace, err)
+				return fmt.Errorf("failed to apply ResourceFunc for %s, %w", svc.Namespace, err)
 			}
 			resources = append(resources, newResources...)
 			c.logger.Debug("ResourceFunc finished", "total", len(resources))
@@ -180,16 +256,19 @@ func (c client) GetResources(ctx context.Context, job model.DiscoveryJob, region
 		if ext.FilterFunc != nil {
 			filteredResources, err := ext.FilterFunc(ctx, c, resources)
 			if err != nil {
-				return nil, fmt.Errorf("failed to apply FilterFunc for %s, %w", svc.Namespace, err)
+				return fmt.Errorf("failed to apply FilterFunc for %s, %w", svc.Namespace, err)
 			}
 			resources = filteredResources
 			c.logger.Debug("FilterFunc finished", "total", len(resources))
 		}
-	}
 
-	if shouldHaveDiscoveredResources && len(resources) == 0 {
-		return nil, ErrExpectedToFindResources
+		if len(resources) > 0 {
+			handlePage(resources)
+		}
 	}
 
-	return resources, nil
+	if shouldHaveDiscoveredResources && discovered == 0 {
+		return ErrExpectedToFindResources
+	}
+	return nil
 }
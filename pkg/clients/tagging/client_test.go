@@ -0,0 +1,115 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tagging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+func TestGetResources_ObservesPages(t *testing.T) {
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	tokens := []string{"page-2", ""}
+	calls := 0
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: scrapeMetrics,
+		taggingAPI: taggingClientAdapter{
+			getResources: func(context.Context, *resourcegroupstaggingapi.GetResourcesInput, ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+				output := &resourcegroupstaggingapi.GetResourcesOutput{
+					PaginationToken: aws.String(tokens[calls]),
+					ResourceTagMappingList: []types.ResourceTagMapping{
+						{ResourceARN: aws.String("arn:aws:sqs:us-east-1:123456789012:queue-" + tokens[calls])},
+					},
+				}
+				calls++
+				return output, nil
+			},
+		},
+	}
+
+	resources, err := c.GetResources(context.Background(), model.DiscoveryJob{Namespace: "AWS/SQS"}, "us-east-1")
+	require.NoError(t, err)
+	require.Len(t, resources, 2)
+
+	var m dto.Metric
+	require.NoError(t, scrapeMetrics.APIPagesHistogram.Raw().WithLabelValues("GetResources").(prometheus.Metric).Write(&m))
+	require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+	require.Equal(t, float64(2), m.GetHistogram().GetSampleSum())
+}
+
+// pageRecorder is a PageHandler recording the pages it's handed.
+type pageRecorder struct {
+	pages  [][]*model.TaggedResource
+	resets int
+}
+
+func (r *pageRecorder) HandlePage(page []*model.TaggedResource) {
+	r.pages = append(r.pages, page)
+}
+
+func (r *pageRecorder) Reset() {
+	r.pages = nil
+	r.resets++
+}
+
+func TestGetResourcePages_HandsPagesOver(t *testing.T) {
+	tokens := []string{"page-2", ""}
+	calls := 0
+	c := client{
+		logger:        promslog.NewNopLogger(),
+		scrapeMetrics: promutil.Discard,
+		taggingAPI: taggingClientAdapter{
+			getResources: func(context.Context, *resourcegroupstaggingapi.GetResourcesInput, ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+				output := &resourcegroupstaggingapi.GetResourcesOutput{
+					PaginationToken: aws.String(tokens[calls]),
+					ResourceTagMappingList: []types.ResourceTagMapping{
+						{ResourceARN: aws.String("arn:aws:sqs:us-east-1:123456789012:queue-" + tokens[calls])},
+					},
+				}
+				calls++
+				return output, nil
+			},
+		},
+	}
+
+	var recorder pageRecorder
+	require.NoError(t, c.GetResourcePages(context.Background(), model.DiscoveryJob{Namespace: "AWS/SQS"}, "us-east-1", &recorder))
+	require.Len(t, recorder.pages, 2)
+	for i, page := range recorder.pages {
+		require.Len(t, page, 1)
+		require.Equal(t, "arn:aws:sqs:us-east-1:123456789012:queue-"+tokens[i], page[0].ARN)
+		require.Equal(t, "us-east-1", page[0].Region)
+	}
+
+	t.Run("no resources", func(t *testing.T) {
+		c.taggingAPI.getResources = func(context.Context, *resourcegroupstaggingapi.GetResourcesInput, ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.GetResourcesOutput, error) {
+			return &resourcegroupstaggingapi.GetResourcesOutput{}, nil
+		}
+		var recorder pageRecorder
+		err := c.GetResourcePages(context.Background(), model.DiscoveryJob{Namespace: "AWS/SQS"}, "us-east-1", &recorder)
+		require.ErrorIs(t, err, ErrExpectedToFindResources)
+		require.Empty(t, recorder.pages)
+	})
+}
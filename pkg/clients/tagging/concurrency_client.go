@@ -14,25 +14,112 @@ package tagging
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
-type limitedConcurrencyClient struct {
-	client Client
-	sem    chan struct{}
+// Limiter bounds the concurrency and rate of GetResources calls. It can be shared by
+// several clients, e.g. all the clients of a role, so that they draw from one budget.
+type Limiter struct {
+	scrapeMetrics     *promutil.ScrapeMetrics
+	sem               chan struct{}
+	requestsPerSecond float64
+	interval          time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewLimiter creates a limiter allowing maxConcurrency concurrent calls, started at most
+// requestsPerSecond times per second. A requestsPerSecond of zero disables the rate limit.
+// Calls which are delayed or allowed by the rate limit are counted in scrapeMetrics.
+func NewLimiter(scrapeMetrics *promutil.ScrapeMetrics, maxConcurrency int, requestsPerSecond float64) *Limiter {
+	if scrapeMetrics == nil {
+		scrapeMetrics = promutil.Discard
+	}
+	l := &Limiter{
+		scrapeMetrics:     scrapeMetrics,
+		sem:               make(chan struct{}, maxConcurrency),
+		requestsPerSecond: requestsPerSecond,
+	}
+	if requestsPerSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / requestsPerSecond)
+	}
+	return l
+}
+
+// MaxConcurrency returns the number of calls the limiter allows concurrently.
+func (l *Limiter) MaxConcurrency() int { return cap(l.sem) }
+
+// RequestsPerSecond returns the rate limit of the limiter, zero when disabled.
+func (l *Limiter) RequestsPerSecond() float64 { return l.requestsPerSecond }
+
+// wait blocks until the rate limit allows another call.
+func (l *Limiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	at := l.next
+	if now := time.Now(); at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		l.scrapeMetrics.TaggingAPIRateLimitAllowedCounter.Inc()
+		return nil
+	}
+	l.scrapeMetrics.TaggingAPIRateLimitWaitCounter.Inc()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type limitedClient struct {
+	client  Client
+	limiter *Limiter
 }
 
 func NewLimitedConcurrencyClient(client Client, maxConcurrency int) Client {
-	return &limitedConcurrencyClient{
-		client: client,
-		sem:    make(chan struct{}, maxConcurrency),
+	return NewLimitedClient(client, NewLimiter(promutil.Discard, maxConcurrency, 0))
+}
+
+// NewLimitedClient wraps client so that its calls are bounded by limiter.
+func NewLimitedClient(client Client, limiter *Limiter) Client {
+	return &limitedClient{
+		client:  client,
+		limiter: limiter,
 	}
 }
 
-func (c limitedConcurrencyClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
-	c.sem <- struct{}{}
-	res, err := c.client.GetResources(ctx, job, region)
-	<-c.sem
-	return res, err
+func (c limitedClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	c.limiter.sem <- struct{}{}
+	defer func() { <-c.limiter.sem }()
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.client.GetResources(ctx, job, region)
+}
+
+func (c limitedClient) GetResourcePages(ctx context.Context, job model.DiscoveryJob, region string, handler PageHandler) error {
+	c.limiter.sem <- struct{}{}
+	defer func() { <-c.limiter.sem }()
+
+	if err := c.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return GetResourcePages(ctx, c.client, job, region, handler)
 }
@@ -0,0 +1,67 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tagging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+type clientFunc func(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error)
+
+func (f clientFunc) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	return f(ctx, job, region)
+}
+
+func TestLimitedClient_RateLimit(t *testing.T) {
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	var calls []time.Time
+	client := NewLimitedClient(clientFunc(func(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+		calls = append(calls, time.Now())
+		return nil, nil
+	}), NewLimiter(scrapeMetrics, 1, 2))
+
+	for range 3 {
+		_, err := client.GetResources(context.Background(), model.DiscoveryJob{Namespace: "AWS/SQS"}, "us-east-1")
+		require.NoError(t, err)
+	}
+
+	require.Len(t, calls, 3)
+	// At 2 calls per second the third call starts a second after the first one.
+	require.GreaterOrEqual(t, calls[2].Sub(calls[0]), 900*time.Millisecond)
+	require.Equal(t, float64(1), testutil.ToFloat64(scrapeMetrics.TaggingAPIRateLimitAllowedCounter.Raw()))
+	require.Equal(t, float64(2), testutil.ToFloat64(scrapeMetrics.TaggingAPIRateLimitWaitCounter.Raw()))
+}
+
+func TestLimitedClient_RateLimitCanceled(t *testing.T) {
+	limiter := NewLimiter(nil, 1, 0.1)
+	client := NewLimitedClient(clientFunc(func(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+		return nil, nil
+	}), limiter)
+
+	_, err := client.GetResources(context.Background(), model.DiscoveryJob{}, "us-east-1")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.GetResources(ctx, model.DiscoveryJob{}, "us-east-1")
+	require.ErrorIs(t, err, context.Canceled)
+}
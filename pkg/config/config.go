@@ -13,13 +13,45 @@
 
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	prom_model "github.com/prometheus/common/model"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+const (
+	DefaultScrapeConfigFile        = "config.yml"
+	DefaultMetricsPerQuery         = 500
+	DefaultLabelsSnakeCase         = false
+	DefaultCustomTagsLabelPrefix   = "custom_tag_"
+	DefaultARNLabelName            = promutil.DefaultARNLabelName
+	DefaultTaggingAPIConcurrency   = 5
+	DefaultMaxSeries               = 0
+	DefaultSeriesLimitAction       = SeriesLimitActionTruncate
+	DefaultInvalidLabelNameAction  = promutil.InvalidLabelNameActionSkip
+	DefaultCloudwatchMaxBackoff    = 3 * time.Second
+	DefaultBuildMetricsConcurrency = 1
+	DefaultAccountAliasSource      = account.AliasSourceIAM
+	DefaultDiscoveryRetries        = 0
+	DefaultDiscoveryRetryBackoff   = time.Second
+	DefaultAccountConcurrency      = 0
+	DefaultJobConcurrency          = 0
+	DefaultNamespaceJobShare       = 1.0
+)
+
+// SeriesLimitAction controls what happens to a scrape producing more series than Config.MaxSeries.
+type SeriesLimitAction string
 
 const (
-	DefaultScrapeConfigFile      = "config.yml"
-	DefaultMetricsPerQuery       = 500
-	DefaultLabelsSnakeCase       = false
-	DefaultTaggingAPIConcurrency = 5
+	// SeriesLimitActionTruncate drops the series exceeding the limit and exports the rest, ordered by name and labels.
+	SeriesLimitActionTruncate SeriesLimitAction = "truncate"
+	// SeriesLimitActionFail fails the whole scrape.
+	SeriesLimitActionFail SeriesLimitAction = "fail"
 )
 
 var DefaultCloudwatchConcurrency = CloudWatchConcurrencyConfig{
@@ -50,21 +82,69 @@ type Config struct {
 	ScrapeConfigFile      string
 	MetricsPerQuery       int
 	LabelsSnakeCase       bool
+	CustomTagsLabelPrefix string
+	// ARNLabelName is the name of the label holding the ARN of the resource of the info and data metrics, so that
+	// they can be joined.
+	ARNLabelName          string
+	MetricsMetadataFile   string
 	TaggingAPIConcurrency int
 	FeatureFlags          []string
 	FIPSEnabled           bool
 	CloudwatchConcurrency CloudWatchConcurrencyConfig
+	// CloudwatchMaxBackoff is the maximum delay between the retries of a throttled or failed CloudWatch API call.
+	// The retries are delayed by an exponential backoff with jitter, capped to it.
+	CloudwatchMaxBackoff time.Duration
+	// BuildMetricsConcurrency is the number of workers converting the scraped CloudWatch data to Prometheus metrics.
+	BuildMetricsConcurrency int
+	// LogDuplicateMetrics logs the labels of the duplicate series dropped from the exported metrics at debug level.
+	LogDuplicateMetrics bool
+	// ClampFutureTimestamps exports the CloudWatch timestamps which are in the future, e.g. because of clock skew,
+	// with the time of the scrape instead, keeping only the newest future data point of every series.
+	ClampFutureTimestamps bool
+	// MaxSeries is the maximum number of series a single scrape may export. Zero disables the limit.
+	MaxSeries         int
+	SeriesLimitAction SeriesLimitAction
+	// InvalidLabelNameAction is what happens to the dimensions and tags whose name isn't a valid label name.
+	InvalidLabelNameAction promutil.InvalidLabelNameAction
+	// AccountAliasSource is the API the account_alias label is resolved with.
+	AccountAliasSource account.AliasSource
+	// DiscoveryRetries is the number of times a failed resource discovery of a discovery job is retried. The first
+	// retry waits for DiscoveryRetryBackoff, which is doubled before every following retry.
+	DiscoveryRetries      int
+	DiscoveryRetryBackoff time.Duration
+	// AccountConcurrency bounds how many account and alias lookups, one per job, role and region, run at the
+	// same time. Zero doesn't bound them.
+	AccountConcurrency int
+	// JobConcurrency bounds how many jobs, one per job, role and region, run at the same time. Zero doesn't
+	// bound them.
+	JobConcurrency int
+	// NamespaceJobShare is the share of JobConcurrency the jobs of a single namespace can take, so that a
+	// namespace with many jobs cannot starve the others.
+	NamespaceJobShare float64
 }
 
 func DefaultConfig() Config {
 	return Config{
-		ScrapeConfigFile:      DefaultScrapeConfigFile,
-		MetricsPerQuery:       DefaultMetricsPerQuery,
-		LabelsSnakeCase:       DefaultLabelsSnakeCase,
-		TaggingAPIConcurrency: DefaultTaggingAPIConcurrency,
-		FeatureFlags:          []string{},
-		FIPSEnabled:           false,
-		CloudwatchConcurrency: DefaultCloudwatchConcurrency,
+		ScrapeConfigFile:        DefaultScrapeConfigFile,
+		MetricsPerQuery:         DefaultMetricsPerQuery,
+		LabelsSnakeCase:         DefaultLabelsSnakeCase,
+		CustomTagsLabelPrefix:   DefaultCustomTagsLabelPrefix,
+		ARNLabelName:            DefaultARNLabelName,
+		TaggingAPIConcurrency:   DefaultTaggingAPIConcurrency,
+		FeatureFlags:            []string{},
+		FIPSEnabled:             false,
+		CloudwatchConcurrency:   DefaultCloudwatchConcurrency,
+		CloudwatchMaxBackoff:    DefaultCloudwatchMaxBackoff,
+		BuildMetricsConcurrency: DefaultBuildMetricsConcurrency,
+		MaxSeries:               DefaultMaxSeries,
+		SeriesLimitAction:       DefaultSeriesLimitAction,
+		InvalidLabelNameAction:  DefaultInvalidLabelNameAction,
+		AccountAliasSource:      DefaultAccountAliasSource,
+		DiscoveryRetries:        DefaultDiscoveryRetries,
+		DiscoveryRetryBackoff:   DefaultDiscoveryRetryBackoff,
+		AccountConcurrency:      DefaultAccountConcurrency,
+		JobConcurrency:          DefaultJobConcurrency,
+		NamespaceJobShare:       DefaultNamespaceJobShare,
 	}
 }
 
@@ -75,6 +155,52 @@ func (c Config) Validate() error {
 	if c.TaggingAPIConcurrency <= 0 {
 		return fmt.Errorf("tagging api concurrency must be a positive value")
 	}
+	if c.CustomTagsLabelPrefix != "" && !prom_model.LegacyValidation.IsValidLabelName(c.CustomTagsLabelPrefix) {
+		return fmt.Errorf("custom tags label prefix %q is not a valid label name", c.CustomTagsLabelPrefix)
+	}
+	if c.ARNLabelName != "" && !prom_model.LegacyValidation.IsValidLabelName(c.ARNLabelName) {
+		return fmt.Errorf("arn label name %q is not a valid label name", c.ARNLabelName)
+	}
+	if err := validateARNLabelName(c.ARNLabelName, c.CustomTagsLabelPrefix); err != nil {
+		return err
+	}
+	if c.CloudwatchMaxBackoff <= 0 {
+		return fmt.Errorf("cloudwatch max backoff must be a positive value")
+	}
+	if c.BuildMetricsConcurrency <= 0 {
+		return fmt.Errorf("build metrics concurrency must be a positive value")
+	}
+	if c.MaxSeries < 0 {
+		return fmt.Errorf("max series must not be negative")
+	}
+	if c.MaxSeries > 0 && c.SeriesLimitAction != SeriesLimitActionTruncate && c.SeriesLimitAction != SeriesLimitActionFail {
+		return fmt.Errorf("series limit action must be one of %q or %q", SeriesLimitActionTruncate, SeriesLimitActionFail)
+	}
+	switch c.InvalidLabelNameAction {
+	case "", promutil.InvalidLabelNameActionSkip, promutil.InvalidLabelNameActionSanitize, promutil.InvalidLabelNameActionFail:
+	default:
+		return fmt.Errorf("invalid label name action must be one of %q, %q or %q", promutil.InvalidLabelNameActionSkip, promutil.InvalidLabelNameActionSanitize, promutil.InvalidLabelNameActionFail)
+	}
+	if c.DiscoveryRetries < 0 {
+		return fmt.Errorf("discovery retries must not be negative")
+	}
+	if c.DiscoveryRetries > 0 && c.DiscoveryRetryBackoff <= 0 {
+		return fmt.Errorf("discovery retry backoff must be a positive value")
+	}
+	if c.AccountConcurrency < 0 {
+		return fmt.Errorf("account concurrency must not be negative")
+	}
+	if c.JobConcurrency < 0 {
+		return fmt.Errorf("job concurrency must not be negative")
+	}
+	if c.NamespaceJobShare <= 0 || c.NamespaceJobShare > 1 {
+		return fmt.Errorf("namespace job share must be greater than 0 and at most 1")
+	}
+	switch c.AccountAliasSource {
+	case "", account.AliasSourceIAM, account.AliasSourceOrganizations:
+	default:
+		return fmt.Errorf("account alias source must be one of %q or %q", account.AliasSourceIAM, account.AliasSourceOrganizations)
+	}
 
 	if c.CloudwatchConcurrency.PerAPILimitEnabled {
 		if c.CloudwatchConcurrency.ListMetrics <= 0 {
@@ -95,3 +221,18 @@ func (c Config) Validate() error {
 
 	return nil
 }
+
+// validateARNLabelName checks that the ARN label doesn't collide with the context, dimension or tag labels of
+// the metrics, which would overwrite it or be overwritten by it.
+func validateARNLabelName(arnLabelName, customTagsLabelPrefix string) error {
+	switch arnLabelName {
+	case "region", "account_id", "account_alias":
+		return fmt.Errorf("arn label name %q conflicts with the context label of the same name", arnLabelName)
+	}
+	for _, prefix := range []string{"dimension_", "tag_", customTagsLabelPrefix} {
+		if prefix != "" && strings.HasPrefix(arnLabelName, prefix) {
+			return fmt.Errorf("arn label name %q conflicts with the labels prefixed with %q", arnLabelName, prefix)
+		}
+	}
+	return nil
+}
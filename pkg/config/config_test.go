@@ -64,6 +64,116 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantError: "tagging api concurrency",
 		},
+		{
+			name: "empty custom tags label prefix",
+			mutate: func(cfg *Config) {
+				cfg.CustomTagsLabelPrefix = ""
+			},
+		},
+		{
+			name: "invalid custom tags label prefix",
+			mutate: func(cfg *Config) {
+				cfg.CustomTagsLabelPrefix = "custom-tag-"
+			},
+			wantError: "custom tags label prefix",
+		},
+		{
+			name: "empty arn label name",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = ""
+			},
+		},
+		{
+			name: "invalid arn label name",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = "resource-arn"
+			},
+			wantError: "arn label name",
+		},
+		{
+			name: "arn label name conflicting with a context label",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = "account_id"
+			},
+			wantError: "conflicts with the context label",
+		},
+		{
+			name: "arn label name conflicting with the dimension labels",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = "dimension_arn"
+			},
+			wantError: "conflicts with the labels prefixed",
+		},
+		{
+			name: "arn label name conflicting with the tag labels",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = "tag_arn"
+			},
+			wantError: "conflicts with the labels prefixed",
+		},
+		{
+			name: "arn label name conflicting with the custom tag labels",
+			mutate: func(cfg *Config) {
+				cfg.ARNLabelName = "custom_tag_arn"
+			},
+			wantError: "conflicts with the labels prefixed",
+		},
+		{
+			name: "organizations account alias source",
+			mutate: func(cfg *Config) {
+				cfg.AccountAliasSource = "organizations"
+			},
+		},
+		{
+			name: "invalid account alias source",
+			mutate: func(cfg *Config) {
+				cfg.AccountAliasSource = "sso"
+			},
+			wantError: "account alias source",
+		},
+		{
+			name: "invalid discovery retries",
+			mutate: func(cfg *Config) {
+				cfg.DiscoveryRetries = -1
+			},
+			wantError: "discovery retries",
+		},
+		{
+			name: "invalid discovery retry backoff",
+			mutate: func(cfg *Config) {
+				cfg.DiscoveryRetries = 2
+				cfg.DiscoveryRetryBackoff = 0
+			},
+			wantError: "discovery retry backoff",
+		},
+		{
+			name: "invalid account concurrency",
+			mutate: func(cfg *Config) {
+				cfg.AccountConcurrency = -1
+			},
+			wantError: "account concurrency",
+		},
+		{
+			name: "invalid job concurrency",
+			mutate: func(cfg *Config) {
+				cfg.JobConcurrency = -1
+			},
+			wantError: "job concurrency",
+		},
+		{
+			name: "zero namespace job share",
+			mutate: func(cfg *Config) {
+				cfg.NamespaceJobShare = 0
+			},
+			wantError: "namespace job share",
+		},
+		{
+			name: "namespace job share above 1",
+			mutate: func(cfg *Config) {
+				cfg.NamespaceJobShare = 1.5
+			},
+			wantError: "namespace job share",
+		},
 		{
 			name: "invalid cloudwatch single concurrency",
 			mutate: func(cfg *Config) {
@@ -95,6 +205,42 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantError: "getmetricstatistics concurrency",
 		},
+		{
+			name: "invalid cloudwatch max backoff",
+			mutate: func(cfg *Config) {
+				cfg.CloudwatchMaxBackoff = 0
+			},
+			wantError: "cloudwatch max backoff",
+		},
+		{
+			name: "invalid build metrics concurrency",
+			mutate: func(cfg *Config) {
+				cfg.BuildMetricsConcurrency = 0
+			},
+			wantError: "build metrics concurrency",
+		},
+		{
+			name: "invalid max series",
+			mutate: func(cfg *Config) {
+				cfg.MaxSeries = -1
+			},
+			wantError: "max series",
+		},
+		{
+			name: "invalid series limit action",
+			mutate: func(cfg *Config) {
+				cfg.MaxSeries = 10
+				cfg.SeriesLimitAction = "drop"
+			},
+			wantError: "series limit action",
+		},
+		{
+			name: "invalid label name action",
+			mutate: func(cfg *Config) {
+				cfg.InvalidLabelNameAction = "drop"
+			},
+			wantError: "invalid label name action",
+		},
 	}
 
 	for _, tt := range tests {
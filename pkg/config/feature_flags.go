@@ -19,6 +19,21 @@ type flagsCtxKey struct{}
 // AlwaysReturnInfoMetrics is a feature flag used to enable the return of info metrics even when there are no corresponding CloudWatch metrics
 const AlwaysReturnInfoMetrics = "always-return-info-metrics"
 
+// StaleResourceMarkers is a feature flag used to export a NaN value, for one scrape, for the series of resources which are no longer discovered
+const StaleResourceMarkers = "stale-resource-markers"
+
+// DedupeGetMetricDataQueries is a feature flag used to send identical GetMetricData queries from several jobs only once per scrape, sharing their result
+const DedupeGetMetricDataQueries = "dedupe-getmetricdata-queries"
+
+// ScrapeWindowLabels is a feature flag used to add the start, end and period of the GetMetricData query window as labels on the exported metrics
+const ScrapeWindowLabels = "scrape-window-labels"
+
+// PartitionLabel is a feature flag used to add the AWS partition of the resource ARN as a label on the metrics of discovery jobs
+const PartitionLabel = "partition-label"
+
+// OmitGlobalNameLabel is a feature flag used to export the metrics of discovery jobs which aren't associated with a resource without a name label, instead of name="global"
+const OmitGlobalNameLabel = "omit-global-name-label"
+
 // FeatureFlags is an interface all objects that can tell wether or not a feature flag is enabled can implement.
 type FeatureFlags interface {
 	// IsFeatureEnabled tells if the feature flag identified by flag is enabled.
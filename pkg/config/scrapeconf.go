@@ -13,26 +13,118 @@
 package config
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/grafana/regexp"
+	prom_model "github.com/prometheus/common/model"
 	"go.yaml.in/yaml/v2"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
+// statisticNameRegexp matches the names statistics and metrics can be renamed to in the exported metric names.
+var statisticNameRegexp = regexp.MustCompile("^[a-zA-Z0-9_]+$")
+
+var (
+	// standardStatistics are the statistics of CloudWatch which aren't extended statistics.
+	standardStatistics = []string{"SampleCount", "Average", "Sum", "Minimum", "Maximum"}
+	// shorthandExtendedStatisticRegexp matches the extended statistics with a single percentage, e.g. p99, tm90 or TM90.
+	shorthandExtendedStatisticRegexp = regexp.MustCompile(`^(?i)(p|tm|wm|tc|ts)(\d{1,2}(\.\d{1,10})?|100)$`)
+	// rangeExtendedStatisticRegexp matches the extended statistics over a range, e.g. TM(10%:90%) or PR(:300).
+	rangeExtendedStatisticRegexp = regexp.MustCompile(`^(?i)(tm|wm|tc|ts|pr)\(([\d.]*%?):([\d.]*%?)\)$`)
+)
+
+const (
+	// SeriesCapActionDrop exports the first series of a metric up to MaxSeriesPerMetric.
+	SeriesCapActionDrop = "drop"
+	// SeriesCapActionSample exports a deterministic sample of MaxSeriesPerMetric series, which is
+	// stable across scrapes as long as the set of series doesn't change.
+	SeriesCapActionSample = "sample"
+
+	// EnhancedMetricsFailurePolicyFail drops the metrics of a discovery job when its enhanced metrics fail.
+	EnhancedMetricsFailurePolicyFail = "fail"
+	// EnhancedMetricsFailurePolicyWarnAndContinue logs a warning and exports the CloudWatch metrics of a
+	// discovery job without its enhanced metrics when they fail.
+	EnhancedMetricsFailurePolicyWarnAndContinue = "warn-and-continue"
+
+	// CrossRegionResourcesFilter drops the resources whose ARN is in another region than the one the job
+	// queries, as their metrics would be exported with the wrong region label.
+	CrossRegionResourcesFilter = "filter"
+	// CrossRegionResourcesKeep exports the resources whose ARN is in another region than the one the job queries.
+	CrossRegionResourcesKeep = "keep"
+
+	// DimensionGranularityMostSpecific queries only the metrics with the most dimensions among those
+	// associated with the same resource.
+	DimensionGranularityMostSpecific = "most-specific"
+	// DimensionGranularityMostAggregate queries only the metrics with the fewest dimensions among those
+	// associated with the same resource.
+	DimensionGranularityMostAggregate = "most-aggregate"
+)
+
 // ScrapeConf models the YAML file that defines AWS jobs and resources.
 type ScrapeConf struct {
-	APIVersion      string             `yaml:"apiVersion"`
-	StsRegion       string             `yaml:"sts-region"`
-	Discovery       Discovery          `yaml:"discovery"`
-	Static          []*Static          `yaml:"static"`
-	CustomNamespace []*CustomNamespace `yaml:"customNamespace"`
+	APIVersion          string             `yaml:"apiVersion"`
+	StsRegion           string             `yaml:"sts-region"`
+	GlobalServiceRegion string             `yaml:"globalServiceRegion"`
+	Endpoints           map[string]string  `yaml:"endpoints"`
+	Discovery           Discovery          `yaml:"discovery"`
+	Static              []*Static          `yaml:"static"`
+	CustomNamespace     []*CustomNamespace `yaml:"customNamespace"`
+}
+
+// DefaultGlobalServiceRegion is the region where region-agnostic services publish their metrics.
+const DefaultGlobalServiceRegion = "us-east-1"
+
+// partitionGlobalServiceRegions are the regions where region-agnostic services publish their metrics in the
+// partitions other than aws, by the prefix of the regions of the partition.
+var partitionGlobalServiceRegions = []struct {
+	regionPrefix string
+	globalRegion string
+}{
+	{regionPrefix: "us-gov-", globalRegion: "us-gov-west-1"},
+	{regionPrefix: "cn-", globalRegion: "cn-northwest-1"},
+}
+
+// regionPartition returns the region prefix identifying the partition of region, empty for the aws partition, and
+// the default region of the global services in the partition.
+func regionPartition(region string) (string, string) {
+	for _, p := range partitionGlobalServiceRegions {
+		if strings.HasPrefix(region, p.regionPrefix) {
+			return p.regionPrefix, p.globalRegion
+		}
+	}
+	return "", DefaultGlobalServiceRegion
+}
+
+// globalServiceRegions returns the regions the discovery jobs of global services configured with regions run in:
+// one per partition of the regions. GlobalServiceRegion overrides the region of its own partition.
+func (c *ScrapeConf) globalServiceRegions(regions []string) []string {
+	var configuredPartition string
+	if c.GlobalServiceRegion != "" {
+		configuredPartition, _ = regionPartition(c.GlobalServiceRegion)
+	}
+
+	globalRegions := make([]string, 0, 1)
+	for _, region := range regions {
+		partition, globalRegion := regionPartition(region)
+		if c.GlobalServiceRegion != "" && partition == configuredPartition {
+			globalRegion = c.GlobalServiceRegion
+		}
+		if !slices.Contains(globalRegions, globalRegion) {
+			globalRegions = append(globalRegions, globalRegion)
+		}
+	}
+	return globalRegions
 }
 
 type Discovery struct {
@@ -55,21 +147,69 @@ type JobLevelMetricFields struct {
 	NilToZero              *bool    `yaml:"nilToZero"`
 	AddCloudwatchTimestamp *bool    `yaml:"addCloudwatchTimestamp"`
 	ExportAllDataPoints    *bool    `yaml:"exportAllDataPoints"`
+	KeepLastN              int      `yaml:"keepLastN"`
+	EmptyResultGrace       int      `yaml:"emptyResultGrace"`
+
+	// StatisticNames renames statistics in the suffix of the exported metric names, e.g. Sum: total.
+	StatisticNames map[string]string `yaml:"statisticNames"`
+	// NilToZeroStatistics overrides NilToZero for some statistics, e.g. to export 0 for Sum but NaN for Average.
+	NilToZeroStatistics map[string]bool `yaml:"nilToZeroStatistics"`
+	// AdjustPeriodToDataPointLimit increases the period when length/period would request more data points
+	// than a GetMetricData call returns, instead of failing validation.
+	AdjustPeriodToDataPointLimit *bool `yaml:"adjustPeriodToDataPointLimit"`
+	// MaxSeriesPerMetric caps the number of series exported per metric name. Zero disables the cap.
+	MaxSeriesPerMetric int `yaml:"maxSeriesPerMetric"`
+	// SeriesCapAction selects the series kept when MaxSeriesPerMetric is exceeded: drop (default) or sample.
+	SeriesCapAction string `yaml:"seriesCapAction"`
+	// SumWithoutDimensions sums the series of a metric which only differ by these dimensions, and exports the sum
+	// without them.
+	SumWithoutDimensions []string `yaml:"sumWithoutDimensions"`
 }
 
 type Job struct {
-	Regions                     []string          `yaml:"regions"`
-	Type                        string            `yaml:"type"`
-	Roles                       []Role            `yaml:"roles"`
-	SearchTags                  []Tag             `yaml:"searchTags"`
-	CustomTags                  []Tag             `yaml:"customTags"`
-	DimensionNameRequirements   []string          `yaml:"dimensionNameRequirements"`
-	Metrics                     []*Metric         `yaml:"metrics"`
-	RoundingPeriod              *int64            `yaml:"roundingPeriod"`
-	RecentlyActiveOnly          bool              `yaml:"recentlyActiveOnly"`
-	IncludeContextOnInfoMetrics bool              `yaml:"includeContextOnInfoMetrics"`
-	EnhancedMetrics             []*EnhancedMetric `yaml:"enhancedMetrics"`
-	JobLevelMetricFields        `yaml:",inline"`
+	Regions                       []string          `yaml:"regions"`
+	Type                          string            `yaml:"type"`
+	Roles                         []Role            `yaml:"roles"`
+	SearchTags                    []Tag             `yaml:"searchTags"`
+	CustomTags                    []Tag             `yaml:"customTags"`
+	DimensionNameRequirements     []string          `yaml:"dimensionNameRequirements"`
+	Metrics                       []*Metric         `yaml:"metrics"`
+	RoundingPeriod                *int64            `yaml:"roundingPeriod"`
+	RecentlyActiveOnly            bool              `yaml:"recentlyActiveOnly"`
+	IncludeContextOnInfoMetrics   bool              `yaml:"includeContextOnInfoMetrics"`
+	SkipZeroDimensionMetrics      bool              `yaml:"skipZeroDimensionMetrics"`
+	DirectQuery                   bool              `yaml:"directQuery"`
+	ResourceCountGroupByTag       string            `yaml:"resourceCountGroupByTag"`
+	AllowMultipleResourceMappings bool              `yaml:"allowMultipleResourceMappings"`
+	LogUnmatchedMetrics           bool              `yaml:"logUnmatchedMetrics"`
+	DimensionGranularity          string            `yaml:"dimensionGranularity"`
+	EnhancedMetrics               []*EnhancedMetric `yaml:"enhancedMetrics"`
+	JobLevelMetricFields          `yaml:",inline"`
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job.
+	LabelsSnakeCase *bool `yaml:"labelsSnakeCase"`
+	// MetricNameOverrides maps CloudWatch metric names to the name they are exported with, after the namespace prefix.
+	MetricNameOverrides map[string]string `yaml:"metricNameOverrides"`
+	// EnhancedMetricsFailurePolicy is the behavior when the enhanced metrics fail: fail or warn-and-continue (default).
+	EnhancedMetricsFailurePolicy string `yaml:"enhancedMetricsFailurePolicy"`
+	// EnhancedMetricsConcurrency bounds how many regions and roles of the job describe resources for the
+	// enhanced metrics at the same time. Zero doesn't bound them.
+	EnhancedMetricsConcurrency int `yaml:"enhancedMetricsConcurrency"`
+	// RequiredTags are the tags, with their exact value, a resource must carry for its metrics to be exported.
+	RequiredTags []Tag `yaml:"requiredTags"`
+	// CrossRegionResources is what happens to the resources discovered in another region than the queried one:
+	// filter (default) or keep. The resources of global namespaces are always kept.
+	CrossRegionResources string `yaml:"crossRegionResources"`
+	// FIPS overrides the -fips flag for the AWS API endpoints of this job.
+	FIPS *bool `yaml:"fips"`
+	// DedupeInfoMetricsAcrossRegions exports the info metric of a resource discovered in several regions, e.g. a
+	// global resource, only once instead of once per region.
+	DedupeInfoMetricsAcrossRegions bool `yaml:"dedupeInfoMetricsAcrossRegions"`
+	// IncludeLinkedAccounts is rejected for discovery jobs: their resources are only discovered in the
+	// monitoring account, so the metrics of linked accounts couldn't be associated with them.
+	IncludeLinkedAccounts bool `yaml:"includeLinkedAccounts"`
+	// ExportRecentlyActive exports, for jobs with RecentlyActiveOnly, whether every discovered resource had recently
+	// active metrics.
+	ExportRecentlyActive bool `yaml:"exportRecentlyActive"`
 }
 
 type EnhancedMetric struct {
@@ -84,6 +224,10 @@ type Static struct {
 	CustomTags []Tag       `yaml:"customTags"`
 	Dimensions []Dimension `yaml:"dimensions"`
 	Metrics    []*Metric   `yaml:"metrics"`
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job.
+	LabelsSnakeCase *bool `yaml:"labelsSnakeCase"`
+	// FIPS overrides the -fips flag for the AWS API endpoints of this job.
+	FIPS *bool `yaml:"fips"`
 }
 
 type CustomNamespace struct {
@@ -97,6 +241,30 @@ type CustomNamespace struct {
 	DimensionNameRequirements []string  `yaml:"dimensionNameRequirements"`
 	RoundingPeriod            *int64    `yaml:"roundingPeriod"`
 	JobLevelMetricFields      `yaml:",inline"`
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job.
+	LabelsSnakeCase *bool `yaml:"labelsSnakeCase"`
+	// MetricNameOverrides maps CloudWatch metric names to the name they are exported with, after the namespace prefix.
+	MetricNameOverrides map[string]string `yaml:"metricNameOverrides"`
+	// IncludeLinkedAccounts also lists and queries the metrics of the source accounts linked to the
+	// monitoring account with CloudWatch cross-account observability.
+	IncludeLinkedAccounts bool `yaml:"includeLinkedAccounts"`
+	// FIPS overrides the -fips flag for the AWS API endpoints of this job.
+	FIPS *bool `yaml:"fips"`
+	// Expressions are metric math expressions queried along with the metrics of the job.
+	Expressions []*Expression `yaml:"expressions"`
+}
+
+// Expression is a metric math expression, e.g. a Metrics Insights query, whose result is exported as a metric.
+// It can't reference the metrics of the job, so it must return a single time series on its own.
+type Expression struct {
+	// Name is the name the result is exported with, after the namespace prefix.
+	Name       string `yaml:"name"`
+	Expression string `yaml:"expression"`
+	Period     int64  `yaml:"period"`
+	Length     int64  `yaml:"length"`
+	Delay      int64  `yaml:"delay"`
+	// Labels are static labels added to the exported metric.
+	Labels map[string]string `yaml:"labels"`
 }
 
 type Metric struct {
@@ -108,6 +276,29 @@ type Metric struct {
 	NilToZero              *bool    `yaml:"nilToZero"`
 	AddCloudwatchTimestamp *bool    `yaml:"addCloudwatchTimestamp"`
 	ExportAllDataPoints    *bool    `yaml:"exportAllDataPoints"`
+	KeepLastN              int      `yaml:"keepLastN"`
+	EmptyResultGrace       int      `yaml:"emptyResultGrace"`
+
+	// StatisticNames renames statistics in the suffix of the exported metric names, e.g. Sum: total.
+	StatisticNames map[string]string `yaml:"statisticNames"`
+	// NilToZeroStatistics overrides NilToZero for some statistics, e.g. to export 0 for Sum but NaN for Average.
+	NilToZeroStatistics map[string]bool `yaml:"nilToZeroStatistics"`
+	// AdjustPeriodToDataPointLimit increases the period when length/period would request more data points
+	// than a GetMetricData call returns, instead of failing validation.
+	AdjustPeriodToDataPointLimit *bool `yaml:"adjustPeriodToDataPointLimit"`
+	// MaxSeriesPerMetric caps the number of series exported per metric name. Zero disables the cap.
+	MaxSeriesPerMetric int `yaml:"maxSeriesPerMetric"`
+	// SeriesCapAction selects the series kept when MaxSeriesPerMetric is exceeded: drop (default) or sample.
+	SeriesCapAction string `yaml:"seriesCapAction"`
+	// SumWithoutDimensions sums the series of the metric which only differ by these dimensions, and exports the sum
+	// without them.
+	SumWithoutDimensions []string `yaml:"sumWithoutDimensions"`
+	// UseGetMetricStatistics queries the metric with the GetMetricStatistics API instead of GetMetricData.
+	UseGetMetricStatistics bool `yaml:"useGetMetricStatistics"`
+	// ExportedTags overrides the exportedTagsOnMetrics of the namespace of the discovery job for this metric.
+	ExportedTags []string `yaml:"exportedTags"`
+	// MetricType is the type the metric is exported with: gauge (default), counter or untyped.
+	MetricType string `yaml:"metricType"`
 }
 
 type Dimension struct {
@@ -116,14 +307,39 @@ type Dimension struct {
 }
 
 type Role struct {
-	RoleArn    string `yaml:"roleArn"`
-	ExternalID string `yaml:"externalId"`
+	RoleArn                             string `yaml:"roleArn"`
+	ExternalID                          string `yaml:"externalId"`
+	CredentialProcess                   string `yaml:"credentialProcess"`
+	UseCurrentCredentialsForSameAccount bool   `yaml:"useCurrentCredentialsForSameAccount"`
+	SourceRoleArn                       string `yaml:"sourceRoleArn"`
+
+	// TaggingAPIConcurrency and TaggingAPIRateLimit give the role its own tagging API budget,
+	// shared by all its jobs and regions.
+	TaggingAPIConcurrency int     `yaml:"taggingAPIConcurrency"`
+	TaggingAPIRateLimit   float64 `yaml:"taggingAPIRateLimit"`
+
+	// ExternalIDByRegion overrides ExternalID when assuming RoleArn in some regions.
+	ExternalIDByRegion map[string]string `yaml:"externalIdByRegion"`
 }
 
 func (r *Role) ValidateRole(roleIdx int, parent string) error {
-	if r.RoleArn == "" && r.ExternalID != "" {
+	if r.RoleArn == "" && (r.ExternalID != "" || len(r.ExternalIDByRegion) > 0) {
 		return fmt.Errorf("Role [%d] in %v: RoleArn should not be empty", roleIdx, parent)
 	}
+	for region := range r.ExternalIDByRegion {
+		if region == "" {
+			return fmt.Errorf("Role [%d] in %v: ExternalIDByRegion should not contain empty region names", roleIdx, parent)
+		}
+	}
+	if r.RoleArn == "" && r.SourceRoleArn != "" {
+		return fmt.Errorf("Role [%d] in %v: RoleArn should not be empty when SourceRoleArn is set", roleIdx, parent)
+	}
+	if r.TaggingAPIConcurrency < 0 {
+		return fmt.Errorf("Role [%d] in %v: TaggingAPIConcurrency should not be negative", roleIdx, parent)
+	}
+	if r.TaggingAPIRateLimit < 0 {
+		return fmt.Errorf("Role [%d] in %v: TaggingAPIRateLimit should not be negative", roleIdx, parent)
+	}
 
 	return nil
 }
@@ -179,6 +395,17 @@ func (c *ScrapeConf) Validate(logger *slog.Logger) (model.JobsConfig, error) {
 			if err != nil {
 				return model.JobsConfig{}, err
 			}
+
+			if svc := SupportedServices.GetService(job.Type); svc != nil && svc.Global {
+				globalRegions := c.globalServiceRegions(job.Regions)
+				ignored := slices.DeleteFunc(slices.Clone(job.Regions), func(region string) bool {
+					return slices.Contains(globalRegions, region)
+				})
+				if len(ignored) > 0 {
+					logger.Warn("Discovery job of a global service only runs in the region where its metrics are published, ignoring the other regions",
+						"job", fmt.Sprintf("%s/%d", job.Type, idx), "regions", globalRegions, "ignored_regions", ignored)
+				}
+			}
 		}
 
 		if len(c.Discovery.ExportedTagsOnMetrics) > 0 {
@@ -225,6 +452,15 @@ func (c *ScrapeConf) Validate(logger *slog.Logger) (model.JobsConfig, error) {
 		return model.JobsConfig{}, fmt.Errorf("unknown apiVersion value '%s'", c.APIVersion)
 	}
 
+	for service, endpoint := range c.Endpoints {
+		if !slices.Contains(model.EndpointServices, service) {
+			return model.JobsConfig{}, fmt.Errorf("endpoints: unknown service %q, must be one of %v", service, model.EndpointServices)
+		}
+		if endpoint == "" {
+			return model.JobsConfig{}, fmt.Errorf("endpoints: endpoint for service %q must not be empty", service)
+		}
+	}
+
 	return c.toModelConfig(), nil
 }
 
@@ -255,6 +491,9 @@ func (j *Job) validateDiscoveryJob(logger *slog.Logger, jobIdx int) error {
 	if len(j.Metrics) == 0 && len(j.EnhancedMetrics) == 0 {
 		return fmt.Errorf("Discovery job [%s/%d]: Metrics and EnhancedMetrics should not both be empty", j.Type, jobIdx)
 	}
+	if j.Period < 0 || j.Period%60 != 0 {
+		return fmt.Errorf("Discovery job [%s/%d]: period should be a positive multiple of 60, got %d. Set high resolution periods on the metrics", j.Type, jobIdx, j.Period)
+	}
 	for metricIdx, metric := range j.Metrics {
 		err := metric.validateMetric(logger, metricIdx, parent, &j.JobLevelMetricFields)
 		if err != nil {
@@ -262,12 +501,30 @@ func (j *Job) validateDiscoveryJob(logger *slog.Logger, jobIdx int) error {
 		}
 	}
 
+	for _, rt := range j.RequiredTags {
+		if rt.Key == "" {
+			return fmt.Errorf("Discovery job [%s/%d]: required tag key should not be empty", j.Type, jobIdx)
+		}
+	}
+
 	for _, st := range j.SearchTags {
 		if _, err := regexp.Compile(st.Value); err != nil {
 			return fmt.Errorf("Discovery job [%s/%d]: search tag value for %s has invalid regex value %s: %w", j.Type, jobIdx, st.Key, st.Value, err)
 		}
 	}
 
+	if j.ExportRecentlyActive && (!j.RecentlyActiveOnly || j.DirectQuery) {
+		return fmt.Errorf("Discovery job [%s/%d]: exportRecentlyActive requires recentlyActiveOnly, and cannot be combined with directQuery", j.Type, jobIdx)
+	}
+
+	if j.IncludeLinkedAccounts {
+		return fmt.Errorf("Discovery job [%s/%d]: includeLinkedAccounts is only supported by custom namespace jobs, resources are only discovered in the monitoring account", j.Type, jobIdx)
+	}
+
+	if j.DirectQuery && len(SupportedServices.GetService(j.Type).DimensionRegexps) == 0 {
+		return fmt.Errorf("Discovery job [%s/%d]: directQuery is not supported for this namespace, its dimensions can't be derived from resource ARNs", j.Type, jobIdx)
+	}
+
 	if j.RoundingPeriod != nil {
 		logger.Warn(fmt.Sprintf("Discovery job [%s/%d]: Setting a rounding period is deprecated. In a future release it will always be enabled and set to the value of the metric period.", j.Type, jobIdx))
 	}
@@ -278,11 +535,46 @@ func (j *Job) validateDiscoveryJob(logger *slog.Logger, jobIdx int) error {
 			return fmt.Errorf("Discovery job [%s/%d]: enhanced metrics are not supported for this namespace: %w", j.Type, jobIdx, err)
 		}
 
+		// Report all the unsupported enhanced metrics of the job at once rather than one per reload.
+		var unsupported []string
 		for _, em := range j.EnhancedMetrics {
 			if !svc.IsMetricSupported(em.Name) {
-				return fmt.Errorf("Discovery job [%s/%d]: enhanced metric %q is not supported for this namespace", j.Type, jobIdx, em.Name)
+				unsupported = append(unsupported, strconv.Quote(em.Name))
 			}
 		}
+		switch len(unsupported) {
+		case 0:
+		case 1:
+			return fmt.Errorf("Discovery job [%s/%d]: enhanced metric %s is not supported for this namespace", j.Type, jobIdx, unsupported[0])
+		default:
+			return fmt.Errorf("Discovery job [%s/%d]: enhanced metrics %s are not supported for this namespace", j.Type, jobIdx, strings.Join(unsupported, ", "))
+		}
+	}
+
+	switch j.EnhancedMetricsFailurePolicy {
+	case "", EnhancedMetricsFailurePolicyFail, EnhancedMetricsFailurePolicyWarnAndContinue:
+	default:
+		return fmt.Errorf("Discovery job [%s/%d]: EnhancedMetricsFailurePolicy should be one of %q or %q", j.Type, jobIdx, EnhancedMetricsFailurePolicyFail, EnhancedMetricsFailurePolicyWarnAndContinue)
+	}
+
+	switch j.DimensionGranularity {
+	case "", DimensionGranularityMostSpecific, DimensionGranularityMostAggregate:
+	default:
+		return fmt.Errorf("Discovery job [%s/%d]: DimensionGranularity should be one of %q or %q", j.Type, jobIdx, DimensionGranularityMostSpecific, DimensionGranularityMostAggregate)
+	}
+
+	if j.EnhancedMetricsConcurrency < 0 {
+		return fmt.Errorf("Discovery job [%s/%d]: EnhancedMetricsConcurrency should not be negative", j.Type, jobIdx)
+	}
+
+	switch j.CrossRegionResources {
+	case "", CrossRegionResourcesFilter, CrossRegionResourcesKeep:
+	default:
+		return fmt.Errorf("Discovery job [%s/%d]: CrossRegionResources should be one of %q or %q", j.Type, jobIdx, CrossRegionResourcesFilter, CrossRegionResourcesKeep)
+	}
+
+	if err := validateMetricNameOverrides(j.MetricNameOverrides, parent); err != nil {
+		return err
 	}
 
 	return nil
@@ -308,19 +600,74 @@ func (j *CustomNamespace) validateCustomNamespaceJob(logger *slog.Logger, jobIdx
 	if len(j.Regions) == 0 {
 		return fmt.Errorf("CustomNamespace job [%s/%d]: Regions should not be empty", j.Name, jobIdx)
 	}
-	if len(j.Metrics) == 0 {
-		return fmt.Errorf("CustomNamespace job [%s/%d]: Metrics should not be empty", j.Name, jobIdx)
+	if len(j.Metrics) == 0 && len(j.Expressions) == 0 {
+		return fmt.Errorf("CustomNamespace job [%s/%d]: Metrics and Expressions should not both be empty", j.Name, jobIdx)
+	}
+	for expressionIdx, expression := range j.Expressions {
+		if err := expression.validateExpression(expressionIdx, parent, &j.JobLevelMetricFields); err != nil {
+			return err
+		}
 	}
 	for metricIdx, metric := range j.Metrics {
 		err := metric.validateMetric(logger, metricIdx, parent, &j.JobLevelMetricFields)
 		if err != nil {
 			return err
 		}
+		if j.IncludeLinkedAccounts && metric.UseGetMetricStatistics {
+			return fmt.Errorf("Metric [%s/%d] in %v: UseGetMetricStatistics can't query linked accounts, and cannot be combined with IncludeLinkedAccounts", metric.Name, metricIdx, parent)
+		}
+		if metric.ExportedTags != nil {
+			return fmt.Errorf("Metric [%s/%d] in %v: ExportedTags only applies to the metrics of discovery jobs", metric.Name, metricIdx, parent)
+		}
 	}
 
 	if j.RoundingPeriod != nil {
 		logger.Warn(fmt.Sprintf("CustomNamespace job [%s/%d]: Setting a rounding period is deprecated. It is always enabled and set to the value of the metric period.", j.Name, jobIdx))
 	}
+
+	if err := validateMetricNameOverrides(j.MetricNameOverrides, parent); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (e *Expression) validateExpression(expressionIdx int, parent string, jobLevel *JobLevelMetricFields) error {
+	if !statisticNameRegexp.MatchString(e.Name) {
+		return fmt.Errorf("Expression [%s/%d] in %v: Name %q is not a valid metric name", e.Name, expressionIdx, parent, e.Name)
+	}
+	if e.Expression == "" {
+		return fmt.Errorf("Expression [%s/%d] in %v: Expression should not be empty", e.Name, expressionIdx, parent)
+	}
+	for _, name := range slices.Sorted(maps.Keys(e.Labels)) {
+		if !prom_model.LegacyValidation.IsValidLabelName(name) {
+			return fmt.Errorf("Expression [%s/%d] in %v: label name %q is invalid", e.Name, expressionIdx, parent, name)
+		}
+	}
+
+	if e.Period == 0 {
+		e.Period = cmp.Or(jobLevel.Period, model.DefaultPeriodSeconds)
+	}
+	if e.Length == 0 {
+		e.Length = cmp.Or(jobLevel.Length, model.DefaultLengthSeconds)
+	}
+	if e.Delay == 0 {
+		e.Delay = jobLevel.Delay
+	}
+	if e.Period < 1 {
+		return fmt.Errorf("Expression [%s/%d] in %v: Period value should be a positive integer", e.Name, expressionIdx, parent)
+	}
+	if e.Length < e.Period {
+		return fmt.Errorf("Expression [%s/%d] in %v: length(%d) is smaller than period(%d)", e.Name, expressionIdx, parent, e.Length, e.Period)
+	}
+	return nil
+}
+
+func validateMetricNameOverrides(overrides map[string]string, parent string) error {
+	for _, metricName := range slices.Sorted(maps.Keys(overrides)) {
+		if !statisticNameRegexp.MatchString(overrides[metricName]) {
+			return fmt.Errorf("%v: MetricNameOverrides renames %s to %q, which is not a valid metric name", parent, metricName, overrides[metricName])
+		}
+	}
 	return nil
 }
 
@@ -344,16 +691,70 @@ func (j *Static) validateStaticJob(logger *slog.Logger, jobIdx int) error {
 	if len(j.Regions) == 0 {
 		return fmt.Errorf("Static job [%s/%d]: Regions should not be empty", j.Name, jobIdx)
 	}
+	if len(j.Metrics) == 0 {
+		return fmt.Errorf("Static job [%s/%d]: Metrics should not be empty", j.Name, jobIdx)
+	}
 	for metricIdx, metric := range j.Metrics {
 		err := metric.validateMetric(logger, metricIdx, parent, nil)
 		if err != nil {
 			return err
 		}
+		if metric.ExportedTags != nil {
+			return fmt.Errorf("Metric [%s/%d] in %v: ExportedTags only applies to the metrics of discovery jobs", metric.Name, metricIdx, parent)
+		}
+		if metric.SumWithoutDimensions != nil {
+			return fmt.Errorf("Metric [%s/%d] in %v: SumWithoutDimensions only applies to the metrics of discovery and custom namespace jobs", metric.Name, metricIdx, parent)
+		}
+		if !isGetMetricStatisticsPeriod(metric.Period) {
+			return fmt.Errorf("Metric [%s/%d] in %v: static jobs use GetMetricStatistics, which requires a period of 1, 5, 10, 30 or a multiple of 60, got %d", metric.Name, metricIdx, parent, metric.Period)
+		}
 	}
 
 	return nil
 }
 
+// isGetMetricStatisticsPeriod returns whether GetMetricStatistics accepts period, see
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricStatistics.html
+func isGetMetricStatisticsPeriod(period int64) bool {
+	return period%60 == 0 || slices.Contains([]int64{1, 5, 10, 30}, period)
+}
+
+// normalizeStatistic returns the case CloudWatch expects for statistic, e.g. Average for average or p99 for P99,
+// and whether it is a statistic of CloudWatch at all. See
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/Statistics-definitions.html
+func normalizeStatistic(statistic string) (string, bool) {
+	for _, standard := range standardStatistics {
+		if strings.EqualFold(statistic, standard) {
+			return standard, true
+		}
+	}
+	if strings.EqualFold(statistic, "IQM") {
+		return "IQM", true
+	}
+	if shorthandExtendedStatisticRegexp.MatchString(statistic) {
+		return strings.ToLower(statistic), true
+	}
+	if match := rangeExtendedStatisticRegexp.FindStringSubmatch(statistic); match != nil && match[2]+match[3] != "" {
+		return fmt.Sprintf("%s(%s:%s)", strings.ToUpper(match[1]), match[2], match[3]), true
+	}
+	return "", false
+}
+
+// normalizeStatisticKeys returns statistics keyed by their normalized statistic. Keys which aren't statistics are kept as-is.
+func normalizeStatisticKeys[V any](statistics map[string]V) map[string]V {
+	if statistics == nil {
+		return nil
+	}
+	normalized := make(map[string]V, len(statistics))
+	for statistic, value := range statistics {
+		if n, ok := normalizeStatistic(statistic); ok {
+			statistic = n
+		}
+		normalized[statistic] = value
+	}
+	return normalized
+}
+
 func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent string, discovery *JobLevelMetricFields) error {
 	if m.Name == "" {
 		return fmt.Errorf("Metric [%s/%d] in %v: Name should not be empty", m.Name, metricIdx, parent)
@@ -367,6 +768,65 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 			return fmt.Errorf("Metric [%s/%d] in %v: Statistics should not be empty", m.Name, metricIdx, parent)
 		}
 	}
+	// CloudWatch statistics are case sensitive, and return no data points for e.g. average
+	normalizedStatistics := make([]string, 0, len(mStatistics))
+	for _, statistic := range mStatistics {
+		normalized, ok := normalizeStatistic(statistic)
+		if !ok {
+			return fmt.Errorf("Metric [%s/%d] in %v: %q is not a valid CloudWatch statistic", m.Name, metricIdx, parent, statistic)
+		}
+		normalizedStatistics = append(normalizedStatistics, normalized)
+	}
+	mStatistics = normalizedStatistics
+
+	mStatisticNames := normalizeStatisticKeys(m.StatisticNames)
+	if len(mStatisticNames) == 0 && discovery != nil {
+		mStatisticNames = normalizeStatisticKeys(discovery.StatisticNames)
+	}
+	for _, statistic := range slices.Sorted(maps.Keys(mStatisticNames)) {
+		if !statisticNameRegexp.MatchString(mStatisticNames[statistic]) {
+			return fmt.Errorf("Metric [%s/%d] in %v: StatisticNames renames %s to %q, which is not a valid metric name suffix", m.Name, metricIdx, parent, statistic, mStatisticNames[statistic])
+		}
+	}
+
+	mNilToZeroStatistics := normalizeStatisticKeys(m.NilToZeroStatistics)
+	if len(mNilToZeroStatistics) == 0 && discovery != nil {
+		mNilToZeroStatistics = normalizeStatisticKeys(discovery.NilToZeroStatistics)
+	}
+	// Statistics set at job level only apply to the metrics which have them.
+	for _, statistic := range slices.Sorted(maps.Keys(normalizeStatisticKeys(m.NilToZeroStatistics))) {
+		if !slices.Contains(mStatistics, statistic) {
+			return fmt.Errorf("Metric [%s/%d] in %v: NilToZeroStatistics sets %s, which is not one of the statistics of the metric", m.Name, metricIdx, parent, statistic)
+		}
+	}
+
+	mMaxSeriesPerMetric := m.MaxSeriesPerMetric
+	if mMaxSeriesPerMetric == 0 && discovery != nil {
+		mMaxSeriesPerMetric = discovery.MaxSeriesPerMetric
+	}
+	if mMaxSeriesPerMetric < 0 {
+		return fmt.Errorf("Metric [%s/%d] in %v: MaxSeriesPerMetric should not be negative", m.Name, metricIdx, parent)
+	}
+	mSeriesCapAction := m.SeriesCapAction
+	if mSeriesCapAction == "" {
+		if discovery != nil && discovery.SeriesCapAction != "" {
+			mSeriesCapAction = discovery.SeriesCapAction
+		} else {
+			mSeriesCapAction = SeriesCapActionDrop
+		}
+	}
+	if mSeriesCapAction != SeriesCapActionDrop && mSeriesCapAction != SeriesCapActionSample {
+		return fmt.Errorf("Metric [%s/%d] in %v: SeriesCapAction should be one of %q or %q", m.Name, metricIdx, parent, SeriesCapActionDrop, SeriesCapActionSample)
+	}
+
+	if m.MetricType == "" {
+		m.MetricType = model.MetricTypeGauge
+	}
+	switch m.MetricType {
+	case model.MetricTypeGauge, model.MetricTypeCounter, model.MetricTypeUntyped:
+	default:
+		return fmt.Errorf("Metric [%s/%d] in %v: MetricType should be one of %q, %q or %q", m.Name, metricIdx, parent, model.MetricTypeGauge, model.MetricTypeCounter, model.MetricTypeUntyped)
+	}
 
 	mPeriod := m.Period
 	if mPeriod == 0 {
@@ -388,6 +848,26 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 		}
 	}
 
+	mAdjustPeriodToDataPointLimit := m.AdjustPeriodToDataPointLimit
+	if mAdjustPeriodToDataPointLimit == nil {
+		if discovery != nil && discovery.AdjustPeriodToDataPointLimit != nil {
+			mAdjustPeriodToDataPointLimit = discovery.AdjustPeriodToDataPointLimit
+		} else {
+			mAdjustPeriodToDataPointLimit = aws.Bool(false)
+		}
+	}
+	if dataPoints := mLength / mPeriod; dataPoints > model.MaxGetMetricDataDataPoints {
+		if !aws.ToBool(mAdjustPeriodToDataPointLimit) {
+			return fmt.Errorf(
+				"Metric [%s/%d] in %v: length(%d) and period(%d) request %d data points, more than the %d returned by GetMetricData. Increase the period or enable AdjustPeriodToDataPointLimit",
+				m.Name, metricIdx, parent, mLength, mPeriod, dataPoints, model.MaxGetMetricDataDataPoints,
+			)
+		}
+		adjustedPeriod := periodForDataPointLimit(mLength)
+		logger.Warn(fmt.Sprintf("Metric [%s/%d] in %v: period(%d) increased to %d to stay within the %d data points returned by GetMetricData", m.Name, metricIdx, parent, mPeriod, adjustedPeriod, model.MaxGetMetricDataDataPoints))
+		mPeriod = adjustedPeriod
+	}
+
 	// Delay at the metric level has been ignored for an incredibly long time. If we started respecting metric delay
 	// now a lot of configurations would break on release. This logs a warning for now
 	if m.Delay != 0 {
@@ -425,6 +905,56 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 		}
 	}
 
+	mKeepLastN := m.KeepLastN
+	if mKeepLastN == 0 && discovery != nil {
+		mKeepLastN = discovery.KeepLastN
+	}
+	if mKeepLastN < 0 {
+		return fmt.Errorf("Metric [%s/%d] in %v: KeepLastN should not be negative", m.Name, metricIdx, parent)
+	}
+	if mKeepLastN > 1 && !aws.ToBool(mAddCloudwatchTimestamp) {
+		return fmt.Errorf("Metric [%s/%d] in %v: KeepLastN can only be set above 1 if AddCloudwatchTimestamp is enabled", m.Name, metricIdx, parent)
+	}
+	if mKeepLastN > 0 && aws.ToBool(mExportAllDataPoints) {
+		return fmt.Errorf("Metric [%s/%d] in %v: KeepLastN cannot be combined with ExportAllDataPoints", m.Name, metricIdx, parent)
+	}
+
+	mEmptyResultGrace := m.EmptyResultGrace
+	if mEmptyResultGrace == 0 && discovery != nil {
+		mEmptyResultGrace = discovery.EmptyResultGrace
+	}
+	if mEmptyResultGrace < 0 {
+		return fmt.Errorf("Metric [%s/%d] in %v: EmptyResultGrace should not be negative", m.Name, metricIdx, parent)
+	}
+
+	mSumWithoutDimensions := m.SumWithoutDimensions
+	if len(mSumWithoutDimensions) == 0 && discovery != nil {
+		mSumWithoutDimensions = discovery.SumWithoutDimensions
+	}
+	for _, dimension := range mSumWithoutDimensions {
+		if dimension == "" {
+			return fmt.Errorf("Metric [%s/%d] in %v: SumWithoutDimensions should not contain empty dimension names", m.Name, metricIdx, parent)
+		}
+	}
+	if len(mSumWithoutDimensions) > 0 && m.UseGetMetricStatistics {
+		return fmt.Errorf("Metric [%s/%d] in %v: SumWithoutDimensions only applies to the metrics queried with GetMetricData, and cannot be combined with UseGetMetricStatistics", m.Name, metricIdx, parent)
+	}
+	if len(mSumWithoutDimensions) > 0 {
+		// mStatistics are normalized, so e.g. sum is accepted as Sum
+		for _, statistic := range mStatistics {
+			if statistic != "Sum" && statistic != "SampleCount" {
+				return fmt.Errorf("Metric [%s/%d] in %v: SumWithoutDimensions only supports the Sum and SampleCount statistics, which can be summed, got %s", m.Name, metricIdx, parent, statistic)
+			}
+		}
+	}
+
+	if m.UseGetMetricStatistics && !isGetMetricStatisticsPeriod(mPeriod) {
+		return fmt.Errorf("Metric [%s/%d] in %v: UseGetMetricStatistics requires a period of 1, 5, 10, 30 or a multiple of 60, got %d", m.Name, metricIdx, parent, mPeriod)
+	}
+	if m.UseGetMetricStatistics && (aws.ToBool(mExportAllDataPoints) || mKeepLastN > 1) {
+		return fmt.Errorf("Metric [%s/%d] in %v: UseGetMetricStatistics only exports the most recent data point, and cannot be combined with ExportAllDataPoints or KeepLastN", m.Name, metricIdx, parent)
+	}
+
 	if aws.ToBool(mExportAllDataPoints) && !aws.ToBool(mAddCloudwatchTimestamp) {
 		return fmt.Errorf("Metric [%s/%d] in %v: ExportAllDataPoints can only be enabled if AddCloudwatchTimestamp is enabled", m.Name, metricIdx, parent)
 	}
@@ -441,31 +971,80 @@ func (m *Metric) validateMetric(logger *slog.Logger, metricIdx int, parent strin
 	m.NilToZero = mNilToZero
 	m.AddCloudwatchTimestamp = mAddCloudwatchTimestamp
 	m.ExportAllDataPoints = mExportAllDataPoints
+	m.KeepLastN = mKeepLastN
+	m.EmptyResultGrace = mEmptyResultGrace
 	m.Statistics = mStatistics
+	m.StatisticNames = mStatisticNames
+	m.NilToZeroStatistics = mNilToZeroStatistics
+	m.AdjustPeriodToDataPointLimit = mAdjustPeriodToDataPointLimit
+	m.MaxSeriesPerMetric = mMaxSeriesPerMetric
+	m.SeriesCapAction = mSeriesCapAction
+	m.SumWithoutDimensions = mSumWithoutDimensions
 
 	return nil
 }
 
+// periodForDataPointLimit returns the smallest period supported by CloudWatch (1, 5, 10, 30 or a
+// multiple of 60 seconds) for which length doesn't exceed the data points returned by GetMetricData.
+func periodForDataPointLimit(length int64) int64 {
+	minPeriod := (length + model.MaxGetMetricDataDataPoints - 1) / model.MaxGetMetricDataDataPoints
+	for _, period := range []int64{1, 5, 10, 30} {
+		if minPeriod <= period {
+			return period
+		}
+	}
+	return (minPeriod + 59) / 60 * 60
+}
+
 func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 	jobsCfg := model.JobsConfig{}
 	jobsCfg.StsRegion = c.StsRegion
+	jobsCfg.Endpoints = c.Endpoints
 
 	for _, discoveryJob := range c.Discovery.Jobs {
 		svc := SupportedServices.GetService(discoveryJob.Type)
 
 		job := model.DiscoveryJob{}
 		job.Regions = discoveryJob.Regions
+		if svc.Global {
+			// Querying a global service from several regions would only duplicate clients and queries
+			job.Regions = c.globalServiceRegions(discoveryJob.Regions)
+		}
 		job.Namespace = svc.Namespace
 		job.DimensionNameRequirements = discoveryJob.DimensionNameRequirements
 		job.RecentlyActiveOnly = discoveryJob.RecentlyActiveOnly
+		job.ExportRecentlyActive = discoveryJob.ExportRecentlyActive
 		job.RoundingPeriod = discoveryJob.RoundingPeriod
-		job.Roles = toModelRoles(discoveryJob.Roles)
+		job.Roles = toModelRoles(discoveryJob.Roles, discoveryJob.FIPS)
 		job.SearchTags = toModelSearchTags(discoveryJob.SearchTags)
 		job.CustomTags = toModelTags(discoveryJob.CustomTags)
+		job.RequiredTags = toModelTags(discoveryJob.RequiredTags)
 		job.Metrics = toModelMetricConfig(discoveryJob.Metrics)
+		if discoveryJob.Period != 0 {
+			// The metrics using the period of the job get it from the GetMetricData processor
+			job.DefaultPeriodOverride = discoveryJob.Period
+			for _, m := range job.Metrics {
+				if m.Period == discoveryJob.Period {
+					m.Period = 0
+				}
+			}
+		}
 		job.IncludeContextOnInfoMetrics = discoveryJob.IncludeContextOnInfoMetrics
+		job.DedupeInfoMetricsAcrossRegions = discoveryJob.DedupeInfoMetricsAcrossRegions
+		job.SkipZeroDimensionMetrics = discoveryJob.SkipZeroDimensionMetrics
+		job.DirectQuery = discoveryJob.DirectQuery
+		job.ResourceCountGroupByTag = discoveryJob.ResourceCountGroupByTag
+		job.AllowMultipleResourceMappings = discoveryJob.AllowMultipleResourceMappings
+		job.LogUnmatchedMetrics = discoveryJob.LogUnmatchedMetrics
+		job.DimensionGranularity = discoveryJob.DimensionGranularity
+		job.LabelsSnakeCase = discoveryJob.LabelsSnakeCase
+		job.MetricNameOverrides = discoveryJob.MetricNameOverrides
 		job.DimensionsRegexps = svc.ToModelDimensionsRegexp()
 		job.EnhancedMetrics = svc.toModelEnhancedMetricsConfig(discoveryJob.EnhancedMetrics)
+		job.FailOnEnhancedMetricsError = discoveryJob.EnhancedMetricsFailurePolicy == EnhancedMetricsFailurePolicyFail
+		job.EnhancedMetricsConcurrency = discoveryJob.EnhancedMetricsConcurrency
+		// Global services are queried in a single region, whatever the region of their resources.
+		job.KeepCrossRegionResources = svc.Global || discoveryJob.CrossRegionResources == CrossRegionResourcesKeep
 
 		job.ExportedTagsOnMetrics = []string{}
 		if len(c.Discovery.ExportedTagsOnMetrics) > 0 {
@@ -482,10 +1061,11 @@ func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 		job.Name = staticJob.Name
 		job.Namespace = staticJob.Namespace
 		job.Regions = staticJob.Regions
-		job.Roles = toModelRoles(staticJob.Roles)
+		job.Roles = toModelRoles(staticJob.Roles, staticJob.FIPS)
 		job.CustomTags = toModelTags(staticJob.CustomTags)
 		job.Dimensions = toModelDimensions(staticJob.Dimensions)
 		job.Metrics = toModelMetricConfig(staticJob.Metrics)
+		job.LabelsSnakeCase = staticJob.LabelsSnakeCase
 		jobsCfg.StaticJobs = append(jobsCfg.StaticJobs, job)
 	}
 
@@ -497,9 +1077,13 @@ func (c *ScrapeConf) toModelConfig() model.JobsConfig {
 		job.DimensionNameRequirements = customNamespaceJob.DimensionNameRequirements
 		job.RoundingPeriod = customNamespaceJob.RoundingPeriod
 		job.RecentlyActiveOnly = customNamespaceJob.RecentlyActiveOnly
-		job.Roles = toModelRoles(customNamespaceJob.Roles)
+		job.Roles = toModelRoles(customNamespaceJob.Roles, customNamespaceJob.FIPS)
 		job.CustomTags = toModelTags(customNamespaceJob.CustomTags)
 		job.Metrics = toModelMetricConfig(customNamespaceJob.Metrics)
+		job.LabelsSnakeCase = customNamespaceJob.LabelsSnakeCase
+		job.MetricNameOverrides = customNamespaceJob.MetricNameOverrides
+		job.IncludeLinkedAccounts = customNamespaceJob.IncludeLinkedAccounts
+		job.Expressions = toModelExpressions(customNamespaceJob.Expressions)
 		jobsCfg.CustomNamespaceJobs = append(jobsCfg.CustomNamespaceJobs, job)
 	}
 
@@ -530,12 +1114,26 @@ func toModelSearchTags(tags []Tag) []model.SearchTag {
 	return ret
 }
 
-func toModelRoles(roles []Role) []model.Role {
+func toModelRoles(roles []Role, fips *bool) []model.Role {
+	useFIPSEndpoint := aws.FIPSEndpointStateUnset
+	if fips != nil {
+		useFIPSEndpoint = aws.FIPSEndpointStateDisabled
+		if *fips {
+			useFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		}
+	}
 	ret := make([]model.Role, 0, len(roles))
 	for _, r := range roles {
 		ret = append(ret, model.Role{
-			RoleArn:    r.RoleArn,
-			ExternalID: r.ExternalID,
+			RoleArn:                             r.RoleArn,
+			ExternalID:                          r.ExternalID,
+			ExternalIDByRegion:                  model.NewRegionalExternalIDs(r.ExternalIDByRegion),
+			CredentialProcess:                   r.CredentialProcess,
+			UseCurrentCredentialsForSameAccount: r.UseCurrentCredentialsForSameAccount,
+			SourceRoleArn:                       r.SourceRoleArn,
+			TaggingAPIConcurrency:               r.TaggingAPIConcurrency,
+			TaggingAPIRateLimit:                 r.TaggingAPIRateLimit,
+			UseFIPSEndpoint:                     useFIPSEndpoint,
 		})
 	}
 	return ret
@@ -552,6 +1150,21 @@ func toModelDimensions(dimensions []Dimension) []model.Dimension {
 	return ret
 }
 
+func toModelExpressions(expressions []*Expression) []*model.ExpressionConfig {
+	ret := make([]*model.ExpressionConfig, 0, len(expressions))
+	for _, e := range expressions {
+		ret = append(ret, &model.ExpressionConfig{
+			Name:       e.Name,
+			Expression: e.Expression,
+			Period:     e.Period,
+			Length:     e.Length,
+			Delay:      e.Delay,
+			Labels:     e.Labels,
+		})
+	}
+	return ret
+}
+
 func toModelMetricConfig(metrics []*Metric) []*model.MetricConfig {
 	ret := make([]*model.MetricConfig, 0, len(metrics))
 	for _, m := range metrics {
@@ -564,6 +1177,16 @@ func toModelMetricConfig(metrics []*Metric) []*model.MetricConfig {
 			NilToZero:              aws.ToBool(m.NilToZero),
 			AddCloudwatchTimestamp: aws.ToBool(m.AddCloudwatchTimestamp),
 			ExportAllDataPoints:    aws.ToBool(m.ExportAllDataPoints),
+			KeepLastN:              m.KeepLastN,
+			EmptyResultGrace:       m.EmptyResultGrace,
+			StatisticNames:         m.StatisticNames,
+			NilToZeroStatistics:    m.NilToZeroStatistics,
+			UseGetMetricStatistics: m.UseGetMetricStatistics,
+			MaxSeriesPerMetric:     m.MaxSeriesPerMetric,
+			SampleCappedSeries:     m.SeriesCapAction == SeriesCapActionSample,
+			ExportedTags:           m.ExportedTags,
+			MetricType:             m.MetricType,
+			SumWithoutDimensions:   m.SumWithoutDimensions,
 		})
 	}
 	return ret
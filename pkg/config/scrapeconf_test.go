@@ -17,8 +17,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
 func TestConfLoad(t *testing.T) {
@@ -30,6 +33,15 @@ func TestConfLoad(t *testing.T) {
 		{configFile: "sts_region.ok.yml"},
 		{configFile: "multiple_roles.ok.yml"},
 		{configFile: "custom_namespace.ok.yml"},
+		{configFile: "global_service_region.ok.yml"},
+		{configFile: "endpoints.ok.yml"},
+		{configFile: "statistic_names.ok.yml"},
+		{configFile: "series_cap.ok.yml"},
+		{configFile: "job_level_period.ok.yml"},
+		{configFile: "custom_namespace_expressions.ok.yml"},
+		{configFile: "nil_to_zero_statistics.ok.yml"},
+		{configFile: "statistic_case.ok.yml"},
+		{configFile: "fips.ok.yml"},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.configFile, func(t *testing.T) {
@@ -43,6 +55,228 @@ func TestConfLoad(t *testing.T) {
 	}
 }
 
+func TestGlobalServiceRegion(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/global_service_region.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 2)
+	require.Equal(t, "AWS/CloudFront", jobsCfg.DiscoveryJobs[0].Namespace)
+	require.Equal(t, []string{"us-west-2"}, jobsCfg.DiscoveryJobs[0].Regions)
+	require.Equal(t, "AWS/S3", jobsCfg.DiscoveryJobs[1].Namespace)
+	require.Equal(t, []string{"eu-west-1", "us-east-1"}, jobsCfg.DiscoveryJobs[1].Regions)
+
+	config.GlobalServiceRegion = ""
+	jobsCfg = config.toModelConfig()
+	require.Equal(t, []string{DefaultGlobalServiceRegion}, jobsCfg.DiscoveryJobs[0].Regions)
+}
+
+func TestGlobalServiceRegions(t *testing.T) {
+	for _, tc := range []struct {
+		name                string
+		globalServiceRegion string
+		regions             []string
+		want                []string
+	}{
+		{name: "aws partition", regions: []string{"eu-west-1", "us-east-1"}, want: []string{"us-east-1"}},
+		{name: "configured region", globalServiceRegion: "us-west-2", regions: []string{"eu-west-1"}, want: []string{"us-west-2"}},
+		{name: "GovCloud", regions: []string{"us-gov-east-1"}, want: []string{"us-gov-west-1"}},
+		{name: "China", regions: []string{"cn-north-1"}, want: []string{"cn-northwest-1"}},
+		{name: "configured region of another partition", globalServiceRegion: "us-west-2", regions: []string{"cn-north-1"}, want: []string{"cn-northwest-1"}},
+		{name: "configured region of the partition", globalServiceRegion: "us-gov-east-1", regions: []string{"us-gov-west-1"}, want: []string{"us-gov-east-1"}},
+		{name: "several partitions", regions: []string{"eu-west-1", "cn-north-1", "cn-northwest-1"}, want: []string{"us-east-1", "cn-northwest-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := ScrapeConf{GlobalServiceRegion: tc.globalServiceRegion}
+			require.Equal(t, tc.want, config.globalServiceRegions(tc.regions))
+		})
+	}
+}
+
+func TestAdjustPeriodToDataPointLimit(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/data_point_limit.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	metrics := jobsCfg.DiscoveryJobs[0].Metrics
+	require.Len(t, metrics, 2)
+	// 172800 data points at a 1s period are over the limit, 5s is the smallest supported period within it.
+	require.Equal(t, int64(5), metrics[0].Period)
+	// Metrics within the limit keep their period.
+	require.Equal(t, int64(60), metrics[1].Period)
+}
+
+func TestSeriesCap(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/series_cap.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	metrics := jobsCfg.DiscoveryJobs[0].Metrics
+	require.Len(t, metrics, 2)
+	// Count inherits the job level cap.
+	require.Equal(t, 100, metrics[0].MaxSeriesPerMetric)
+	require.True(t, metrics[0].SampleCappedSeries)
+	require.Equal(t, 10, metrics[1].MaxSeriesPerMetric)
+	require.False(t, metrics[1].SampleCappedSeries)
+}
+
+func TestSumWithoutDimensions(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/sum_without_dimensions.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	metrics := jobsCfg.DiscoveryJobs[0].Metrics
+	require.Len(t, metrics, 2)
+	// RequestCount inherits the job level dimensions.
+	require.Equal(t, []string{"AvailabilityZone"}, metrics[0].SumWithoutDimensions)
+	require.Equal(t, []string{"AvailabilityZone", "TargetGroup"}, metrics[1].SumWithoutDimensions)
+}
+
+func TestCrossRegionResources(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/cross_region_resources.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 3)
+	// Regional namespaces filter the resources of other regions by default.
+	require.False(t, jobsCfg.DiscoveryJobs[0].KeepCrossRegionResources)
+	require.True(t, jobsCfg.DiscoveryJobs[1].KeepCrossRegionResources)
+	// Global namespaces always keep them.
+	require.True(t, jobsCfg.DiscoveryJobs[2].KeepCrossRegionResources)
+}
+
+func TestExternalIDByRegion(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/externalid_by_region.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	require.Len(t, jobsCfg.DiscoveryJobs[0].Roles, 1)
+	role := jobsCfg.DiscoveryJobs[0].Roles[0]
+	require.Equal(t, "partner-eu", role.ExternalIDForRegion("eu-west-1"))
+	// Regions without their own external ID use externalId.
+	require.Equal(t, "shared", role.ExternalIDForRegion("us-east-1"))
+}
+
+func TestJobLevelPeriod(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/job_level_period.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	metrics := jobsCfg.DiscoveryJobs[0].Metrics
+	require.Len(t, metrics, 2)
+	// BucketSizeBytes inherits the daily period of the job, which the GetMetricData processor sets.
+	require.Equal(t, int64(86400), jobsCfg.DiscoveryJobs[0].DefaultPeriodOverride)
+	require.Equal(t, int64(0), metrics[0].Period)
+	require.Equal(t, int64(172800), metrics[0].Length)
+	// AllRequests sets its own period, which wins over the job level one.
+	require.Equal(t, int64(300), metrics[1].Period)
+	require.Equal(t, int64(600), metrics[1].Length)
+}
+
+func TestCustomNamespaceExpressions(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/custom_namespace_expressions.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.CustomNamespaceJobs, 1)
+	require.Equal(t, []*model.ExpressionConfig{
+		// error_rate uses the period and length of the job
+		{
+			Name:       "error_rate",
+			Expression: "SELECT AVG(ErrorRate) FROM SCHEMA(MyApp, Service)",
+			Period:     600,
+			Length:     1200,
+			Labels:     map[string]string{"team": "payments"},
+		},
+		{
+			Name:       "latency_p99",
+			Expression: "SELECT MAX(Latency) FROM SCHEMA(MyApp, Service)",
+			Period:     60,
+			Length:     300,
+		},
+	}, jobsCfg.CustomNamespaceJobs[0].Expressions)
+}
+
+func TestStatisticCase(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/statistic_case.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	metrics := jobsCfg.DiscoveryJobs[0].Metrics
+	require.Len(t, metrics, 1)
+	require.Equal(t, []string{"Average", "p99"}, metrics[0].Statistics)
+	require.Equal(t, map[string]string{"Average": "avg"}, metrics[0].StatisticNames)
+	require.Equal(t, map[string]bool{"Average": true}, metrics[0].NilToZeroStatistics)
+}
+
+func TestJobLevelFIPS(t *testing.T) {
+	config := ScrapeConf{}
+	jobsCfg, err := config.Load("testdata/fips.ok.yml", promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, jobsCfg.DiscoveryJobs, 1)
+	require.Equal(t, aws.FIPSEndpointStateEnabled, jobsCfg.DiscoveryJobs[0].Roles[0].UseFIPSEndpoint)
+	require.Len(t, jobsCfg.StaticJobs, 1)
+	require.Equal(t, aws.FIPSEndpointStateDisabled, jobsCfg.StaticJobs[0].Roles[0].UseFIPSEndpoint)
+	// Jobs which don't set fips follow the -fips flag
+	require.Len(t, jobsCfg.CustomNamespaceJobs, 1)
+	require.Equal(t, aws.FIPSEndpointStateUnset, jobsCfg.CustomNamespaceJobs[0].Roles[0].UseFIPSEndpoint)
+}
+
+func TestPeriodForDataPointLimit(t *testing.T) {
+	for _, tc := range []struct {
+		length int64
+		want   int64
+	}{
+		{length: 100800, want: 1},
+		{length: 100801, want: 5},
+		{length: 3024000, want: 30},
+		{length: 3024001, want: 60},
+		{length: 10080000, want: 120},
+	} {
+		t.Run(fmt.Sprint(tc.length), func(t *testing.T) {
+			require.Equal(t, tc.want, periodForDataPointLimit(tc.length))
+		})
+	}
+}
+
+func TestNormalizeStatistic(t *testing.T) {
+	for _, tc := range []struct {
+		statistic string
+		want      string
+	}{
+		{statistic: "average", want: "Average"},
+		{statistic: "AVERAGE", want: "Average"},
+		{statistic: "samplecount", want: "SampleCount"},
+		{statistic: "Sum", want: "Sum"},
+		{statistic: "P99", want: "p99"},
+		{statistic: "p99.9", want: "p99.9"},
+		{statistic: "TM90", want: "tm90"},
+		{statistic: "tm(10%:90%)", want: "TM(10%:90%)"},
+		{statistic: "pr(:300)", want: "PR(:300)"},
+		{statistic: "iqm", want: "IQM"},
+	} {
+		t.Run(tc.statistic, func(t *testing.T) {
+			got, ok := normalizeStatistic(tc.statistic)
+			require.True(t, ok)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	for _, statistic := range []string{"bogus", "", "p", "p101", "avg", "tm()", "tm(:)"} {
+		t.Run(statistic, func(t *testing.T) {
+			_, ok := normalizeStatistic(statistic)
+			require.False(t, ok)
+		})
+	}
+}
+
 func TestBadConfigs(t *testing.T) {
 	testCases := []struct {
 		configFile string
@@ -52,6 +286,14 @@ func TestBadConfigs(t *testing.T) {
 			configFile: "externalid_without_rolearn.bad.yml",
 			errorMsg:   "RoleArn should not be empty",
 		},
+		{
+			configFile: "externalid_by_region_without_rolearn.bad.yml",
+			errorMsg:   "RoleArn should not be empty",
+		},
+		{
+			configFile: "source_role_without_rolearn.bad.yml",
+			errorMsg:   "RoleArn should not be empty when SourceRoleArn is set",
+		},
 		{
 			configFile: "externalid_with_empty_rolearn.bad.yml",
 			errorMsg:   "RoleArn should not be empty",
@@ -72,6 +314,46 @@ func TestBadConfigs(t *testing.T) {
 			configFile: "custom_namespace_without_region.bad.yml",
 			errorMsg:   "Regions should not be empty",
 		},
+		{
+			configFile: "custom_namespace_without_metrics.bad.yml",
+			errorMsg:   "CustomNamespace job [customMetrics/0]: Metrics and Expressions should not both be empty",
+		},
+		{
+			configFile: "custom_namespace_expression_empty.bad.yml",
+			errorMsg:   "Expression [error_rate/0] in CustomNamespace job [MyApp/0]: Expression should not be empty",
+		},
+		{
+			configFile: "custom_namespace_expression_label.bad.yml",
+			errorMsg:   `Expression [error_rate/0] in CustomNamespace job [MyApp/0]: label name "owning-team" is invalid`,
+		},
+		{
+			configFile: "custom_namespace_metric_name_override.bad.yml",
+			errorMsg:   `CustomNamespace job [CustomEC2Metrics/0]: MetricNameOverrides renames ErrorCount to "error-count", which is not a valid metric name`,
+		},
+		{
+			configFile: "custom_namespace_linked_accounts_get_metric_statistics.bad.yml",
+			errorMsg:   "cannot be combined with IncludeLinkedAccounts",
+		},
+		{
+			configFile: "custom_namespace_metric_exported_tags.bad.yml",
+			errorMsg:   "ExportedTags only applies to the metrics of discovery jobs",
+		},
+		{
+			configFile: "discovery_job_without_region.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: Regions should not be empty",
+		},
+		{
+			configFile: "discovery_job_without_metrics.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: Metrics and EnhancedMetrics should not both be empty",
+		},
+		{
+			configFile: "static_job_without_region.bad.yml",
+			errorMsg:   "Static job [autoscaling/0]: Regions should not be empty",
+		},
+		{
+			configFile: "static_job_without_metrics.bad.yml",
+			errorMsg:   "Static job [autoscaling/0]: Metrics should not be empty",
+		},
 		{
 			configFile: "discovery_job_type_unknown.bad.yml",
 			errorMsg:   "Discovery job [0]: Service is not in known list!: AWS/FancyNewNamespace",
@@ -88,6 +370,106 @@ func TestBadConfigs(t *testing.T) {
 			configFile: "discovery_job_exported_tags_mismatch.bad.yml",
 			errorMsg:   "Discovery jobs: 'exportedTagsOnMetrics' key \"AWS/RDS\" does not match with any discovery job type",
 		},
+		{
+			configFile: "discovery_job_direct_query_unsupported.bad.yml",
+			errorMsg:   "Discovery job [AWS/Billing/0]: directQuery is not supported for this namespace",
+		},
+		{
+			configFile: "discovery_job_export_recently_active.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: exportRecentlyActive requires recentlyActiveOnly",
+		},
+		{
+			configFile: "discovery_job_linked_accounts.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: includeLinkedAccounts is only supported by custom namespace jobs",
+		},
+		{
+			configFile: "discovery_job_negative_empty_result_grace.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: EmptyResultGrace should not be negative",
+		},
+		{
+			configFile: "discovery_job_keep_last_n_without_timestamp.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: KeepLastN can only be set above 1 if AddCloudwatchTimestamp is enabled",
+		},
+		{
+			configFile: "discovery_job_data_point_limit.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: length(172800) and period(1) request 172800 data points, more than the 100800 returned by GetMetricData",
+		},
+		{
+			configFile: "discovery_job_get_metric_statistics_export_all.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: UseGetMetricStatistics only exports the most recent data point, and cannot be combined with ExportAllDataPoints or KeepLastN",
+		},
+		{
+			configFile: "static_job_get_metric_statistics_period.bad.yml",
+			errorMsg:   "Metric [GroupInServiceInstances/0] in Static job [autoscaling/0]: static jobs use GetMetricStatistics, which requires a period of 1, 5, 10, 30 or a multiple of 60, got 90",
+		},
+		{
+			configFile: "static_job_sum_without_dimensions.bad.yml",
+			errorMsg:   "Metric [GroupInServiceInstances/0] in Static job [autoscaling/0]: SumWithoutDimensions only applies to the metrics of discovery and custom namespace jobs",
+		},
+		{
+			configFile: "discovery_job_get_metric_statistics_sum_without_dimensions.bad.yml",
+			errorMsg:   "Metric [RequestCount/0] in Discovery job [AWS/ApplicationELB/0]: SumWithoutDimensions only applies to the metrics queried with GetMetricData, and cannot be combined with UseGetMetricStatistics",
+		},
+		{
+			configFile: "discovery_job_sum_without_dimensions_statistic.bad.yml",
+			errorMsg:   "Metric [TargetResponseTime/0] in Discovery job [AWS/ApplicationELB/0]: SumWithoutDimensions only supports the Sum and SampleCount statistics, which can be summed, got Average",
+		},
+		{
+			configFile: "discovery_job_period.bad.yml",
+			errorMsg:   "Discovery job [AWS/S3/0]: period should be a positive multiple of 60, got 90",
+		},
+		{
+			configFile: "discovery_job_get_metric_statistics_period.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: UseGetMetricStatistics requires a period of 1, 5, 10, 30 or a multiple of 60, got 45",
+		},
+		{
+			configFile: "discovery_job_invalid_statistic_name.bad.yml",
+			errorMsg:   "StatisticNames renames Sum to \"total-count\", which is not a valid metric name suffix",
+		},
+		{
+			configFile: "discovery_job_invalid_metric_type.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: MetricType should be one of \"gauge\", \"counter\" or \"untyped\"",
+		},
+		{
+			configFile: "discovery_job_invalid_statistic.bad.yml",
+			errorMsg:   "Metric [CPUUtilization/0] in Discovery job [AWS/EC2/0]: \"bogus\" is not a valid CloudWatch statistic",
+		},
+		{
+			configFile: "discovery_job_negative_tagging_concurrency.bad.yml",
+			errorMsg:   "Role [0] in Discovery job [AWS/EC2/0]: TaggingAPIConcurrency should not be negative",
+		},
+		{
+			configFile: "discovery_job_invalid_series_cap_action.bad.yml",
+			errorMsg:   "Metric [Invocations/0] in Discovery job [AWS/Lambda/0]: SeriesCapAction should be one of \"drop\" or \"sample\"",
+		},
+		{
+			configFile: "nil_to_zero_statistics_unknown_statistic.bad.yml",
+			errorMsg:   "Metric [Invocations/0] in Discovery job [AWS/Lambda/0]: NilToZeroStatistics sets Average, which is not one of the statistics of the metric",
+		},
+		{
+			configFile: "discovery_job_empty_required_tag_key.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: required tag key should not be empty",
+		},
+		{
+			configFile: "discovery_job_invalid_cross_region_resources.bad.yml",
+			errorMsg:   "Discovery job [AWS/EC2/0]: CrossRegionResources should be one of \"filter\" or \"keep\"",
+		},
+		{
+			configFile: "discovery_job_invalid_enhanced_metrics_failure_policy.bad.yml",
+			errorMsg:   "Discovery job [AWS/Lambda/0]: EnhancedMetricsFailurePolicy should be one of \"fail\" or \"warn-and-continue\"",
+		},
+		{
+			configFile: "discovery_job_invalid_dimension_granularity.bad.yml",
+			errorMsg:   "Discovery job [AWS/ApplicationELB/0]: DimensionGranularity should be one of \"most-specific\" or \"most-aggregate\"",
+		},
+		{
+			configFile: "discovery_job_negative_enhanced_metrics_concurrency.bad.yml",
+			errorMsg:   "Discovery job [AWS/Lambda/0]: EnhancedMetricsConcurrency should not be negative",
+		},
+		{
+			configFile: "endpoints_unknown_service.bad.yml",
+			errorMsg:   "endpoints: unknown service \"monitoring\"",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -164,6 +546,27 @@ func TestValidateConfigFailuresWhenUsingAsLibrary(t *testing.T) {
 			},
 			errorMsg: "Discovery job [AWS/Lambda/0]: enhanced metric \"SomeEnhancedMetric\" is not supported for this namespace",
 		},
+		"enhanced metrics are not supported by the enhanced metrics service": {
+			config: ScrapeConf{
+				Discovery: Discovery{
+					Jobs: []*Job{{
+						Regions: []string{"us-east-2"},
+						Type:    "AWS/Lambda",
+						Roles:   []Role{{RoleArn: "arn:aws:iam::123456789012:role/test"}},
+						Metrics: []*Metric{{
+							Name:       "BucketSizeBytes",
+							Statistics: []string{"Average"},
+						}},
+						EnhancedMetrics: []*EnhancedMetric{
+							{Name: "SomeEnhancedMetric"},
+							{Name: "Timeout"},
+							{Name: "OtherEnhancedMetric"},
+						},
+					}},
+				},
+			},
+			errorMsg: "Discovery job [AWS/Lambda/0]: enhanced metrics \"SomeEnhancedMetric\", \"OtherEnhancedMetric\" are not supported for this namespace",
+		},
 	}
 
 	for name, tc := range testCases {
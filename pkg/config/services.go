@@ -38,6 +38,10 @@ type ServiceConfig struct {
 	// In cases where the dimension name has a space, it should be
 	// replaced with an underscore (`_`).
 	DimensionRegexps []*regexp.Regexp
+	// Global marks namespaces of region-agnostic services, whose metrics are
+	// only published in a single region per partition. Discovery jobs for these
+	// namespaces run against the global service region of each partition of their regions.
+	Global bool
 }
 
 func (sc ServiceConfig) ToModelDimensionsRegexp() []model.DimensionsRegexp {
@@ -237,6 +241,7 @@ var SupportedServices = serviceConfigs{
 	{
 		Namespace: "AWS/Billing",
 		Alias:     "billing",
+		Global:    true,
 	},
 	{
 		Namespace: "AWS/Cassandra",
@@ -258,6 +263,7 @@ var SupportedServices = serviceConfigs{
 		DimensionRegexps: []*regexp.Regexp{
 			regexp.MustCompile("distribution/(?P<DistributionId>[^/]+)"),
 		},
+		Global: true,
 	},
 	{
 		Namespace: "AWS/Cognito",
@@ -791,6 +797,8 @@ var SupportedServices = serviceConfigs{
 			aws.String("rds:db-proxy"),
 		},
 		DimensionRegexps: []*regexp.Regexp{
+			// Cluster level metrics are associated with the cluster ARN, never with one of its instances,
+			// so the tags of the cluster itself decide whether they are exported.
 			regexp.MustCompile(":cluster:(?P<DBClusterIdentifier>[^/]+)"),
 			regexp.MustCompile(":db:(?P<DBInstanceIdentifier>[^/]+)"),
 			regexp.MustCompile(":db-proxy:(?P<ProxyIdentifier>[^/]+)"),
@@ -833,6 +841,7 @@ var SupportedServices = serviceConfigs{
 		DimensionRegexps: []*regexp.Regexp{
 			regexp.MustCompile(":healthcheck/(?P<HealthCheckId>[^/]+)"),
 		},
+		Global: true,
 	},
 	{
 		Namespace: "AWS/RUM",
@@ -875,7 +884,8 @@ var SupportedServices = serviceConfigs{
 			aws.String("states"),
 		},
 		DimensionRegexps: []*regexp.Regexp{
-			regexp.MustCompile("(?P<StateMachineArn>.*)"),
+			regexp.MustCompile("^(?P<StateMachineArn>.*:stateMachine:.*)$"),
+			regexp.MustCompile("^(?P<ActivityArn>.*:activity:.*)$"),
 		},
 	},
 	{
@@ -0,0 +1,39 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package enhancedmetrics is the public entry point for registering custom enhanced metrics services
+// when embedding YACE as a library. The built-in services stay internal.
+package enhancedmetrics
+
+import (
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+)
+
+// Service gets the enhanced metrics of the resources of a namespace.
+type Service = service.EnhancedMetricsService
+
+// MetricsService builds the Service instances of a namespace. A new instance is used for every job.
+type MetricsService = enhancedmetrics.MetricsService
+
+// RegionalConfigProvider provides the AWS configuration a Service uses to build its clients. The
+// clients.Factory passed to the scrape must implement it for enhanced metrics to be collected.
+type RegionalConfigProvider = config.RegionalConfigProvider
+
+// Register adds the given service to the registry used by YACE, replacing any service, built-in
+// or not, registered for the same namespace. It must be called before loading the configuration,
+// which validates the enhanced metrics of every job against the registered services.
+func Register(svc MetricsService) {
+	enhancedmetrics.DefaultEnhancedMetricServiceRegistry.Register(svc)
+}
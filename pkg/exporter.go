@@ -231,6 +231,8 @@ func configFromOptions(o options) config.Config {
 	return config.Config{
 		MetricsPerQuery:       o.metricsPerQuery,
 		LabelsSnakeCase:       o.labelsSnakeCase,
+		CustomTagsLabelPrefix: config.DefaultCustomTagsLabelPrefix,
+		ARNLabelName:          config.DefaultARNLabelName,
 		TaggingAPIConcurrency: o.taggingAPIConcurrency,
 		FeatureFlags:          featureFlagsFromMap(o.featureFlags),
 		CloudwatchConcurrency: config.CloudWatchConcurrencyConfig{
@@ -31,6 +31,7 @@ import (
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
+	enhancedmetricsAPI "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/enhancedmetrics"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
 	enhancedmetricsDynamoDBService "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/dynamodb"
 	enhancedmetricsElastiCacheService "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/elasticache"
@@ -543,3 +544,93 @@ func TestUpdateMetrics_WithEnhancedMetrics_DynamoDB(t *testing.T) {
 	err = testutil.GatherAndCompare(registry, strings.NewReader(expectedMetric))
 	require.NoError(t, err)
 }
+
+// customEnhancedMetricsService is an enhanced metrics service registered through the public API
+type customEnhancedMetricsService struct{}
+
+func (s *customEnhancedMetricsService) GetNamespace() string {
+	return "AWS/RDS"
+}
+
+func (s *customEnhancedMetricsService) Instance() enhancedmetricsAPI.Service {
+	return s
+}
+
+func (s *customEnhancedMetricsService) IsMetricSupported(metricName string) bool {
+	return metricName == "CustomMetric"
+}
+
+func (s *customEnhancedMetricsService) GetMetrics(
+	_ context.Context,
+	_ *slog.Logger,
+	resources []*model.TaggedResource,
+	_ []*model.EnhancedMetricConfig,
+	exportedTags []string,
+	_ string,
+	_ model.Role,
+	_ enhancedmetricsAPI.RegionalConfigProvider,
+) ([]*model.CloudwatchData, error) {
+	data := make([]*model.CloudwatchData, 0, len(resources))
+	for _, resource := range resources {
+		data = append(data, &model.CloudwatchData{
+			MetricName:   "CustomMetric",
+			ResourceName: resource.ARN,
+			Namespace:    "AWS/RDS",
+			Tags:         resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{{Value: aws.Float64(42)}},
+			},
+		})
+	}
+	return data, nil
+}
+
+func TestUpdateMetrics_WithEnhancedMetrics_CustomService(t *testing.T) {
+	defer enhancedmetrics.DefaultEnhancedMetricServiceRegistry.Register(
+		enhancedmetricsService.NewRDSService(nil),
+	)
+
+	// Replace the built-in RDS service through the public registration API
+	enhancedmetricsAPI.Register(&customEnhancedMetricsService{})
+
+	factory := &mockFactoryForEnhancedMetrics{
+		accountClient: &mockAccountClient{
+			accountID:    "123456789012",
+			accountAlias: "test-account",
+		},
+		cloudwatchClient: &mockCloudwatchClient{},
+		taggingClient: &mockTaggingClient{
+			resources: []*model.TaggedResource{
+				{
+					ARN:       "arn:aws:rds:us-east-1:123456789012:db:test-db",
+					Namespace: "AWS/RDS",
+					Region:    "us-east-1",
+				},
+			},
+		},
+		awsConfig: &aws.Config{Region: "us-east-1"},
+	}
+
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{
+				Regions:         []string{"us-east-1"},
+				Namespace:       "AWS/RDS",
+				Roles:           []model.Role{{RoleArn: "arn:aws:iam::123456789012:role/test-role"}},
+				EnhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CustomMetric"}},
+			},
+		},
+	}
+
+	registry := prometheus.NewRegistry()
+	err := UpdateMetrics(context.Background(), slog.New(slog.DiscardHandler), jobsCfg, registry, factory)
+	require.NoError(t, err)
+
+	expectedMetric := `
+		# HELP aws_rds_custom_metric Help is not implemented yet.
+		# TYPE aws_rds_custom_metric gauge
+		aws_rds_custom_metric{account_alias="test-account",account_id="123456789012",name="arn:aws:rds:us-east-1:123456789012:db:test-db",region="us-east-1"} 42
+`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expectedMetric), "aws_rds_custom_metric")
+	require.NoError(t, err)
+}
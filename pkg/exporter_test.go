@@ -13,8 +13,13 @@
 package exporter
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -95,7 +100,7 @@ type mockCloudwatchClient struct {
 	err               error
 }
 
-func (m *mockCloudwatchClient) ListMetrics(_ context.Context, _ string, _ *model.MetricConfig, _ bool, fn func(page []*model.Metric)) error {
+func (m *mockCloudwatchClient) ListMetrics(_ context.Context, _ string, _ *model.MetricConfig, _ bool, _ bool, fn func(page []*model.Metric)) error {
 	if m.err != nil {
 		return m.err
 	}
@@ -263,6 +268,87 @@ func TestMetricsScrape_StaticJob(t *testing.T) {
 	require.NoError(t, err, "Metric aws_ec2_cpuutilization_average should match expected output")
 }
 
+func TestMetricsScrape_SeriesLimit(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		StaticJobs: []model.StaticJob{
+			{
+				Name:      "test-static-job",
+				Regions:   []string{"us-east-1", "us-west-2", "eu-west-1"},
+				Roles:     []model.Role{{}},
+				Namespace: "AWS/EC2",
+				Dimensions: []model.Dimension{
+					{Name: "InstanceId", Value: "i-1234567890abcdef0"},
+				},
+				Metrics: []*model.MetricConfig{
+					{
+						Name:       "CPUUtilization",
+						Statistics: []string{"Average"},
+						Period:     300,
+						Length:     300,
+					},
+				},
+			},
+		},
+	}
+
+	factory := &mockFactory{
+		accountClient: mockAccountClient{
+			accountID:    "123456789012",
+			accountAlias: "test-account",
+		},
+		cloudwatchClient: mockCloudwatchClient{},
+	}
+
+	expectedCounter := `
+		# HELP yace_series_limit_exceeded_total Number of scrapes which produced more series than the configured limit
+		# TYPE yace_series_limit_exceeded_total counter
+		yace_series_limit_exceeded_total 1
+	`
+
+	t.Run("truncate", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		cfg := config.DefaultConfig()
+		cfg.MaxSeries = 2
+		cfg.SeriesLimitAction = config.SeriesLimitActionTruncate
+
+		scraper, err := yacemetrics.NewScraper(promslog.NewNopLogger(), promutil.NewScrapeMetrics(registry), cfg, jobsCfg, factory)
+		require.NoError(t, err)
+		metrics, err := scraper.Scrape(context.Background())
+		require.NoError(t, err)
+		require.Len(t, metrics, 2)
+
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedCounter), "yace_series_limit_exceeded_total"))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		cfg := config.DefaultConfig()
+		cfg.MaxSeries = 2
+		cfg.SeriesLimitAction = config.SeriesLimitActionFail
+
+		scraper, err := yacemetrics.NewScraper(promslog.NewNopLogger(), promutil.NewScrapeMetrics(registry), cfg, jobsCfg, factory)
+		require.NoError(t, err)
+		metrics, err := scraper.Scrape(context.Background())
+		require.ErrorContains(t, err, "exceeding the limit of 2")
+		require.Nil(t, metrics)
+
+		require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedCounter), "yace_series_limit_exceeded_total"))
+	})
+
+	t.Run("within limit", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		cfg := config.DefaultConfig()
+		cfg.MaxSeries = 3
+		cfg.SeriesLimitAction = config.SeriesLimitActionFail
+
+		scraper, err := yacemetrics.NewScraper(promslog.NewNopLogger(), promutil.NewScrapeMetrics(registry), cfg, jobsCfg, factory)
+		require.NoError(t, err)
+		metrics, err := scraper.Scrape(context.Background())
+		require.NoError(t, err)
+		require.Len(t, metrics, 3)
+	})
+}
+
 func TestUpdateMetrics_DiscoveryJob(t *testing.T) {
 	ctx := context.Background()
 	logger := promslog.NewNopLogger()
@@ -347,6 +433,435 @@ func TestUpdateMetrics_DiscoveryJob(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestUpdateMetrics_CustomNamespaceJobExpression(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		CustomNamespaceJobs: []model.CustomNamespaceJob{
+			{
+				Name:      "checkout",
+				Namespace: "MyApp",
+				Regions:   []string{"us-east-1"},
+				Roles:     []model.Role{{}},
+				Expressions: []*model.ExpressionConfig{
+					{
+						Name:       "error_rate",
+						Expression: "SELECT AVG(ErrorRate) FROM SCHEMA(MyApp, Service)",
+						Period:     300,
+						Length:     300,
+						Labels:     map[string]string{"team": "payments"},
+					},
+				},
+			},
+		},
+	}
+
+	factory := &mockFactory{
+		accountClient: mockAccountClient{
+			accountID:    "123456789012",
+			accountAlias: "test-account",
+		},
+		cloudwatchClient: mockCloudwatchClient{
+			// The result of the expression, keyed by the ID of its query
+			metricDataResults: []cloudwatch.MetricDataResult{
+				{
+					ID: "id_0",
+					DataPoints: []cloudwatch.DataPoint{
+						{Value: aws.Float64(0.25), Timestamp: time.Now()},
+					},
+				},
+			},
+		},
+	}
+
+	registry := prometheus.NewRegistry()
+	err := UpdateMetrics(context.Background(), promslog.NewNopLogger(), jobsCfg, registry, factory)
+	require.NoError(t, err)
+
+	expectedMetric := `
+		# HELP aws_myapp_error_rate Help is not implemented yet.
+		# TYPE aws_myapp_error_rate gauge
+		aws_myapp_error_rate{account_alias="test-account",account_id="123456789012",name="checkout",region="us-east-1",team="payments"} 0.25
+	`
+	err = testutil.GatherAndCompare(registry, strings.NewReader(expectedMetric))
+	require.NoError(t, err)
+}
+
+func TestMetricsScrape_StaleResourceMarkers(t *testing.T) {
+	ctx := context.Background()
+	logger := promslog.NewNopLogger()
+
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{
+				Namespace: "AWS/EC2",
+				Regions:   []string{"us-east-1"},
+				Roles:     []model.Role{{}},
+				Metrics: []*model.MetricConfig{
+					{
+						Name:       "CPUUtilization",
+						Statistics: []string{"Average"},
+						Period:     300,
+						Length:     300,
+					},
+				},
+				DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+			},
+		},
+	}
+
+	arn := "arn:aws:ec2:us-east-1:123456789012:instance/i-1234567890abcdef0"
+	factory := &mockFactory{
+		accountClient: mockAccountClient{
+			accountID: "123456789012",
+		},
+		taggingClient: mockTaggingClient{
+			resources: []*model.TaggedResource{
+				{ARN: arn, Namespace: "AWS/EC2", Region: "us-east-1"},
+			},
+		},
+		cloudwatchClient: mockCloudwatchClient{
+			metrics: []*model.Metric{
+				{
+					MetricName: "CPUUtilization",
+					Namespace:  "AWS/EC2",
+					Dimensions: []model.Dimension{
+						{Name: "InstanceId", Value: "i-1234567890abcdef0"},
+					},
+				},
+			},
+			metricDataResults: []cloudwatch.MetricDataResult{
+				{
+					ID: "id_0",
+					DataPoints: []cloudwatch.DataPoint{
+						{Value: aws.Float64(42.5), Timestamp: time.Now()},
+					},
+				},
+			},
+		},
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FeatureFlags = []string{config.StaleResourceMarkers}
+	scraper, err := yacemetrics.NewScraper(logger, promutil.Discard, cfg, jobsCfg, factory)
+	require.NoError(t, err)
+
+	valuesByName := func(metrics []*promutil.PrometheusMetric) map[string]float64 {
+		values := make(map[string]float64, len(metrics))
+		for _, metric := range metrics {
+			require.Equal(t, arn, metric.Labels["name"])
+			values[metric.Name] = metric.Value
+		}
+		return values
+	}
+
+	metrics, err := scraper.Scrape(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"aws_ec2_cpuutilization_average": 42.5, "aws_ec2_info": 0}, valuesByName(metrics))
+
+	// The resource vanishes: its series are exported once with a NaN value.
+	factory.taggingClient.resources = nil
+	factory.cloudwatchClient.metrics = nil
+
+	metrics, err = scraper.Scrape(ctx)
+	require.NoError(t, err)
+	values := valuesByName(metrics)
+	require.Len(t, values, 2)
+	require.True(t, math.IsNaN(values["aws_ec2_cpuutilization_average"]))
+	require.True(t, math.IsNaN(values["aws_ec2_info"]))
+
+	metrics, err = scraper.Scrape(ctx)
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+}
+
+func TestMetricsScrape_EmptyResultGrace(t *testing.T) {
+	ctx := context.Background()
+	logger := promslog.NewNopLogger()
+
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{
+				Namespace: "AWS/EC2",
+				Regions:   []string{"us-east-1"},
+				Roles:     []model.Role{{}},
+				Metrics: []*model.MetricConfig{
+					{
+						Name:             "CPUUtilization",
+						Statistics:       []string{"Average"},
+						Period:           300,
+						Length:           300,
+						EmptyResultGrace: 2,
+					},
+				},
+				DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+			},
+		},
+	}
+
+	factory := &mockFactory{
+		accountClient: mockAccountClient{
+			accountID: "123456789012",
+		},
+		taggingClient: mockTaggingClient{
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1234567890abcdef0", Namespace: "AWS/EC2", Region: "us-east-1"},
+			},
+		},
+		cloudwatchClient: mockCloudwatchClient{
+			metrics: []*model.Metric{
+				{
+					MetricName: "CPUUtilization",
+					Namespace:  "AWS/EC2",
+					Dimensions: []model.Dimension{
+						{Name: "InstanceId", Value: "i-1234567890abcdef0"},
+					},
+				},
+			},
+			metricDataResults: []cloudwatch.MetricDataResult{
+				{
+					ID: "id_0",
+					DataPoints: []cloudwatch.DataPoint{
+						{Value: aws.Float64(42.5), Timestamp: time.Now()},
+					},
+				},
+			},
+		},
+	}
+
+	scraper, err := yacemetrics.NewScraper(logger, promutil.Discard, config.DefaultConfig(), jobsCfg, factory)
+	require.NoError(t, err)
+
+	cpuValue := func() float64 {
+		metrics, err := scraper.Scrape(ctx)
+		require.NoError(t, err)
+		for _, metric := range metrics {
+			if metric.Name == "aws_ec2_cpuutilization_average" {
+				return metric.Value
+			}
+		}
+		require.Fail(t, "metric aws_ec2_cpuutilization_average not found")
+		return 0
+	}
+
+	require.Equal(t, 42.5, cpuValue())
+
+	// CloudWatch stops returning data points: the last value is kept for two scrapes, then dropped.
+	factory.cloudwatchClient.metricDataResults = []cloudwatch.MetricDataResult{{ID: "id_0"}}
+
+	require.Equal(t, 42.5, cpuValue())
+	require.Equal(t, 42.5, cpuValue())
+	require.True(t, math.IsNaN(cpuValue()))
+
+	// Data returns and resets the grace.
+	factory.cloudwatchClient.metricDataResults = []cloudwatch.MetricDataResult{
+		{ID: "id_0", DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(7), Timestamp: time.Now()}}},
+	}
+	require.Equal(t, float64(7), cpuValue())
+	factory.cloudwatchClient.metricDataResults = []cloudwatch.MetricDataResult{{ID: "id_0"}}
+	require.Equal(t, float64(7), cpuValue())
+}
+
+// failingRegionFactory is a mockFactory whose account lookups fail in failingRegion.
+type failingRegionFactory struct {
+	mockFactory
+	failingRegion string
+}
+
+func (f *failingRegionFactory) GetAccountClient(region string, role model.Role) account.Client {
+	if region == f.failingRegion {
+		return mockAccountClient{err: errors.New("access denied")}
+	}
+	return f.mockFactory.GetAccountClient(region, role)
+}
+
+func TestMetricsScrape_SummaryLog(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{
+				Namespace: "AWS/EC2",
+				// The job fails in us-west-2
+				Regions: []string{"us-east-1", "us-west-2"},
+				Roles:   []model.Role{{}},
+				Metrics: []*model.MetricConfig{
+					{
+						Name:       "CPUUtilization",
+						Statistics: []string{"Average"},
+						Period:     300,
+						Length:     300,
+					},
+				},
+				DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+			},
+		},
+	}
+
+	factory := &failingRegionFactory{failingRegion: "us-west-2", mockFactory: mockFactory{
+		accountClient: mockAccountClient{
+			accountID: "123456789012",
+		},
+		taggingClient: mockTaggingClient{
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1234567890abcdef0", Namespace: "AWS/EC2", Region: "us-east-1"},
+			},
+		},
+		cloudwatchClient: mockCloudwatchClient{
+			metrics: []*model.Metric{
+				{
+					MetricName: "CPUUtilization",
+					Namespace:  "AWS/EC2",
+					Dimensions: []model.Dimension{
+						{Name: "InstanceId", Value: "i-1234567890abcdef0"},
+					},
+				},
+			},
+			metricDataResults: []cloudwatch.MetricDataResult{
+				{
+					ID: "id_0",
+					DataPoints: []cloudwatch.DataPoint{
+						{Value: aws.Float64(42.5), Timestamp: time.Now()},
+					},
+				},
+			},
+		},
+	}}
+
+	scraper, err := yacemetrics.NewScraper(logger, promutil.Discard, config.DefaultConfig(), jobsCfg, factory)
+	require.NoError(t, err)
+
+	metrics, err := scraper.Scrape(ctx)
+	require.NoError(t, err)
+
+	var summary map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		if entry["msg"] == "Scrape summary" {
+			summary = entry
+		}
+	}
+	require.NotNil(t, summary, "scrape summary was not logged")
+
+	require.Equal(t, "INFO", summary["level"])
+	require.InDelta(t, 2, summary["jobs"], 0)
+	require.InDelta(t, 1, summary["failed_jobs"], 0)
+	require.InDelta(t, 1, summary["resources"], 0)
+	require.InDelta(t, len(metrics), summary["metrics"], 0)
+	require.Equal(t, true, summary["success"])
+	for _, field := range []string{"api_requests", "api_errors", "duration_seconds"} {
+		require.Contains(t, summary, field)
+	}
+}
+
+func TestMetricsScrape_IncludeContextOnInfoMetrics(t *testing.T) {
+	ctx := context.Background()
+	logger := promslog.NewNopLogger()
+	arn := "arn:aws:ec2:us-east-1:123456789012:instance/i-1234567890abcdef0"
+
+	for _, includeContext := range []bool{true, false} {
+		for _, tc := range []struct {
+			name              string
+			resources         []*model.TaggedResource
+			cloudwatchMetrics []*model.Metric
+			expectInfoMetric  bool
+		}{
+			{
+				name:      "resources with metrics",
+				resources: []*model.TaggedResource{{ARN: arn, Namespace: "AWS/EC2", Region: "us-east-1"}},
+				cloudwatchMetrics: []*model.Metric{
+					{
+						MetricName: "CPUUtilization",
+						Namespace:  "AWS/EC2",
+						Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-1234567890abcdef0"}},
+					},
+				},
+				expectInfoMetric: true,
+			},
+			{
+				name:             "resources without metrics",
+				resources:        []*model.TaggedResource{{ARN: arn, Namespace: "AWS/EC2", Region: "us-east-1"}},
+				expectInfoMetric: true,
+			},
+			{
+				name:             "no resources",
+				expectInfoMetric: false,
+			},
+		} {
+			t.Run(fmt.Sprintf("%s/includeContextOnInfoMetrics=%t", tc.name, includeContext), func(t *testing.T) {
+				svc := config.SupportedServices.GetService("AWS/EC2")
+				jobsCfg := model.JobsConfig{
+					DiscoveryJobs: []model.DiscoveryJob{
+						{
+							Namespace: "AWS/EC2",
+							Regions:   []string{"us-east-1"},
+							Roles:     []model.Role{{}},
+							Metrics: []*model.MetricConfig{
+								{
+									Name:       "CPUUtilization",
+									Statistics: []string{"Average"},
+									Period:     300,
+									Length:     300,
+								},
+							},
+							DimensionsRegexps:           svc.ToModelDimensionsRegexp(),
+							IncludeContextOnInfoMetrics: includeContext,
+						},
+					},
+				}
+
+				factory := &mockFactory{
+					accountClient: mockAccountClient{
+						accountID: "123456789012",
+					},
+					taggingClient: mockTaggingClient{
+						resources: tc.resources,
+					},
+					cloudwatchClient: mockCloudwatchClient{
+						metrics: tc.cloudwatchMetrics,
+						metricDataResults: []cloudwatch.MetricDataResult{
+							{ID: "id_0", DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(42.5), Timestamp: time.Now()}}},
+						},
+					},
+				}
+
+				cfg := config.DefaultConfig()
+				cfg.FeatureFlags = []string{config.AlwaysReturnInfoMetrics}
+				scraper, err := yacemetrics.NewScraper(logger, promutil.Discard, cfg, jobsCfg, factory)
+				require.NoError(t, err)
+
+				metrics, err := scraper.Scrape(ctx)
+				require.NoError(t, err)
+
+				var info *promutil.PrometheusMetric
+				for _, metric := range metrics {
+					if metric.Name == "aws_ec2_info" {
+						info = metric
+					}
+				}
+				if !tc.expectInfoMetric {
+					require.Nil(t, info)
+					return
+				}
+				require.NotNil(t, info)
+				require.Equal(t, arn, info.Labels["name"])
+
+				if includeContext {
+					require.Equal(t, "us-east-1", info.Labels["region"])
+					require.Equal(t, "123456789012", info.Labels["account_id"])
+				} else {
+					require.NotContains(t, info.Labels, "region")
+					require.NotContains(t, info.Labels, "account_id")
+				}
+			})
+		}
+	}
+}
+
 func TestUpdateMetrics_ReturnsOptionValidationError(t *testing.T) {
 	ctx := context.Background()
 	logger := promslog.NewNopLogger()
@@ -18,9 +18,13 @@ import (
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/dynamodb"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/ec2"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/ecs"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/elasticache"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/lambda"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/rds"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/sns"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/sqs"
 )
 
 // DefaultEnhancedMetricServiceRegistry is the default registry containing all built-in enhanced metrics services
@@ -32,7 +36,11 @@ var DefaultEnhancedMetricServiceRegistry = (&Registry{}).
 	Register(rds.NewRDSService(nil)).
 	Register(lambda.NewLambdaService(nil)).
 	Register(dynamodb.NewDynamoDBService(nil)).
-	Register(elasticache.NewElastiCacheService(nil))
+	Register(elasticache.NewElastiCacheService(nil)).
+	Register(ec2.NewEC2Service(nil)).
+	Register(sns.NewSNSService(nil)).
+	Register(sqs.NewSQSService(nil)).
+	Register(ecs.NewECSService(nil))
 
 // MetricsService represents an enhanced metrics service with methods to get its instance and namespace.
 // Services implementing this interface can be registered in the Registry.
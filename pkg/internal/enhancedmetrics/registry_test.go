@@ -270,6 +270,26 @@ func TestDefaultRegistry(t *testing.T) {
 			namespace:   "AWS/ElastiCache",
 			expectError: false,
 		},
+		{
+			name:        "AWS/EC2 is registered",
+			namespace:   "AWS/EC2",
+			expectError: false,
+		},
+		{
+			name:        "AWS/SNS is registered",
+			namespace:   "AWS/SNS",
+			expectError: false,
+		},
+		{
+			name:        "AWS/SQS is registered",
+			namespace:   "AWS/SQS",
+			expectError: false,
+		},
+		{
+			name:        "AWS/ECS is registered",
+			namespace:   "AWS/ECS",
+			expectError: false,
+		},
 		{
 			name:        "unknown namespace returns error",
 			namespace:   "AWS/Unknown",
@@ -281,7 +301,7 @@ func TestDefaultRegistry(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			svc, err := DefaultEnhancedMetricServiceRegistry.GetEnhancedMetricsService(tt.namespace)
 
-			assert.Len(t, DefaultEnhancedMetricServiceRegistry.services, 4, "Expected 4 services to be registered in the default registry")
+			assert.Len(t, DefaultEnhancedMetricServiceRegistry.services, 8, "Expected 8 services to be registered in the default registry")
 			if tt.expectError {
 				assert.Error(t, err)
 				assert.Nil(t, svc)
@@ -20,6 +20,7 @@ import (
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 // MetricsServiceRegistry defines an interface to get enhanced metrics services by namespace
@@ -31,6 +32,7 @@ type MetricsServiceRegistry interface {
 type Service struct {
 	configProvider                 config.RegionalConfigProvider
 	enhancedMetricsServiceRegistry MetricsServiceRegistry
+	scrapeMetrics                  *promutil.ScrapeMetrics
 }
 
 // GetMetrics returns the enhanced metrics for the specified namespace using the appropriate enhanced metrics service.
@@ -53,6 +55,7 @@ func (ep *Service) GetMetrics(
 	var filteredResources []*model.TaggedResource
 	for _, res := range resources {
 		if res.Namespace == namespace {
+			res.BackfillRegion()
 			filteredResources = append(filteredResources, res)
 		} else {
 			// Resource validation should have happened earlier, this log will identify any unexpected issues
@@ -66,27 +69,48 @@ func (ep *Service) GetMetrics(
 
 	// filter out metrics that are not supported by the service
 	var filteredMetrics []*model.EnhancedMetricConfig
+	var unsupportedMetrics []string
 	for _, metric := range metrics {
 		if svc.IsMetricSupported(metric.Name) {
 			filteredMetrics = append(filteredMetrics, metric)
 		} else {
-			// Metrics validation should have happened earlier, this log will identify any unexpected issues
-			logger.Warn("Skipping unsupported enhanced metric for service",
-				"namespace", namespace,
-				"metric", metric.Name,
-			)
+			unsupportedMetrics = append(unsupportedMetrics, metric.Name)
+			ep.scrapeMetrics.EnhancedMetricsUnsupportedCounter.Inc(namespace, metric.Name)
 		}
 	}
+	if len(unsupportedMetrics) > 0 {
+		// Metrics validation should have happened earlier, this log will identify any unexpected issues
+		logger.Warn("Skipping unsupported enhanced metrics for service",
+			"namespace", namespace,
+			"metrics", unsupportedMetrics,
+		)
+	}
+
+	data, err := svc.GetMetrics(ctx, logger, filteredResources, filteredMetrics, exportedTagOnMetrics, region, role, ep.configProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	if reporter, ok := svc.(service.CoverageReporter); ok {
+		coverage := reporter.Coverage()
+		ep.scrapeMetrics.EnhancedMetricsResourcesCoveredCounter.Add(float64(coverage.Found), namespace)
+		ep.scrapeMetrics.EnhancedMetricsResourcesMissingCounter.Add(float64(coverage.Missing), namespace)
+	}
 
-	return svc.GetMetrics(ctx, logger, filteredResources, filteredMetrics, exportedTagOnMetrics, region, role, ep.configProvider)
+	return data, nil
 }
 
 func NewService(
 	configProvider config.RegionalConfigProvider,
 	enhancedMetricsServiceRegistry MetricsServiceRegistry,
+	scrapeMetrics *promutil.ScrapeMetrics,
 ) *Service {
+	if scrapeMetrics == nil {
+		scrapeMetrics = promutil.Discard
+	}
 	return &Service{
 		configProvider:                 configProvider,
 		enhancedMetricsServiceRegistry: enhancedMetricsServiceRegistry,
+		scrapeMetrics:                  scrapeMetrics,
 	}
 }
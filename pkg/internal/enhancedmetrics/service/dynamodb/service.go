@@ -49,6 +49,7 @@ func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, t
 type DynamoDB struct {
 	supportedMetrics map[string]supportedMetric
 	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
 }
 
 func NewDynamoDBService(buildClientFunc func(cfg aws.Config) Client) *DynamoDB {
@@ -77,9 +78,37 @@ func NewDynamoDBService(buildClientFunc func(cfg aws.Config) Client) *DynamoDB {
 		},
 	}
 
+	// The provisioned read capacity units of the table and of its global secondary indexes. Not exported for
+	// on-demand tables, which have no provisioned throughput.
+	provisionedReadCapacity := supportedMetric{
+		name: "ProvisionedReadCapacity",
+		buildCloudwatchDataFunc: buildProvisionedThroughputMetric(
+			"ProvisionedReadCapacity",
+			func(throughput *types.ProvisionedThroughputDescription) *int64 { return throughput.ReadCapacityUnits },
+		),
+		requiredPermissions: []string{
+			"dynamodb:DescribeTable",
+		},
+	}
+
+	// The provisioned write capacity units of the table and of its global secondary indexes. Not exported for
+	// on-demand tables, which have no provisioned throughput.
+	provisionedWriteCapacity := supportedMetric{
+		name: "ProvisionedWriteCapacity",
+		buildCloudwatchDataFunc: buildProvisionedThroughputMetric(
+			"ProvisionedWriteCapacity",
+			func(throughput *types.ProvisionedThroughputDescription) *int64 { return throughput.WriteCapacityUnits },
+		),
+		requiredPermissions: []string{
+			"dynamodb:DescribeTable",
+		},
+	}
+
 	svc.supportedMetrics = map[string]supportedMetric{
-		itemCountMetric.name: itemCountMetric,
-		tableSizeBytes.name:  tableSizeBytes,
+		itemCountMetric.name:          itemCountMetric,
+		tableSizeBytes.name:           tableSizeBytes,
+		provisionedReadCapacity.name:  provisionedReadCapacity,
+		provisionedWriteCapacity.name: provisionedWriteCapacity,
 	}
 
 	return svc
@@ -107,7 +136,12 @@ func (s *DynamoDB) loadMetricsMetadata(
 	regionalData := make(map[string]*types.TableDescription, len(tables))
 
 	for _, table := range tables {
-		regionalData[*table.TableArn] = &table
+		tableARN, ok := service.DescribedARN(table.TableArn)
+		if !ok {
+			logger.Warn("Skipping DynamoDB table with invalid ARN", "table", aws.ToString(table.TableName), "arn", aws.ToString(table.TableArn))
+			continue
+		}
+		regionalData[tableARN] = &table
 	}
 
 	return regionalData, nil
@@ -119,6 +153,8 @@ func (s *DynamoDB) IsMetricSupported(metricName string) bool {
 }
 
 func (s *DynamoDB) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
 	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
 		return nil, nil
 	}
@@ -150,9 +186,11 @@ func (s *DynamoDB) GetMetrics(ctx context.Context, logger *slog.Logger, resource
 
 		table, exists := data[resource.ARN]
 		if !exists {
+			s.coverage.Missing++
 			logger.Warn("DynamoDB table not found in data", "arn", resource.ARN)
 			continue
 		}
+		s.coverage.Found++
 
 		for _, enhancedMetric := range enhancedMetricConfigs {
 			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
@@ -162,7 +200,7 @@ func (s *DynamoDB) GetMetrics(ctx context.Context, logger *slog.Logger, resource
 			}
 
 			em, err := supportedMetric.buildCloudwatchData(resource, table, exportedTagOnMetrics)
-			if err != nil || em == nil {
+			if err != nil {
 				logger.Warn("Error building DynamoDB enhanced metric", "metric", enhancedMetric.Name, "error", err)
 				continue
 			}
@@ -192,6 +230,11 @@ func (s *DynamoDB) ListSupportedEnhancedMetrics() []string {
 	return metrics
 }
 
+// Coverage returns the resources found, or not, in the output of the describe calls of the last GetMetrics call.
+func (s *DynamoDB) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
 func (s *DynamoDB) Instance() service.EnhancedMetricsService {
 	// do not use NewDynamoDBService to avoid extra map allocation
 	return &DynamoDB{
@@ -276,6 +319,54 @@ func buildTableSizeBytesMetric(resource *model.TaggedResource, table *types.Tabl
 	return result, nil
 }
 
+// buildProvisionedThroughputMetric returns a buildCloudwatchDataFunc emitting the capacity units selected by getValue
+// from the provisioned throughput of the table and of its global secondary indexes. Nothing is emitted for on-demand
+// tables, as their provisioned throughput is either missing or zero.
+func buildProvisionedThroughputMetric(metricName string, getValue func(*types.ProvisionedThroughputDescription) *int64) buildCloudwatchDataFunc {
+	return func(resource *model.TaggedResource, table *types.TableDescription, exportedTags []string) ([]*model.CloudwatchData, error) {
+		if table.ProvisionedThroughput == nil || getValue(table.ProvisionedThroughput) == nil ||
+			(table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode == types.BillingModePayPerRequest) {
+			return nil, nil
+		}
+
+		value := float64(*getValue(table.ProvisionedThroughput))
+		result := []*model.CloudwatchData{{
+			MetricName:   metricName,
+			ResourceName: resource.ARN,
+			Namespace:    "AWS/DynamoDB",
+			Dimensions:   getTableDimensions(table),
+			Tags:         resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{
+					{
+						Value:     &value,
+						Timestamp: time.Now(),
+					},
+				},
+			},
+		}}
+
+		if len(table.GlobalSecondaryIndexes) > 0 {
+			result = append(result,
+				buildGlobalSecondaryIndexesMetric(
+					resource,
+					table,
+					exportedTags,
+					metricName,
+					func(gsi types.GlobalSecondaryIndexDescription) *int64 {
+						if gsi.ProvisionedThroughput == nil {
+							return nil
+						}
+						return getValue(gsi.ProvisionedThroughput)
+					},
+				)...,
+			)
+		}
+
+		return result, nil
+	}
+}
+
 // buildGlobalSecondaryIndexesMetric emits one datapoint per global secondary index for the given
 // metric. getValue selects the source field on the index (e.g. ItemCount or IndexSizeBytes);
 // indexes whose value or name is nil are skipped.
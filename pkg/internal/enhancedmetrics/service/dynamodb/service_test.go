@@ -45,9 +45,11 @@ func TestNewDynamoDBService(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			got := NewDynamoDBService(tt.buildClientFunc)
 			require.NotNil(t, got)
-			require.Len(t, got.supportedMetrics, 2)
+			require.Len(t, got.supportedMetrics, 4)
 			require.NotNil(t, got.supportedMetrics["ItemCount"])
 			require.NotNil(t, got.supportedMetrics["TableSizeBytes"])
+			require.NotNil(t, got.supportedMetrics["ProvisionedReadCapacity"])
+			require.NotNil(t, got.supportedMetrics["ProvisionedWriteCapacity"])
 		})
 	}
 }
@@ -64,6 +66,12 @@ func TestDynamoDB_ListRequiredPermissions(t *testing.T) {
 		"ItemCount": {
 			"dynamodb:DescribeTable",
 		},
+		"ProvisionedReadCapacity": {
+			"dynamodb:DescribeTable",
+		},
+		"ProvisionedWriteCapacity": {
+			"dynamodb:DescribeTable",
+		},
 		"TableSizeBytes": {
 			"dynamodb:DescribeTable",
 		},
@@ -75,6 +83,8 @@ func TestDynamoDB_ListSupportedEnhancedMetrics(t *testing.T) {
 	service := NewDynamoDBService(nil)
 	expectedMetrics := []string{
 		"ItemCount",
+		"ProvisionedReadCapacity",
+		"ProvisionedWriteCapacity",
 		"TableSizeBytes",
 	}
 	supportedMetrics := service.ListSupportedEnhancedMetrics()
@@ -214,6 +224,60 @@ func TestDynamoDB_GetMetrics(t *testing.T) {
 			wantErr:         false,
 			wantResultCount: 4, // ItemCount: table + GSI; TableSizeBytes: table + GSI
 		},
+		{
+			name: "successfully received provisioned capacity metrics with global secondary indexes",
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:dynamodb:us-east-1:123456789012:table/test-table-provisioned", Namespace: awsDynamoDBNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "ProvisionedReadCapacity"}, {Name: "ProvisionedWriteCapacity"}},
+			tables: []types.TableDescription{
+				{
+					TableArn:           aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/test-table-provisioned"),
+					TableName:          aws.String("test-table-provisioned"),
+					BillingModeSummary: &types.BillingModeSummary{BillingMode: types.BillingModeProvisioned},
+					ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+						ReadCapacityUnits:  aws.Int64(100),
+						WriteCapacityUnits: aws.Int64(50),
+					},
+					GlobalSecondaryIndexes: []types.GlobalSecondaryIndexDescription{
+						{
+							IndexName: aws.String("test-gsi-1"),
+							ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+								ReadCapacityUnits:  aws.Int64(20),
+								WriteCapacityUnits: aws.Int64(10),
+							},
+						},
+					},
+				},
+			},
+			wantErr:         false,
+			wantResultCount: 4, // ProvisionedReadCapacity: table + GSI; ProvisionedWriteCapacity: table + GSI
+		},
+		{
+			name: "no provisioned capacity metrics for on-demand tables",
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:dynamodb:us-east-1:123456789012:table/test-table-on-demand", Namespace: awsDynamoDBNamespace},
+				{ARN: "arn:aws:dynamodb:us-east-1:123456789012:table/test-table-no-throughput", Namespace: awsDynamoDBNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "ProvisionedReadCapacity"}, {Name: "ProvisionedWriteCapacity"}},
+			tables: []types.TableDescription{
+				{
+					TableArn:           aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/test-table-on-demand"),
+					TableName:          aws.String("test-table-on-demand"),
+					BillingModeSummary: &types.BillingModeSummary{BillingMode: types.BillingModePayPerRequest},
+					ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+						ReadCapacityUnits:  aws.Int64(0),
+						WriteCapacityUnits: aws.Int64(0),
+					},
+				},
+				{
+					TableArn:  aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/test-table-no-throughput"),
+					TableName: aws.String("test-table-no-throughput"),
+				},
+			},
+			wantErr:         false,
+			wantResultCount: 0,
+		},
 		{
 			name: "resource not found in metadata",
 			resources: []*model.TaggedResource{
@@ -257,6 +321,17 @@ func TestDynamoDB_GetMetrics(t *testing.T) {
 			wantErr:         false,
 			wantResultCount: 2,
 		},
+		{
+			name:            "skips tables with invalid ARNs",
+			resources:       []*model.TaggedResource{{ARN: "arn:aws:dynamodb:us-east-1:123456789012:table/test-table", Namespace: awsDynamoDBNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "ItemCount"}},
+			tables: append([]types.TableDescription{
+				{TableName: aws.String("nil-arn"), ItemCount: aws.Int64(1)},
+				{TableArn: aws.String("not-an-arn"), TableName: aws.String("invalid-arn"), ItemCount: aws.Int64(1)},
+			}, defaultTables...),
+			wantErr:         false,
+			wantResultCount: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,79 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ec2
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// awsClient is kept for test mocking; production code uses method-value closures.
+type awsClient interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+// AWSEC2Client wraps the AWS EC2 client
+type AWSEC2Client struct {
+	describeInstancesFunc func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+// NewEC2ClientWithConfig creates a new EC2 client with custom AWS configuration
+func NewEC2ClientWithConfig(cfg aws.Config) Client {
+	c := ec2.NewFromConfig(cfg)
+	return &AWSEC2Client{
+		describeInstancesFunc: c.DescribeInstances,
+	}
+}
+
+func (c *AWSEC2Client) describeInstances(ctx context.Context, input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	result, err := c.describeInstancesFunc(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe EC2 instances: %w", err)
+	}
+	return result, nil
+}
+
+// DescribeAllInstances retrieves all the EC2 instances of the region, across all reservations and pages.
+func (c *AWSEC2Client) DescribeAllInstances(ctx context.Context, logger *slog.Logger) ([]types.Instance, error) {
+	logger.Debug("Describing all EC2 instances")
+	var allInstances []types.Instance
+	var nextToken *string
+	maxResults := aws.Int32(1000)
+
+	for {
+		output, err := c.describeInstances(ctx, &ec2.DescribeInstancesInput{
+			NextToken:  nextToken,
+			MaxResults: maxResults,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range output.Reservations {
+			allInstances = append(allInstances, reservation.Instances...)
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	logger.Debug("Completed describing all EC2 instances", slog.Int("totalInstances", len(allInstances)))
+	return allInstances, nil
+}
@@ -0,0 +1,124 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ec2
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestAWSEC2Client_DescribeAllInstances(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        awsClient
+		want          []types.Instance
+		wantNextToken []*string
+		wantErr       bool
+	}{
+		{
+			name: "success - single page",
+			client: &mockEC2Client{
+				describeInstancesFunc: func(_ context.Context, _ *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+					return &ec2.DescribeInstancesOutput{
+						Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-1")}}}},
+						NextToken:    nil,
+					}, nil
+				},
+			},
+			want:          []types.Instance{{InstanceId: aws.String("i-1")}},
+			wantNextToken: []*string{nil},
+			wantErr:       false,
+		},
+		{
+			name: "success - multiple pages",
+			client: &mockEC2Client{
+				describeInstancesFunc: func() func(_ context.Context, _ *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+					callCount := 0
+					return func(_ context.Context, _ *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+						callCount++
+						if callCount == 1 {
+							return &ec2.DescribeInstancesOutput{
+								Reservations: []types.Reservation{
+									{Instances: []types.Instance{{InstanceId: aws.String("i-1")}, {InstanceId: aws.String("i-2")}}},
+									{Instances: []types.Instance{{InstanceId: aws.String("i-3")}}},
+								},
+								NextToken: aws.String("token1"),
+							}, nil
+						}
+						return &ec2.DescribeInstancesOutput{
+							Reservations: []types.Reservation{{Instances: []types.Instance{{InstanceId: aws.String("i-4")}}}},
+							NextToken:    nil,
+						}, nil
+					}
+				}(),
+			},
+			want: []types.Instance{
+				{InstanceId: aws.String("i-1")},
+				{InstanceId: aws.String("i-2")},
+				{InstanceId: aws.String("i-3")},
+				{InstanceId: aws.String("i-4")},
+			},
+			wantNextToken: []*string{nil, aws.String("token1")},
+			wantErr:       false,
+		},
+		{
+			name: "error - API failure",
+			client: &mockEC2Client{
+				describeInstancesFunc: func(_ context.Context, _ *ec2.DescribeInstancesInput, _ ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+			},
+			want:          nil,
+			wantNextToken: []*string{nil},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotNextToken []*string
+			c := &AWSEC2Client{
+				describeInstancesFunc: func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+					gotNextToken = append(gotNextToken, params.NextToken)
+					return tt.client.DescribeInstances(ctx, params, optFns...)
+				},
+			}
+			got, err := c.DescribeAllInstances(context.Background(), slog.New(slog.DiscardHandler))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DescribeAllInstances() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DescribeAllInstances() got = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotNextToken, tt.wantNextToken) {
+				t.Errorf("DescribeAllInstances() requested pages %v, want %v", gotNextToken, tt.wantNextToken)
+			}
+		})
+	}
+}
+
+// mockEC2Client is a mock implementation of AWS EC2 Client
+type mockEC2Client struct {
+	describeInstancesFunc func(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+}
+
+func (m *mockEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return m.describeInstancesFunc(ctx, params, optFns...)
+}
@@ -0,0 +1,243 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ec2
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+const awsEC2Namespace = "AWS/EC2"
+
+type Client interface {
+	DescribeAllInstances(ctx context.Context, logger *slog.Logger) ([]types.Instance, error)
+}
+
+type buildCloudwatchDataFunc func(*model.TaggedResource, *types.Instance, []string) (*model.CloudwatchData, error)
+
+type supportedMetric struct {
+	name                    string
+	buildCloudwatchDataFunc buildCloudwatchDataFunc
+	requiredPermissions     []string
+}
+
+func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, instance *types.Instance, exportedTagOnMetrics []string) (*model.CloudwatchData, error) {
+	return sm.buildCloudwatchDataFunc(resource, instance, exportedTagOnMetrics)
+}
+
+type EC2 struct {
+	supportedMetrics map[string]supportedMetric
+	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
+}
+
+func NewEC2Service(buildClientFunc func(cfg aws.Config) Client) *EC2 {
+	if buildClientFunc == nil {
+		buildClientFunc = NewEC2ClientWithConfig
+	}
+	svc := &EC2{
+		buildClientFunc: buildClientFunc,
+	}
+
+	// The number of CPU cores of the instance.
+	coreCountMetric := supportedMetric{
+		name: "CoreCount",
+		buildCloudwatchDataFunc: buildCPUOptionsMetric("CoreCount", func(options *types.CpuOptions) *int32 {
+			return options.CoreCount
+		}),
+		requiredPermissions: []string{"ec2:DescribeInstances"},
+	}
+
+	// The number of threads per CPU core of the instance.
+	threadsPerCoreMetric := supportedMetric{
+		name: "ThreadsPerCore",
+		buildCloudwatchDataFunc: buildCPUOptionsMetric("ThreadsPerCore", func(options *types.CpuOptions) *int32 {
+			return options.ThreadsPerCore
+		}),
+		requiredPermissions: []string{"ec2:DescribeInstances"},
+	}
+
+	svc.supportedMetrics = map[string]supportedMetric{
+		coreCountMetric.name:      coreCountMetric,
+		threadsPerCoreMetric.name: threadsPerCoreMetric,
+	}
+
+	return svc
+}
+
+func (s *EC2) GetNamespace() string {
+	return awsEC2Namespace
+}
+
+func (s *EC2) loadMetricsMetadata(ctx context.Context, logger *slog.Logger, client Client, region string) (map[string]*types.Instance, error) {
+	instances, err := client.DescribeAllInstances(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error describing EC2 instances in region %s: %w", region, err)
+	}
+
+	regionalData := make(map[string]*types.Instance, len(instances))
+	for i := range instances {
+		if instances[i].InstanceId == nil {
+			continue
+		}
+		regionalData[*instances[i].InstanceId] = &instances[i]
+	}
+
+	logger.Info("Loaded EC2 metrics metadata", "region", region)
+	return regionalData, nil
+}
+
+func (s *EC2) IsMetricSupported(metricName string) bool {
+	_, exists := s.supportedMetrics[metricName]
+	return exists
+}
+
+func (s *EC2) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
+	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
+		return nil, nil
+	}
+
+	client := s.buildClientFunc(*regionalConfigProvider.GetAWSRegionalConfig(region, role))
+	instances, err := s.loadMetricsMetadata(ctx, logger, client, region)
+	if err != nil {
+		return nil, fmt.Errorf("error loading EC2 metrics metadata: %w", err)
+	}
+
+	var result []*model.CloudwatchData
+
+	for _, resource := range resources {
+		if resource.Namespace != s.GetNamespace() {
+			logger.Warn("Resource namespace does not match EC2 namespace, skipping", "arn", resource.ARN, "namespace", resource.Namespace)
+			continue
+		}
+
+		instanceID, ok := instanceIDFromARN(resource.ARN)
+		if !ok {
+			logger.Warn("Skipping EC2 resource: only instances are supported", "arn", resource.ARN)
+			continue
+		}
+
+		instance, exists := instances[instanceID]
+		if !exists {
+			s.coverage.Missing++
+			logger.Warn("EC2 instance not found in data", "arn", resource.ARN)
+			continue
+		}
+		s.coverage.Found++
+
+		for _, enhancedMetric := range enhancedMetricConfigs {
+			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
+			if !ok {
+				logger.Warn("Unsupported EC2 enhanced metric, skipping", "metric", enhancedMetric.Name)
+				continue
+			}
+
+			em, err := supportedMetric.buildCloudwatchData(resource, instance, exportedTagOnMetrics)
+			if err != nil || em == nil {
+				logger.Warn("Error building EC2 enhanced metric", "metric", enhancedMetric.Name, "error", err)
+				continue
+			}
+
+			result = append(result, em)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *EC2) ListRequiredPermissions() map[string][]string {
+	permissions := make(map[string][]string, len(s.supportedMetrics))
+	for _, metric := range s.supportedMetrics {
+		permissions[metric.name] = metric.requiredPermissions
+	}
+	return permissions
+}
+
+func (s *EC2) ListSupportedEnhancedMetrics() []string {
+	var metrics []string
+	for metric := range s.supportedMetrics {
+		metrics = append(metrics, metric)
+	}
+
+	sort.Strings(metrics)
+	return metrics
+}
+
+func (s *EC2) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
+func (s *EC2) Instance() service.EnhancedMetricsService {
+	// do not use NewEC2Service to avoid extra map allocation
+	return &EC2{
+		supportedMetrics: s.supportedMetrics,
+		buildClientFunc:  s.buildClientFunc,
+	}
+}
+
+func buildCPUOptionsMetric(metricName string, option func(*types.CpuOptions) *int32) buildCloudwatchDataFunc {
+	return func(resource *model.TaggedResource, instance *types.Instance, exportedTags []string) (*model.CloudwatchData, error) {
+		if instance.CpuOptions == nil || option(instance.CpuOptions) == nil {
+			return nil, fmt.Errorf("%s is missing for EC2 instance %s", metricName, resource.ARN)
+		}
+		value := float64(*option(instance.CpuOptions))
+
+		return &model.CloudwatchData{
+			MetricName:   metricName,
+			ResourceName: resource.ARN,
+			Namespace:    awsEC2Namespace,
+			Dimensions: []model.Dimension{
+				{Name: "InstanceId", Value: aws.ToString(instance.InstanceId)},
+			},
+			Tags: resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{
+					{
+						Value:     &value,
+						Timestamp: time.Now(),
+					},
+				},
+			},
+		}, nil
+	}
+}
+
+// instanceIDFromARN extracts the instance ID from an EC2 instance ARN, e.g.
+// i-0123456789abcdef0 for arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0.
+// It returns ok=false for other EC2 resources, such as volumes or NAT gateways.
+func instanceIDFromARN(instanceARN string) (string, bool) {
+	parsed, err := arn.Parse(instanceARN)
+	if err != nil || parsed.Service != "ec2" {
+		return "", false
+	}
+
+	resourceType, id, found := strings.Cut(parsed.Resource, "/")
+	if !found || resourceType != "instance" || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
@@ -0,0 +1,263 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ec2
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestNewEC2Service(t *testing.T) {
+	tests := []struct {
+		name            string
+		buildClientFunc func(cfg aws.Config) Client
+	}{
+		{
+			name:            "with nil buildClientFunc",
+			buildClientFunc: nil,
+		},
+		{
+			name: "with custom buildClientFunc",
+			buildClientFunc: func(_ aws.Config) Client {
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewEC2Service(tt.buildClientFunc)
+			require.NotNil(t, got)
+			require.Len(t, got.supportedMetrics, 2)
+			require.NotNil(t, got.supportedMetrics["CoreCount"])
+			require.NotNil(t, got.supportedMetrics["ThreadsPerCore"])
+		})
+	}
+}
+
+func TestEC2_GetNamespace(t *testing.T) {
+	service := NewEC2Service(nil)
+	require.Equal(t, awsEC2Namespace, service.GetNamespace())
+}
+
+func TestEC2_ListRequiredPermissions(t *testing.T) {
+	service := NewEC2Service(nil)
+	expectedPermissions := map[string][]string{
+		"CoreCount":      {"ec2:DescribeInstances"},
+		"ThreadsPerCore": {"ec2:DescribeInstances"},
+	}
+	require.Equal(t, expectedPermissions, service.ListRequiredPermissions())
+}
+
+func TestEC2_ListSupportedEnhancedMetrics(t *testing.T) {
+	service := NewEC2Service(nil)
+	expectedMetrics := []string{
+		"CoreCount",
+		"ThreadsPerCore",
+	}
+	require.Equal(t, expectedMetrics, service.ListSupportedEnhancedMetrics())
+}
+
+func TestInstanceIDFromARN(t *testing.T) {
+	tests := []struct {
+		name        string
+		instanceARN string
+		want        string
+		wantOK      bool
+	}{
+		{
+			name:        "instance",
+			instanceARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-0123456789abcdef0",
+			want:        "i-0123456789abcdef0",
+			wantOK:      true,
+		},
+		{
+			name:        "instance in another partition",
+			instanceARN: "arn:aws-cn:ec2:cn-north-1:123456789012:instance/i-0123456789abcdef0",
+			want:        "i-0123456789abcdef0",
+			wantOK:      true,
+		},
+		{
+			name:        "volume",
+			instanceARN: "arn:aws:ec2:us-east-1:123456789012:volume/vol-0123456789abcdef0",
+		},
+		{
+			name:        "missing instance ID",
+			instanceARN: "arn:aws:ec2:us-east-1:123456789012:instance/",
+		},
+		{
+			name:        "another service",
+			instanceARN: "arn:aws:rds:us-east-1:123456789012:db:my-db",
+		},
+		{
+			name:        "not an ARN",
+			instanceARN: "i-0123456789abcdef0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := instanceIDFromARN(tt.instanceARN)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestEC2_GetMetrics(t *testing.T) {
+	makeInstanceARN := func(id string) string {
+		return fmt.Sprintf("arn:aws:ec2:us-east-1:123456789012:instance/%s", id)
+	}
+	makeInstance := func(id string, coreCount, threadsPerCore int32) types.Instance {
+		return types.Instance{
+			InstanceId: aws.String(id),
+			CpuOptions: &types.CpuOptions{CoreCount: aws.Int32(coreCount), ThreadsPerCore: aws.Int32(threadsPerCore)},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		resources       []*model.TaggedResource
+		enhancedMetrics []*model.EnhancedMetricConfig
+		instances       []types.Instance
+		wantValues      map[string]float64
+		wantCoverage    service.ResourceCoverage
+	}{
+		{
+			name:            "empty resources returns empty",
+			resources:       []*model.TaggedResource{},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2)},
+		},
+		{
+			name:            "empty enhanced metrics returns empty",
+			resources:       []*model.TaggedResource{{ARN: makeInstanceARN("i-1"), Namespace: awsEC2Namespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2)},
+		},
+		{
+			name:            "wrong namespace is skipped",
+			resources:       []*model.TaggedResource{{ARN: makeInstanceARN("i-1")}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2)},
+		},
+		{
+			name:            "resources other than instances are skipped",
+			resources:       []*model.TaggedResource{{ARN: "arn:aws:ec2:us-east-1:123456789012:volume/vol-1", Namespace: awsEC2Namespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2)},
+		},
+		{
+			name:            "successfully received multiple metrics for a single instance",
+			resources:       []*model.TaggedResource{{ARN: makeInstanceARN("i-1"), Namespace: awsEC2Namespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}, {Name: "ThreadsPerCore"}},
+			instances:       []types.Instance{makeInstance("i-1", 4, 2)},
+			wantValues: map[string]float64{
+				"i-1/CoreCount":      4,
+				"i-1/ThreadsPerCore": 2,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 1},
+		},
+		{
+			name: "processes multiple resources and counts missing instances",
+			resources: []*model.TaggedResource{
+				{ARN: makeInstanceARN("i-1"), Namespace: awsEC2Namespace},
+				{ARN: makeInstanceARN("i-2"), Namespace: awsEC2Namespace},
+				{ARN: makeInstanceARN("i-terminated"), Namespace: awsEC2Namespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2), makeInstance("i-2", 8, 1)},
+			wantValues: map[string]float64{
+				"i-1/CoreCount": 2,
+				"i-2/CoreCount": 8,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 2, Missing: 1},
+		},
+		{
+			name:            "skips unsupported metrics",
+			resources:       []*model.TaggedResource{{ARN: makeInstanceARN("i-1"), Namespace: awsEC2Namespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "UnsupportedMetric"}},
+			instances:       []types.Instance{makeInstance("i-1", 2, 2)},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+		{
+			name:            "skips instances without CPU options",
+			resources:       []*model.TaggedResource{{ARN: makeInstanceARN("i-1"), Namespace: awsEC2Namespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "CoreCount"}},
+			instances:       []types.Instance{{InstanceId: aws.String("i-1")}},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewEC2Service(func(_ aws.Config) Client {
+				return &mockServiceEC2Client{instances: tt.instances}
+			})
+
+			result, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), tt.resources, tt.enhancedMetrics, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+
+			require.NoError(t, err)
+			require.Len(t, result, len(tt.wantValues))
+			require.Equal(t, tt.wantCoverage, service.Coverage())
+
+			for _, metric := range result {
+				require.Equal(t, awsEC2Namespace, metric.Namespace)
+				require.Len(t, metric.Dimensions, 1)
+				require.Equal(t, "InstanceId", metric.Dimensions[0].Name)
+				require.NotNil(t, metric.GetMetricDataResult)
+				require.Len(t, metric.GetMetricDataResult.DataPoints, 1)
+
+				want, ok := tt.wantValues[metric.Dimensions[0].Value+"/"+metric.MetricName]
+				require.True(t, ok, "unexpected metric %s for instance %s", metric.MetricName, metric.Dimensions[0].Value)
+				require.Equal(t, want, *metric.GetMetricDataResult.DataPoints[0].Value)
+			}
+		})
+	}
+}
+
+func TestEC2_GetMetrics_DescribeInstancesError(t *testing.T) {
+	service := NewEC2Service(func(_ aws.Config) Client {
+		return &mockServiceEC2Client{describeErr: fmt.Errorf("API error")}
+	})
+
+	_, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), []*model.TaggedResource{{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Namespace: awsEC2Namespace}}, []*model.EnhancedMetricConfig{{Name: "CoreCount"}}, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+	require.Error(t, err)
+}
+
+type mockServiceEC2Client struct {
+	instances   []types.Instance
+	describeErr error
+}
+
+func (m *mockServiceEC2Client) DescribeAllInstances(_ context.Context, _ *slog.Logger) ([]types.Instance, error) {
+	if m.describeErr != nil {
+		return nil, m.describeErr
+	}
+	return m.instances, nil
+}
+
+type mockConfigProvider struct {
+	c *aws.Config
+}
+
+func (m *mockConfigProvider) GetAWSRegionalConfig(_ string, _ model.Role) *aws.Config {
+	return m.c
+}
@@ -0,0 +1,144 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// maxDescribedServices is the maximum number of services DescribeServices accepts per call.
+const maxDescribedServices = 10
+
+type awsClient interface {
+	ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error)
+	ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error)
+	DescribeServices(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+}
+
+type AWSECSClient struct {
+	listClustersFunc     func(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error)
+	listServicesFunc     func(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error)
+	describeServicesFunc func(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+}
+
+func NewECSClientWithConfig(cfg aws.Config) Client {
+	c := ecs.NewFromConfig(cfg)
+	return &AWSECSClient{
+		listClustersFunc:     c.ListClusters,
+		listServicesFunc:     c.ListServices,
+		describeServicesFunc: c.DescribeServices,
+	}
+}
+
+func (c *AWSECSClient) listClusterARNs(ctx context.Context) ([]string, error) {
+	var clusterARNs []string
+	var nextToken *string
+	var maxResults int32 = 100
+
+	for {
+		output, err := c.listClustersFunc(ctx, &ecs.ListClustersInput{
+			NextToken:  nextToken,
+			MaxResults: &maxResults,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list ECS clusters: %w", err)
+		}
+
+		clusterARNs = append(clusterARNs, output.ClusterArns...)
+
+		if output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	return clusterARNs, nil
+}
+
+func (c *AWSECSClient) listServiceARNs(ctx context.Context, clusterARN string) ([]string, error) {
+	var serviceARNs []string
+	var nextToken *string
+	var maxResults int32 = 100
+
+	for {
+		output, err := c.listServicesFunc(ctx, &ecs.ListServicesInput{
+			Cluster:    aws.String(clusterARN),
+			NextToken:  nextToken,
+			MaxResults: &maxResults,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list services of ECS cluster %s: %w", clusterARN, err)
+		}
+
+		serviceARNs = append(serviceARNs, output.ServiceArns...)
+
+		if output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	return serviceARNs, nil
+}
+
+func (c *AWSECSClient) describeServices(ctx context.Context, clusterARN string, serviceARNs []string) ([]types.Service, error) {
+	var services []types.Service
+
+	for start := 0; start < len(serviceARNs); start += maxDescribedServices {
+		end := min(start+maxDescribedServices, len(serviceARNs))
+		output, err := c.describeServicesFunc(ctx, &ecs.DescribeServicesInput{
+			Cluster:  aws.String(clusterARN),
+			Services: serviceARNs[start:end],
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe services of ECS cluster %s: %w", clusterARN, err)
+		}
+
+		services = append(services, output.Services...)
+	}
+
+	return services, nil
+}
+
+// DescribeAllServices retrieves the services of all the ECS clusters of the region.
+func (c *AWSECSClient) DescribeAllServices(ctx context.Context, logger *slog.Logger) ([]types.Service, error) {
+	logger.Debug("Describing all ECS services")
+
+	clusterARNs, err := c.listClusterARNs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var allServices []types.Service
+	for _, clusterARN := range clusterARNs {
+		serviceARNs, err := c.listServiceARNs(ctx, clusterARN)
+		if err != nil {
+			return nil, err
+		}
+
+		services, err := c.describeServices(ctx, clusterARN, serviceARNs)
+		if err != nil {
+			return nil, err
+		}
+		allServices = append(allServices, services...)
+	}
+
+	logger.Debug("Completed describing ECS services", slog.Int("totalClusters", len(clusterARNs)), slog.Int("totalServices", len(allServices)))
+	return allServices, nil
+}
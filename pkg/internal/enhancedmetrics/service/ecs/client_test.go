@@ -0,0 +1,177 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func TestAWSECSClient_DescribeAllServices(t *testing.T) {
+	clusterARN := func(name string) string {
+		return "arn:aws:ecs:us-east-1:123456789012:cluster/" + name
+	}
+	serviceARN := func(cluster string, i int) string {
+		return fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:service/%s/service-%d", cluster, i)
+	}
+
+	var describeCalls [][]string
+	client := &mockECSClient{
+		listClustersFunc: func(_ context.Context, params *ecs.ListClustersInput, _ ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+			if params.NextToken == nil {
+				return &ecs.ListClustersOutput{ClusterArns: []string{clusterARN("a")}, NextToken: aws.String("token1")}, nil
+			}
+			return &ecs.ListClustersOutput{ClusterArns: []string{clusterARN("b")}}, nil
+		},
+		listServicesFunc: func(_ context.Context, params *ecs.ListServicesInput, _ ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+			// cluster a has 12 services over two pages, cluster b has none
+			if aws.ToString(params.Cluster) != clusterARN("a") {
+				return &ecs.ListServicesOutput{}, nil
+			}
+			if params.NextToken == nil {
+				var arns []string
+				for i := range 7 {
+					arns = append(arns, serviceARN("a", i))
+				}
+				return &ecs.ListServicesOutput{ServiceArns: arns, NextToken: aws.String("token1")}, nil
+			}
+			var arns []string
+			for i := 7; i < 12; i++ {
+				arns = append(arns, serviceARN("a", i))
+			}
+			return &ecs.ListServicesOutput{ServiceArns: arns}, nil
+		},
+		describeServicesFunc: func(_ context.Context, params *ecs.DescribeServicesInput, _ ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+			describeCalls = append(describeCalls, params.Services)
+			services := make([]types.Service, 0, len(params.Services))
+			for _, arn := range params.Services {
+				services = append(services, types.Service{ServiceArn: aws.String(arn), ClusterArn: params.Cluster})
+			}
+			return &ecs.DescribeServicesOutput{Services: services}, nil
+		},
+	}
+
+	c := &AWSECSClient{
+		listClustersFunc:     client.ListClusters,
+		listServicesFunc:     client.ListServices,
+		describeServicesFunc: client.DescribeServices,
+	}
+	got, err := c.DescribeAllServices(context.Background(), slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("DescribeAllServices() error = %v", err)
+	}
+
+	var gotARNs []string
+	for _, svc := range got {
+		gotARNs = append(gotARNs, aws.ToString(svc.ServiceArn))
+	}
+	var wantARNs []string
+	for i := range 12 {
+		wantARNs = append(wantARNs, serviceARN("a", i))
+	}
+	if !reflect.DeepEqual(gotARNs, wantARNs) {
+		t.Errorf("DescribeAllServices() got = %v, want %v", gotARNs, wantARNs)
+	}
+	// DescribeServices accepts at most 10 services per call
+	if len(describeCalls) != 2 || len(describeCalls[0]) != 10 || len(describeCalls[1]) != 2 {
+		t.Errorf("DescribeServices() calls = %v, want batches of 10 and 2 services", describeCalls)
+	}
+}
+
+func TestAWSECSClient_DescribeAllServices_Errors(t *testing.T) {
+	listClusters := func(_ context.Context, _ *ecs.ListClustersInput, _ ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+		return &ecs.ListClustersOutput{ClusterArns: []string{"arn:aws:ecs:us-east-1:123456789012:cluster/a"}}, nil
+	}
+	listServices := func(_ context.Context, _ *ecs.ListServicesInput, _ ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+		return &ecs.ListServicesOutput{ServiceArns: []string{"arn:aws:ecs:us-east-1:123456789012:service/a/b"}}, nil
+	}
+	describeServices := func(_ context.Context, _ *ecs.DescribeServicesInput, _ ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+		return &ecs.DescribeServicesOutput{}, nil
+	}
+
+	tests := []struct {
+		name   string
+		client *mockECSClient
+	}{
+		{
+			name: "list clusters error",
+			client: &mockECSClient{
+				listClustersFunc: func(_ context.Context, _ *ecs.ListClustersInput, _ ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+				listServicesFunc:     listServices,
+				describeServicesFunc: describeServices,
+			},
+		},
+		{
+			name: "list services error",
+			client: &mockECSClient{
+				listClustersFunc: listClusters,
+				listServicesFunc: func(_ context.Context, _ *ecs.ListServicesInput, _ ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+				describeServicesFunc: describeServices,
+			},
+		},
+		{
+			name: "describe services error",
+			client: &mockECSClient{
+				listClustersFunc: listClusters,
+				listServicesFunc: listServices,
+				describeServicesFunc: func(_ context.Context, _ *ecs.DescribeServicesInput, _ ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AWSECSClient{
+				listClustersFunc:     tt.client.ListClusters,
+				listServicesFunc:     tt.client.ListServices,
+				describeServicesFunc: tt.client.DescribeServices,
+			}
+			if _, err := c.DescribeAllServices(context.Background(), slog.New(slog.DiscardHandler)); err == nil {
+				t.Error("DescribeAllServices() expected an error")
+			}
+		})
+	}
+}
+
+// mockECSClient is a mock implementation of AWS ECS Client
+type mockECSClient struct {
+	listClustersFunc     func(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error)
+	listServicesFunc     func(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error)
+	describeServicesFunc func(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+}
+
+var _ awsClient = &mockECSClient{}
+
+func (m *mockECSClient) ListClusters(ctx context.Context, params *ecs.ListClustersInput, optFns ...func(*ecs.Options)) (*ecs.ListClustersOutput, error) {
+	return m.listClustersFunc(ctx, params, optFns...)
+}
+
+func (m *mockECSClient) ListServices(ctx context.Context, params *ecs.ListServicesInput, optFns ...func(*ecs.Options)) (*ecs.ListServicesOutput, error) {
+	return m.listServicesFunc(ctx, params, optFns...)
+}
+
+func (m *mockECSClient) DescribeServices(ctx context.Context, params *ecs.DescribeServicesInput, optFns ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error) {
+	return m.describeServicesFunc(ctx, params, optFns...)
+}
@@ -0,0 +1,290 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+const awsECSNamespace = "AWS/ECS"
+
+type Client interface {
+	DescribeAllServices(ctx context.Context, logger *slog.Logger) ([]types.Service, error)
+}
+
+// ecsService is a described ECS service, with the values of its ClusterName and ServiceName dimensions.
+type ecsService struct {
+	clusterName string
+	serviceName string
+	service     *types.Service
+}
+
+type buildCloudwatchDataFunc func(*model.TaggedResource, *ecsService, []string) (*model.CloudwatchData, error)
+
+type supportedMetric struct {
+	name                    string
+	buildCloudwatchDataFunc buildCloudwatchDataFunc
+	requiredPermissions     []string
+}
+
+func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, svc *ecsService, exportedTagOnMetrics []string) (*model.CloudwatchData, error) {
+	return sm.buildCloudwatchDataFunc(resource, svc, exportedTagOnMetrics)
+}
+
+type ECS struct {
+	supportedMetrics map[string]supportedMetric
+	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
+}
+
+func NewECSService(buildClientFunc func(cfg aws.Config) Client) *ECS {
+	if buildClientFunc == nil {
+		buildClientFunc = NewECSClientWithConfig
+	}
+	svc := &ECS{
+		buildClientFunc: buildClientFunc,
+	}
+
+	// The number of tasks the service is configured to keep running.
+	desiredCountMetric := supportedMetric{
+		name:                    "DesiredCount",
+		buildCloudwatchDataFunc: buildTaskCountMetric("DesiredCount", func(s *types.Service) int32 { return s.DesiredCount }),
+		requiredPermissions:     []string{"ecs:ListClusters", "ecs:ListServices", "ecs:DescribeServices"},
+	}
+
+	// The number of tasks of the service in the RUNNING state.
+	runningCountMetric := supportedMetric{
+		name:                    "RunningCount",
+		buildCloudwatchDataFunc: buildTaskCountMetric("RunningCount", func(s *types.Service) int32 { return s.RunningCount }),
+		requiredPermissions:     []string{"ecs:ListClusters", "ecs:ListServices", "ecs:DescribeServices"},
+	}
+
+	svc.supportedMetrics = map[string]supportedMetric{
+		desiredCountMetric.name: desiredCountMetric,
+		runningCountMetric.name: runningCountMetric,
+	}
+
+	return svc
+}
+
+func (s *ECS) GetNamespace() string {
+	return awsECSNamespace
+}
+
+func (s *ECS) loadMetricsMetadata(ctx context.Context, logger *slog.Logger, region string, role model.Role, configProvider config.RegionalConfigProvider) (map[string]*types.Service, error) {
+	client := s.buildClientFunc(*configProvider.GetAWSRegionalConfig(region, role))
+
+	services, err := client.DescribeAllServices(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error listing services in region %s: %w", region, err)
+	}
+
+	regionalData := make(map[string]*types.Service, len(services))
+	for _, svc := range services {
+		serviceARN, ok := service.DescribedARN(svc.ServiceArn)
+		if !ok {
+			logger.Warn("Skipping ECS service with invalid ARN", "service", aws.ToString(svc.ServiceName), "arn", aws.ToString(svc.ServiceArn))
+			continue
+		}
+		regionalData[serviceARN] = &svc
+	}
+
+	logger.Info("Loaded ECS metrics metadata", "region", region)
+	return regionalData, nil
+}
+
+func (s *ECS) IsMetricSupported(metricName string) bool {
+	_, exists := s.supportedMetrics[metricName]
+	return exists
+}
+
+func (s *ECS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
+	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
+		return nil, nil
+	}
+
+	data, err := s.loadMetricsMetadata(ctx, logger, region, role, regionalConfigProvider)
+	if err != nil {
+		return nil, fmt.Errorf("error loading ecs metrics metadata: %w", err)
+	}
+
+	var result []*model.CloudwatchData
+
+	for _, resource := range resources {
+		if resource.Namespace != s.GetNamespace() {
+			logger.Warn("Resource namespace does not match ECS namespace, skipping", "arn", resource.ARN, "namespace", resource.Namespace)
+			continue
+		}
+
+		// The AWS/ECS discovery jobs also find the clusters, which have no enhanced metrics and aren't
+		// described, so they don't count towards the coverage.
+		if isClusterARN(resource.ARN) {
+			continue
+		}
+
+		described, exists := data[resource.ARN]
+		if !exists {
+			s.coverage.Missing++
+			logger.Warn("ECS service not found in data", "arn", resource.ARN)
+			continue
+		}
+
+		svc, err := newECSService(resource.ARN, described)
+		if err != nil {
+			s.coverage.Missing++
+			logger.Warn("Couldn't get ECS service dimensions, skipping", "arn", resource.ARN, "err", err)
+			continue
+		}
+		s.coverage.Found++
+
+		for _, enhancedMetric := range enhancedMetricConfigs {
+			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
+			if !ok {
+				logger.Warn("Unsupported ECS enhanced metric, skipping", "metric", enhancedMetric.Name)
+				continue
+			}
+
+			em, err := supportedMetric.buildCloudwatchData(resource, svc, exportedTagOnMetrics)
+			if err != nil || em == nil {
+				logger.Warn("Error building ECS enhanced metric", "metric", enhancedMetric.Name, "error", err)
+				continue
+			}
+
+			result = append(result, em)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *ECS) ListRequiredPermissions() map[string][]string {
+	permissions := make(map[string][]string, len(s.supportedMetrics))
+	for _, metric := range s.supportedMetrics {
+		permissions[metric.name] = metric.requiredPermissions
+	}
+	return permissions
+}
+
+func (s *ECS) ListSupportedEnhancedMetrics() []string {
+	var metrics []string
+	for metric := range s.supportedMetrics {
+		metrics = append(metrics, metric)
+	}
+
+	sort.Strings(metrics)
+	return metrics
+}
+
+func (s *ECS) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
+func (s *ECS) Instance() service.EnhancedMetricsService {
+	// do not use NewECSService to avoid extra map allocation
+	return &ECS{
+		supportedMetrics: s.supportedMetrics,
+		buildClientFunc:  s.buildClientFunc,
+	}
+}
+
+// buildTaskCountMetric returns a buildCloudwatchDataFunc exporting a task count of the service as the metric.
+func buildTaskCountMetric(metricName string, getValue func(*types.Service) int32) buildCloudwatchDataFunc {
+	return func(resource *model.TaggedResource, svc *ecsService, exportedTags []string) (*model.CloudwatchData, error) {
+		value := float64(getValue(svc.service))
+		return &model.CloudwatchData{
+			MetricName:   metricName,
+			ResourceName: resource.ARN,
+			Namespace:    awsECSNamespace,
+			Dimensions: []model.Dimension{
+				{Name: "ClusterName", Value: svc.clusterName},
+				{Name: "ServiceName", Value: svc.serviceName},
+			},
+			Tags: resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{
+					{
+						Value:     &value,
+						Timestamp: time.Now(),
+					},
+				},
+			},
+		}, nil
+	}
+}
+
+// newECSService returns the described service with the values of its dimensions. They are parsed from the
+// service ARN, e.g. arn:aws:ecs:us-east-1:123456789012:service/my-cluster/my-service. The cluster of the
+// services with an ARN in the old format, without the cluster, e.g. arn:aws:ecs:us-east-1:123456789012:service/my-service,
+// is parsed from the cluster ARN of the described service instead.
+func newECSService(serviceARN string, described *types.Service) (*ecsService, error) {
+	parsed, err := arn.Parse(serviceARN)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := strings.CutPrefix(parsed.Resource, "service/")
+	if !ok || parsed.Service != "ecs" {
+		return nil, fmt.Errorf("not an ECS service ARN: %s", serviceARN)
+	}
+
+	clusterName, serviceName, ok := strings.Cut(path, "/")
+	if !ok {
+		serviceName = path
+		clusterName, err = clusterNameFromARN(aws.ToString(described.ClusterArn))
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get the cluster of ECS service %s: %w", serviceARN, err)
+		}
+	}
+	if clusterName == "" || serviceName == "" || strings.Contains(serviceName, "/") {
+		return nil, fmt.Errorf("not an ECS service ARN: %s", serviceARN)
+	}
+
+	return &ecsService{
+		clusterName: clusterName,
+		serviceName: serviceName,
+		service:     described,
+	}, nil
+}
+
+// clusterNameFromARN returns the name of the cluster of a cluster ARN, e.g. my-cluster for
+// arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster.
+func clusterNameFromARN(clusterARN string) (string, error) {
+	parsed, err := arn.Parse(clusterARN)
+	if err != nil {
+		return "", err
+	}
+	name, ok := strings.CutPrefix(parsed.Resource, "cluster/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", fmt.Errorf("not an ECS cluster ARN: %s", clusterARN)
+	}
+	return name, nil
+}
+
+func isClusterARN(resourceARN string) bool {
+	_, err := clusterNameFromARN(resourceARN)
+	return err == nil
+}
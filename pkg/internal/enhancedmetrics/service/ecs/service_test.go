@@ -0,0 +1,290 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ecs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestNewECSService(t *testing.T) {
+	tests := []struct {
+		name            string
+		buildClientFunc func(cfg aws.Config) Client
+	}{
+		{
+			name:            "with nil buildClientFunc",
+			buildClientFunc: nil,
+		},
+		{
+			name: "with custom buildClientFunc",
+			buildClientFunc: func(_ aws.Config) Client {
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewECSService(tt.buildClientFunc)
+			require.NotNil(t, got)
+			require.Len(t, got.supportedMetrics, 2)
+			require.NotNil(t, got.supportedMetrics["DesiredCount"])
+			require.NotNil(t, got.supportedMetrics["RunningCount"])
+		})
+	}
+}
+
+func TestECS_GetNamespace(t *testing.T) {
+	service := NewECSService(nil)
+	require.Equal(t, awsECSNamespace, service.GetNamespace())
+}
+
+func TestECS_ListRequiredPermissions(t *testing.T) {
+	service := NewECSService(nil)
+	expectedPermissions := map[string][]string{
+		"DesiredCount": {"ecs:ListClusters", "ecs:ListServices", "ecs:DescribeServices"},
+		"RunningCount": {"ecs:ListClusters", "ecs:ListServices", "ecs:DescribeServices"},
+	}
+	require.Equal(t, expectedPermissions, service.ListRequiredPermissions())
+}
+
+func TestECS_ListSupportedEnhancedMetrics(t *testing.T) {
+	service := NewECSService(nil)
+	require.Equal(t, []string{"DesiredCount", "RunningCount"}, service.ListSupportedEnhancedMetrics())
+}
+
+func TestNewECSService_Dimensions(t *testing.T) {
+	tests := []struct {
+		name        string
+		serviceARN  string
+		clusterARN  string
+		wantCluster string
+		wantService string
+		wantErr     bool
+	}{
+		{
+			name:        "cluster parsed from the service ARN",
+			serviceARN:  "arn:aws:ecs:us-east-1:123456789012:service/my-cluster/my-service",
+			wantCluster: "my-cluster",
+			wantService: "my-service",
+		},
+		{
+			name:        "service ARN takes precedence over the cluster ARN",
+			serviceARN:  "arn:aws:ecs:us-east-1:123456789012:service/my-cluster/my-service",
+			clusterARN:  "arn:aws:ecs:us-east-1:123456789012:cluster/other-cluster",
+			wantCluster: "my-cluster",
+			wantService: "my-service",
+		},
+		{
+			name:        "old service ARN format uses the cluster ARN",
+			serviceARN:  "arn:aws:ecs:us-east-1:123456789012:service/my-service",
+			clusterARN:  "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster",
+			wantCluster: "my-cluster",
+			wantService: "my-service",
+		},
+		{
+			name:       "old service ARN format without a cluster ARN",
+			serviceARN: "arn:aws:ecs:us-east-1:123456789012:service/my-service",
+			wantErr:    true,
+		},
+		{
+			name:       "cluster ARN",
+			serviceARN: "arn:aws:ecs:us-east-1:123456789012:cluster/my-cluster",
+			wantErr:    true,
+		},
+		{
+			name:       "task ARN",
+			serviceARN: "arn:aws:ecs:us-east-1:123456789012:task/my-cluster/0123456789abcdef",
+			wantErr:    true,
+		},
+		{
+			name:       "another service",
+			serviceARN: "arn:aws:sqs:us-east-1:123456789012:service/my-cluster/my-service",
+			wantErr:    true,
+		},
+		{
+			name:       "not an ARN",
+			serviceARN: "my-service",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			described := &types.Service{ServiceArn: aws.String(tt.serviceARN)}
+			if tt.clusterARN != "" {
+				described.ClusterArn = aws.String(tt.clusterARN)
+			}
+
+			got, err := newECSService(tt.serviceARN, described)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantCluster, got.clusterName)
+			require.Equal(t, tt.wantService, got.serviceName)
+			require.Same(t, described, got.service)
+		})
+	}
+}
+
+func TestECS_GetMetrics(t *testing.T) {
+	makeServiceARN := func(cluster, name string) string {
+		return fmt.Sprintf("arn:aws:ecs:us-east-1:123456789012:service/%s/%s", cluster, name)
+	}
+	makeService := func(serviceARN string, desired, running int32) types.Service {
+		// ClusterArn is left unset, so that the cluster of the services must be parsed from their ARN
+		return types.Service{ServiceArn: aws.String(serviceARN), DesiredCount: desired, RunningCount: running}
+	}
+
+	tests := []struct {
+		name            string
+		resources       []*model.TaggedResource
+		enhancedMetrics []*model.EnhancedMetricConfig
+		services        []types.Service
+		wantValues      map[string]float64
+		wantCoverage    service.ResourceCoverage
+	}{
+		{
+			name:            "empty resources returns empty",
+			resources:       []*model.TaggedResource{},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "DesiredCount"}},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 2, 2)},
+		},
+		{
+			name:            "empty enhanced metrics returns empty",
+			resources:       []*model.TaggedResource{{ARN: makeServiceARN("cluster", "svc"), Namespace: awsECSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 2, 2)},
+		},
+		{
+			name:            "wrong namespace is skipped",
+			resources:       []*model.TaggedResource{{ARN: makeServiceARN("cluster", "svc")}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "DesiredCount"}},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 2, 2)},
+		},
+		{
+			name:            "successfully received multiple metrics for a single service",
+			resources:       []*model.TaggedResource{{ARN: makeServiceARN("cluster", "svc"), Namespace: awsECSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "DesiredCount"}, {Name: "RunningCount"}},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 3, 2)},
+			wantValues: map[string]float64{
+				"cluster/svc/DesiredCount": 3,
+				"cluster/svc/RunningCount": 2,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 1},
+		},
+		{
+			name: "services with the same name in different clusters",
+			resources: []*model.TaggedResource{
+				{ARN: makeServiceARN("blue", "svc"), Namespace: awsECSNamespace},
+				{ARN: makeServiceARN("green", "svc"), Namespace: awsECSNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "RunningCount"}},
+			services: []types.Service{
+				makeService(makeServiceARN("blue", "svc"), 2, 2),
+				makeService(makeServiceARN("green", "svc"), 2, 0),
+			},
+			wantValues: map[string]float64{
+				"blue/svc/RunningCount":  2,
+				"green/svc/RunningCount": 0,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 2},
+		},
+		{
+			name: "clusters are skipped and missing services are counted",
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:ecs:us-east-1:123456789012:cluster/cluster", Namespace: awsECSNamespace},
+				{ARN: makeServiceARN("cluster", "svc"), Namespace: awsECSNamespace},
+				{ARN: makeServiceARN("cluster", "deleted"), Namespace: awsECSNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "DesiredCount"}},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 1, 1)},
+			wantValues:      map[string]float64{"cluster/svc/DesiredCount": 1},
+			wantCoverage:    service.ResourceCoverage{Found: 1, Missing: 1},
+		},
+		{
+			name:            "skips unsupported metrics",
+			resources:       []*model.TaggedResource{{ARN: makeServiceARN("cluster", "svc"), Namespace: awsECSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "UnsupportedMetric"}},
+			services:        []types.Service{makeService(makeServiceARN("cluster", "svc"), 1, 1)},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewECSService(func(_ aws.Config) Client {
+				return &mockServiceECSClient{services: tt.services}
+			})
+
+			result, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), tt.resources, tt.enhancedMetrics, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+
+			require.NoError(t, err)
+			require.Len(t, result, len(tt.wantValues))
+			require.Equal(t, tt.wantCoverage, service.Coverage())
+
+			for _, metric := range result {
+				require.Equal(t, awsECSNamespace, metric.Namespace)
+				require.Len(t, metric.Dimensions, 2)
+				require.Equal(t, "ClusterName", metric.Dimensions[0].Name)
+				require.Equal(t, "ServiceName", metric.Dimensions[1].Name)
+				require.NotNil(t, metric.GetMetricDataResult)
+				require.Len(t, metric.GetMetricDataResult.DataPoints, 1)
+
+				key := metric.Dimensions[0].Value + "/" + metric.Dimensions[1].Value + "/" + metric.MetricName
+				want, ok := tt.wantValues[key]
+				require.True(t, ok, "unexpected metric %s", key)
+				require.Equal(t, want, *metric.GetMetricDataResult.DataPoints[0].Value)
+			}
+		})
+	}
+}
+
+func TestECS_GetMetrics_DescribeServicesError(t *testing.T) {
+	service := NewECSService(func(_ aws.Config) Client {
+		return &mockServiceECSClient{err: fmt.Errorf("API error")}
+	})
+
+	_, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), []*model.TaggedResource{{ARN: "arn:aws:ecs:us-east-1:123456789012:service/cluster/svc", Namespace: awsECSNamespace}}, []*model.EnhancedMetricConfig{{Name: "DesiredCount"}}, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+	require.Error(t, err)
+}
+
+type mockServiceECSClient struct {
+	services []types.Service
+	err      error
+}
+
+func (m *mockServiceECSClient) DescribeAllServices(_ context.Context, _ *slog.Logger) ([]types.Service, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.services, nil
+}
+
+type mockConfigProvider struct {
+	c *aws.Config
+}
+
+func (m *mockConfigProvider) GetAWSRegionalConfig(_ string, _ model.Role) *aws.Config {
+	return m.c
+}
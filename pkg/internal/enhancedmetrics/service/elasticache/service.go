@@ -47,6 +47,7 @@ func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, e
 type ElastiCache struct {
 	supportedMetrics map[string]supportedMetric
 	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
 }
 
 func NewElastiCacheService(buildClientFunc func(cfg aws.Config) Client) *ElastiCache {
@@ -86,7 +87,12 @@ func (s *ElastiCache) loadMetricsMetadata(ctx context.Context, logger *slog.Logg
 	regionalData := make(map[string]*types.CacheCluster, len(instances))
 
 	for _, instance := range instances {
-		regionalData[*instance.ARN] = &instance
+		clusterARN, ok := service.DescribedARN(instance.ARN)
+		if !ok {
+			logger.Warn("Skipping ElastiCache cluster with invalid ARN", "cluster", aws.ToString(instance.CacheClusterId), "arn", aws.ToString(instance.ARN))
+			continue
+		}
+		regionalData[clusterARN] = &instance
 	}
 
 	return regionalData, nil
@@ -98,6 +104,8 @@ func (s *ElastiCache) IsMetricSupported(metricName string) bool {
 }
 
 func (s *ElastiCache) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
 	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
 		return nil, nil
 	}
@@ -123,9 +131,11 @@ func (s *ElastiCache) GetMetrics(ctx context.Context, logger *slog.Logger, resou
 
 		elastiCacheCluster, exists := data[resource.ARN]
 		if !exists {
+			s.coverage.Missing++
 			logger.Warn("ElastiCache cluster not found in data", "arn", resource.ARN)
 			continue
 		}
+		s.coverage.Found++
 
 		for _, enhancedMetric := range enhancedMetricConfigs {
 			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
@@ -163,6 +173,11 @@ func (s *ElastiCache) ListSupportedEnhancedMetrics() []string {
 	return metrics
 }
 
+// Coverage returns the resources found, or not, in the output of the describe calls of the last GetMetrics call.
+func (s *ElastiCache) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
 func (s *ElastiCache) Instance() service.EnhancedMetricsService {
 	// do not use NewElastiCacheService to avoid extra map allocation
 	return &ElastiCache{
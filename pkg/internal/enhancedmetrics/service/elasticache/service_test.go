@@ -159,6 +159,17 @@ func TestElastiCache_GetMetrics(t *testing.T) {
 			},
 			wantResultCount: 2,
 		},
+		{
+			name:            "skips clusters with invalid ARNs",
+			resources:       []*model.TaggedResource{{ARN: "arn:aws:elasticache:us-east-1:123456789012:cluster:test-cluster", Namespace: awsElastiCacheNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "NumCacheNodes"}},
+			clusters: []types.CacheCluster{
+				{CacheClusterId: aws.String("nil-arn"), NumCacheNodes: aws.Int32(1)},
+				{ARN: aws.String("not-an-arn"), CacheClusterId: aws.String("invalid-arn"), NumCacheNodes: aws.Int32(1)},
+				testCluster,
+			},
+			wantResultCount: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -48,6 +48,7 @@ func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, f
 type Lambda struct {
 	supportedMetrics map[string]supportedMetric
 	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
 }
 
 func NewLambdaService(buildClientFunc func(cfg aws.Config) Client) *Lambda {
@@ -93,7 +94,12 @@ func (s *Lambda) loadMetricsMetadata(ctx context.Context, logger *slog.Logger, r
 
 	regionalData := make(map[string]*types.FunctionConfiguration, len(instances))
 	for _, instance := range instances {
-		regionalData[*instance.FunctionArn] = &instance
+		functionARN, ok := service.DescribedARN(instance.FunctionArn)
+		if !ok {
+			logger.Warn("Skipping Lambda function with invalid ARN", "function", aws.ToString(instance.FunctionName), "arn", aws.ToString(instance.FunctionArn))
+			continue
+		}
+		regionalData[functionARN] = &instance
 	}
 
 	logger.Info("Loaded Lambda metrics metadata", "region", region)
@@ -106,6 +112,8 @@ func (s *Lambda) IsMetricSupported(metricName string) bool {
 }
 
 func (s *Lambda) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
 	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
 		return nil, nil
 	}
@@ -131,9 +139,11 @@ func (s *Lambda) GetMetrics(ctx context.Context, logger *slog.Logger, resources
 
 		functionConfiguration, exists := data[resource.ARN]
 		if !exists {
+			s.coverage.Missing++
 			logger.Warn("Lambda function not found in data", "arn", resource.ARN)
 			continue
 		}
+		s.coverage.Found++
 
 		for _, enhancedMetric := range enhancedMetricConfigs {
 			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
@@ -173,6 +183,11 @@ func (s *Lambda) ListSupportedEnhancedMetrics() []string {
 	return metrics
 }
 
+// Coverage returns the resources found, or not, in the output of the describe calls of the last GetMetrics call.
+func (s *Lambda) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
 func (s *Lambda) Instance() service.EnhancedMetricsService {
 	// do not use NewLambdaService to avoid extra map allocation
 	return &Lambda{
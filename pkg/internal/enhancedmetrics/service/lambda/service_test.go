@@ -174,6 +174,19 @@ func TestLambda_GetMetrics(t *testing.T) {
 			},
 			wantCount: 2, // 1 for Timeout + 1 for MemorySize
 		},
+		{
+			name: "skips functions with invalid ARNs",
+			resources: []*model.TaggedResource{
+				{ARN: "arn:aws:lambda:us-east-1:123456789012:function:test", Namespace: awsLambdaNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "Timeout"}},
+			functions: []types.FunctionConfiguration{
+				{FunctionName: aws.String("nil-arn"), Timeout: aws.Int32(60)},
+				{FunctionArn: aws.String("not-an-arn"), FunctionName: aws.String("invalid-arn"), Timeout: aws.Int32(60)},
+				makeFunctionConfiguration("test", 300),
+			},
+			wantCount: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -16,6 +16,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -76,6 +77,7 @@ func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, i
 type RDS struct {
 	supportedMetrics map[string]supportedMetric
 	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
 }
 
 func NewRDSService(buildClientFunc func(cfg aws.Config) Client) *RDS {
@@ -93,8 +95,15 @@ func NewRDSService(buildClientFunc func(cfg aws.Config) Client) *RDS {
 		buildCloudwatchDataFunc: buildAllocatedStorageMetric,
 		requiredPermissions:     []string{"rds:DescribeDBInstances"},
 	}
+	// The upper limit in gibibytes (GiB) to which storage autoscaling can scale the DB instance.
+	maxAllocatedStorageMetrics := supportedMetric{
+		name:                    "MaxAllocatedStorage",
+		buildCloudwatchDataFunc: buildMaxAllocatedStorageMetric,
+		requiredPermissions:     []string{"rds:DescribeDBInstances"},
+	}
 	rds.supportedMetrics = map[string]supportedMetric{
-		allocatedStorageMetrics.name: allocatedStorageMetrics,
+		allocatedStorageMetrics.name:    allocatedStorageMetrics,
+		maxAllocatedStorageMetrics.name: maxAllocatedStorageMetrics,
 	}
 
 	return rds
@@ -123,7 +132,12 @@ func (s *RDS) loadMetricsMetadata(
 
 	regionalData := make(map[string]*types.DBInstance, len(instances))
 	for i := range instances {
-		regionalData[*instances[i].DBInstanceArn] = &instances[i]
+		instanceARN, ok := service.DescribedARN(instances[i].DBInstanceArn)
+		if !ok {
+			logger.Warn("Skipping RDS DB instance with invalid ARN", "instance", aws.ToString(instances[i].DBInstanceIdentifier), "arn", aws.ToString(instances[i].DBInstanceArn))
+			continue
+		}
+		regionalData[instanceARN] = &instances[i]
 	}
 
 	return regionalData, nil
@@ -135,6 +149,8 @@ func (s *RDS) IsMetricSupported(metricName string) bool {
 }
 
 func (s *RDS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
 	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
 		return nil, nil
 	}
@@ -184,9 +200,11 @@ func (s *RDS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*
 	for _, resource := range instanceResources {
 		dbInstance, exists := data[resource.ARN]
 		if !exists {
+			s.coverage.Missing++
 			logger.Warn("RDS DB instance not found in metadata", "arn", resource.ARN)
 			continue
 		}
+		s.coverage.Found++
 
 		for _, enhancedMetric := range enhancedMetricConfigs {
 			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
@@ -196,10 +214,14 @@ func (s *RDS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*
 			}
 
 			em, err := supportedMetric.buildCloudwatchData(resource, dbInstance, exportedTagOnMetrics)
-			if err != nil || em == nil {
+			if err != nil {
 				logger.Warn("Error building RDS enhanced metric", "metric", enhancedMetric.Name, "error", err)
 				continue
 			}
+			// the metric doesn't apply to this DB instance
+			if em == nil {
+				continue
+			}
 
 			result = append(result, em)
 		}
@@ -221,9 +243,16 @@ func (s *RDS) ListSupportedEnhancedMetrics() []string {
 	for metric := range s.supportedMetrics {
 		metrics = append(metrics, metric)
 	}
+
+	sort.Strings(metrics)
 	return metrics
 }
 
+// Coverage returns the resources found, or not, in the output of the describe calls of the last GetMetrics call.
+func (s *RDS) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
 func (s *RDS) Instance() service.EnhancedMetricsService {
 	// do not use NewRDSService to avoid extra map allocation
 	return &RDS{
@@ -237,6 +266,57 @@ func buildAllocatedStorageMetric(resource *model.TaggedResource, instance *types
 		return nil, fmt.Errorf("AllocatedStorage is nil for DB instance %s", resource.ARN)
 	}
 
+	// Convert from GiB to bytes
+	valueInBytes := float64(*instance.AllocatedStorage) * 1024 * 1024 * 1024
+
+	return &model.CloudwatchData{
+		MetricName:   "AllocatedStorage",
+		ResourceName: resource.ARN,
+		Namespace:    awsRdsNamespace,
+		Dimensions:   getDBInstanceDimensions(instance),
+		Tags:         resource.MetricTags(exportedTags),
+
+		// Store the value as a single data point
+		GetMetricDataResult: &model.GetMetricDataResult{
+			DataPoints: []model.DataPoint{
+				{
+					Value:     &valueInBytes,
+					Timestamp: time.Now(),
+				},
+			},
+		},
+	}, nil
+}
+
+func buildMaxAllocatedStorageMetric(resource *model.TaggedResource, instance *types.DBInstance, exportedTags []string) (*model.CloudwatchData, error) {
+	// MaxAllocatedStorage is only set when storage autoscaling is enabled, there is no metric otherwise
+	if instance.MaxAllocatedStorage == nil {
+		return nil, nil
+	}
+
+	// Convert from GiB to bytes
+	valueInBytes := float64(*instance.MaxAllocatedStorage) * 1024 * 1024 * 1024
+
+	return &model.CloudwatchData{
+		MetricName:   "MaxAllocatedStorage",
+		ResourceName: resource.ARN,
+		Namespace:    awsRdsNamespace,
+		Dimensions:   getDBInstanceDimensions(instance),
+		Tags:         resource.MetricTags(exportedTags),
+
+		// Store the value as a single data point
+		GetMetricDataResult: &model.GetMetricDataResult{
+			DataPoints: []model.DataPoint{
+				{
+					Value:     &valueInBytes,
+					Timestamp: time.Now(),
+				},
+			},
+		},
+	}, nil
+}
+
+func getDBInstanceDimensions(instance *types.DBInstance) []model.Dimension {
 	var dimensions []model.Dimension
 
 	if instance.DBInstanceIdentifier != nil && len(*instance.DBInstanceIdentifier) > 0 {
@@ -260,24 +340,5 @@ func buildAllocatedStorageMetric(resource *model.TaggedResource, instance *types
 		})
 	}
 
-	// Convert from GiB to bytes
-	valueInBytes := float64(*instance.AllocatedStorage) * 1024 * 1024 * 1024
-
-	return &model.CloudwatchData{
-		MetricName:   "AllocatedStorage",
-		ResourceName: resource.ARN,
-		Namespace:    awsRdsNamespace,
-		Dimensions:   dimensions,
-		Tags:         resource.MetricTags(exportedTags),
-
-		// Store the value as a single data point
-		GetMetricDataResult: &model.GetMetricDataResult{
-			DataPoints: []model.DataPoint{
-				{
-					Value:     &valueInBytes,
-					Timestamp: time.Now(),
-				},
-			},
-		},
-	}, nil
+	return dimensions
 }
@@ -45,8 +45,9 @@ func TestNewRDSService(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			got := NewRDSService(tt.buildClientFunc)
 			require.NotNil(t, got)
-			require.Len(t, got.supportedMetrics, 1)
+			require.Len(t, got.supportedMetrics, 2)
 			require.NotNil(t, got.supportedMetrics["AllocatedStorage"])
+			require.NotNil(t, got.supportedMetrics["MaxAllocatedStorage"])
 		})
 	}
 }
@@ -60,7 +61,8 @@ func TestRDS_GetNamespace(t *testing.T) {
 func TestRDS_ListRequiredPermissions(t *testing.T) {
 	service := NewRDSService(nil)
 	expectedPermissions := map[string][]string{
-		"AllocatedStorage": {"rds:DescribeDBInstances"},
+		"AllocatedStorage":    {"rds:DescribeDBInstances"},
+		"MaxAllocatedStorage": {"rds:DescribeDBInstances"},
 	}
 	require.Equal(t, expectedPermissions, service.ListRequiredPermissions())
 }
@@ -69,6 +71,7 @@ func TestRDS_ListSupportedEnhancedMetrics(t *testing.T) {
 	service := NewRDSService(nil)
 	expectedMetrics := []string{
 		"AllocatedStorage",
+		"MaxAllocatedStorage",
 	}
 	require.Equal(t, expectedMetrics, service.ListSupportedEnhancedMetrics())
 }
@@ -76,6 +79,8 @@ func TestRDS_ListSupportedEnhancedMetrics(t *testing.T) {
 func TestRDS_GetMetrics(t *testing.T) {
 	testInstance := makeTestDBInstance("test-instance", 100)
 	testARN := *testInstance.DBInstanceArn
+	autoscalingInstance := makeTestDBInstance("test-instance", 100)
+	autoscalingInstance.MaxAllocatedStorage = aws.Int32(500)
 
 	tests := []struct {
 		name            string
@@ -122,6 +127,22 @@ func TestRDS_GetMetrics(t *testing.T) {
 			wantResultCount: 1,
 			wantValues:      []float64{107374182400}, // 100 GiB in bytes
 		},
+		{
+			name:            "max allocated storage with storage autoscaling",
+			resources:       []*model.TaggedResource{{ARN: testARN, Namespace: awsRdsNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "MaxAllocatedStorage"}},
+			regionalData:    map[string]*types.DBInstance{testARN: autoscalingInstance},
+			wantResultCount: 1,
+			wantValues:      []float64{536870912000}, // 500 GiB in bytes
+		},
+		{
+			name:            "max allocated storage omitted without storage autoscaling",
+			resources:       []*model.TaggedResource{{ARN: testARN, Namespace: awsRdsNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}, {Name: "MaxAllocatedStorage"}},
+			regionalData:    map[string]*types.DBInstance{testARN: testInstance},
+			wantResultCount: 1,
+			wantValues:      []float64{107374182400}, // only AllocatedStorage, 100 GiB in bytes
+		},
 		{
 			name:            "resource not found in metadata",
 			resources:       []*model.TaggedResource{{ARN: "arn:aws:rds:us-east-1:123456789012:db:non-existent"}},
@@ -177,7 +198,7 @@ func TestRDS_GetMetrics(t *testing.T) {
 					require.Len(t, metric.GetMetricDataResult.DataPoints, 1)
 					require.NotNil(t, metric.GetMetricDataResult.DataPoints[0].Value)
 					require.Equal(t, tt.wantValues[i], *metric.GetMetricDataResult.DataPoints[0].Value,
-						"expected value in bytes for AllocatedStorage")
+						"expected value in bytes for %s", metric.MetricName)
 				}
 			}
 		})
@@ -258,6 +279,85 @@ func TestRDS_GetMetrics_MixedResources(t *testing.T) {
 	require.Equal(t, 107374182400.0, *result[0].GetMetricDataResult.DataPoints[0].Value) // 100 GiB
 }
 
+func TestRDS_GetMetrics_MaxAllocatedStorageDimensions(t *testing.T) {
+	instance := makeTestDBInstance("test-instance", 100)
+	instance.MaxAllocatedStorage = aws.Int32(500)
+	service := newTestRDSService(map[string]*types.DBInstance{*instance.DBInstanceArn: instance})
+
+	result, err := service.GetMetrics(
+		context.Background(),
+		slog.New(slog.DiscardHandler),
+		[]*model.TaggedResource{{ARN: *instance.DBInstanceArn, Namespace: awsRdsNamespace}},
+		[]*model.EnhancedMetricConfig{{Name: "MaxAllocatedStorage"}},
+		nil,
+		"us-east-1",
+		model.Role{},
+		&mockConfigProvider{c: &aws.Config{Region: "us-east-1"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Equal(t, "MaxAllocatedStorage", result[0].MetricName)
+	require.Equal(t, []model.Dimension{
+		{Name: "DBInstanceIdentifier", Value: "test-instance"},
+		{Name: "DatabaseClass", Value: "db.t3.micro"},
+		{Name: "EngineName", Value: "postgres"},
+	}, result[0].Dimensions)
+}
+
+func TestRDS_GetMetrics_Coverage(t *testing.T) {
+	found := makeTestDBInstance("found", 100)
+	service := NewRDSService(func(_ aws.Config) Client {
+		return &mockServiceRDSClient{instances: []types.DBInstance{*found}}
+	})
+
+	resources := []*model.TaggedResource{
+		{ARN: *found.DBInstanceArn, Namespace: awsRdsNamespace},
+		{ARN: "arn:aws:rds:us-east-1:123456789012:db:missing", Namespace: awsRdsNamespace},
+		// not a DB instance, so not described at all
+		{ARN: "arn:aws:rds:us-east-1:123456789012:cluster:my-aurora", Namespace: awsRdsNamespace},
+	}
+
+	_, err := service.GetMetrics(
+		context.Background(),
+		slog.New(slog.DiscardHandler),
+		resources,
+		[]*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}},
+		nil,
+		"us-east-1",
+		model.Role{},
+		&mockConfigProvider{c: &aws.Config{Region: "us-east-1"}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, service.Coverage().Found)
+	require.Equal(t, 1, service.Coverage().Missing)
+}
+
+func TestRDS_GetMetrics_SkipsInvalidDescribedARNs(t *testing.T) {
+	valid := makeTestDBInstance("valid", 100)
+	nilARN := makeTestDBInstance("nil-arn", 100)
+	nilARN.DBInstanceArn = nil
+	invalidARN := makeTestDBInstance("invalid-arn", 100)
+	invalidARN.DBInstanceArn = aws.String("not-an-arn")
+
+	service := NewRDSService(func(_ aws.Config) Client {
+		return &mockServiceRDSClient{instances: []types.DBInstance{*nilARN, *invalidARN, *valid}}
+	})
+
+	result, err := service.GetMetrics(
+		context.Background(),
+		slog.New(slog.DiscardHandler),
+		[]*model.TaggedResource{{ARN: *valid.DBInstanceArn, Namespace: awsRdsNamespace}},
+		[]*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}},
+		nil,
+		"us-east-1",
+		model.Role{},
+		&mockConfigProvider{c: &aws.Config{Region: "us-east-1"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	require.Equal(t, *valid.DBInstanceArn, result[0].ResourceName)
+}
+
 type mockServiceRDSClient struct {
 	instances   []types.DBInstance
 	describeErr bool
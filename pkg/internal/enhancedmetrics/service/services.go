@@ -16,6 +16,8 @@ import (
 	"context"
 	"log/slog"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
@@ -38,3 +40,24 @@ type EnhancedMetricsService interface {
 	// IsMetricSupported checks if the given metric name is supported by this service.
 	IsMetricSupported(metricName string) bool
 }
+
+// ResourceCoverage counts the resources found, or not, in the output of the describe calls of an EnhancedMetricsService.
+type ResourceCoverage struct {
+	Found   int
+	Missing int
+}
+
+// CoverageReporter is implemented by the EnhancedMetricsService instances which report the ResourceCoverage of their
+// last GetMetrics call. Instances are not shared between calls, see Registry.GetEnhancedMetricsService.
+type CoverageReporter interface {
+	Coverage() ResourceCoverage
+}
+
+// DescribedARN returns the ARN of a resource in the output of a describe call, and whether it is a valid ARN.
+// Resources without a valid ARN can't be associated with the tagged resources and should be skipped.
+func DescribedARN(value *string) (string, bool) {
+	if value == nil || !arn.IsARN(*value) {
+		return "", false
+	}
+	return *value, true
+}
@@ -0,0 +1,89 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+)
+
+type awsClient interface {
+	ListTopics(ctx context.Context, params *sns.ListTopicsInput, optFns ...func(*sns.Options)) (*sns.ListTopicsOutput, error)
+	GetTopicAttributes(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error)
+}
+
+type AWSSNSClient struct {
+	listTopicsFunc         func(ctx context.Context, params *sns.ListTopicsInput, optFns ...func(*sns.Options)) (*sns.ListTopicsOutput, error)
+	getTopicAttributesFunc func(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error)
+}
+
+func NewSNSClientWithConfig(cfg aws.Config) Client {
+	c := sns.NewFromConfig(cfg)
+	return &AWSSNSClient{
+		listTopicsFunc:         c.ListTopics,
+		getTopicAttributesFunc: c.GetTopicAttributes,
+	}
+}
+
+func (c *AWSSNSClient) listTopics(ctx context.Context, input *sns.ListTopicsInput) (*sns.ListTopicsOutput, error) {
+	result, err := c.listTopicsFunc(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SNS topics: %w", err)
+	}
+
+	return result, nil
+}
+
+func (c *AWSSNSClient) ListAllTopicARNs(ctx context.Context, logger *slog.Logger) ([]string, error) {
+	logger.Debug("Listing all SNS topics")
+	var allTopicARNs []string
+	var nextToken *string
+
+	for {
+		output, err := c.listTopics(ctx, &sns.ListTopicsInput{
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, topic := range output.Topics {
+			if topic.TopicArn != nil {
+				allTopicARNs = append(allTopicARNs, *topic.TopicArn)
+			}
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	logger.Debug("Completed listing all SNS topics", slog.Int("totalTopics", len(allTopicARNs)))
+	return allTopicARNs, nil
+}
+
+func (c *AWSSNSClient) GetTopicAttributes(ctx context.Context, topicARN string) (map[string]string, error) {
+	output, err := c.getTopicAttributesFunc(ctx, &sns.GetTopicAttributesInput{
+		TopicArn: aws.String(topicARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attributes of SNS topic %s: %w", topicARN, err)
+	}
+
+	return output.Attributes, nil
+}
@@ -0,0 +1,136 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+func TestAWSSNSClient_ListAllTopicARNs(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  awsClient
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "success - single page",
+			client: &mockSNSClient{
+				listTopicsFunc: func(_ context.Context, _ *sns.ListTopicsInput, _ ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+					return &sns.ListTopicsOutput{
+						Topics:    []types.Topic{{TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:topic-1")}},
+						NextToken: nil,
+					}, nil
+				},
+			},
+			want:    []string{"arn:aws:sns:us-east-1:123456789012:topic-1"},
+			wantErr: false,
+		},
+		{
+			name: "success - multiple pages",
+			client: &mockSNSClient{
+				listTopicsFunc: func() func(_ context.Context, _ *sns.ListTopicsInput, _ ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+					callCount := 0
+					return func(_ context.Context, _ *sns.ListTopicsInput, _ ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+						callCount++
+						if callCount == 1 {
+							return &sns.ListTopicsOutput{
+								Topics:    []types.Topic{{TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:topic-1")}},
+								NextToken: aws.String("token1"),
+							}, nil
+						}
+						return &sns.ListTopicsOutput{
+							Topics:    []types.Topic{{TopicArn: aws.String("arn:aws:sns:us-east-1:123456789012:topic-2")}},
+							NextToken: nil,
+						}, nil
+					}
+				}(),
+			},
+			want: []string{
+				"arn:aws:sns:us-east-1:123456789012:topic-1",
+				"arn:aws:sns:us-east-1:123456789012:topic-2",
+			},
+			wantErr: false,
+		},
+		{
+			name: "error - API failure",
+			client: &mockSNSClient{
+				listTopicsFunc: func(_ context.Context, _ *sns.ListTopicsInput, _ ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AWSSNSClient{
+				listTopicsFunc: tt.client.ListTopics,
+			}
+			got, err := c.ListAllTopicARNs(context.Background(), slog.New(slog.DiscardHandler))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ListAllTopicARNs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAllTopicARNs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSSNSClient_GetTopicAttributes(t *testing.T) {
+	var gotInput *sns.GetTopicAttributesInput
+	c := &AWSSNSClient{
+		getTopicAttributesFunc: func(_ context.Context, params *sns.GetTopicAttributesInput, _ ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error) {
+			gotInput = params
+			return &sns.GetTopicAttributesOutput{
+				Attributes: map[string]string{"SubscriptionsConfirmed": "3"},
+			}, nil
+		},
+	}
+
+	got, err := c.GetTopicAttributes(context.Background(), "arn:aws:sns:us-east-1:123456789012:topic-1")
+	if err != nil {
+		t.Fatalf("GetTopicAttributes() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"SubscriptionsConfirmed": "3"}) {
+		t.Errorf("GetTopicAttributes() got = %v", got)
+	}
+	if aws.ToString(gotInput.TopicArn) != "arn:aws:sns:us-east-1:123456789012:topic-1" {
+		t.Errorf("GetTopicAttributes() topic ARN = %v", aws.ToString(gotInput.TopicArn))
+	}
+}
+
+// mockSNSClient is a mock implementation of AWS SNS Client
+type mockSNSClient struct {
+	listTopicsFunc         func(ctx context.Context, params *sns.ListTopicsInput, optFns ...func(*sns.Options)) (*sns.ListTopicsOutput, error)
+	getTopicAttributesFunc func(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error)
+}
+
+func (m *mockSNSClient) ListTopics(ctx context.Context, params *sns.ListTopicsInput, optFns ...func(*sns.Options)) (*sns.ListTopicsOutput, error) {
+	return m.listTopicsFunc(ctx, params, optFns...)
+}
+
+func (m *mockSNSClient) GetTopicAttributes(ctx context.Context, params *sns.GetTopicAttributesInput, optFns ...func(*sns.Options)) (*sns.GetTopicAttributesOutput, error) {
+	return m.getTopicAttributesFunc(ctx, params, optFns...)
+}
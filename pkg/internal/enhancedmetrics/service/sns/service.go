@@ -0,0 +1,255 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+const awsSNSNamespace = "AWS/SNS"
+
+type Client interface {
+	ListAllTopicARNs(ctx context.Context, logger *slog.Logger) ([]string, error)
+	GetTopicAttributes(ctx context.Context, topicARN string) (map[string]string, error)
+}
+
+type topic struct {
+	name       string
+	attributes map[string]string
+}
+
+type buildCloudwatchDataFunc func(*model.TaggedResource, *topic, []string) (*model.CloudwatchData, error)
+
+type supportedMetric struct {
+	name                    string
+	buildCloudwatchDataFunc buildCloudwatchDataFunc
+	requiredPermissions     []string
+}
+
+func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, t *topic, exportedTagOnMetrics []string) (*model.CloudwatchData, error) {
+	return sm.buildCloudwatchDataFunc(resource, t, exportedTagOnMetrics)
+}
+
+type SNS struct {
+	supportedMetrics map[string]supportedMetric
+	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
+}
+
+func NewSNSService(buildClientFunc func(cfg aws.Config) Client) *SNS {
+	if buildClientFunc == nil {
+		buildClientFunc = NewSNSClientWithConfig
+	}
+	svc := &SNS{
+		buildClientFunc: buildClientFunc,
+	}
+
+	// The number of confirmed subscriptions for the topic.
+	subscriptionsConfirmedMetric := supportedMetric{
+		name:                    "SubscriptionsConfirmed",
+		buildCloudwatchDataFunc: buildAttributeMetric("SubscriptionsConfirmed"),
+		requiredPermissions:     []string{"sns:ListTopics", "sns:GetTopicAttributes"},
+	}
+
+	// The number of subscriptions pending confirmation for the topic.
+	subscriptionsPendingMetric := supportedMetric{
+		name:                    "SubscriptionsPending",
+		buildCloudwatchDataFunc: buildAttributeMetric("SubscriptionsPending"),
+		requiredPermissions:     []string{"sns:ListTopics", "sns:GetTopicAttributes"},
+	}
+
+	svc.supportedMetrics = map[string]supportedMetric{
+		subscriptionsConfirmedMetric.name: subscriptionsConfirmedMetric,
+		subscriptionsPendingMetric.name:   subscriptionsPendingMetric,
+	}
+
+	return svc
+}
+
+func (s *SNS) GetNamespace() string {
+	return awsSNSNamespace
+}
+
+func (s *SNS) listTopicARNs(ctx context.Context, logger *slog.Logger, client Client, region string) (map[string]struct{}, error) {
+	topicARNs, err := client.ListAllTopicARNs(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error listing topics in region %s: %w", region, err)
+	}
+
+	regionalData := make(map[string]struct{}, len(topicARNs))
+	for _, topicARN := range topicARNs {
+		regionalData[topicARN] = struct{}{}
+	}
+
+	logger.Info("Loaded SNS metrics metadata", "region", region)
+	return regionalData, nil
+}
+
+func (s *SNS) IsMetricSupported(metricName string) bool {
+	_, exists := s.supportedMetrics[metricName]
+	return exists
+}
+
+func (s *SNS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
+	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
+		return nil, nil
+	}
+
+	client := s.buildClientFunc(*regionalConfigProvider.GetAWSRegionalConfig(region, role))
+	topicARNs, err := s.listTopicARNs(ctx, logger, client, region)
+	if err != nil {
+		return nil, fmt.Errorf("error loading sns metrics metadata: %w", err)
+	}
+
+	var result []*model.CloudwatchData
+
+	for _, resource := range resources {
+		if resource.Namespace != s.GetNamespace() {
+			logger.Warn("Resource namespace does not match SNS namespace, skipping", "arn", resource.ARN, "namespace", resource.Namespace)
+			continue
+		}
+
+		if _, exists := topicARNs[resource.ARN]; !exists {
+			s.coverage.Missing++
+			logger.Warn("SNS topic not found in data", "arn", resource.ARN)
+			continue
+		}
+
+		name, err := topicName(resource.ARN)
+		if err != nil {
+			s.coverage.Missing++
+			logger.Warn("Couldn't get SNS topic name, skipping", "arn", resource.ARN, "err", err)
+			continue
+		}
+
+		attributes, err := client.GetTopicAttributes(ctx, resource.ARN)
+		if err != nil {
+			s.coverage.Missing++
+			logger.Warn("Couldn't get SNS topic attributes, skipping", "arn", resource.ARN, "err", err)
+			continue
+		}
+		s.coverage.Found++
+		t := &topic{name: name, attributes: attributes}
+
+		for _, enhancedMetric := range enhancedMetricConfigs {
+			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
+			if !ok {
+				logger.Warn("Unsupported SNS enhanced metric, skipping", "metric", enhancedMetric.Name)
+				continue
+			}
+
+			em, err := supportedMetric.buildCloudwatchData(resource, t, exportedTagOnMetrics)
+			if err != nil || em == nil {
+				logger.Warn("Error building SNS enhanced metric", "metric", enhancedMetric.Name, "error", err)
+				continue
+			}
+
+			result = append(result, em)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *SNS) ListRequiredPermissions() map[string][]string {
+	permissions := make(map[string][]string, len(s.supportedMetrics))
+	for _, metric := range s.supportedMetrics {
+		permissions[metric.name] = metric.requiredPermissions
+	}
+	return permissions
+}
+
+func (s *SNS) ListSupportedEnhancedMetrics() []string {
+	var metrics []string
+	for metric := range s.supportedMetrics {
+		metrics = append(metrics, metric)
+	}
+
+	sort.Strings(metrics)
+	return metrics
+}
+
+func (s *SNS) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
+func (s *SNS) Instance() service.EnhancedMetricsService {
+	// do not use NewSNSService to avoid extra map allocation
+	return &SNS{
+		supportedMetrics: s.supportedMetrics,
+		buildClientFunc:  s.buildClientFunc,
+	}
+}
+
+func buildAttributeMetric(attribute string) buildCloudwatchDataFunc {
+	return func(resource *model.TaggedResource, t *topic, exportedTags []string) (*model.CloudwatchData, error) {
+		raw, ok := t.attributes[attribute]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing for SNS topic %s", attribute, resource.ARN)
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s of SNS topic %s is not a number: %w", attribute, resource.ARN, err)
+		}
+
+		return &model.CloudwatchData{
+			MetricName:   attribute,
+			ResourceName: resource.ARN,
+			Namespace:    awsSNSNamespace,
+			Dimensions: []model.Dimension{
+				{Name: "TopicName", Value: t.name},
+			},
+			Tags: resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{
+					{
+						Value:     &value,
+						Timestamp: time.Now(),
+					},
+				},
+			},
+		}, nil
+	}
+}
+
+// topicName returns the value of the TopicName dimension of a topic ARN, which CloudWatch sets
+// to the last part of the ARN, e.g. my-topic for arn:aws:sns:us-east-1:123456789012:my-topic.
+// Subscription ARNs, which append the subscription ID to the ARN of their topic, are rejected.
+func topicName(topicARN string) (string, error) {
+	parsed, err := arn.Parse(topicARN)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Service != "sns" {
+		return "", fmt.Errorf("not an SNS ARN: %s", topicARN)
+	}
+	if parsed.Resource == "" || strings.Contains(parsed.Resource, ":") {
+		return "", fmt.Errorf("not an SNS topic ARN: %s", topicARN)
+	}
+	return parsed.Resource, nil
+}
@@ -0,0 +1,283 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sns
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestNewSNSService(t *testing.T) {
+	tests := []struct {
+		name            string
+		buildClientFunc func(cfg aws.Config) Client
+	}{
+		{
+			name:            "with nil buildClientFunc",
+			buildClientFunc: nil,
+		},
+		{
+			name: "with custom buildClientFunc",
+			buildClientFunc: func(_ aws.Config) Client {
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSNSService(tt.buildClientFunc)
+			require.NotNil(t, got)
+			require.Len(t, got.supportedMetrics, 2)
+			require.NotNil(t, got.supportedMetrics["SubscriptionsConfirmed"])
+			require.NotNil(t, got.supportedMetrics["SubscriptionsPending"])
+		})
+	}
+}
+
+func TestSNS_GetNamespace(t *testing.T) {
+	service := NewSNSService(nil)
+	require.Equal(t, awsSNSNamespace, service.GetNamespace())
+}
+
+func TestSNS_ListRequiredPermissions(t *testing.T) {
+	service := NewSNSService(nil)
+	expectedPermissions := map[string][]string{
+		"SubscriptionsConfirmed": {"sns:ListTopics", "sns:GetTopicAttributes"},
+		"SubscriptionsPending":   {"sns:ListTopics", "sns:GetTopicAttributes"},
+	}
+	require.Equal(t, expectedPermissions, service.ListRequiredPermissions())
+}
+
+func TestSNS_ListSupportedEnhancedMetrics(t *testing.T) {
+	service := NewSNSService(nil)
+	expectedMetrics := []string{
+		"SubscriptionsConfirmed",
+		"SubscriptionsPending",
+	}
+	require.Equal(t, expectedMetrics, service.ListSupportedEnhancedMetrics())
+}
+
+func TestTopicName(t *testing.T) {
+	tests := []struct {
+		name     string
+		topicARN string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "standard topic",
+			topicARN: "arn:aws:sns:us-east-1:123456789012:my-topic",
+			want:     "my-topic",
+		},
+		{
+			name:     "fifo topic",
+			topicARN: "arn:aws:sns:eu-west-1:123456789012:my-topic.fifo",
+			want:     "my-topic.fifo",
+		},
+		{
+			name:     "topic in another partition",
+			topicARN: "arn:aws-cn:sns:cn-north-1:123456789012:my_topic",
+			want:     "my_topic",
+		},
+		{
+			name:     "subscription",
+			topicARN: "arn:aws:sns:us-east-1:123456789012:my-topic:8a21d249-4329-4871-acc6-7be709c6ea7f",
+			wantErr:  true,
+		},
+		{
+			name:     "another service",
+			topicARN: "arn:aws:sqs:us-east-1:123456789012:my-queue",
+			wantErr:  true,
+		},
+		{
+			name:     "not an ARN",
+			topicARN: "my-topic",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := topicName(tt.topicARN)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSNS_GetMetrics(t *testing.T) {
+	makeTopicARN := func(name string) string {
+		return fmt.Sprintf("arn:aws:sns:us-east-1:123456789012:%s", name)
+	}
+	defaultAttributes := map[string]string{
+		"SubscriptionsConfirmed": "3",
+		"SubscriptionsPending":   "1",
+	}
+
+	tests := []struct {
+		name            string
+		resources       []*model.TaggedResource
+		enhancedMetrics []*model.EnhancedMetricConfig
+		topics          map[string]map[string]string
+		wantValues      map[string]float64
+		wantCoverage    service.ResourceCoverage
+	}{
+		{
+			name:            "empty resources returns empty",
+			resources:       []*model.TaggedResource{},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}},
+			topics:          map[string]map[string]string{makeTopicARN("test"): defaultAttributes},
+		},
+		{
+			name:            "empty enhanced metrics returns empty",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test"), Namespace: awsSNSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{},
+			topics:          map[string]map[string]string{makeTopicARN("test"): defaultAttributes},
+		},
+		{
+			name:            "wrong namespace is skipped",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test")}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}},
+			topics:          map[string]map[string]string{makeTopicARN("test"): defaultAttributes},
+		},
+		{
+			name:            "successfully received single metric",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test"), Namespace: awsSNSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}},
+			topics:          map[string]map[string]string{makeTopicARN("test"): defaultAttributes},
+			wantValues:      map[string]float64{"test/SubscriptionsConfirmed": 3},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+		{
+			name:            "successfully received multiple metrics for a single topic",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test.fifo"), Namespace: awsSNSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}, {Name: "SubscriptionsPending"}},
+			topics:          map[string]map[string]string{makeTopicARN("test.fifo"): defaultAttributes},
+			wantValues: map[string]float64{
+				"test.fifo/SubscriptionsConfirmed": 3,
+				"test.fifo/SubscriptionsPending":   1,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 1},
+		},
+		{
+			name: "processes multiple resources and counts missing topics",
+			resources: []*model.TaggedResource{
+				{ARN: makeTopicARN("topic1"), Namespace: awsSNSNamespace},
+				{ARN: makeTopicARN("topic2"), Namespace: awsSNSNamespace},
+				{ARN: makeTopicARN("deleted"), Namespace: awsSNSNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsPending"}},
+			topics: map[string]map[string]string{
+				makeTopicARN("topic1"): {"SubscriptionsPending": "0"},
+				makeTopicARN("topic2"): {"SubscriptionsPending": "2"},
+			},
+			wantValues: map[string]float64{
+				"topic1/SubscriptionsPending": 0,
+				"topic2/SubscriptionsPending": 2,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 2, Missing: 1},
+		},
+		{
+			name:            "skips unsupported metrics",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test"), Namespace: awsSNSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "UnsupportedMetric"}},
+			topics:          map[string]map[string]string{makeTopicARN("test"): defaultAttributes},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+		{
+			name:            "skips non-numeric attributes",
+			resources:       []*model.TaggedResource{{ARN: makeTopicARN("test"), Namespace: awsSNSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}},
+			topics:          map[string]map[string]string{makeTopicARN("test"): {"SubscriptionsConfirmed": "three"}},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewSNSService(func(_ aws.Config) Client {
+				return &mockServiceSNSClient{topics: tt.topics}
+			})
+
+			result, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), tt.resources, tt.enhancedMetrics, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+
+			require.NoError(t, err)
+			require.Len(t, result, len(tt.wantValues))
+			require.Equal(t, tt.wantCoverage, service.Coverage())
+
+			for _, metric := range result {
+				require.Equal(t, awsSNSNamespace, metric.Namespace)
+				require.Len(t, metric.Dimensions, 1)
+				require.Equal(t, "TopicName", metric.Dimensions[0].Name)
+				require.NotNil(t, metric.GetMetricDataResult)
+				require.Len(t, metric.GetMetricDataResult.DataPoints, 1)
+
+				want, ok := tt.wantValues[metric.Dimensions[0].Value+"/"+metric.MetricName]
+				require.True(t, ok, "unexpected metric %s for topic %s", metric.MetricName, metric.Dimensions[0].Value)
+				require.Equal(t, want, *metric.GetMetricDataResult.DataPoints[0].Value)
+			}
+		})
+	}
+}
+
+func TestSNS_GetMetrics_ListTopicsError(t *testing.T) {
+	service := NewSNSService(func(_ aws.Config) Client {
+		return &mockServiceSNSClient{listErr: fmt.Errorf("API error")}
+	})
+
+	_, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), []*model.TaggedResource{{ARN: "arn:aws:sns:us-east-1:123456789012:test", Namespace: awsSNSNamespace}}, []*model.EnhancedMetricConfig{{Name: "SubscriptionsConfirmed"}}, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+	require.Error(t, err)
+}
+
+type mockServiceSNSClient struct {
+	topics  map[string]map[string]string
+	listErr error
+}
+
+func (m *mockServiceSNSClient) ListAllTopicARNs(_ context.Context, _ *slog.Logger) ([]string, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	arns := make([]string, 0, len(m.topics))
+	for topicARN := range m.topics {
+		arns = append(arns, topicARN)
+	}
+	return arns, nil
+}
+
+func (m *mockServiceSNSClient) GetTopicAttributes(_ context.Context, topicARN string) (map[string]string, error) {
+	attributes, ok := m.topics[topicARN]
+	if !ok {
+		return nil, fmt.Errorf("topic %s does not exist", topicARN)
+	}
+	return attributes, nil
+}
+
+type mockConfigProvider struct {
+	c *aws.Config
+}
+
+func (m *mockConfigProvider) GetAWSRegionalConfig(_ string, _ model.Role) *aws.Config {
+	return m.c
+}
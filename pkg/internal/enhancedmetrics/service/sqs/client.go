@@ -0,0 +1,92 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+type awsClient interface {
+	ListQueues(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error)
+	GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+}
+
+type AWSSQSClient struct {
+	listQueuesFunc         func(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error)
+	getQueueAttributesFunc func(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+}
+
+func NewSQSClientWithConfig(cfg aws.Config) Client {
+	c := sqs.NewFromConfig(cfg)
+	return &AWSSQSClient{
+		listQueuesFunc:         c.ListQueues,
+		getQueueAttributesFunc: c.GetQueueAttributes,
+	}
+}
+
+func (c *AWSSQSClient) listQueues(ctx context.Context, input *sqs.ListQueuesInput) (*sqs.ListQueuesOutput, error) {
+	result, err := c.listQueuesFunc(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SQS queues: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListAllQueueURLs returns the URLs of all the queues of the region.
+func (c *AWSSQSClient) ListAllQueueURLs(ctx context.Context, logger *slog.Logger) ([]string, error) {
+	logger.Debug("Listing all SQS queues")
+	var allQueueURLs []string
+	var nextToken *string
+	// ListQueues only paginates, with NextToken, when MaxResults is set
+	var maxResults int32 = 1000
+
+	for {
+		output, err := c.listQueues(ctx, &sqs.ListQueuesInput{
+			NextToken:  nextToken,
+			MaxResults: &maxResults,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		allQueueURLs = append(allQueueURLs, output.QueueUrls...)
+
+		if output.NextToken == nil {
+			break
+		}
+		nextToken = output.NextToken
+	}
+
+	logger.Debug("Completed listing all SQS queues", slog.Int("totalQueues", len(allQueueURLs)))
+	return allQueueURLs, nil
+}
+
+// GetQueueAttributes returns the attributes of the queue with the given URL.
+func (c *AWSSQSClient) GetQueueAttributes(ctx context.Context, queueURL string, attributeNames []types.QueueAttributeName) (map[string]string, error) {
+	output, err := c.getQueueAttributesFunc(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: attributeNames,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attributes of SQS queue %s: %w", queueURL, err)
+	}
+
+	return output.Attributes, nil
+}
@@ -0,0 +1,139 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+func TestAWSSQSClient_ListAllQueueURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  awsClient
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "success - single page",
+			client: &mockSQSClient{
+				listQueuesFunc: func(_ context.Context, _ *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+					return &sqs.ListQueuesOutput{
+						QueueUrls: []string{"https://sqs.us-east-1.amazonaws.com/123456789012/queue-1"},
+						NextToken: nil,
+					}, nil
+				},
+			},
+			want:    []string{"https://sqs.us-east-1.amazonaws.com/123456789012/queue-1"},
+			wantErr: false,
+		},
+		{
+			name: "success - multiple pages",
+			client: &mockSQSClient{
+				listQueuesFunc: func() func(_ context.Context, _ *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+					callCount := 0
+					return func(_ context.Context, _ *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+						callCount++
+						if callCount == 1 {
+							return &sqs.ListQueuesOutput{
+								QueueUrls: []string{"https://sqs.us-east-1.amazonaws.com/123456789012/queue-1"},
+								NextToken: aws.String("token1"),
+							}, nil
+						}
+						return &sqs.ListQueuesOutput{
+							QueueUrls: []string{"https://sqs.us-east-1.amazonaws.com/123456789012/queue-2"},
+							NextToken: nil,
+						}, nil
+					}
+				}(),
+			},
+			want: []string{
+				"https://sqs.us-east-1.amazonaws.com/123456789012/queue-1",
+				"https://sqs.us-east-1.amazonaws.com/123456789012/queue-2",
+			},
+			wantErr: false,
+		},
+		{
+			name: "error - API failure",
+			client: &mockSQSClient{
+				listQueuesFunc: func(_ context.Context, _ *sqs.ListQueuesInput, _ ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+					return nil, fmt.Errorf("API error")
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &AWSSQSClient{
+				listQueuesFunc: tt.client.ListQueues,
+			}
+			got, err := c.ListAllQueueURLs(context.Background(), slog.New(slog.DiscardHandler))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ListAllQueueURLs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ListAllQueueURLs() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAWSSQSClient_GetQueueAttributes(t *testing.T) {
+	var gotInput *sqs.GetQueueAttributesInput
+	c := &AWSSQSClient{
+		getQueueAttributesFunc: func(_ context.Context, params *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+			gotInput = params
+			return &sqs.GetQueueAttributesOutput{
+				Attributes: map[string]string{"VisibilityTimeout": "30"},
+			}, nil
+		},
+	}
+
+	got, err := c.GetQueueAttributes(context.Background(), "https://sqs.us-east-1.amazonaws.com/123456789012/queue-1", []types.QueueAttributeName{types.QueueAttributeNameVisibilityTimeout})
+	if err != nil {
+		t.Fatalf("GetQueueAttributes() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]string{"VisibilityTimeout": "30"}) {
+		t.Errorf("GetQueueAttributes() got = %v", got)
+	}
+	if aws.ToString(gotInput.QueueUrl) != "https://sqs.us-east-1.amazonaws.com/123456789012/queue-1" {
+		t.Errorf("GetQueueAttributes() queue URL = %v", aws.ToString(gotInput.QueueUrl))
+	}
+	if !reflect.DeepEqual(gotInput.AttributeNames, []types.QueueAttributeName{types.QueueAttributeNameVisibilityTimeout}) {
+		t.Errorf("GetQueueAttributes() attribute names = %v", gotInput.AttributeNames)
+	}
+}
+
+// mockSQSClient is a mock implementation of AWS SQS Client
+type mockSQSClient struct {
+	listQueuesFunc         func(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error)
+	getQueueAttributesFunc func(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+}
+
+func (m *mockSQSClient) ListQueues(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	return m.listQueuesFunc(ctx, params, optFns...)
+}
+
+func (m *mockSQSClient) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	return m.getQueueAttributesFunc(ctx, params, optFns...)
+}
@@ -0,0 +1,278 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+const awsSQSNamespace = "AWS/SQS"
+
+type Client interface {
+	ListAllQueueURLs(ctx context.Context, logger *slog.Logger) ([]string, error)
+	GetQueueAttributes(ctx context.Context, queueURL string, attributeNames []types.QueueAttributeName) (map[string]string, error)
+}
+
+// queue is a queue listed by ListQueues, with the attributes of the enhanced metrics.
+type queue struct {
+	name       string
+	attributes map[string]string
+}
+
+type buildCloudwatchDataFunc func(*model.TaggedResource, *queue, []string) (*model.CloudwatchData, error)
+
+type supportedMetric struct {
+	name                    string
+	attribute               types.QueueAttributeName
+	buildCloudwatchDataFunc buildCloudwatchDataFunc
+	requiredPermissions     []string
+}
+
+func (sm *supportedMetric) buildCloudwatchData(resource *model.TaggedResource, q *queue, exportedTagOnMetrics []string) (*model.CloudwatchData, error) {
+	return sm.buildCloudwatchDataFunc(resource, q, exportedTagOnMetrics)
+}
+
+type SQS struct {
+	supportedMetrics map[string]supportedMetric
+	buildClientFunc  func(cfg aws.Config) Client
+	coverage         service.ResourceCoverage
+}
+
+func NewSQSService(buildClientFunc func(cfg aws.Config) Client) *SQS {
+	if buildClientFunc == nil {
+		buildClientFunc = NewSQSClientWithConfig
+	}
+	svc := &SQS{
+		buildClientFunc: buildClientFunc,
+	}
+
+	// The length of time, in seconds, for which a message received from the queue is invisible to other consumers.
+	visibilityTimeoutMetric := supportedMetric{
+		name:                    "VisibilityTimeout",
+		attribute:               types.QueueAttributeNameVisibilityTimeout,
+		buildCloudwatchDataFunc: buildAttributeMetric("VisibilityTimeout", types.QueueAttributeNameVisibilityTimeout),
+		requiredPermissions:     []string{"sqs:ListQueues", "sqs:GetQueueAttributes"},
+	}
+
+	// The length of time, in seconds, for which the queue retains a message.
+	messageRetentionPeriodMetric := supportedMetric{
+		name:                    "MessageRetentionPeriod",
+		attribute:               types.QueueAttributeNameMessageRetentionPeriod,
+		buildCloudwatchDataFunc: buildAttributeMetric("MessageRetentionPeriod", types.QueueAttributeNameMessageRetentionPeriod),
+		requiredPermissions:     []string{"sqs:ListQueues", "sqs:GetQueueAttributes"},
+	}
+
+	svc.supportedMetrics = map[string]supportedMetric{
+		visibilityTimeoutMetric.name:      visibilityTimeoutMetric,
+		messageRetentionPeriodMetric.name: messageRetentionPeriodMetric,
+	}
+
+	return svc
+}
+
+func (s *SQS) GetNamespace() string {
+	return awsSQSNamespace
+}
+
+// listQueueURLs returns the URLs of the queues of the region, keyed by the account ID and name of the queue.
+func (s *SQS) listQueueURLs(ctx context.Context, logger *slog.Logger, client Client, region string) (map[string]string, error) {
+	queueURLs, err := client.ListAllQueueURLs(ctx, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error listing queues in region %s: %w", region, err)
+	}
+
+	regionalData := make(map[string]string, len(queueURLs))
+	for _, queueURL := range queueURLs {
+		accountID, name, err := parseQueueURL(queueURL)
+		if err != nil {
+			logger.Warn("Couldn't parse SQS queue URL, skipping", "url", queueURL, "err", err)
+			continue
+		}
+		regionalData[queueKey(accountID, name)] = queueURL
+	}
+
+	logger.Info("Loaded SQS metrics metadata", "region", region)
+	return regionalData, nil
+}
+
+func (s *SQS) IsMetricSupported(metricName string) bool {
+	_, exists := s.supportedMetrics[metricName]
+	return exists
+}
+
+func (s *SQS) GetMetrics(ctx context.Context, logger *slog.Logger, resources []*model.TaggedResource, enhancedMetricConfigs []*model.EnhancedMetricConfig, exportedTagOnMetrics []string, region string, role model.Role, regionalConfigProvider config.RegionalConfigProvider) ([]*model.CloudwatchData, error) {
+	s.coverage = service.ResourceCoverage{}
+
+	if len(resources) == 0 || len(enhancedMetricConfigs) == 0 {
+		return nil, nil
+	}
+
+	client := s.buildClientFunc(*regionalConfigProvider.GetAWSRegionalConfig(region, role))
+	queueURLs, err := s.listQueueURLs(ctx, logger, client, region)
+	if err != nil {
+		return nil, fmt.Errorf("error loading sqs metrics metadata: %w", err)
+	}
+
+	var attributeNames []types.QueueAttributeName
+	for _, enhancedMetric := range enhancedMetricConfigs {
+		if supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]; ok {
+			attributeNames = append(attributeNames, supportedMetric.attribute)
+		}
+	}
+
+	var result []*model.CloudwatchData
+
+	for _, resource := range resources {
+		if resource.Namespace != s.GetNamespace() {
+			logger.Warn("Resource namespace does not match SQS namespace, skipping", "arn", resource.ARN, "namespace", resource.Namespace)
+			continue
+		}
+
+		// ListQueues only returns the URLs of the queues, which are matched to the resources by the
+		// account ID and queue name of their ARN.
+		resourceARN, err := arn.Parse(resource.ARN)
+		if err != nil {
+			s.coverage.Missing++
+			logger.Warn("Couldn't parse SQS queue ARN, skipping", "arn", resource.ARN, "err", err)
+			continue
+		}
+		queueURL, exists := queueURLs[queueKey(resourceARN.AccountID, resourceARN.Resource)]
+		if !exists {
+			s.coverage.Missing++
+			logger.Warn("SQS queue not found in data", "arn", resource.ARN)
+			continue
+		}
+
+		attributes, err := client.GetQueueAttributes(ctx, queueURL, attributeNames)
+		if err != nil {
+			s.coverage.Missing++
+			logger.Warn("Couldn't get SQS queue attributes, skipping", "arn", resource.ARN, "err", err)
+			continue
+		}
+		s.coverage.Found++
+		q := &queue{name: resourceARN.Resource, attributes: attributes}
+
+		for _, enhancedMetric := range enhancedMetricConfigs {
+			supportedMetric, ok := s.supportedMetrics[enhancedMetric.Name]
+			if !ok {
+				logger.Warn("Unsupported SQS enhanced metric, skipping", "metric", enhancedMetric.Name)
+				continue
+			}
+
+			em, err := supportedMetric.buildCloudwatchData(resource, q, exportedTagOnMetrics)
+			if err != nil || em == nil {
+				logger.Warn("Error building SQS enhanced metric", "metric", enhancedMetric.Name, "error", err)
+				continue
+			}
+
+			result = append(result, em)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *SQS) ListRequiredPermissions() map[string][]string {
+	permissions := make(map[string][]string, len(s.supportedMetrics))
+	for _, metric := range s.supportedMetrics {
+		permissions[metric.name] = metric.requiredPermissions
+	}
+	return permissions
+}
+
+func (s *SQS) ListSupportedEnhancedMetrics() []string {
+	var metrics []string
+	for metric := range s.supportedMetrics {
+		metrics = append(metrics, metric)
+	}
+
+	sort.Strings(metrics)
+	return metrics
+}
+
+func (s *SQS) Coverage() service.ResourceCoverage {
+	return s.coverage
+}
+
+func (s *SQS) Instance() service.EnhancedMetricsService {
+	// do not use NewSQSService to avoid extra map allocation
+	return &SQS{
+		supportedMetrics: s.supportedMetrics,
+		buildClientFunc:  s.buildClientFunc,
+	}
+}
+
+// buildAttributeMetric returns a buildCloudwatchDataFunc exporting a numeric queue attribute as the metric.
+func buildAttributeMetric(metricName string, attribute types.QueueAttributeName) buildCloudwatchDataFunc {
+	return func(resource *model.TaggedResource, q *queue, exportedTags []string) (*model.CloudwatchData, error) {
+		raw, ok := q.attributes[string(attribute)]
+		if !ok {
+			return nil, fmt.Errorf("%s is missing for SQS queue %s", attribute, resource.ARN)
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s of SQS queue %s is not a number: %w", attribute, resource.ARN, err)
+		}
+
+		return &model.CloudwatchData{
+			MetricName:   metricName,
+			ResourceName: resource.ARN,
+			Namespace:    awsSQSNamespace,
+			Dimensions: []model.Dimension{
+				{Name: "QueueName", Value: q.name},
+			},
+			Tags: resource.MetricTags(exportedTags),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				DataPoints: []model.DataPoint{
+					{
+						Value:     &value,
+						Timestamp: time.Now(),
+					},
+				},
+			},
+		}, nil
+	}
+}
+
+// parseQueueURL extracts the account ID and queue name from a queue URL, e.g.
+// https://sqs.us-east-1.amazonaws.com/123456789012/my-queue. The legacy endpoints use the same path.
+func parseQueueURL(queueURL string) (string, string, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return "", "", err
+	}
+	accountID, name, ok := strings.Cut(strings.Trim(u.Path, "/"), "/")
+	if !ok || accountID == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("unexpected path %q", u.Path)
+	}
+	return accountID, name, nil
+}
+
+func queueKey(accountID, name string) string {
+	return accountID + "/" + name
+}
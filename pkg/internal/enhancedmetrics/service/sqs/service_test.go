@@ -0,0 +1,294 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestNewSQSService(t *testing.T) {
+	tests := []struct {
+		name            string
+		buildClientFunc func(cfg aws.Config) Client
+	}{
+		{
+			name:            "with nil buildClientFunc",
+			buildClientFunc: nil,
+		},
+		{
+			name: "with custom buildClientFunc",
+			buildClientFunc: func(_ aws.Config) Client {
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSQSService(tt.buildClientFunc)
+			require.NotNil(t, got)
+			require.Len(t, got.supportedMetrics, 2)
+			require.NotNil(t, got.supportedMetrics["VisibilityTimeout"])
+			require.NotNil(t, got.supportedMetrics["MessageRetentionPeriod"])
+		})
+	}
+}
+
+func TestSQS_GetNamespace(t *testing.T) {
+	service := NewSQSService(nil)
+	require.Equal(t, awsSQSNamespace, service.GetNamespace())
+}
+
+func TestSQS_ListRequiredPermissions(t *testing.T) {
+	service := NewSQSService(nil)
+	expectedPermissions := map[string][]string{
+		"VisibilityTimeout":      {"sqs:ListQueues", "sqs:GetQueueAttributes"},
+		"MessageRetentionPeriod": {"sqs:ListQueues", "sqs:GetQueueAttributes"},
+	}
+	require.Equal(t, expectedPermissions, service.ListRequiredPermissions())
+}
+
+func TestSQS_ListSupportedEnhancedMetrics(t *testing.T) {
+	service := NewSQSService(nil)
+	expectedMetrics := []string{
+		"MessageRetentionPeriod",
+		"VisibilityTimeout",
+	}
+	require.Equal(t, expectedMetrics, service.ListSupportedEnhancedMetrics())
+}
+
+func TestParseQueueURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		queueURL      string
+		wantAccountID string
+		wantName      string
+		wantErr       bool
+	}{
+		{
+			name:          "regional endpoint",
+			queueURL:      "https://sqs.us-east-1.amazonaws.com/123456789012/my-queue",
+			wantAccountID: "123456789012",
+			wantName:      "my-queue",
+		},
+		{
+			name:          "legacy endpoint with fifo queue",
+			queueURL:      "https://queue.amazonaws.com/123456789012/my-queue.fifo",
+			wantAccountID: "123456789012",
+			wantName:      "my-queue.fifo",
+		},
+		{
+			name:     "missing queue name",
+			queueURL: "https://sqs.us-east-1.amazonaws.com/123456789012",
+			wantErr:  true,
+		},
+		{
+			name:     "unexpected path",
+			queueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/my-queue/extra",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accountID, name, err := parseQueueURL(tt.queueURL)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAccountID, accountID)
+			require.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestSQS_GetMetrics(t *testing.T) {
+	makeQueueURL := func(name string) string {
+		return fmt.Sprintf("https://sqs.us-east-1.amazonaws.com/123456789012/%s", name)
+	}
+	makeQueueARN := func(name string) string {
+		return fmt.Sprintf("arn:aws:sqs:us-east-1:123456789012:%s", name)
+	}
+	defaultAttributes := map[string]string{
+		"VisibilityTimeout":      "30",
+		"MessageRetentionPeriod": "345600",
+	}
+
+	tests := []struct {
+		name            string
+		resources       []*model.TaggedResource
+		enhancedMetrics []*model.EnhancedMetricConfig
+		queues          map[string]map[string]string
+		wantErr         bool
+		wantValues      map[string]float64
+		wantCoverage    service.ResourceCoverage
+	}{
+		{
+			name:            "empty resources returns empty",
+			resources:       []*model.TaggedResource{},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+		},
+		{
+			name:            "empty enhanced metrics returns empty",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test"), Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+		},
+		{
+			name:            "wrong namespace is skipped",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test")}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+		},
+		{
+			name:            "successfully received single metric",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test"), Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+			wantValues:      map[string]float64{"test/VisibilityTimeout": 30},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+		{
+			name:            "successfully received multiple metrics for a single queue",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test.fifo"), Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}, {Name: "MessageRetentionPeriod"}},
+			queues:          map[string]map[string]string{makeQueueURL("test.fifo"): defaultAttributes},
+			wantValues: map[string]float64{
+				"test.fifo/VisibilityTimeout":      30,
+				"test.fifo/MessageRetentionPeriod": 345600,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 1},
+		},
+		{
+			name: "processes multiple resources and counts missing queues",
+			resources: []*model.TaggedResource{
+				{ARN: makeQueueARN("queue1"), Namespace: awsSQSNamespace},
+				{ARN: makeQueueARN("queue2"), Namespace: awsSQSNamespace},
+				{ARN: makeQueueARN("deleted"), Namespace: awsSQSNamespace},
+			},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "MessageRetentionPeriod"}},
+			queues: map[string]map[string]string{
+				makeQueueURL("queue1"): {"MessageRetentionPeriod": "60"},
+				makeQueueURL("queue2"): {"MessageRetentionPeriod": "120"},
+			},
+			wantValues: map[string]float64{
+				"queue1/MessageRetentionPeriod": 60,
+				"queue2/MessageRetentionPeriod": 120,
+			},
+			wantCoverage: service.ResourceCoverage{Found: 2, Missing: 1},
+		},
+		{
+			name:            "queue of another account is not matched",
+			resources:       []*model.TaggedResource{{ARN: "arn:aws:sqs:us-east-1:210987654321:test", Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+			wantCoverage:    service.ResourceCoverage{Missing: 1},
+		},
+		{
+			name:            "skips unsupported metrics",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test"), Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "UnsupportedMetric"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): defaultAttributes},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+		{
+			name:            "skips non-numeric attributes",
+			resources:       []*model.TaggedResource{{ARN: makeQueueARN("test"), Namespace: awsSQSNamespace}},
+			enhancedMetrics: []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}},
+			queues:          map[string]map[string]string{makeQueueURL("test"): {"VisibilityTimeout": "thirty"}},
+			wantCoverage:    service.ResourceCoverage{Found: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewSQSService(func(_ aws.Config) Client {
+				return &mockServiceSQSClient{queues: tt.queues}
+			})
+
+			result, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), tt.resources, tt.enhancedMetrics, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, result, len(tt.wantValues))
+			require.Equal(t, tt.wantCoverage, service.Coverage())
+
+			for _, metric := range result {
+				require.Equal(t, awsSQSNamespace, metric.Namespace)
+				require.Len(t, metric.Dimensions, 1)
+				require.Equal(t, "QueueName", metric.Dimensions[0].Name)
+				require.NotNil(t, metric.GetMetricDataResult)
+				require.Len(t, metric.GetMetricDataResult.DataPoints, 1)
+
+				want, ok := tt.wantValues[metric.Dimensions[0].Value+"/"+metric.MetricName]
+				require.True(t, ok, "unexpected metric %s for queue %s", metric.MetricName, metric.Dimensions[0].Value)
+				require.Equal(t, want, *metric.GetMetricDataResult.DataPoints[0].Value)
+			}
+		})
+	}
+}
+
+func TestSQS_GetMetrics_ListQueuesError(t *testing.T) {
+	service := NewSQSService(func(_ aws.Config) Client {
+		return &mockServiceSQSClient{listErr: fmt.Errorf("API error")}
+	})
+
+	_, err := service.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), []*model.TaggedResource{{ARN: "arn:aws:sqs:us-east-1:123456789012:test", Namespace: awsSQSNamespace}}, []*model.EnhancedMetricConfig{{Name: "VisibilityTimeout"}}, nil, "us-east-1", model.Role{}, &mockConfigProvider{c: &aws.Config{Region: "us-east-1"}})
+	require.Error(t, err)
+}
+
+type mockServiceSQSClient struct {
+	queues  map[string]map[string]string
+	listErr error
+}
+
+func (m *mockServiceSQSClient) ListAllQueueURLs(_ context.Context, _ *slog.Logger) ([]string, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	urls := make([]string, 0, len(m.queues))
+	for queueURL := range m.queues {
+		urls = append(urls, queueURL)
+	}
+	return urls, nil
+}
+
+func (m *mockServiceSQSClient) GetQueueAttributes(_ context.Context, queueURL string, _ []types.QueueAttributeName) (map[string]string, error) {
+	attributes, ok := m.queues[queueURL]
+	if !ok {
+		return nil, fmt.Errorf("queue %s does not exist", queueURL)
+	}
+	return attributes, nil
+}
+
+type mockConfigProvider struct {
+	c *aws.Config
+}
+
+func (m *mockConfigProvider) GetAWSRegionalConfig(_ string, _ model.Role) *aws.Config {
+	return m.c
+}
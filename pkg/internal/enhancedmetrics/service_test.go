@@ -20,11 +20,16 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/service/rds"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 // mockConfigProvider is a mock implementation of config.RegionalConfigProvider
@@ -84,7 +89,7 @@ func (m *mockMetricsServiceRegistry) GetEnhancedMetricsService(namespace string)
 }
 
 func TestNewService(t *testing.T) {
-	svc := NewService(&mockConfigProvider{}, &mockMetricsServiceRegistry{})
+	svc := NewService(&mockConfigProvider{}, &mockMetricsServiceRegistry{}, nil)
 	require.NotNil(t, svc)
 	require.NotNil(t, svc.configProvider)
 }
@@ -172,6 +177,7 @@ func TestService_GetMetrics(t *testing.T) {
 			svc := NewService(
 				&mockConfigProvider{},
 				tt.registry,
+				promutil.Discard,
 			)
 
 			data, err := svc.GetMetrics(ctx, logger, tt.namespace, resources, metrics, exportedTags, region, role)
@@ -192,3 +198,59 @@ func TestService_GetMetrics(t *testing.T) {
 		})
 	}
 }
+
+// staticRDSClient is an rds.Client which describes the same DB instances whatever the filter.
+type staticRDSClient struct {
+	instances []types.DBInstance
+}
+
+func (c staticRDSClient) DescribeDBInstances(context.Context, *slog.Logger, []string) ([]types.DBInstance, error) {
+	return c.instances, nil
+}
+
+func TestService_GetMetrics_ResourceCoverage(t *testing.T) {
+	client := staticRDSClient{instances: []types.DBInstance{{
+		DBInstanceArn:        aws.String("arn:aws:rds:us-east-1:123456789012:db:found"),
+		DBInstanceIdentifier: aws.String("found"),
+		AllocatedStorage:     aws.Int32(100),
+	}}}
+	registry := (&Registry{}).Register(rds.NewRDSService(func(aws.Config) rds.Client { return client }))
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	svc := NewService(&mockConfigProvider{}, registry, scrapeMetrics)
+
+	resources := []*model.TaggedResource{
+		{ARN: "arn:aws:rds:us-east-1:123456789012:db:found", Namespace: "AWS/RDS"},
+		{ARN: "arn:aws:rds:us-east-1:123456789012:db:missing-1", Namespace: "AWS/RDS"},
+		{ARN: "arn:aws:rds:us-east-1:123456789012:db:missing-2", Namespace: "AWS/RDS"},
+	}
+	data, err := svc.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), "AWS/RDS", resources, []*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}}, nil, "us-east-1", model.Role{})
+	require.NoError(t, err)
+	require.Len(t, data, 1)
+
+	require.Equal(t, float64(1), testutil.ToFloat64(scrapeMetrics.EnhancedMetricsResourcesCoveredCounter.Raw().WithLabelValues("AWS/RDS")))
+	require.Equal(t, float64(2), testutil.ToFloat64(scrapeMetrics.EnhancedMetricsResourcesMissingCounter.Raw().WithLabelValues("AWS/RDS")))
+}
+
+func TestService_GetMetrics_UnsupportedMetrics(t *testing.T) {
+	client := staticRDSClient{instances: []types.DBInstance{{
+		DBInstanceArn:        aws.String("arn:aws:rds:us-east-1:123456789012:db:found"),
+		DBInstanceIdentifier: aws.String("found"),
+		AllocatedStorage:     aws.Int32(100),
+	}}}
+	registry := (&Registry{}).Register(rds.NewRDSService(func(aws.Config) rds.Client { return client }))
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	svc := NewService(&mockConfigProvider{}, registry, scrapeMetrics)
+
+	resources := []*model.TaggedResource{{ARN: "arn:aws:rds:us-east-1:123456789012:db:found", Namespace: "AWS/RDS"}}
+	metrics := []*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}, {Name: "Unsupported1"}, {Name: "Unsupported2"}}
+	for range 2 {
+		data, err := svc.GetMetrics(context.Background(), slog.New(slog.DiscardHandler), "AWS/RDS", resources, metrics, nil, "us-east-1", model.Role{})
+		require.NoError(t, err)
+		require.Len(t, data, 1)
+	}
+
+	counter := scrapeMetrics.EnhancedMetricsUnsupportedCounter.Raw()
+	require.Equal(t, 2, testutil.CollectAndCount(counter))
+	require.Equal(t, float64(2), testutil.ToFloat64(counter.WithLabelValues("AWS/RDS", "Unsupported1")))
+	require.Equal(t, float64(2), testutil.ToFloat64(counter.WithLabelValues("AWS/RDS", "Unsupported2")))
+}
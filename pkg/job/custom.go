@@ -21,27 +21,59 @@ import (
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
+// runCustomNamespaceJob queries the metrics of a custom namespace job. The returned error reports a failed run.
 func runCustomNamespaceJob(
 	ctx context.Context,
 	logger *slog.Logger,
 	job model.CustomNamespaceJob,
 	clientCloudwatch cloudwatch.Client,
 	gmdProcessor getMetricDataProcessor,
-) []*model.CloudwatchData {
+) ([]*model.CloudwatchData, error) {
 	cloudwatchDatas := getMetricDataForQueriesForCustomNamespace(ctx, job, clientCloudwatch, logger)
+	cloudwatchDatas = append(cloudwatchDatas, getMetricDataForExpressions(job)...)
 	if len(cloudwatchDatas) == 0 {
 		logger.Debug("No metrics data found")
-		return nil
+		return nil, ctx.Err()
+	}
+	cloudwatchDatas, statisticsDatas := splitGetMetricStatisticsData(cloudwatchDatas)
+	if ctx.Err() != nil {
+		logger.Debug("Scrape canceled, skipping GetMetricData", "err", ctx.Err())
+		return nil, ctx.Err()
 	}
 
-	var err error
-	cloudwatchDatas, err = gmdProcessor.Run(ctx, job.Namespace, cloudwatchDatas)
-	if err != nil {
-		logger.Error("Failed to get metric data", "err", err)
-		return nil
+	if len(cloudwatchDatas) > 0 {
+		var err error
+		cloudwatchDatas, err = gmdProcessor.Run(ctx, job.Namespace, cloudwatchDatas)
+		if err != nil {
+			logger.Error("Failed to get metric data", "err", err)
+			return nil, err
+		}
 	}
 
-	return cloudwatchDatas
+	return append(cloudwatchDatas, runGetMetricStatistics(ctx, logger, clientCloudwatch, statisticsDatas)...), nil
+}
+
+// getMetricDataForExpressions returns the GetMetricData queries of the expressions of a custom namespace job.
+// Their results are exported with the name and labels of the expression.
+func getMetricDataForExpressions(job model.CustomNamespaceJob) []*model.CloudwatchData {
+	data := make([]*model.CloudwatchData, 0, len(job.Expressions))
+	for _, expression := range job.Expressions {
+		data = append(data, &model.CloudwatchData{
+			MetricName:   expression.Name,
+			ResourceName: job.Name,
+			Namespace:    job.Namespace,
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{
+				Expression: expression.Expression,
+				Period:     expression.Period,
+				Length:     expression.Length,
+				Delay:      expression.Delay,
+			},
+			MetricMigrationParams: model.MetricMigrationParams{
+				Labels: expression.Labels,
+			},
+		})
+	}
+	return data
 }
 
 func getMetricDataForQueriesForCustomNamespace(
@@ -63,7 +95,7 @@ func getMetricDataForQueriesForCustomNamespace(
 
 		go func(metric *model.MetricConfig) {
 			defer wg.Done()
-			err := clientCloudwatch.ListMetrics(ctx, customNamespaceJob.Namespace, metric, customNamespaceJob.RecentlyActiveOnly, func(page []*model.Metric) {
+			err := clientCloudwatch.ListMetrics(ctx, customNamespaceJob.Namespace, metric, customNamespaceJob.RecentlyActiveOnly, customNamespaceJob.IncludeLinkedAccounts, func(page []*model.Metric) {
 				var data []*model.CloudwatchData
 
 				for _, cwMetric := range page {
@@ -71,12 +103,39 @@ func getMetricDataForQueriesForCustomNamespace(
 						continue
 					}
 
-					for _, stat := range metric.Statistics {
+					if metric.UseGetMetricStatistics {
 						data = append(data, &model.CloudwatchData{
 							MetricName:   metric.Name,
 							ResourceName: customNamespaceJob.Name,
 							Namespace:    customNamespaceJob.Namespace,
 							Dimensions:   cwMetric.Dimensions,
+							GetMetricStatisticsProcessingParams: &model.GetMetricStatisticsProcessingParams{
+								Statistics: metric.Statistics,
+								Period:     metric.Period,
+								Length:     metric.Length,
+								Delay:      metric.Delay,
+							},
+							MetricMigrationParams: model.MetricMigrationParams{
+								NilToZero:              metric.NilToZero,
+								AddCloudwatchTimestamp: metric.AddCloudwatchTimestamp,
+								EmptyResultGrace:       metric.EmptyResultGrace,
+								StatisticNames:         metric.StatisticNames,
+								NilToZeroStatistics:    metric.NilToZeroStatistics,
+								MaxSeriesPerMetric:     metric.MaxSeriesPerMetric,
+								SampleCappedSeries:     metric.SampleCappedSeries,
+								MetricType:             metric.MetricType,
+							},
+						})
+						continue
+					}
+
+					for _, stat := range metric.Statistics {
+						data = append(data, &model.CloudwatchData{
+							MetricName:      metric.Name,
+							ResourceName:    customNamespaceJob.Name,
+							Namespace:       customNamespaceJob.Namespace,
+							Dimensions:      cwMetric.Dimensions,
+							SourceAccountID: cwMetric.SourceAccountID,
 							GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{
 								Period:    metric.Period,
 								Length:    metric.Length,
@@ -87,6 +146,14 @@ func getMetricDataForQueriesForCustomNamespace(
 								NilToZero:              metric.NilToZero,
 								AddCloudwatchTimestamp: metric.AddCloudwatchTimestamp,
 								ExportAllDataPoints:    metric.ExportAllDataPoints,
+								KeepLastN:              metric.KeepLastN,
+								EmptyResultGrace:       metric.EmptyResultGrace,
+								StatisticNames:         metric.StatisticNames,
+								NilToZeroStatistics:    metric.NilToZeroStatistics,
+								MaxSeriesPerMetric:     metric.MaxSeriesPerMetric,
+								SampleCappedSeries:     metric.SampleCappedSeries,
+								MetricType:             metric.MetricType,
+								SumWithoutDimensions:   metric.SumWithoutDimensions,
 							},
 							Tags:                      nil,
 							GetMetricDataResult:       nil,
@@ -13,18 +13,23 @@
 package job
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job/maxdimassociator"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 type resourceAssociator interface {
@@ -48,6 +53,146 @@ type enhancedMetricsService interface {
 	) ([]*model.CloudwatchData, error)
 }
 
+// concurrencyLimitedEnhancedMetricsService bounds the calls to the enhanced metrics service made at the same
+// time, e.g. by the regions and roles of a discovery job, whose describe calls otherwise all run at once.
+type concurrencyLimitedEnhancedMetricsService struct {
+	enhancedMetricsService
+	sem chan struct{}
+}
+
+// enhancedMetricsServiceForJob returns the enhanced metrics service to use for all the regions and roles of
+// a discovery job, bounded by its EnhancedMetricsConcurrency. It returns nil when svc is nil.
+func enhancedMetricsServiceForJob(job model.DiscoveryJob, svc *enhancedmetrics.Service) enhancedMetricsService {
+	if svc == nil {
+		return nil
+	}
+	if job.EnhancedMetricsConcurrency <= 0 {
+		return svc
+	}
+	return &concurrencyLimitedEnhancedMetricsService{
+		enhancedMetricsService: svc,
+		sem:                    make(chan struct{}, job.EnhancedMetricsConcurrency),
+	}
+}
+
+func (s *concurrencyLimitedEnhancedMetricsService) GetMetrics(
+	ctx context.Context,
+	logger *slog.Logger,
+	namespace string,
+	resources []*model.TaggedResource,
+	metrics []*model.EnhancedMetricConfig,
+	exportedTagOnMetrics []string,
+	region string,
+	role model.Role,
+) ([]*model.CloudwatchData, error) {
+	select {
+	case s.sem <- struct{}{}:
+		defer func() { <-s.sem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return s.enhancedMetricsService.GetMetrics(ctx, logger, namespace, resources, metrics, exportedTagOnMetrics, region, role)
+}
+
+// retryingTaggingClient retries the failed resource discoveries of a tagging client. The first retry waits for
+// backoff, which is doubled before every following retry. Retries stop as soon as the context is done.
+type retryingTaggingClient struct {
+	tagging.Client
+	logger  *slog.Logger
+	retries int
+	backoff time.Duration
+}
+
+// newRetryingTaggingClient returns client itself when retries is not positive.
+func newRetryingTaggingClient(client tagging.Client, logger *slog.Logger, retries int, backoff time.Duration) tagging.Client {
+	if retries <= 0 {
+		return client
+	}
+	return &retryingTaggingClient{Client: client, logger: logger, retries: retries, backoff: backoff}
+}
+
+func (c *retryingTaggingClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		resources, err := c.Client.GetResources(ctx, job, region)
+		// Finding no resources is not a transient failure, retrying would not change the result
+		if err == nil || attempt > c.retries || errors.Is(err, tagging.ErrExpectedToFindResources) {
+			return resources, err
+		}
+
+		c.logger.Warn("Resource discovery failed, retrying", "err", err, "attempt", attempt, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *retryingTaggingClient) GetResourcePages(ctx context.Context, job model.DiscoveryJob, region string, handler tagging.PageHandler) error {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		err := tagging.GetResourcePages(ctx, c.Client, job, region, handler)
+		// Finding no resources is not a transient failure, retrying would not change the result
+		if err == nil || attempt > c.retries || errors.Is(err, tagging.ErrExpectedToFindResources) {
+			return err
+		}
+
+		c.logger.Warn("Resource discovery failed, retrying", "err", err, "attempt", attempt, "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		// The retry starts over from the first page
+		handler.Reset()
+		backoff *= 2
+	}
+}
+
+// discoveredResources filters the resources of a discovery job page by page, as they're discovered, and adds the
+// ones it keeps to the associator builder. The resources filtered out are then never buffered.
+type discoveredResources struct {
+	logger     *slog.Logger
+	job        model.DiscoveryJob
+	region     string
+	newBuilder func() *maxdimassociator.Builder
+
+	resources []*model.TaggedResource
+	// builder is nil when the namespace has no dimensions regexps
+	builder *maxdimassociator.Builder
+}
+
+func newDiscoveredResources(logger *slog.Logger, job model.DiscoveryJob, region string, newBuilder func() *maxdimassociator.Builder) *discoveredResources {
+	d := &discoveredResources{logger: logger, job: job, region: region, newBuilder: newBuilder}
+	d.Reset()
+	return d
+}
+
+// HandlePage only merges the duplicated ARNs within a page, so that the pages handled before don't have to be kept.
+func (d *discoveredResources) HandlePage(page []*model.TaggedResource) {
+	page = dedupeResourcesByARN(page)
+	if !d.job.KeepCrossRegionResources {
+		page = filterCrossRegionResources(d.logger, page, d.region)
+	}
+	if len(d.job.RequiredTags) > 0 {
+		page = filterByRequiredTags(d.logger, page, d.job.RequiredTags)
+	}
+	if d.builder != nil {
+		d.builder.Add(page)
+	}
+	d.resources = append(d.resources, page...)
+}
+
+func (d *discoveredResources) Reset() {
+	d.resources = nil
+	d.builder = d.newBuilder()
+}
+
+// runDiscoveryJob discovers the resources of a job and queries their metrics. For jobs with ExportRecentlyActive,
+// it also returns the ARNs of the resources which had metrics in the recently active list. The returned error
+// reports a failed run, whose resources and metrics are still returned when some of them could be collected.
 func runDiscoveryJob(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -58,26 +203,48 @@ func runDiscoveryJob(
 	gmdProcessor getMetricDataProcessor,
 	enhancedMetricsService enhancedMetricsService,
 	role model.Role,
-) ([]*model.TaggedResource, []*model.CloudwatchData) {
+	scrapeMetrics *promutil.ScrapeMetrics,
+) ([]*model.TaggedResource, []*model.CloudwatchData, map[string]struct{}, error) {
 	logger.Debug("Get tagged resources")
 
-	resources, err := clientTag.GetResources(ctx, job, region)
+	svc := config.SupportedServices.GetService(job.Namespace)
+	discovered := newDiscoveredResources(logger, job, region, func() *maxdimassociator.Builder {
+		return newAssociatorBuilder(logger, job, svc, scrapeMetrics)
+	})
+	err := tagging.GetResourcePages(ctx, clientTag, job, region, discovered)
 	if err != nil {
 		if errors.Is(err, tagging.ErrExpectedToFindResources) {
 			logger.Warn("No tagged resources made it through filtering", "err", err)
-		} else {
-			logger.Error("Couldn't describe resources", "err", err)
+			return nil, nil, nil, nil
 		}
-		return nil, nil
+		logger.Error("Couldn't describe resources", "err", err)
+		return nil, nil, nil, err
 	}
 
+	resources := discovered.resources
 	if len(resources) == 0 {
 		logger.Debug("No tagged resources", "region", region, "namespace", job.Namespace)
 	}
 
-	svc := config.SupportedServices.GetService(job.Namespace)
-	metricData := getMetricDataForQueries(ctx, logger, job, svc, clientCloudwatch, resources)
+	metricData := getMetricDataForQueries(ctx, logger, job, svc, clientCloudwatch, resources, discovered.builder, scrapeMetrics)
+	var recentlyActive map[string]struct{}
+	if job.ExportRecentlyActive && job.RecentlyActiveOnly && !job.DirectQuery {
+		recentlyActive = recentlyActiveARNs(metricData)
+	}
+	if len(job.RequiredTags) > 0 {
+		metricData = dropUnassociatedMetricDatas(metricData)
+	}
+	metricData, statisticsData := splitGetMetricStatisticsData(metricData)
+	for _, data := range statisticsData {
+		// Unlike GetMetricData, GetMetricStatistics isn't queried through the processor setting the period of the job
+		data.GetMetricStatisticsProcessingParams.Period = cmp.Or(data.GetMetricStatisticsProcessingParams.Period, job.DefaultPeriodOverride)
+	}
+	if ctx.Err() != nil {
+		logger.Debug("Scrape canceled, skipping GetMetricData", "err", ctx.Err())
+		return nil, nil, nil, ctx.Err()
+	}
 
+	var runErr error
 	if len(metricData) > 0 && svc != nil {
 		metricData, err = gmdProcessor.Run(ctx, svc.Namespace, metricData)
 		if err != nil {
@@ -85,14 +252,16 @@ func runDiscoveryJob(
 
 			// ensure we do not return cw metrics on data processing failure
 			metricData = nil
+			runErr = err
 		}
 	}
+	metricData = append(metricData, runGetMetricStatistics(ctx, logger, clientCloudwatch, statisticsData)...)
 
 	if enhancedMetricsService == nil || !job.HasEnhancedMetrics() || svc == nil {
 		if len(metricData) == 0 {
 			logger.Info("No metrics data found")
 		}
-		return resources, metricData
+		return resources, metricData, recentlyActive, runErr
 	}
 
 	logger.Debug("Processing enhanced metrics", "count", len(job.EnhancedMetrics), "namespace", svc.Namespace)
@@ -107,8 +276,12 @@ func runDiscoveryJob(
 		role,
 	)
 	if err != nil {
-		logger.Error("Failed to get enhanced metrics", "err", err)
-		return resources, metricData
+		if job.FailOnEnhancedMetricsError {
+			logger.Error("Failed to get enhanced metrics, dropping the metrics of the job", "err", err)
+			return resources, nil, recentlyActive, err
+		}
+		logger.Warn("Failed to get enhanced metrics, exporting the CloudWatch metrics of the job without them", "err", err)
+		return resources, metricData, recentlyActive, runErr
 	}
 
 	metricData = append(metricData, enhancedMetricData...)
@@ -117,9 +290,105 @@ func runDiscoveryJob(
 		logger.Info("No metrics data found")
 	}
 
-	return resources, metricData
+	return resources, metricData, recentlyActive, runErr
+}
+
+// recentlyActiveARNs returns the ARNs of the resources associated with at least one of the metrics listed by a
+// job with RecentlyActiveOnly, i.e. which had metric data in the last three hours.
+func recentlyActiveARNs(metricData []*model.CloudwatchData) map[string]struct{} {
+	arns := make(map[string]struct{})
+	for _, data := range metricData {
+		if data.ResourceName != "global" {
+			arns[data.ResourceName] = struct{}{}
+		}
+	}
+	return arns
+}
+
+// dedupeResourcesByARN merges resources sharing the same ARN, which the tagging API occasionally returns more than once.
+// The merged resource keeps the union of the tags, with the first seen value winning for a duplicated tag key.
+func dedupeResourcesByARN(resources []*model.TaggedResource) []*model.TaggedResource {
+	indexByARN := make(map[string]int, len(resources))
+	deduped := make([]*model.TaggedResource, 0, len(resources))
+	for _, resource := range resources {
+		idx, ok := indexByARN[resource.ARN]
+		if !ok {
+			indexByARN[resource.ARN] = len(deduped)
+			deduped = append(deduped, resource)
+			continue
+		}
+
+		// Merge into a copy so the resources returned by the tagging client are not mutated
+		merged := *deduped[idx]
+		merged.Tags = slices.Clone(merged.Tags)
+		for _, tag := range resource.Tags {
+			if !slices.ContainsFunc(merged.Tags, func(t model.Tag) bool { return t.Key == tag.Key }) {
+				merged.Tags = append(merged.Tags, tag)
+			}
+		}
+		deduped[idx] = &merged
+	}
+	return deduped
+}
+
+// filterCrossRegionResources drops the resources whose ARN is in another region than the queried one. The
+// resources whose ARN has no region are kept.
+func filterCrossRegionResources(logger *slog.Logger, resources []*model.TaggedResource, region string) []*model.TaggedResource {
+	filtered := make([]*model.TaggedResource, 0, len(resources))
+	for _, resource := range resources {
+		if arnRegion, ok := model.RegionFromARN(resource.ARN); ok && arnRegion != region {
+			logger.Debug("Skipping resource because it is in another region", "arn", resource.ARN, "region", region)
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// filterByRequiredTags keeps the resources which carry all the required tags.
+func filterByRequiredTags(logger *slog.Logger, resources []*model.TaggedResource, requiredTags []model.Tag) []*model.TaggedResource {
+	filtered := make([]*model.TaggedResource, 0, len(resources))
+	for _, resource := range resources {
+		if !resource.HasTags(requiredTags) {
+			logger.Debug("Skipping resource because it is missing required tags", "arn", resource.ARN)
+			continue
+		}
+		filtered = append(filtered, resource)
+	}
+	return filtered
+}
+
+// dropUnassociatedMetricDatas drops the metrics which weren't associated with a resource, and would
+// otherwise be exported as global metrics.
+func dropUnassociatedMetricDatas(metricDatas []*model.CloudwatchData) []*model.CloudwatchData {
+	return slices.DeleteFunc(metricDatas, func(data *model.CloudwatchData) bool {
+		return data.ResourceName == "global"
+	})
+}
+
+// unmatchedMetricsExamples is the number of examples logged by jobs with LogUnmatchedMetrics enabled.
+const unmatchedMetricsExamples = 5
+
+// newAssociatorBuilder returns the builder of the associator of the resources of a discovery job, or nil when the
+// namespace has no dimensions regexps.
+func newAssociatorBuilder(logger *slog.Logger, discoveryJob model.DiscoveryJob, svc *config.ServiceConfig, scrapeMetrics *promutil.ScrapeMetrics) *maxdimassociator.Builder {
+	if svc == nil || len(svc.DimensionRegexps) == 0 {
+		return nil
+	}
+	opts := []maxdimassociator.Option{
+		maxdimassociator.WithRegexNoMatchCounter(scrapeMetrics.AssociatorRegexNoMatchCounter, svc.Namespace),
+	}
+	if discoveryJob.AllowMultipleResourceMappings {
+		opts = append(opts, maxdimassociator.WithMultipleMappings())
+	}
+	if discoveryJob.LogUnmatchedMetrics {
+		opts = append(opts, maxdimassociator.WithUnmatchedSummary(unmatchedMetricsExamples))
+	}
+	return maxdimassociator.NewBuilder(logger, discoveryJob.DimensionsRegexps, opts...)
 }
 
+// getMetricDataForQueries lists the metrics of a discovery job and associates them with its resources, which were
+// added to builder as they were discovered. builder is nil when the namespace has no dimensions regexps.
 func getMetricDataForQueries(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -127,13 +396,22 @@ func getMetricDataForQueries(
 	svc *config.ServiceConfig,
 	clientCloudwatch cloudwatch.Client,
 	resources []*model.TaggedResource,
+	builder *maxdimassociator.Builder,
+	scrapeMetrics *promutil.ScrapeMetrics,
 ) []*model.CloudwatchData {
 	mux := &sync.Mutex{}
 	var getMetricDatas []*model.CloudwatchData
 
 	var assoc resourceAssociator
-	if len(svc.DimensionRegexps) > 0 && len(resources) > 0 {
-		assoc = maxdimassociator.NewAssociator(logger, discoveryJob.DimensionsRegexps, resources)
+	if builder != nil && len(resources) > 0 {
+		associator := builder.Build()
+		if discoveryJob.DirectQuery {
+			return getDirectQueryMetricDatas(logger, discoveryJob, svc, associator, scrapeMetrics)
+		}
+		assoc = associator
+	} else if discoveryJob.DirectQuery {
+		// Without resources mapped to dimensions there's nothing to query
+		return nil
 	} else {
 		// If we don't have dimension regex's and resources there's nothing to associate but metrics shouldn't be skipped
 		assoc = nopAssociator{}
@@ -149,8 +427,10 @@ func getMetricDataForQueries(
 		go func(metric *model.MetricConfig) {
 			defer wg.Done()
 
-			err := clientCloudwatch.ListMetrics(ctx, svc.Namespace, metric, discoveryJob.RecentlyActiveOnly, func(page []*model.Metric) {
-				data := getFilteredMetricDatas(logger, discoveryJob.Namespace, discoveryJob.ExportedTagsOnMetrics, page, discoveryJob.DimensionNameRequirements, metric, assoc)
+			// Linked accounts aren't listed: their metrics couldn't be associated with the resources, which
+			// are only discovered in the monitoring account
+			err := clientCloudwatch.ListMetrics(ctx, svc.Namespace, metric, discoveryJob.RecentlyActiveOnly, false, func(page []*model.Metric) {
+				data := getFilteredMetricDatas(logger, discoveryJob.Namespace, discoveryJob.ExportedTagsOnMetrics, page, discoveryJob.DimensionNameRequirements, metric, assoc, discoveryJob.SkipZeroDimensionMetrics, scrapeMetrics)
 
 				mux.Lock()
 				getMetricDatas = append(getMetricDatas, data...)
@@ -164,6 +444,75 @@ func getMetricDataForQueries(
 	}
 
 	wg.Wait()
+	if associator, ok := assoc.(maxdimassociator.Associator); ok {
+		associator.LogUnmatchedSummary(svc.Namespace)
+	}
+	return filterByDimensionGranularity(getMetricDatas, discoveryJob.DimensionGranularity)
+}
+
+// filterByDimensionGranularity keeps, among the metrics of the same name associated with the same resource,
+// only those with the most (most-specific) or fewest (most-aggregate) dimensions, so that a metric published
+// at several granularities isn't counted more than once. Metrics which aren't associated with a resource are kept.
+func filterByDimensionGranularity(metricDatas []*model.CloudwatchData, granularity string) []*model.CloudwatchData {
+	var prefer func(candidate, current int) bool
+	switch granularity {
+	case config.DimensionGranularityMostSpecific:
+		prefer = func(candidate, current int) bool { return candidate > current }
+	case config.DimensionGranularityMostAggregate:
+		prefer = func(candidate, current int) bool { return candidate < current }
+	default:
+		return metricDatas
+	}
+
+	type resourceMetric struct {
+		resourceName string
+		metricName   string
+	}
+	preferred := make(map[resourceMetric]int)
+	for _, data := range metricDatas {
+		if data.ResourceName == "global" {
+			continue
+		}
+		key := resourceMetric{resourceName: data.ResourceName, metricName: data.MetricName}
+		if current, ok := preferred[key]; !ok || prefer(len(data.Dimensions), current) {
+			preferred[key] = len(data.Dimensions)
+		}
+	}
+
+	return slices.DeleteFunc(metricDatas, func(data *model.CloudwatchData) bool {
+		if data.ResourceName == "global" {
+			return false
+		}
+		return len(data.Dimensions) != preferred[resourceMetric{resourceName: data.ResourceName, metricName: data.MetricName}]
+	})
+}
+
+// getDirectQueryMetricDatas builds the GetMetricData queries for the metrics of the job straight from the
+// dimensions extracted from the resources ARNs, without calling the ListMetrics API.
+func getDirectQueryMetricDatas(
+	logger *slog.Logger,
+	discoveryJob model.DiscoveryJob,
+	svc *config.ServiceConfig,
+	assoc maxdimassociator.Associator,
+	scrapeMetrics *promutil.ScrapeMetrics,
+) []*model.CloudwatchData {
+	resourceDimensions := assoc.ResourceDimensions()
+
+	var getMetricDatas []*model.CloudwatchData
+	for _, metric := range discoveryJob.Metrics {
+		metricsList := make([]*model.Metric, 0, len(resourceDimensions))
+		for _, rd := range resourceDimensions {
+			metricsList = append(metricsList, &model.Metric{
+				MetricName: metric.Name,
+				Namespace:  svc.Namespace,
+				Dimensions: rd.Dimensions,
+			})
+		}
+
+		data := getFilteredMetricDatas(logger, discoveryJob.Namespace, discoveryJob.ExportedTagsOnMetrics, metricsList, discoveryJob.DimensionNameRequirements, metric, assoc, discoveryJob.SkipZeroDimensionMetrics, scrapeMetrics)
+		getMetricDatas = append(getMetricDatas, data...)
+	}
+
 	return getMetricDatas
 }
 
@@ -181,7 +530,13 @@ func getFilteredMetricDatas(
 	dimensionNameList []string,
 	m *model.MetricConfig,
 	assoc resourceAssociator,
+	skipZeroDimensionMetrics bool,
+	scrapeMetrics *promutil.ScrapeMetrics,
 ) []*model.CloudwatchData {
+	if m.ExportedTags != nil {
+		tagsOnMetrics = m.ExportedTags
+	}
+
 	getMetricsData := make([]*model.CloudwatchData, 0, len(metricsList))
 	for _, cwMetric := range metricsList {
 		if len(dimensionNameList) > 0 && !metricDimensionsMatchNames(cwMetric, dimensionNameList) {
@@ -200,6 +555,11 @@ func getFilteredMetricDatas(
 		}
 
 		resource := matchedResource
+		if resource == nil && len(cwMetric.Dimensions) == 0 && skipZeroDimensionMetrics {
+			logger.Debug("skipping metric without dimensions", "metric", m.Name)
+			scrapeMetrics.ZeroDimensionMetricsSkippedCounter.Inc()
+			continue
+		}
 		if resource == nil {
 			resource = &model.TaggedResource{
 				ARN:       "global",
@@ -208,6 +568,32 @@ func getFilteredMetricDatas(
 		}
 
 		metricTags := resource.MetricTags(tagsOnMetrics)
+		if m.UseGetMetricStatistics {
+			getMetricsData = append(getMetricsData, &model.CloudwatchData{
+				MetricName:   m.Name,
+				ResourceName: resource.ARN,
+				Namespace:    namespace,
+				Dimensions:   cwMetric.Dimensions,
+				GetMetricStatisticsProcessingParams: &model.GetMetricStatisticsProcessingParams{
+					Statistics: m.Statistics,
+					Period:     m.Period,
+					Length:     m.Length,
+					Delay:      m.Delay,
+				},
+				MetricMigrationParams: model.MetricMigrationParams{
+					NilToZero:              m.NilToZero,
+					AddCloudwatchTimestamp: m.AddCloudwatchTimestamp,
+					EmptyResultGrace:       m.EmptyResultGrace,
+					StatisticNames:         m.StatisticNames,
+					NilToZeroStatistics:    m.NilToZeroStatistics,
+					MaxSeriesPerMetric:     m.MaxSeriesPerMetric,
+					SampleCappedSeries:     m.SampleCappedSeries,
+					MetricType:             m.MetricType,
+				},
+				Tags: metricTags,
+			})
+			continue
+		}
 		for _, stat := range m.Statistics {
 			getMetricsData = append(getMetricsData, &model.CloudwatchData{
 				MetricName:   m.Name,
@@ -224,6 +610,14 @@ func getFilteredMetricDatas(
 					NilToZero:              m.NilToZero,
 					AddCloudwatchTimestamp: m.AddCloudwatchTimestamp,
 					ExportAllDataPoints:    m.ExportAllDataPoints,
+					KeepLastN:              m.KeepLastN,
+					EmptyResultGrace:       m.EmptyResultGrace,
+					StatisticNames:         m.StatisticNames,
+					NilToZeroStatistics:    m.NilToZeroStatistics,
+					MaxSeriesPerMetric:     m.MaxSeriesPerMetric,
+					SampleCappedSeries:     m.SampleCappedSeries,
+					MetricType:             m.MetricType,
+					SumWithoutDimensions:   m.SumWithoutDimensions,
 				},
 				Tags:                      metricTags,
 				GetMetricDataResult:       nil,
@@ -13,14 +13,29 @@
 package job
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job/maxdimassociator"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 func Test_getFilteredMetricDatas(t *testing.T) {
@@ -483,7 +498,7 @@ func Test_getFilteredMetricDatas(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assoc := maxdimassociator.NewAssociator(promslog.NewNopLogger(), tt.args.dimensionRegexps, tt.args.resources)
-			metricDatas := getFilteredMetricDatas(promslog.NewNopLogger(), tt.args.namespace, tt.args.tagsOnMetrics, tt.args.metricsList, tt.args.dimensionNameRequirements, tt.args.m, assoc)
+			metricDatas := getFilteredMetricDatas(promslog.NewNopLogger(), tt.args.namespace, tt.args.tagsOnMetrics, tt.args.metricsList, tt.args.dimensionNameRequirements, tt.args.m, assoc, false, promutil.Discard)
 			if len(metricDatas) != len(tt.wantGetMetricsData) {
 				t.Errorf("len(getFilteredMetricDatas()) = %v, want %v", len(metricDatas), len(tt.wantGetMetricsData))
 			}
@@ -505,3 +520,763 @@ func Test_getFilteredMetricDatas(t *testing.T) {
 		})
 	}
 }
+
+func Test_getFilteredMetricDatas_SkipZeroDimensionMetrics(t *testing.T) {
+	resources := []*model.TaggedResource{
+		{
+			ARN:       "arn:aws:elasticfilesystem:us-east-1:123123123123:file-system/fs-abc123",
+			Namespace: "efs",
+			Region:    "us-east-1",
+		},
+	}
+	metricsList := []*model.Metric{
+		{
+			MetricName: "StorageBytes",
+			Namespace:  "AWS/EFS",
+			Dimensions: []model.Dimension{{Name: "FileSystemId", Value: "fs-abc123"}},
+		},
+		{
+			MetricName: "StorageBytes",
+			Namespace:  "AWS/EFS",
+		},
+	}
+	m := &model.MetricConfig{
+		Name:       "StorageBytes",
+		Statistics: []string{"Average"},
+		Period:     60,
+		Length:     600,
+	}
+	assoc := maxdimassociator.NewAssociator(promslog.NewNopLogger(), config.SupportedServices.GetService("AWS/EFS").ToModelDimensionsRegexp(), resources)
+
+	t.Run("zero-dimension metrics are exported as global by default", func(t *testing.T) {
+		metricDatas := getFilteredMetricDatas(promslog.NewNopLogger(), "efs", nil, metricsList, nil, m, assoc, false, promutil.Discard)
+		require.Len(t, metricDatas, 2)
+		assert.Equal(t, "global", metricDatas[1].ResourceName)
+	})
+
+	t.Run("zero-dimension metrics are skipped and counted when enabled", func(t *testing.T) {
+		scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+
+		metricDatas := getFilteredMetricDatas(promslog.NewNopLogger(), "efs", nil, metricsList, nil, m, assoc, true, scrapeMetrics)
+		require.Len(t, metricDatas, 1)
+		assert.Equal(t, "arn:aws:elasticfilesystem:us-east-1:123123123123:file-system/fs-abc123", metricDatas[0].ResourceName)
+		assert.Equal(t, float64(1), testutil.ToFloat64(scrapeMetrics.ZeroDimensionMetricsSkippedCounter.Raw()))
+	})
+}
+
+func Test_getFilteredMetricDatas_MetricExportedTags(t *testing.T) {
+	resources := []*model.TaggedResource{
+		{
+			ARN:       "arn:aws:elasticfilesystem:us-east-1:123123123123:file-system/fs-abc123",
+			Namespace: "efs",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "Name", Value: "shared"}, {Key: "CostCenter", Value: "1234"}},
+		},
+		{
+			ARN:       "arn:aws:elasticfilesystem:us-east-1:123123123123:file-system/fs-def456",
+			Namespace: "efs",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "Name", Value: "scratch"}},
+		},
+	}
+	assoc := maxdimassociator.NewAssociator(promslog.NewNopLogger(), config.SupportedServices.GetService("AWS/EFS").ToModelDimensionsRegexp(), resources)
+	metricsList := func(metricName string) []*model.Metric {
+		return []*model.Metric{
+			{MetricName: metricName, Namespace: "AWS/EFS", Dimensions: []model.Dimension{{Name: "FileSystemId", Value: "fs-abc123"}}},
+			{MetricName: metricName, Namespace: "AWS/EFS", Dimensions: []model.Dimension{{Name: "FileSystemId", Value: "fs-def456"}}},
+		}
+	}
+
+	jobTags := []string{"Name"}
+	storageBytes := &model.MetricConfig{Name: "StorageBytes", Statistics: []string{"Average"}, Period: 60, Length: 600}
+	meteredIOBytes := &model.MetricConfig{Name: "MeteredIOBytes", Statistics: []string{"Sum"}, Period: 60, Length: 600, ExportedTags: []string{"CostCenter"}}
+
+	var cwData []*model.CloudwatchData
+	cwData = append(cwData, getFilteredMetricDatas(promslog.NewNopLogger(), "AWS/EFS", jobTags, metricsList("StorageBytes"), nil, storageBytes, assoc, false, promutil.Discard)...)
+	cwData = append(cwData, getFilteredMetricDatas(promslog.NewNopLogger(), "AWS/EFS", jobTags, metricsList("MeteredIOBytes"), nil, meteredIOBytes, assoc, false, promutil.Discard)...)
+	require.Len(t, cwData, 4)
+
+	assert.Equal(t, []model.Tag{{Key: "Name", Value: "shared"}}, cwData[0].Tags)
+	assert.Equal(t, []model.Tag{{Key: "Name", Value: "scratch"}}, cwData[1].Tags)
+	assert.Equal(t, []model.Tag{{Key: "CostCenter", Value: "1234"}}, cwData[2].Tags)
+	// The tag is exported with an empty value on the resources without it.
+	assert.Equal(t, []model.Tag{{Key: "CostCenter", Value: ""}}, cwData[3].Tags)
+
+	// Every metric name keeps a consistent set of tag labels.
+	for _, data := range cwData {
+		data.GetMetricDataResult = &model.GetMetricDataResult{
+			Statistic:  data.GetMetricDataProcessingParams.Statistic,
+			DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: time.Now()}},
+		}
+		data.GetMetricDataProcessingParams = nil
+	}
+	metrics, observedMetricLabels, err := promutil.BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123123123123"},
+		Data:    cwData,
+	}}, promutil.BuildOptions{}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	metrics = promutil.EnsureLabelConsistencyAndRemoveDuplicates(promutil.Discard, metrics, observedMetricLabels)
+	require.Len(t, metrics, 4)
+	for _, metric := range metrics {
+		switch metric.Name {
+		case "aws_efs_storage_bytes_average":
+			assert.Contains(t, metric.Labels, "tag_Name")
+			assert.NotContains(t, metric.Labels, "tag_CostCenter")
+		case "aws_efs_metered_iobytes_sum":
+			assert.Contains(t, metric.Labels, "tag_CostCenter")
+			assert.NotContains(t, metric.Labels, "tag_Name")
+		default:
+			t.Errorf("unexpected metric %s", metric.Name)
+		}
+	}
+}
+
+// listMetricsCountingClient is a cloudwatch.Client which only counts ListMetrics calls,
+// and records their recentlyActiveOnly argument.
+type listMetricsCountingClient struct {
+	listMetricsCalls   int
+	recentlyActiveOnly []bool
+}
+
+func (c *listMetricsCountingClient) ListMetrics(_ context.Context, _ string, _ *model.MetricConfig, recentlyActiveOnly bool, _ bool, _ func(page []*model.Metric)) error {
+	c.listMetricsCalls++
+	c.recentlyActiveOnly = append(c.recentlyActiveOnly, recentlyActiveOnly)
+	return nil
+}
+
+func (c *listMetricsCountingClient) GetMetricData(context.Context, []*model.CloudwatchData, string, time.Time, time.Time) []cloudwatch.MetricDataResult {
+	return nil
+}
+
+func (c *listMetricsCountingClient) GetMetricStatistics(context.Context, *slog.Logger, []model.Dimension, string, *model.MetricConfig) []*model.MetricStatisticsResult {
+	return nil
+}
+
+func Test_getMetricDataForQueries_DirectQuery(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	resources := []*model.TaggedResource{
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+			Namespace: "AWS/EC2",
+			Tags:      []model.Tag{{Key: "Name", Value: "instance-1"}},
+		},
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-def456",
+			Namespace: "AWS/EC2",
+			Tags:      []model.Tag{{Key: "Name", Value: "instance-2"}},
+		},
+	}
+	discoveryJob := model.DiscoveryJob{
+		Namespace:             "AWS/EC2",
+		DirectQuery:           true,
+		ExportedTagsOnMetrics: []string{"Name"},
+		DimensionsRegexps:     svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+			{Name: "NetworkIn", Statistics: []string{"Sum"}, Period: 300, Length: 300},
+		},
+	}
+
+	client := &listMetricsCountingClient{}
+	builder := newAssociatorBuilder(promslog.NewNopLogger(), discoveryJob, svc, promutil.Discard)
+	builder.Add(resources)
+	metricDatas := getMetricDataForQueries(context.Background(), promslog.NewNopLogger(), discoveryJob, svc, client, resources, builder, promutil.Discard)
+
+	assert.Equal(t, 0, client.listMetricsCalls)
+	require.Len(t, metricDatas, 4)
+
+	dimensionsByResource := map[string][]model.Dimension{}
+	for _, md := range metricDatas {
+		assert.Equal(t, "AWS/EC2", md.Namespace)
+		assert.Contains(t, []string{"CPUUtilization", "NetworkIn"}, md.MetricName)
+		dimensionsByResource[md.ResourceName] = md.Dimensions
+	}
+	assert.Equal(t, map[string][]model.Dimension{
+		"arn:aws:ec2:us-east-1:123456789012:instance/i-abc123": {{Name: "InstanceId", Value: "i-abc123"}},
+		"arn:aws:ec2:us-east-1:123456789012:instance/i-def456": {{Name: "InstanceId", Value: "i-def456"}},
+	}, dimensionsByResource)
+
+	t.Run("no resources", func(t *testing.T) {
+		client := &listMetricsCountingClient{}
+		builder := newAssociatorBuilder(promslog.NewNopLogger(), discoveryJob, svc, promutil.Discard)
+		metricDatas := getMetricDataForQueries(context.Background(), promslog.NewNopLogger(), discoveryJob, svc, client, nil, builder, promutil.Discard)
+
+		assert.Equal(t, 0, client.listMetricsCalls)
+		assert.Empty(t, metricDatas)
+	})
+}
+
+func Test_dedupeResourcesByARN(t *testing.T) {
+	resources := []*model.TaggedResource{
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "Name", Value: "instance-1"}, {Key: "env", Value: "prod"}},
+		},
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-def456",
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "Name", Value: "instance-2"}},
+		},
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "env", Value: "prod"}, {Key: "team", Value: "platform"}},
+		},
+	}
+
+	deduped := dedupeResourcesByARN(resources)
+
+	require.Len(t, deduped, 2)
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", deduped[0].ARN)
+	assert.Equal(t, []model.Tag{
+		{Key: "Name", Value: "instance-1"},
+		{Key: "env", Value: "prod"},
+		{Key: "team", Value: "platform"},
+	}, deduped[0].Tags)
+	assert.Equal(t, resources[1], deduped[1])
+
+	// The input resources are left untouched
+	assert.Len(t, resources[0].Tags, 2)
+}
+
+type staticTaggingClient struct {
+	resources []*model.TaggedResource
+}
+
+func (c staticTaggingClient) GetResources(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+	return c.resources, nil
+}
+
+// getMetricStatisticsRecordingClient is a cloudwatch.Client which records the metrics queried with GetMetricStatistics,
+// and their period.
+type getMetricStatisticsRecordingClient struct {
+	listMetricsCountingClient
+	statisticsMetrics []string
+	statisticsPeriods []int64
+}
+
+func (c *getMetricStatisticsRecordingClient) GetMetricStatistics(_ context.Context, _ *slog.Logger, _ []model.Dimension, _ string, metric *model.MetricConfig) []*model.MetricStatisticsResult {
+	c.statisticsMetrics = append(c.statisticsMetrics, metric.Name)
+	c.statisticsPeriods = append(c.statisticsPeriods, metric.Period)
+	now := time.Now()
+	return []*model.MetricStatisticsResult{{Timestamp: &now, Maximum: aws.Float64(42)}}
+}
+
+// getMetricDataRecordingProcessor is a getMetricDataProcessor which records the metrics queried with GetMetricData.
+type getMetricDataRecordingProcessor struct {
+	metrics []string
+}
+
+func (p *getMetricDataRecordingProcessor) Run(_ context.Context, _ string, requests []*model.CloudwatchData) ([]*model.CloudwatchData, error) {
+	for _, request := range requests {
+		p.metrics = append(p.metrics, request.MetricName)
+		request.GetMetricDataResult = &model.GetMetricDataResult{
+			Statistic:  request.GetMetricDataProcessingParams.Statistic,
+			DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: time.Now()}},
+		}
+		request.GetMetricDataProcessingParams = nil
+	}
+	return requests, nil
+}
+
+func Test_runDiscoveryJob_UseGetMetricStatistics(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	job := model.DiscoveryJob{
+		Namespace:         "AWS/EC2",
+		DirectQuery:       true,
+		DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+			// NetworkIn uses the period of the job
+			{Name: "NetworkIn", Statistics: []string{"Maximum", "p99"}, Length: 600, UseGetMetricStatistics: true},
+		},
+		DefaultPeriodOverride: 600,
+	}
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: "AWS/EC2", Region: "us-east-1"},
+	}}
+	client := &getMetricStatisticsRecordingClient{}
+	processor := &getMetricDataRecordingProcessor{}
+
+	_, metricDatas, _, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, processor, nil, model.Role{}, promutil.Discard)
+
+	assert.Equal(t, []string{"CPUUtilization"}, processor.metrics)
+	assert.Equal(t, []string{"NetworkIn"}, client.statisticsMetrics)
+	assert.Equal(t, []int64{600}, client.statisticsPeriods)
+
+	require.Len(t, metricDatas, 2)
+	for _, md := range metricDatas {
+		switch md.MetricName {
+		case "CPUUtilization":
+			assert.NotNil(t, md.GetMetricDataResult)
+			assert.Nil(t, md.GetMetricStatisticsResult)
+		case "NetworkIn":
+			assert.Nil(t, md.GetMetricDataResult)
+			require.NotNil(t, md.GetMetricStatisticsResult)
+			assert.Equal(t, []string{"Maximum", "p99"}, md.GetMetricStatisticsResult.Statistics)
+			assert.Nil(t, md.GetMetricStatisticsProcessingParams)
+		}
+	}
+}
+
+type failingEnhancedMetricsService struct{}
+
+func (failingEnhancedMetricsService) GetMetrics(context.Context, *slog.Logger, string, []*model.TaggedResource, []*model.EnhancedMetricConfig, []string, string, model.Role) ([]*model.CloudwatchData, error) {
+	return nil, errors.New("describe failed")
+}
+
+func Test_runDiscoveryJob_EnhancedMetricsFailure(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	newJob := func(failOnEnhancedMetricsError bool) model.DiscoveryJob {
+		return model.DiscoveryJob{
+			Namespace:         "AWS/EC2",
+			DirectQuery:       true,
+			DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+			Metrics: []*model.MetricConfig{
+				{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+			},
+			EnhancedMetrics:            []*model.EnhancedMetricConfig{{Name: "StorageSpace"}},
+			FailOnEnhancedMetricsError: failOnEnhancedMetricsError,
+		}
+	}
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: "AWS/EC2", Region: "us-east-1"},
+	}}
+
+	t.Run("warn and continue exports the CloudWatch metrics", func(t *testing.T) {
+		resources, metricDatas, _, err := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), newJob(false), "us-east-1", tagging, &listMetricsCountingClient{}, &getMetricDataRecordingProcessor{}, failingEnhancedMetricsService{}, model.Role{}, promutil.Discard)
+
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		require.Len(t, metricDatas, 1)
+		assert.Equal(t, "CPUUtilization", metricDatas[0].MetricName)
+		assert.NotNil(t, metricDatas[0].GetMetricDataResult)
+	})
+
+	t.Run("fail drops the metrics of the job", func(t *testing.T) {
+		resources, metricDatas, _, err := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), newJob(true), "us-east-1", tagging, &listMetricsCountingClient{}, &getMetricDataRecordingProcessor{}, failingEnhancedMetricsService{}, model.Role{}, promutil.Discard)
+
+		require.Error(t, err)
+		require.Len(t, resources, 1)
+		assert.Empty(t, metricDatas)
+	})
+}
+
+// blockingEnhancedMetricsService is an enhanced metrics service whose calls block until release is closed.
+type blockingEnhancedMetricsService struct {
+	mu          sync.Mutex
+	calls       int
+	inFlight    int
+	maxInFlight int
+	release     chan struct{}
+}
+
+func (s *blockingEnhancedMetricsService) GetMetrics(context.Context, *slog.Logger, string, []*model.TaggedResource, []*model.EnhancedMetricConfig, []string, string, model.Role) ([]*model.CloudwatchData, error) {
+	s.mu.Lock()
+	s.calls++
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+
+	<-s.release
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return nil, nil
+}
+
+func (s *blockingEnhancedMetricsService) getInFlight() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight
+}
+
+func Test_concurrencyLimitedEnhancedMetricsService(t *testing.T) {
+	t.Run("describes of the regions run concurrently up to the bound", func(t *testing.T) {
+		inner := &blockingEnhancedMetricsService{release: make(chan struct{})}
+		limited := &concurrencyLimitedEnhancedMetricsService{enhancedMetricsService: inner, sem: make(chan struct{}, 2)}
+
+		regions := []string{"us-east-1", "us-east-2", "us-west-1", "us-west-2", "eu-west-1"}
+		var wg sync.WaitGroup
+		for _, region := range regions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := limited.GetMetrics(context.Background(), promslog.NewNopLogger(), "AWS/RDS", nil, nil, nil, region, model.Role{})
+				assert.NoError(t, err)
+			}()
+		}
+
+		require.Eventually(t, func() bool { return inner.getInFlight() == 2 }, time.Second, time.Millisecond)
+		require.Never(t, func() bool { return inner.getInFlight() > 2 }, 50*time.Millisecond, time.Millisecond)
+
+		close(inner.release)
+		wg.Wait()
+
+		assert.Equal(t, len(regions), inner.calls)
+		assert.Equal(t, 2, inner.maxInFlight)
+	})
+
+	t.Run("waiting for the bound stops when the context is canceled", func(t *testing.T) {
+		inner := &blockingEnhancedMetricsService{release: make(chan struct{})}
+		limited := &concurrencyLimitedEnhancedMetricsService{enhancedMetricsService: inner, sem: make(chan struct{}, 1)}
+		limited.sem <- struct{}{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := limited.GetMetrics(ctx, promslog.NewNopLogger(), "AWS/RDS", nil, nil, nil, "us-east-1", model.Role{})
+
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Zero(t, inner.calls)
+	})
+}
+
+func Test_runDiscoveryJob_RecentlyActiveOnly(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: "AWS/EC2", Region: "us-east-1"},
+	}}
+
+	for _, recentlyActiveOnly := range []bool{false, true} {
+		job := model.DiscoveryJob{
+			Namespace:         "AWS/EC2",
+			DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+			Metrics: []*model.MetricConfig{
+				{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+				{Name: "NetworkIn", Statistics: []string{"Sum"}, Period: 300, Length: 300},
+			},
+			RecentlyActiveOnly: recentlyActiveOnly,
+		}
+		client := &listMetricsCountingClient{}
+
+		runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+
+		assert.Equal(t, []bool{recentlyActiveOnly, recentlyActiveOnly}, client.recentlyActiveOnly)
+	}
+}
+
+func Test_runDiscoveryJob_RecentlyActiveARNs(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	active := "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123"
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{
+		{ARN: active, Namespace: "AWS/EC2", Region: "us-east-1"},
+		{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-idle", Namespace: "AWS/EC2", Region: "us-east-1"},
+	}}
+	// The recently active list only holds the metrics of i-abc123, and a metric of no resource
+	client := &staticListMetricsClient{metrics: []*model.Metric{
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-abc123"}}},
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceType", Value: "t3.micro"}}},
+	}}
+	job := model.DiscoveryJob{
+		Namespace:         "AWS/EC2",
+		DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+		},
+		RecentlyActiveOnly:   true,
+		ExportRecentlyActive: true,
+	}
+
+	_, _, recentlyActive, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+	assert.Equal(t, map[string]struct{}{active: {}}, recentlyActive)
+
+	// The recently active metric is opt-in
+	job.ExportRecentlyActive = false
+	_, _, recentlyActive, _ = runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+	assert.Nil(t, recentlyActive)
+
+	job.ExportRecentlyActive = true
+	job.RecentlyActiveOnly = false
+	_, _, recentlyActive, _ = runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+	assert.Nil(t, recentlyActive)
+}
+
+// staticListMetricsClient is a cloudwatch.Client which lists the same metrics for every metric config.
+type staticListMetricsClient struct {
+	listMetricsCountingClient
+	metrics []*model.Metric
+}
+
+func (c *staticListMetricsClient) ListMetrics(_ context.Context, _ string, metric *model.MetricConfig, _ bool, _ bool, fn func(page []*model.Metric)) error {
+	page := make([]*model.Metric, 0, len(c.metrics))
+	for _, m := range c.metrics {
+		if m.MetricName == metric.Name {
+			page = append(page, m)
+		}
+	}
+	fn(page)
+	return nil
+}
+
+func Test_runDiscoveryJob_RequiredTags(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	job := model.DiscoveryJob{
+		Namespace:         "AWS/EC2",
+		DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+		},
+		RequiredTags: []model.Tag{{Key: "monitored", Value: "true"}},
+	}
+	monitored := &model.TaggedResource{
+		ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+		Namespace: "AWS/EC2",
+		Region:    "us-east-1",
+		Tags:      []model.Tag{{Key: "Name", Value: "instance-1"}, {Key: "monitored", Value: "true"}},
+	}
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{
+		monitored,
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-def456",
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+			Tags:      []model.Tag{{Key: "monitored", Value: "false"}},
+		},
+		{
+			ARN:       "arn:aws:ec2:us-east-1:123456789012:instance/i-ghi789",
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+		},
+	}}
+	client := &staticListMetricsClient{metrics: []*model.Metric{
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-abc123"}}},
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-def456"}}},
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-ghi789"}}},
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2", Dimensions: []model.Dimension{{Name: "InstanceType", Value: "t3.micro"}}},
+		{MetricName: "CPUUtilization", Namespace: "AWS/EC2"},
+	}}
+
+	resources, metricDatas, _, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+
+	assert.Equal(t, []*model.TaggedResource{monitored}, resources)
+	require.Len(t, metricDatas, 1)
+	assert.Equal(t, monitored.ARN, metricDatas[0].ResourceName)
+
+	t.Run("without required tags", func(t *testing.T) {
+		job := job
+		job.RequiredTags = nil
+		resources, metricDatas, _, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, client, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+
+		assert.Len(t, resources, 3)
+		// The InstanceType and dimensionless metrics are exported as global metrics
+		assert.Len(t, metricDatas, 5)
+	})
+}
+
+func Test_runDiscoveryJob_CrossRegionResources(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	job := model.DiscoveryJob{
+		Namespace:         "AWS/EC2",
+		DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+		},
+	}
+	local := &model.TaggedResource{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: "AWS/EC2", Region: "us-east-1"}
+	crossRegion := &model.TaggedResource{ARN: "arn:aws:ec2:eu-west-1:123456789012:instance/i-def456", Namespace: "AWS/EC2", Region: "us-east-1"}
+	withoutRegion := &model.TaggedResource{ARN: "arn:aws:ec2::123456789012:instance/i-ghi789", Namespace: "AWS/EC2", Region: "us-east-1"}
+	tagging := staticTaggingClient{resources: []*model.TaggedResource{local, crossRegion, withoutRegion}}
+
+	t.Run("regional jobs filter the resources of other regions", func(t *testing.T) {
+		resources, _, _, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, &listMetricsCountingClient{}, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+
+		assert.Equal(t, []*model.TaggedResource{local, withoutRegion}, resources)
+	})
+
+	t.Run("global jobs keep the resources of other regions", func(t *testing.T) {
+		job := job
+		job.KeepCrossRegionResources = true
+		resources, _, _, _ := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", tagging, &listMetricsCountingClient{}, &getMetricDataRecordingProcessor{}, nil, model.Role{}, promutil.Discard)
+
+		assert.Equal(t, []*model.TaggedResource{local, crossRegion, withoutRegion}, resources)
+	})
+}
+
+func Test_getMetricDataForQueries_DimensionGranularity(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/ApplicationELB")
+	lbARN := "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/my-alb/1234567890abcdef"
+	resources := []*model.TaggedResource{{ARN: lbARN, Namespace: "AWS/ApplicationELB", Region: "us-east-1"}}
+	loadBalancer := model.Dimension{Name: "LoadBalancer", Value: "app/my-alb/1234567890abcdef"}
+	client := &staticListMetricsClient{metrics: []*model.Metric{
+		{MetricName: "RequestCount", Namespace: "AWS/ApplicationELB", Dimensions: []model.Dimension{loadBalancer}},
+		{MetricName: "RequestCount", Namespace: "AWS/ApplicationELB", Dimensions: []model.Dimension{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1a"}}},
+		{MetricName: "RequestCount", Namespace: "AWS/ApplicationELB", Dimensions: []model.Dimension{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1b"}}},
+		{MetricName: "RequestCount", Namespace: "AWS/ApplicationELB", Dimensions: []model.Dimension{{Name: "AvailabilityZone", Value: "us-east-1a"}}},
+	}}
+
+	for _, tc := range []struct {
+		granularity        string
+		expectedDimensions [][]model.Dimension
+	}{
+		{
+			granularity: "",
+			expectedDimensions: [][]model.Dimension{
+				{loadBalancer},
+				{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1a"}},
+				{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1b"}},
+			},
+		},
+		{
+			granularity: config.DimensionGranularityMostSpecific,
+			expectedDimensions: [][]model.Dimension{
+				{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1a"}},
+				{loadBalancer, {Name: "AvailabilityZone", Value: "us-east-1b"}},
+			},
+		},
+		{
+			granularity:        config.DimensionGranularityMostAggregate,
+			expectedDimensions: [][]model.Dimension{{loadBalancer}},
+		},
+	} {
+		t.Run(tc.granularity, func(t *testing.T) {
+			job := model.DiscoveryJob{
+				Namespace:            "AWS/ApplicationELB",
+				DimensionsRegexps:    svc.ToModelDimensionsRegexp(),
+				DimensionGranularity: tc.granularity,
+				Metrics: []*model.MetricConfig{
+					{Name: "RequestCount", Statistics: []string{"Sum"}, Period: 300, Length: 300},
+				},
+			}
+
+			builder := newAssociatorBuilder(promslog.NewNopLogger(), job, svc, promutil.Discard)
+			builder.Add(resources)
+			metricDatas := getMetricDataForQueries(context.Background(), promslog.NewNopLogger(), job, svc, client, resources, builder, promutil.Discard)
+
+			var dimensions [][]model.Dimension
+			globalMetrics := 0
+			for _, md := range metricDatas {
+				if md.ResourceName == "global" {
+					globalMetrics++
+					continue
+				}
+				assert.Equal(t, lbARN, md.ResourceName)
+				dimensions = append(dimensions, md.Dimensions)
+			}
+			assert.ElementsMatch(t, tc.expectedDimensions, dimensions)
+			// Metrics which aren't associated with a resource are not affected
+			assert.Equal(t, 1, globalMetrics)
+		})
+	}
+}
+
+// generatedPagesTaggingClient discovers pages of generated EC2 instances with many tags, only one instance in
+// monitoredEvery carrying the monitored tag. It samples the heap once the resources are handed over, after every
+// page when discovering page by page, to track the peak memory of a discovery.
+type generatedPagesTaggingClient struct {
+	pages, pageSize, monitoredEvery int
+
+	baseline, peak uint64
+}
+
+func (c *generatedPagesTaggingClient) page(i int) []*model.TaggedResource {
+	page := make([]*model.TaggedResource, 0, c.pageSize)
+	for j := range c.pageSize {
+		id := i*c.pageSize + j
+		tags := make([]model.Tag, 0, 21)
+		for k := range 20 {
+			tags = append(tags, model.Tag{Key: fmt.Sprintf("tag-%d", k), Value: fmt.Sprintf("%s-%d", strings.Repeat("v", 100), id)})
+		}
+		if id%c.monitoredEvery == 0 {
+			tags = append(tags, model.Tag{Key: "monitored", Value: "true"})
+		}
+		page = append(page, &model.TaggedResource{
+			ARN:       fmt.Sprintf("arn:aws:ec2:us-east-1:123456789012:instance/i-%08d", id),
+			Namespace: "AWS/EC2",
+			Region:    "us-east-1",
+			Tags:      tags,
+		})
+	}
+	return page
+}
+
+func (c *generatedPagesTaggingClient) sample() {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > c.baseline {
+		c.peak = max(c.peak, stats.HeapAlloc-c.baseline)
+	}
+}
+
+func (c *generatedPagesTaggingClient) resetPeak() {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	c.baseline, c.peak = stats.HeapAlloc, 0
+}
+
+func (c *generatedPagesTaggingClient) GetResources(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+	var resources []*model.TaggedResource
+	for i := range c.pages {
+		resources = append(resources, c.page(i)...)
+	}
+	c.sample()
+	return resources, nil
+}
+
+func (c *generatedPagesTaggingClient) GetResourcePages(_ context.Context, _ model.DiscoveryJob, _ string, handler tagging.PageHandler) error {
+	for i := range c.pages {
+		handler.HandlePage(c.page(i))
+		c.sample()
+	}
+	return nil
+}
+
+// bufferedTaggingClient hides the GetResourcePages of a client, so that all its resources are discovered first.
+type bufferedTaggingClient struct {
+	client tagging.Client
+}
+
+func (c bufferedTaggingClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	return c.client.GetResources(ctx, job, region)
+}
+
+type passthroughProcessor struct{}
+
+func (passthroughProcessor) Run(_ context.Context, _ string, requests []*model.CloudwatchData) ([]*model.CloudwatchData, error) {
+	return requests, nil
+}
+
+func Test_runDiscoveryJob_ResourcePages(t *testing.T) {
+	svc := config.SupportedServices.GetService("AWS/EC2")
+	job := model.DiscoveryJob{
+		Namespace:         "AWS/EC2",
+		DimensionsRegexps: svc.ToModelDimensionsRegexp(),
+		Metrics: []*model.MetricConfig{
+			{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300},
+		},
+		RequiredTags: []model.Tag{{Key: "monitored", Value: "true"}},
+	}
+	client := &staticListMetricsClient{}
+	for _, id := range []int{0, 1, 100, 4999, 5000} {
+		client.metrics = append(client.metrics, &model.Metric{
+			MetricName: "CPUUtilization",
+			Namespace:  "AWS/EC2",
+			Dimensions: []model.Dimension{{Name: "InstanceId", Value: fmt.Sprintf("i-%08d", id)}},
+		})
+	}
+	taggingClient := &generatedPagesTaggingClient{pages: 50, pageSize: 100, monitoredEvery: 100}
+
+	taggingClient.resetPeak()
+	bufferedResources, bufferedMetricDatas, _, err := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", bufferedTaggingClient{taggingClient}, client, passthroughProcessor{}, nil, model.Role{}, promutil.Discard)
+	require.NoError(t, err)
+	bufferedPeak := taggingClient.peak
+
+	taggingClient.resetPeak()
+	resources, metricDatas, _, err := runDiscoveryJob(context.Background(), promslog.NewNopLogger(), job, "us-east-1", taggingClient, client, passthroughProcessor{}, nil, model.Role{}, promutil.Discard)
+	require.NoError(t, err)
+	pagedPeak := taggingClient.peak
+
+	require.Len(t, resources, 50)
+	require.Equal(t, bufferedResources, resources)
+	require.Len(t, metricDatas, 2)
+	require.Equal(t, bufferedMetricDatas, metricDatas)
+
+	// Only a page and the kept resources are held at once, instead of all the discovered resources
+	require.Less(t, pagedPeak*10, bufferedPeak, "paged peak %d, buffered peak %d", pagedPeak, bufferedPeak)
+}
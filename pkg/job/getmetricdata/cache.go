@@ -0,0 +1,106 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package getmetricdata
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// ResultCache shares GetMetricData results between the processors of a single scrape, so that
+// identical queries produced by several jobs are only sent to CloudWatch once. The first processor
+// to see a query runs it, the others wait for its result. A ResultCache must not be reused across scrapes.
+type ResultCache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done   chan struct{}
+	result *model.GetMetricDataResult
+}
+
+func NewResultCache() *ResultCache {
+	return &ResultCache{entries: map[string]*cacheEntry{}}
+}
+
+// claim returns the entry of the given key, and whether the caller is the one responsible for resolving it.
+func (c *ResultCache) claim(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		return entry, false
+	}
+	entry := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true
+}
+
+func (e *cacheEntry) resolve(result *model.GetMetricDataResult) {
+	e.result = result
+	close(e.done)
+}
+
+// wait blocks until the entry is resolved and returns a copy of its result, which is nil if the query
+// didn't return any result.
+func (e *cacheEntry) wait(ctx context.Context) (*model.GetMetricDataResult, error) {
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if e.result == nil {
+		return nil, nil
+	}
+	result := &model.GetMetricDataResult{
+		Statistic:  e.result.Statistic,
+		DataPoints: slices.Clone(e.result.DataPoints),
+	}
+	if e.result.Window != nil {
+		window := *e.result.Window
+		result.Window = &window
+	}
+	return result, nil
+}
+
+// cacheKey identifies the GetMetricData query of a request within the given scope (e.g. account and region).
+// Besides the query itself, it covers the options that change which data points the client returns.
+func cacheKey(scope string, namespace string, data *model.CloudwatchData) string {
+	dimensions := make([]string, 0, len(data.Dimensions))
+	for _, dimension := range data.Dimensions {
+		dimensions = append(dimensions, dimension.Name+"="+dimension.Value)
+	}
+	slices.Sort(dimensions)
+
+	params := data.GetMetricDataProcessingParams
+	return strings.Join([]string{
+		scope,
+		data.SourceAccountID,
+		namespace,
+		data.MetricName,
+		strings.Join(dimensions, ","),
+		params.Statistic,
+		params.Expression,
+		strconv.FormatInt(params.Period, 10),
+		strconv.FormatInt(params.Length, 10),
+		strconv.FormatInt(params.Delay, 10),
+		strconv.FormatBool(data.MetricMigrationParams.ExportAllDataPoints),
+		strconv.Itoa(data.MetricMigrationParams.KeepLastN),
+	}, "\x00")
+}
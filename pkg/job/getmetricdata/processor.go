@@ -23,6 +23,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
@@ -58,6 +59,15 @@ type Processor struct {
 	windowCalculator MetricWindowCalculator
 	logger           *slog.Logger
 	factory          IteratorFactory
+	cache            *ResultCache
+	cacheScope       string
+	defaultPeriod    int64
+}
+
+// cachedRequest is a request whose GetMetricData query is shared through a ResultCache.
+type cachedRequest struct {
+	request *model.CloudwatchData
+	entry   *cacheEntry
 }
 
 func NewDefaultProcessor(logger *slog.Logger, client Client, metricsPerQuery int, concurrency int) Processor {
@@ -74,25 +84,114 @@ func NewProcessor(logger *slog.Logger, client Client, concurrency int, windowCal
 	}
 }
 
+// WithResultCache returns a copy of the processor which shares the results of its queries through the
+// given cache. The scope identifies where the queries run (e.g. account and region), as identical
+// queries are only shared within the same scope.
+func (p Processor) WithResultCache(cache *ResultCache, scope string) Processor {
+	p.cache = cache
+	p.cacheScope = scope
+	return p
+}
+
+// WithDefaultPeriod returns a copy of the processor which queries the requests without a period, i.e. whose
+// metric uses the period of its job, with the given one.
+func (p Processor) WithDefaultPeriod(period int64) Processor {
+	p.defaultPeriod = period
+	return p
+}
+
 func (p Processor) Run(ctx context.Context, namespace string, requests []*model.CloudwatchData) ([]*model.CloudwatchData, error) {
 	if len(requests) == 0 {
 		return requests, nil
 	}
 
+	// Set the period before sharing the queries, as it's part of their cache key
+	if p.defaultPeriod != 0 {
+		for _, request := range requests {
+			if request.GetMetricDataProcessingParams.Period == 0 {
+				request.GetMetricDataProcessingParams.Period = p.defaultPeriod
+			}
+		}
+	}
+
+	toQuery := requests
+	var owned, followed []cachedRequest
+	if p.cache != nil {
+		toQuery, owned, followed = p.claimRequests(namespace, requests)
+	}
+
+	err := p.query(ctx, namespace, toQuery)
+
+	// Resolve owned entries even on error, so that processors waiting on them never hang.
+	for _, o := range owned {
+		o.entry.resolve(o.request.GetMetricDataResult)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range followed {
+		result, err := f.entry.wait(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("waiting for shared GetMetricData result: %w", err)
+		}
+		if result != nil {
+			f.request.GetMetricDataResult = result
+			f.request.GetMetricDataProcessingParams = nil
+		}
+	}
+
+	// Remove unprocessed/unknown elements in place, if any. Since getMetricDatas
+	// is a slice of pointers, the compaction can be easily done in-place.
+	requests = compact(requests, func(m *model.CloudwatchData) bool {
+		return m.GetMetricDataResult != nil
+	})
+
+	return requests, nil
+}
+
+// claimRequests splits the requests between the ones this processor must query, and the ones
+// whose identical query was already claimed by another processor sharing the cache.
+func (p Processor) claimRequests(namespace string, requests []*model.CloudwatchData) ([]*model.CloudwatchData, []cachedRequest, []cachedRequest) {
+	toQuery := make([]*model.CloudwatchData, 0, len(requests))
+	var owned, followed []cachedRequest
+	for _, request := range requests {
+		entry, owner := p.cache.claim(cacheKey(p.cacheScope, namespace, request))
+		if owner {
+			toQuery = append(toQuery, request)
+			owned = append(owned, cachedRequest{request: request, entry: entry})
+		} else {
+			followed = append(followed, cachedRequest{request: request, entry: entry})
+		}
+	}
+	return toQuery, owned, followed
+}
+
+func (p Processor) query(ctx context.Context, namespace string, requests []*model.CloudwatchData) error {
 	g, gCtx := errgroup.WithContext(ctx)
 	g.SetLimit(p.concurrency)
+	includeWindow := config.FlagsFromCtx(ctx).IsFeatureEnabled(config.ScrapeWindowLabels)
 
 	iterator := p.factory.Build(requests)
 	for iterator.HasMore() {
 		batch, batchParams := iterator.Next()
 		g.Go(func() error {
+			// Don't send the batches left once the scrape is canceled
+			if err := gCtx.Err(); err != nil {
+				return err
+			}
 			batch = addQueryIDsToBatch(batch)
 			startTime, endTime := p.windowCalculator.Calculate(toSecondDuration(batchParams.Period), toSecondDuration(batchParams.Length), toSecondDuration(batchParams.Delay))
 			p.logger.Debug("GetMetricData Window", "start_time", startTime.Format(TimeFormat), "end_time", endTime.Format(TimeFormat))
 
+			var window *model.QueryWindow
+			if includeWindow {
+				window = &model.QueryWindow{StartTime: startTime, EndTime: endTime}
+			}
+
 			data := p.client.GetMetricData(gCtx, batch, namespace, startTime, endTime)
 			if data != nil {
-				mapResultsToBatch(p.logger, data, batch)
+				mapResultsToBatch(p.logger, data, batch, window)
 			} else {
 				p.logger.Warn("GetMetricData partition empty result", "start", startTime, "end", endTime)
 			}
@@ -102,16 +201,10 @@ func (p Processor) Run(ctx context.Context, namespace string, requests []*model.
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, fmt.Errorf("GetMetricData work group error: %w", err)
+		return fmt.Errorf("GetMetricData work group error: %w", err)
 	}
 
-	// Remove unprocessed/unknown elements in place, if any. Since getMetricDatas
-	// is a slice of pointers, the compaction can be easily done in-place.
-	requests = compact(requests, func(m *model.CloudwatchData) bool {
-		return m.GetMetricDataResult != nil
-	})
-
-	return requests, nil
+	return nil
 }
 
 func addQueryIDsToBatch(batch []*model.CloudwatchData) []*model.CloudwatchData {
@@ -122,11 +215,16 @@ func addQueryIDsToBatch(batch []*model.CloudwatchData) []*model.CloudwatchData {
 	return batch
 }
 
-func mapResultsToBatch(logger *slog.Logger, results []cloudwatch.MetricDataResult, batch []*model.CloudwatchData) {
+// mapResultsToBatch sets the results on the entries of the batch they were queried for. When window is
+// set, it is attached to every result along with the period of its entry.
+func mapResultsToBatch(logger *slog.Logger, results []cloudwatch.MetricDataResult, batch []*model.CloudwatchData, window *model.QueryWindow) {
 	for _, entry := range results {
 		id, err := queryIDToIndex(entry.ID)
+		if err == nil && id >= len(batch) {
+			err = fmt.Errorf("query ID index %d is out of the batch range", id)
+		}
 		if err != nil {
-			logger.Warn("GetMetricData returned unknown Query ID", "err", err, "query_id", id)
+			logger.Warn("GetMetricData returned unknown Query ID", "err", err, "query_id", entry.ID)
 			continue
 		}
 		if batch[id].GetMetricDataResult == nil {
@@ -141,6 +239,13 @@ func mapResultsToBatch(logger *slog.Logger, results []cloudwatch.MetricDataResul
 				Statistic:  cloudwatchData.GetMetricDataProcessingParams.Statistic,
 				DataPoints: mappedDataPoints,
 			}
+			if window != nil {
+				cloudwatchData.GetMetricDataResult.Window = &model.QueryWindow{
+					StartTime: window.StartTime,
+					EndTime:   window.EndTime,
+					Period:    cloudwatchData.GetMetricDataProcessingParams.Period,
+				}
+			}
 
 			// All GetMetricData processing is done clear the params
 			cloudwatchData.GetMetricDataProcessingParams = nil
@@ -148,14 +253,23 @@ func mapResultsToBatch(logger *slog.Logger, results []cloudwatch.MetricDataResul
 	}
 }
 
+// queryIDPrefix starts every query ID, as GetMetricData requires IDs to start with a lowercase letter
+// and only contain letters, numbers and underscores.
+const queryIDPrefix = "id_"
+
+// indexToQueryID builds the query ID of the i-th entry of a batch. IDs are unique within a batch, which is all
+// GetMetricData requires, regardless of the metric names and dimensions being queried.
 func indexToQueryID(i int) string {
-	return fmt.Sprintf("id_%d", i)
+	return queryIDPrefix + strconv.Itoa(i)
 }
 
+// queryIDToIndex is the inverse of indexToQueryID. It rejects any ID that indexToQueryID couldn't have built.
 func queryIDToIndex(queryID string) (int, error) {
-	noID := strings.TrimPrefix(queryID, "id_")
-	id, err := strconv.Atoi(noID)
-	return id, err
+	digits, ok := strings.CutPrefix(queryID, queryIDPrefix)
+	if !ok || digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, fmt.Errorf("malformed query ID %q", queryID)
+	}
+	return strconv.Atoi(digits)
 }
 
 func toSecondDuration(i int64) time.Duration {
@@ -15,15 +15,18 @@ package getmetricdata
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/grafana/regexp"
 	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
@@ -270,6 +273,54 @@ func getSampleMetricDatas(id string) *model.CloudwatchData {
 	}
 }
 
+func TestQueryIDs(t *testing.T) {
+	const count = 5000
+	validQueryID := regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+
+	batch := make([]*model.CloudwatchData, 0, count)
+	for i := 0; i < count; i++ {
+		batch = append(batch, &model.CloudwatchData{
+			MetricName: fmt.Sprintf("Odd.Metric-Name/%d (percent)", i),
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{
+				Statistic: "Average",
+			},
+		})
+	}
+	batch = addQueryIDsToBatch(batch)
+
+	seen := make(map[string]struct{}, count)
+	for i, entry := range batch {
+		queryID := entry.GetMetricDataProcessingParams.QueryID
+		require.Regexp(t, validQueryID, queryID)
+		_, duplicate := seen[queryID]
+		require.False(t, duplicate, "duplicate query ID %s", queryID)
+		seen[queryID] = struct{}{}
+
+		index, err := queryIDToIndex(queryID)
+		require.NoError(t, err)
+		require.Equal(t, i, index)
+	}
+
+	// Results come back in reverse order, along with IDs that can't belong to the batch.
+	results := make([]cloudwatch.MetricDataResult, 0, count+4)
+	for i := count - 1; i >= 0; i-- {
+		results = append(results, cloudwatch.MetricDataResult{
+			ID:         batch[i].GetMetricDataProcessingParams.QueryID,
+			DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(float64(i)), Timestamp: time.Now()}},
+		})
+	}
+	for _, id := range []string{indexToQueryID(count), "id_-1", "id_", "unknown"} {
+		results = append(results, cloudwatch.MetricDataResult{ID: id})
+	}
+
+	require.NotPanics(t, func() { mapResultsToBatch(promslog.NewNopLogger(), results, batch, nil) })
+	for i, entry := range batch {
+		require.NotNil(t, entry.GetMetricDataResult)
+		require.Len(t, entry.GetMetricDataResult.DataPoints, 1)
+		assert.Equal(t, float64(i), *entry.GetMetricDataResult.DataPoints[0].Value)
+	}
+}
+
 func BenchmarkProcessorRun(b *testing.B) {
 	type testcase struct {
 		concurrency        int
@@ -336,3 +387,208 @@ func doBench(b *testing.B, metricsPerQuery, testResourcesCount int, concurrency
 		r.Run(context.Background(), "anything_is_fine", datas)
 	}
 }
+
+func TestProcessor_RunSharesIdenticalQueriesThroughResultCache(t *testing.T) {
+	now := time.Now()
+	var mu sync.Mutex
+	var requestedMetrics []string
+	client := testClient{GetMetricDataFunc: func(_ context.Context, getMetricData []*model.CloudwatchData, _ string, _ time.Time, _ time.Time) []cloudwatch.MetricDataResult {
+		result := make([]cloudwatch.MetricDataResult, 0, len(getMetricData))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, data := range getMetricData {
+			requestedMetrics = append(requestedMetrics, data.MetricName)
+			result = append(result, cloudwatch.MetricDataResult{
+				ID:         data.GetMetricDataProcessingParams.QueryID,
+				DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(42), Timestamp: now}},
+			})
+		}
+		return result
+	}}
+
+	newRequest := func(metricName string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:                    metricName,
+			Dimensions:                    []model.Dimension{{Name: "InstanceId", Value: "i-abc123"}},
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Period: 60, Length: 60},
+		}
+	}
+
+	cache := NewResultCache()
+	newProcessor := func(scope string) Processor {
+		return NewDefaultProcessor(promslog.NewNopLogger(), client, 500, 1).WithResultCache(cache, scope)
+	}
+
+	jobs := []struct {
+		processor Processor
+		requests  []*model.CloudwatchData
+	}{
+		{processor: newProcessor("123456789012/us-east-1"), requests: []*model.CloudwatchData{newRequest("CPUUtilization")}},
+		{processor: newProcessor("123456789012/us-east-1"), requests: []*model.CloudwatchData{newRequest("CPUUtilization")}},
+		{processor: newProcessor("123456789012/eu-west-1"), requests: []*model.CloudwatchData{newRequest("CPUUtilization")}},
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]*model.CloudwatchData, len(jobs))
+	for i, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			results[i], err = job.processor.Run(context.Background(), "AWS/EC2", job.requests)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	// The identical queries of the first two jobs are only sent once, the third one runs in another region.
+	assert.Equal(t, []string{"CPUUtilization", "CPUUtilization"}, requestedMetrics)
+	for _, result := range results {
+		require.Len(t, result, 1)
+		require.NotNil(t, result[0].GetMetricDataResult)
+		assert.Nil(t, result[0].GetMetricDataProcessingParams)
+		assert.Equal(t, &model.GetMetricDataResult{Statistic: "Average", DataPoints: []model.DataPoint{{Value: aws.Float64(42), Timestamp: now}}}, result[0].GetMetricDataResult)
+	}
+}
+
+func TestProcessor_RunStopsOnceCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	client := testClient{GetMetricDataFunc: func(context.Context, []*model.CloudwatchData, string, time.Time, time.Time) []cloudwatch.MetricDataResult {
+		calls++
+		cancel()
+		return nil
+	}}
+
+	requests := make([]*model.CloudwatchData, 0, 3)
+	for _, metricName := range []string{"CPUUtilization", "NetworkIn", "NetworkOut"} {
+		requests = append(requests, &model.CloudwatchData{
+			MetricName:                    metricName,
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Period: 60, Length: 60},
+		})
+	}
+
+	// One metric per query and a single worker, so that the batches are sent one after the other
+	_, err := NewDefaultProcessor(promslog.NewNopLogger(), client, 1, 1).Run(ctx, "AWS/EC2", requests)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
+
+type featureFlags map[string]bool
+
+func (f featureFlags) IsFeatureEnabled(flag string) bool {
+	return f[flag]
+}
+
+func TestProcessor_RunSetsQueryWindow(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 10, 7, 10, 0, time.UTC)
+	client := testClient{GetMetricDataResultForMetrics: []metricDataResultForMetric{
+		{MetricName: "CPUUtilization", result: cloudwatch.MetricDataResult{DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(42), Timestamp: now}}}},
+	}}
+	newRequests := func() []*model.CloudwatchData {
+		return []*model.CloudwatchData{{
+			MetricName:                    "CPUUtilization",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Period: 300, Length: 600, Delay: 120},
+		}}
+	}
+	processor := NewProcessor(promslog.NewNopLogger(), client, 1, MetricWindowCalculator{clock: StubClock{currentTime: now}}, &iteratorFactory{metricsPerQuery: 500})
+
+	ctx := config.CtxWithFlags(context.Background(), featureFlags{config.ScrapeWindowLabels: true})
+	results, err := processor.Run(ctx, "AWS/EC2", newRequests())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	// The current time is rounded down to the period, then shifted by the delay.
+	assert.Equal(t, &model.QueryWindow{
+		StartTime: time.Date(2024, time.January, 1, 9, 53, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, time.January, 1, 10, 3, 0, 0, time.UTC),
+		Period:    300,
+	}, results[0].GetMetricDataResult.Window)
+
+	results, err = processor.Run(context.Background(), "AWS/EC2", newRequests())
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].GetMetricDataResult.Window)
+}
+
+func TestProcessor_RunSharesQueryWindowThroughResultCache(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 10, 7, 10, 0, time.UTC)
+	calls := 0
+	client := testClient{GetMetricDataFunc: func(_ context.Context, getMetricData []*model.CloudwatchData, _ string, _ time.Time, _ time.Time) []cloudwatch.MetricDataResult {
+		calls++
+		result := make([]cloudwatch.MetricDataResult, 0, len(getMetricData))
+		for _, data := range getMetricData {
+			result = append(result, cloudwatch.MetricDataResult{
+				ID:         data.GetMetricDataProcessingParams.QueryID,
+				DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(42), Timestamp: now}},
+			})
+		}
+		return result
+	}}
+	newRequests := func() []*model.CloudwatchData {
+		return []*model.CloudwatchData{{
+			MetricName:                    "CPUUtilization",
+			GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Period: 300, Length: 600, Delay: 120},
+		}}
+	}
+	cache := NewResultCache()
+	newProcessor := func() Processor {
+		return NewProcessor(promslog.NewNopLogger(), client, 1, MetricWindowCalculator{clock: StubClock{currentTime: now}}, &iteratorFactory{metricsPerQuery: 500}).WithResultCache(cache, "123456789012/us-east-1")
+	}
+
+	ctx := config.CtxWithFlags(context.Background(), featureFlags{config.ScrapeWindowLabels: true})
+	first, err := newProcessor().Run(ctx, "AWS/EC2", newRequests())
+	require.NoError(t, err)
+	second, err := newProcessor().Run(ctx, "AWS/EC2", newRequests())
+	require.NoError(t, err)
+
+	require.Equal(t, 1, calls)
+	require.Len(t, first, 1)
+	require.Len(t, second, 1)
+	// The job sharing the result of the query keeps its window
+	require.NotNil(t, first[0].GetMetricDataResult.Window)
+	assert.Equal(t, first[0].GetMetricDataResult.Window, second[0].GetMetricDataResult.Window)
+	assert.NotSame(t, first[0].GetMetricDataResult.Window, second[0].GetMetricDataResult.Window)
+}
+
+func TestProcessor_RunWithDefaultPeriod(t *testing.T) {
+	now := time.Date(2024, time.January, 2, 10, 7, 10, 0, time.UTC)
+	var mu sync.Mutex
+	queriedPeriods := map[string]int64{}
+	client := testClient{GetMetricDataFunc: func(_ context.Context, getMetricData []*model.CloudwatchData, _ string, _ time.Time, _ time.Time) []cloudwatch.MetricDataResult {
+		mu.Lock()
+		defer mu.Unlock()
+		result := make([]cloudwatch.MetricDataResult, 0, len(getMetricData))
+		for _, data := range getMetricData {
+			queriedPeriods[data.MetricName] = data.GetMetricDataProcessingParams.Period
+			result = append(result, cloudwatch.MetricDataResult{
+				ID:         data.GetMetricDataProcessingParams.QueryID,
+				DataPoints: []cloudwatch.DataPoint{{Value: aws.Float64(42), Timestamp: now}},
+			})
+		}
+		return result
+	}}
+	// BucketSizeBytes and NumberOfObjects use the daily period of their job, AllRequests sets its own
+	requests := []*model.CloudwatchData{
+		{MetricName: "BucketSizeBytes", GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Length: 172800}},
+		{MetricName: "NumberOfObjects", GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Average", Length: 172800}},
+		{MetricName: "AllRequests", GetMetricDataProcessingParams: &model.GetMetricDataProcessingParams{Statistic: "Sum", Period: 300, Length: 600}},
+	}
+	processor := NewProcessor(promslog.NewNopLogger(), client, 1, MetricWindowCalculator{clock: StubClock{currentTime: now}}, &iteratorFactory{metricsPerQuery: 500}).
+		WithDefaultPeriod(86400)
+
+	ctx := config.CtxWithFlags(context.Background(), featureFlags{config.ScrapeWindowLabels: true})
+	results, err := processor.Run(ctx, "AWS/S3", requests)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	require.Equal(t, map[string]int64{
+		"BucketSizeBytes": 86400,
+		"NumberOfObjects": 86400,
+		"AllRequests":     300,
+	}, queriedPeriods)
+	for _, result := range results {
+		assert.Equal(t, queriedPeriods[result.MetricName], result.GetMetricDataResult.Window.Period, result.MetricName)
+	}
+}
@@ -19,11 +19,13 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/grafana/regexp"
 	prom_model "github.com/prometheus/common/model"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 var amazonMQBrokerSuffix = regexp.MustCompile("-[0-9]+$")
@@ -37,10 +39,91 @@ type Associator struct {
 	// mappings is a slice of dimensions-based mappings, one for each regex of a given namespace
 	mappings []*dimensionsRegexpMapping
 
+	// resourceDimensions holds the dimensions extracted from the ARN of every mapped resource
+	resourceDimensions []ResourceDimensions
+
+	// allowMultipleMappings lets a resource be mapped by every regex matching its ARN,
+	// instead of only the first one
+	allowMultipleMappings bool
+
+	// unmatched accumulates the metrics skipped for not matching any resource, when a summary is enabled
+	unmatched *unmatchedSummary
+
+	// regexNoMatchCounter counts the regexes which match none of the resources, by namespace and regex
+	regexNoMatchCounter *promutil.CounterVec
+	namespace           string
+
 	logger       *slog.Logger
 	debugEnabled bool
 }
 
+// Option configures optional behaviour of an Associator.
+type Option func(*Associator)
+
+// WithMultipleMappings allows a resource to be associated through every dimensions regexp
+// matching its ARN. By default, a resource is mapped by at most one regexp so that its
+// metrics aren't double-counted.
+func WithMultipleMappings() Option {
+	return func(assoc *Associator) {
+		assoc.allowMultipleMappings = true
+	}
+}
+
+// WithUnmatchedSummary accumulates the metrics skipped because they don't match any resource,
+// so that LogUnmatchedSummary can report their count along with up to maxExamples of their
+// dimensions. Examples are picked with distinct sets of dimensions names where possible.
+func WithUnmatchedSummary(maxExamples int) Option {
+	return func(assoc *Associator) {
+		assoc.unmatched = &unmatchedSummary{maxExamples: maxExamples}
+	}
+}
+
+// WithRegexNoMatchCounter increments counter, labelled with namespace and the regex, for every dimensions
+// regexp which matches the ARN of none of the resources. A regex which stops matching after an ARN format
+// change can then be alerted on.
+func WithRegexNoMatchCounter(counter promutil.CounterVec, namespace string) Option {
+	return func(assoc *Associator) {
+		assoc.regexNoMatchCounter = &counter
+		assoc.namespace = namespace
+	}
+}
+
+// unmatchedSummary is safe for concurrent use, as metrics are associated from concurrent ListMetrics calls.
+type unmatchedSummary struct {
+	mu          sync.Mutex
+	maxExamples int
+	count       int
+	// examples are keyed by the sorted dimensions names of the metric
+	examples     map[string]string
+	exampleOrder []string
+}
+
+func (s *unmatchedSummary) record(cwMetric *model.Metric) {
+	names := make([]string, 0, len(cwMetric.Dimensions))
+	dimensions := make([]string, 0, len(cwMetric.Dimensions))
+	for _, dim := range cwMetric.Dimensions {
+		names = append(names, dim.Name)
+		dimensions = append(dimensions, fmt.Sprintf("%s=%s", dim.Name, dim.Value))
+	}
+	slices.Sort(names)
+	key := strings.Join(names, ",")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	if len(s.exampleOrder) >= s.maxExamples {
+		return
+	}
+	if _, ok := s.examples[key]; ok {
+		return
+	}
+	if s.examples == nil {
+		s.examples = map[string]string{}
+	}
+	s.examples[key] = fmt.Sprintf("%s{%s}", cwMetric.MetricName, strings.Join(dimensions, ","))
+	s.exampleOrder = append(s.exampleOrder, key)
+}
+
 type dimensionsRegexpMapping struct {
 	// dimensions is a slice of dimensions names in a regex (normally 1 name is enough
 	// to identify the resource type by its ARN, sometimes 2 or 3 dimensions names are
@@ -52,6 +135,12 @@ type dimensionsRegexpMapping struct {
 	dimensionsMapping map[uint64]*model.TaggedResource
 }
 
+// ResourceDimensions is a resource along with the CloudWatch dimensions extracted from its ARN.
+type ResourceDimensions struct {
+	Resource   *model.TaggedResource
+	Dimensions []model.Dimension
+}
+
 func (rm dimensionsRegexpMapping) toString() string {
 	sb := strings.Builder{}
 	sb.WriteString("{dimensions=[")
@@ -70,26 +159,64 @@ func (rm dimensionsRegexpMapping) toString() string {
 }
 
 // NewAssociator builds all mappings for the given dimensions regexps and list of resources.
-func NewAssociator(logger *slog.Logger, dimensionsRegexps []model.DimensionsRegexp, resources []*model.TaggedResource) Associator {
-	assoc := Associator{
-		mappings:     []*dimensionsRegexpMapping{},
-		logger:       logger,
-		debugEnabled: logger.Handler().Enabled(context.Background(), slog.LevelDebug), // caching if debug is enabled
+func NewAssociator(logger *slog.Logger, dimensionsRegexps []model.DimensionsRegexp, resources []*model.TaggedResource, opts ...Option) Associator {
+	builder := NewBuilder(logger, dimensionsRegexps, opts...)
+	builder.Add(resources)
+	return builder.Build()
+}
+
+// Builder builds the mappings of an Associator from resources added in several batches, e.g. the pages of a
+// discovery as they arrive, so that the full list of resources doesn't have to be held first. Building from
+// batches gives the same Associator as NewAssociator with all the resources, in the same order.
+type Builder struct {
+	assoc             Associator
+	dimensionsRegexps []model.DimensionsRegexp
+
+	// mappings and resourceDimensions are indexed like dimensionsRegexps
+	mappings           []*dimensionsRegexpMapping
+	resourceDimensions [][]ResourceDimensions
+
+	// matched tracks the regexes matching at least one of the resources, mapped or not
+	matched []bool
+}
+
+// NewBuilder returns a builder of the mappings of the given dimensions regexps.
+func NewBuilder(logger *slog.Logger, dimensionsRegexps []model.DimensionsRegexp, opts ...Option) *Builder {
+	b := &Builder{
+		assoc: Associator{
+			mappings:     []*dimensionsRegexpMapping{},
+			logger:       logger,
+			debugEnabled: logger.Handler().Enabled(context.Background(), slog.LevelDebug), // caching if debug is enabled
+		},
+		dimensionsRegexps:  dimensionsRegexps,
+		mappings:           make([]*dimensionsRegexpMapping, 0, len(dimensionsRegexps)),
+		resourceDimensions: make([][]ResourceDimensions, len(dimensionsRegexps)),
+		matched:            make([]bool, len(dimensionsRegexps)),
+	}
+	for _, opt := range opts {
+		opt(&b.assoc)
+	}
+	for _, dr := range dimensionsRegexps {
+		b.mappings = append(b.mappings, &dimensionsRegexpMapping{
+			dimensions:        dr.DimensionsNames,
+			dimensionsMapping: map[uint64]*model.TaggedResource{},
+		})
 	}
+	return b
+}
 
+// Add maps the given resources. The resources are only referenced by the mappings, the slice can be reused.
+func (b *Builder) Add(resources []*model.TaggedResource) {
 	// Keep track of resources that have already been mapped.
-	// Each resource will be matched against at most one regex.
+	// Unless multiple mappings are allowed, each resource will be matched against at most one regex.
 	// TODO(cristian): use a more memory-efficient data structure
 	mappedResources := make([]bool, len(resources))
 
-	for _, dr := range dimensionsRegexps {
-		m := &dimensionsRegexpMapping{
-			dimensions:        dr.DimensionsNames,
-			dimensionsMapping: map[uint64]*model.TaggedResource{},
-		}
+	for regexIdx, dr := range b.dimensionsRegexps {
+		m := b.mappings[regexIdx]
 
 		for idx, r := range resources {
-			if mappedResources[idx] {
+			if mappedResources[idx] && !b.assoc.allowMultipleMappings {
 				continue
 			}
 
@@ -99,16 +226,35 @@ func NewAssociator(logger *slog.Logger, dimensionsRegexps []model.DimensionsRege
 			}
 
 			labels := make(map[string]string, len(match))
+			dimensions := make([]model.Dimension, 0, len(match)-1)
 			for i := 1; i < len(match); i++ {
 				labels[dr.DimensionsNames[i-1]] = match[i]
+				dimensions = append(dimensions, model.Dimension{Name: dr.DimensionsNames[i-1], Value: match[i]})
 			}
 			signature := prom_model.LabelsToSignature(labels)
 			m.dimensionsMapping[signature] = r
 			mappedResources[idx] = true
+			b.resourceDimensions[regexIdx] = append(b.resourceDimensions[regexIdx], ResourceDimensions{Resource: r, Dimensions: dimensions})
+		}
+
+		// Resources already mapped by a previous regex are skipped above, so check them again
+		// to only count the regexes which really match none of the resources.
+		if b.assoc.regexNoMatchCounter != nil && !b.matched[regexIdx] {
+			b.matched[regexIdx] = len(m.dimensionsMapping) > 0 || slices.ContainsFunc(resources, func(r *model.TaggedResource) bool {
+				return dr.Regexp.MatchString(r.ARN)
+			})
 		}
+	}
+}
 
+// Build returns the Associator of the resources added so far. The builder must not be used afterwards.
+func (b *Builder) Build() Associator {
+	assoc := b.assoc
+	for i, m := range b.mappings {
 		if len(m.dimensionsMapping) > 0 {
 			assoc.mappings = append(assoc.mappings, m)
+			assoc.resourceDimensions = append(assoc.resourceDimensions, b.resourceDimensions[i]...)
+			continue
 		}
 
 		// The mapping might end up as empty in cases e.g. where
@@ -118,7 +264,10 @@ func NewAssociator(logger *slog.Logger, dimensionsRegexps []model.DimensionsRege
 		// or sub-resources) and one of them doesn't match any resource.
 		// This behaviour is ok, we just want to debug log to keep track of it.
 		if assoc.debugEnabled {
-			logger.Debug("unable to define a regex mapping", "regex", dr.Regexp.String())
+			assoc.logger.Debug("unable to define a regex mapping", "regex", b.dimensionsRegexps[i].Regexp.String())
+		}
+		if assoc.regexNoMatchCounter != nil && !b.matched[i] {
+			assoc.regexNoMatchCounter.Inc(assoc.namespace, b.dimensionsRegexps[i].Regexp.String())
 		}
 	}
 
@@ -131,17 +280,52 @@ func NewAssociator(logger *slog.Logger, dimensionsRegexps []model.DimensionsRege
 
 	if assoc.debugEnabled {
 		for idx, regexpMapping := range assoc.mappings {
-			logger.Debug("associator mapping", "mapping_idx", idx, "mapping", regexpMapping.toString())
+			assoc.logger.Debug("associator mapping", "mapping_idx", idx, "mapping", regexpMapping.toString())
 		}
 	}
 
 	return assoc
 }
 
+// ResourceDimensions returns every resource mapped by the associator, along with the dimensions
+// extracted from its ARN. Resources whose ARN didn't match any dimensions regexp are not included.
+func (assoc Associator) ResourceDimensions() []ResourceDimensions {
+	return assoc.resourceDimensions
+}
+
+// LogUnmatchedSummary logs the number of metrics skipped so far for not matching any resource,
+// with a few examples of their dimensions. It does nothing unless WithUnmatchedSummary is set.
+func (assoc Associator) LogUnmatchedSummary(namespace string) {
+	if assoc.unmatched == nil {
+		return
+	}
+
+	assoc.unmatched.mu.Lock()
+	defer assoc.unmatched.mu.Unlock()
+	if assoc.unmatched.count == 0 {
+		return
+	}
+	examples := make([]string, 0, len(assoc.unmatched.exampleOrder))
+	for _, key := range assoc.unmatched.exampleOrder {
+		examples = append(examples, assoc.unmatched.examples[key])
+	}
+	assoc.logger.Info("metrics skipped as unmatched by associator",
+		"namespace", namespace,
+		"count", assoc.unmatched.count,
+		"examples", strings.Join(examples, "; "),
+	)
+}
+
 // AssociateMetricToResource finds the resource that corresponds to the given set of dimensions
 // names and values of a metric. The guess is based on the mapping built from dimensions regexps.
 // In case a map can't be found, the second return parameter indicates whether the metric should be
 // ignored or not.
+//
+// The mappings are tried by decreasing number of dimensions, and a metric can carry more dimensions
+// than a mapping. The dimensions of the other regexes of the namespace therefore act as fallbacks:
+// e.g. an AWS/ApplicationELB metric with LoadBalancer and TargetGroup dimensions is associated with
+// its target group when it was discovered, and otherwise with its load balancer. The metric is only
+// skipped when some mapping has all its dimensions names, but none of them has a matching resource.
 func (assoc Associator) AssociateMetricToResource(cwMetric *model.Metric) (*model.TaggedResource, bool) {
 	logger := assoc.logger.With("metric_name", cwMetric.MetricName)
 
@@ -219,6 +403,9 @@ func (assoc Associator) AssociateMetricToResource(cwMetric *model.Metric) (*mode
 	// correctly map the dimensions names to a resource arn regex,
 	// but we still want to keep the metric and create a "global" metric.
 	logger.Debug("associate loop end", "skip", mappingFound)
+	if mappingFound && assoc.unmatched != nil {
+		assoc.unmatched.record(cwMetric)
+	}
 	return nil, mappingFound
 }
 
@@ -0,0 +1,106 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+var applicationLoadBalancer1 = &model.TaggedResource{
+	ARN:       "arn:aws:elasticloadbalancing:eu-central-1:123456789012:loadbalancer/app/alb-1/4a049e69add14452",
+	Namespace: "AWS/ApplicationELB",
+}
+
+var applicationTargetGroup1 = &model.TaggedResource{
+	ARN:       "arn:aws:elasticloadbalancing:eu-central-1:123456789012:targetgroup/alb-target-group-1/012e9f368748cd34",
+	Namespace: "AWS/ApplicationELB",
+}
+
+func TestAssociatorAlb(t *testing.T) {
+	type args struct {
+		dimensionRegexps []model.DimensionsRegexp
+		resources        []*model.TaggedResource
+		metric           *model.Metric
+	}
+
+	type testCase struct {
+		name             string
+		args             args
+		expectedSkip     bool
+		expectedResource *model.TaggedResource
+	}
+
+	targetGroupMetric := &model.Metric{
+		MetricName: "HealthyHostCount",
+		Namespace:  "AWS/ApplicationELB",
+		Dimensions: []model.Dimension{
+			{Name: "LoadBalancer", Value: "app/alb-1/4a049e69add14452"},
+			{Name: "TargetGroup", Value: "targetgroup/alb-target-group-2/a96cc19724cf1a87"},
+		},
+	}
+
+	testcases := []testCase{
+		{
+			name: "should match target group metric with the load balancer when only the load balancer is discovered",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/ApplicationELB").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{applicationLoadBalancer1},
+				metric:           targetGroupMetric,
+			},
+			expectedSkip:     false,
+			expectedResource: applicationLoadBalancer1,
+		},
+		{
+			name: "should match target group metric with the load balancer when its target group isn't discovered",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/ApplicationELB").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{applicationLoadBalancer1, applicationTargetGroup1},
+				metric:           targetGroupMetric,
+			},
+			expectedSkip:     false,
+			expectedResource: applicationLoadBalancer1,
+		},
+		{
+			name: "should match target group metric with the target group when it is discovered",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/ApplicationELB").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{applicationLoadBalancer1, applicationTargetGroup1},
+				metric: &model.Metric{
+					MetricName: "HealthyHostCount",
+					Namespace:  "AWS/ApplicationELB",
+					Dimensions: []model.Dimension{
+						{Name: "LoadBalancer", Value: "app/alb-1/4a049e69add14452"},
+						{Name: "TargetGroup", Value: "targetgroup/alb-target-group-1/012e9f368748cd34"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: applicationTargetGroup1,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			associator := NewAssociator(promslog.NewNopLogger(), tc.args.dimensionRegexps, tc.args.resources)
+			res, skip := associator.AssociateMetricToResource(tc.args.metric)
+			require.Equal(t, tc.expectedSkip, skip)
+			require.Equal(t, tc.expectedResource, res)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// The associator indexes every resource of a region before metrics are associated, as a metric can
+// belong to any resource returned by the tagging API. The resources are added page by page as they're
+// discovered, but the index itself still grows with them. These benchmarks track the cost of that index
+// for regions with many resources.
+
+func newLambdaResources(n int) []*model.TaggedResource {
+	resources := make([]*model.TaggedResource, 0, n)
+	for i := 0; i < n; i++ {
+		resources = append(resources, &model.TaggedResource{
+			ARN:       fmt.Sprintf("arn:aws:lambda:us-east-2:123456789012:function:function-%d", i),
+			Namespace: "AWS/Lambda",
+		})
+	}
+	return resources
+}
+
+func BenchmarkNewAssociator(b *testing.B) {
+	dimensionRegexps := config.SupportedServices.GetService("AWS/Lambda").ToModelDimensionsRegexp()
+	logger := promslog.NewNopLogger()
+
+	for _, n := range []int{1000, 10000, 50000, 100000} {
+		resources := newLambdaResources(n)
+		b.Run(fmt.Sprintf("resources=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				NewAssociator(logger, dimensionRegexps, resources)
+			}
+		})
+	}
+}
+
+func BenchmarkAssociateMetricToResource(b *testing.B) {
+	dimensionRegexps := config.SupportedServices.GetService("AWS/Lambda").ToModelDimensionsRegexp()
+	resources := newLambdaResources(50000)
+	associator := NewAssociator(promslog.NewNopLogger(), dimensionRegexps, resources)
+	metric := &model.Metric{
+		MetricName: "Invocations",
+		Namespace:  "AWS/Lambda",
+		Dimensions: []model.Dimension{{Name: "FunctionName", Value: "function-25000"}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resource, skip := associator.AssociateMetricToResource(metric)
+		if skip || resource != resources[25000] {
+			b.Fatalf("unexpected association: %v, %v", resource, skip)
+		}
+	}
+}
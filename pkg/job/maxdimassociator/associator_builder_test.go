@@ -0,0 +1,69 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/grafana/regexp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+func TestBuilder_MatchesNewAssociator(t *testing.T) {
+	layerRegex := ":layer:(?P<LayerName>[^/]+)"
+	dimensionRegexps := append([]model.DimensionsRegexp{
+		{Regexp: regexp.MustCompile(layerRegex), DimensionsNames: []string{"LayerName"}},
+	}, multiMappingDimensionRegexps...)
+	resources := append(newLambdaResources(5), multiMappingFunction, &model.TaggedResource{
+		// shares its signature with the first function, the last added one wins
+		ARN:       "arn:aws:lambda:us-east-1:123456789012:function:function-0",
+		Namespace: "AWS/Lambda",
+	})
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "single mapping"},
+		{name: "multiple mappings", opts: []Option{WithMultipleMappings()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+			opts := append(tc.opts, WithRegexNoMatchCounter(scrapeMetrics.AssociatorRegexNoMatchCounter, "AWS/Lambda"))
+			expected := NewAssociator(promslog.NewNopLogger(), dimensionRegexps, resources, opts...)
+
+			for _, pageSize := range []int{1, 2, len(resources)} {
+				builder := NewBuilder(promslog.NewNopLogger(), dimensionRegexps, opts...)
+				for page := range slices.Chunk(resources, pageSize) {
+					builder.Add(page)
+				}
+				associator := builder.Build()
+
+				require.Equal(t, expected.mappings, associator.mappings, "page size %d", pageSize)
+				require.Equal(t, expected.ResourceDimensions(), associator.ResourceDimensions(), "page size %d", pageSize)
+			}
+
+			// Every build counts the layer regex, which matches none of the resources, once
+			counter := scrapeMetrics.AssociatorRegexNoMatchCounter.Raw()
+			require.Equal(t, float64(4), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", layerRegex)))
+			require.Equal(t, float64(0), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", multiMappingDimensionRegexps[1].Regexp.String())))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"testing"
+
+	"github.com/grafana/regexp"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+var multiMappingFunction = &model.TaggedResource{
+	ARN:       "arn:aws:lambda:us-east-1:123456789012:function:multi",
+	Namespace: "AWS/Lambda",
+}
+
+// Both regexps match the same ARN, each extracting a different dimension name.
+var multiMappingDimensionRegexps = []model.DimensionsRegexp{
+	{
+		Regexp:          regexp.MustCompile(":function:(?P<FunctionName>[^/]+)"),
+		DimensionsNames: []string{"FunctionName"},
+	},
+	{
+		Regexp:          regexp.MustCompile(":function:(?P<Resource>[^/]+)"),
+		DimensionsNames: []string{"Resource"},
+	},
+}
+
+func TestAssociatorMultipleMappings(t *testing.T) {
+	functionNameMetric := &model.Metric{
+		Namespace:  "AWS/Lambda",
+		MetricName: "Invocations",
+		Dimensions: []model.Dimension{{Name: "FunctionName", Value: "multi"}},
+	}
+	resourceMetric := &model.Metric{
+		Namespace:  "AWS/Lambda",
+		MetricName: "Invocations",
+		Dimensions: []model.Dimension{{Name: "Resource", Value: "multi"}},
+	}
+
+	testcases := []struct {
+		name                       string
+		opts                       []Option
+		expectedResourceDimensions int
+		expectedResource           *model.TaggedResource
+	}{
+		{
+			name:                       "single mapping by default",
+			expectedResourceDimensions: 1,
+			expectedResource:           nil,
+		},
+		{
+			name:                       "multiple mappings when allowed",
+			opts:                       []Option{WithMultipleMappings()},
+			expectedResourceDimensions: 2,
+			expectedResource:           multiMappingFunction,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			associator := NewAssociator(promslog.NewNopLogger(), multiMappingDimensionRegexps, []*model.TaggedResource{multiMappingFunction}, tc.opts...)
+
+			require.Len(t, associator.ResourceDimensions(), tc.expectedResourceDimensions)
+
+			res, skip := associator.AssociateMetricToResource(functionNameMetric)
+			require.False(t, skip)
+			require.Equal(t, multiMappingFunction, res)
+
+			res, skip = associator.AssociateMetricToResource(resourceMetric)
+			require.False(t, skip)
+			require.Equal(t, tc.expectedResource, res)
+		})
+	}
+}
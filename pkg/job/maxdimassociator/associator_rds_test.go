@@ -0,0 +1,148 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+var auroraCluster = &model.TaggedResource{
+	ARN:       "arn:aws:rds:us-east-1:123456789012:cluster:aurora-cluster",
+	Namespace: "AWS/RDS",
+}
+
+var auroraInstance = &model.TaggedResource{
+	ARN:       "arn:aws:rds:us-east-1:123456789012:db:aurora-cluster-instance-1",
+	Namespace: "AWS/RDS",
+}
+
+var otherAuroraCluster = &model.TaggedResource{
+	ARN:       "arn:aws:rds:us-east-1:123456789012:cluster:other-cluster",
+	Namespace: "AWS/RDS",
+}
+
+func TestAssociatorRDS(t *testing.T) {
+	type args struct {
+		dimensionRegexps []model.DimensionsRegexp
+		resources        []*model.TaggedResource
+		metric           *model.Metric
+	}
+
+	type testCase struct {
+		name             string
+		args             args
+		expectedSkip     bool
+		expectedResource *model.TaggedResource
+	}
+
+	testcases := []testCase{
+		{
+			name: "should match instance metric with DBInstanceIdentifier dimension",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/RDS").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{auroraCluster, auroraInstance},
+				metric: &model.Metric{
+					MetricName: "CPUUtilization",
+					Namespace:  "AWS/RDS",
+					Dimensions: []model.Dimension{
+						{Name: "DBInstanceIdentifier", Value: "aurora-cluster-instance-1"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: auroraInstance,
+		},
+		{
+			name: "should match cluster metric with DBClusterIdentifier dimension to the cluster ARN",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/RDS").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{auroraCluster, auroraInstance},
+				metric: &model.Metric{
+					MetricName: "VolumeBytesUsed",
+					Namespace:  "AWS/RDS",
+					Dimensions: []model.Dimension{
+						{Name: "DBClusterIdentifier", Value: "aurora-cluster"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: auroraCluster,
+		},
+		{
+			name: "should match cluster metric with DBClusterIdentifier and Role dimensions to the cluster ARN",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/RDS").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{auroraCluster, auroraInstance},
+				metric: &model.Metric{
+					MetricName: "CPUUtilization",
+					Namespace:  "AWS/RDS",
+					Dimensions: []model.Dimension{
+						{Name: "DBClusterIdentifier", Value: "aurora-cluster"},
+						{Name: "Role", Value: "WRITER"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: auroraCluster,
+		},
+		{
+			name: "should skip cluster metric of a cluster which wasn't discovered",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/RDS").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{otherAuroraCluster, auroraInstance},
+				metric: &model.Metric{
+					MetricName: "VolumeBytesUsed",
+					Namespace:  "AWS/RDS",
+					Dimensions: []model.Dimension{
+						{Name: "DBClusterIdentifier", Value: "aurora-cluster"},
+					},
+				},
+			},
+			expectedSkip:     true,
+			expectedResource: nil,
+		},
+		{
+			// A cluster metric can't be attributed to one of the instances of the cluster: when only
+			// instances are discovered, e.g. because the cluster itself isn't tagged, it's kept as a global metric.
+			name: "should not match cluster metric to an instance ARN when no cluster was discovered",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/RDS").ToModelDimensionsRegexp(),
+				resources:        []*model.TaggedResource{auroraInstance},
+				metric: &model.Metric{
+					MetricName: "VolumeBytesUsed",
+					Namespace:  "AWS/RDS",
+					Dimensions: []model.Dimension{
+						{Name: "DBClusterIdentifier", Value: "aurora-cluster"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			associator := NewAssociator(promslog.NewNopLogger(), tc.args.dimensionRegexps, tc.args.resources)
+			res, skip := associator.AssociateMetricToResource(tc.args.metric)
+			require.Equal(t, tc.expectedSkip, skip)
+			require.Equal(t, tc.expectedResource, res)
+		})
+	}
+}
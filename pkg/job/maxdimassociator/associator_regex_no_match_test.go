@@ -0,0 +1,55 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"testing"
+
+	"github.com/grafana/regexp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+func TestAssociatorRegexNoMatchCounter(t *testing.T) {
+	functionRegex := ":function:(?P<FunctionName>[^/]+)"
+	// matches the same resources as functionRegex, which are already mapped when it's evaluated
+	functionResourceRegex := ":function:(?P<Resource>[^/]+)"
+	layerRegex := ":layer:(?P<LayerName>[^/]+)"
+
+	dimensionRegexps := []model.DimensionsRegexp{
+		{Regexp: regexp.MustCompile(functionRegex), DimensionsNames: []string{"FunctionName"}},
+		{Regexp: regexp.MustCompile(functionResourceRegex), DimensionsNames: []string{"Resource"}},
+		{Regexp: regexp.MustCompile(layerRegex), DimensionsNames: []string{"LayerName"}},
+	}
+
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	counter := scrapeMetrics.AssociatorRegexNoMatchCounter.Raw()
+
+	NewAssociator(promslog.NewNopLogger(), dimensionRegexps, lambdaResources,
+		WithRegexNoMatchCounter(scrapeMetrics.AssociatorRegexNoMatchCounter, "AWS/Lambda"))
+
+	require.Equal(t, float64(1), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", layerRegex)))
+	require.Equal(t, float64(0), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", functionRegex)))
+	require.Equal(t, float64(0), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", functionResourceRegex)))
+
+	// every association counts again, so a regex that keeps matching nothing keeps increasing
+	NewAssociator(promslog.NewNopLogger(), dimensionRegexps, lambdaResources,
+		WithRegexNoMatchCounter(scrapeMetrics.AssociatorRegexNoMatchCounter, "AWS/Lambda"))
+
+	require.Equal(t, float64(2), testutil.ToFloat64(counter.WithLabelValues("AWS/Lambda", layerRegex)))
+}
@@ -0,0 +1,129 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+var stateMachine = &model.TaggedResource{
+	ARN:       "arn:aws:states:us-east-1:123456789012:stateMachine:OrderProcessing",
+	Namespace: "AWS/States",
+}
+
+var stateMachineActivity = &model.TaggedResource{
+	ARN:       "arn:aws:states:us-east-1:123456789012:activity:ManualApproval",
+	Namespace: "AWS/States",
+}
+
+var statesResources = []*model.TaggedResource{
+	stateMachine,
+	stateMachineActivity,
+}
+
+func TestAssociatorStates(t *testing.T) {
+	type args struct {
+		dimensionRegexps []model.DimensionsRegexp
+		resources        []*model.TaggedResource
+		metric           *model.Metric
+	}
+
+	type testCase struct {
+		name             string
+		args             args
+		expectedSkip     bool
+		expectedResource *model.TaggedResource
+	}
+
+	testcases := []testCase{
+		{
+			name: "should match execution metric with StateMachineArn dimension",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/States").ToModelDimensionsRegexp(),
+				resources:        statesResources,
+				metric: &model.Metric{
+					Namespace:  "AWS/States",
+					MetricName: "ExecutionsSucceeded",
+					Dimensions: []model.Dimension{
+						{Name: "StateMachineArn", Value: "arn:aws:states:us-east-1:123456789012:stateMachine:OrderProcessing"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: stateMachine,
+		},
+		{
+			name: "should match activity metric with ActivityArn dimension",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/States").ToModelDimensionsRegexp(),
+				resources:        statesResources,
+				metric: &model.Metric{
+					Namespace:  "AWS/States",
+					MetricName: "ActivitiesSucceeded",
+					Dimensions: []model.Dimension{
+						{Name: "ActivityArn", Value: "arn:aws:states:us-east-1:123456789012:activity:ManualApproval"},
+					},
+				},
+			},
+			expectedSkip:     false,
+			expectedResource: stateMachineActivity,
+		},
+		{
+			name: "should not match an activity ARN as StateMachineArn",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/States").ToModelDimensionsRegexp(),
+				resources:        statesResources,
+				metric: &model.Metric{
+					Namespace:  "AWS/States",
+					MetricName: "ExecutionsSucceeded",
+					Dimensions: []model.Dimension{
+						{Name: "StateMachineArn", Value: "arn:aws:states:us-east-1:123456789012:activity:ManualApproval"},
+					},
+				},
+			},
+			expectedSkip:     true,
+			expectedResource: nil,
+		},
+		{
+			name: "should skip with unmatched StateMachineArn dimension",
+			args: args{
+				dimensionRegexps: config.SupportedServices.GetService("AWS/States").ToModelDimensionsRegexp(),
+				resources:        statesResources,
+				metric: &model.Metric{
+					Namespace:  "AWS/States",
+					MetricName: "ExecutionsFailed",
+					Dimensions: []model.Dimension{
+						{Name: "StateMachineArn", Value: "arn:aws:states:us-east-1:123456789012:stateMachine:Unknown"},
+					},
+				},
+			},
+			expectedSkip:     true,
+			expectedResource: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			associator := NewAssociator(promslog.NewNopLogger(), tc.args.dimensionRegexps, tc.args.resources)
+			res, skip := associator.AssociateMetricToResource(tc.args.metric)
+			require.Equal(t, tc.expectedSkip, skip)
+			require.Equal(t, tc.expectedResource, res)
+		})
+	}
+}
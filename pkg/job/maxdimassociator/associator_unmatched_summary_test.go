@@ -0,0 +1,68 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package maxdimassociator
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestAssociatorUnmatchedSummary(t *testing.T) {
+	newMetric := func(dimensions ...model.Dimension) *model.Metric {
+		return &model.Metric{MetricName: "Invocations", Namespace: "AWS/Lambda", Dimensions: dimensions}
+	}
+	metrics := []*model.Metric{
+		// matched
+		newMetric(model.Dimension{Name: "FunctionName", Value: "lambdaFunction"}),
+		// not mapped by any regexp, kept as a global metric
+		newMetric(model.Dimension{Name: "EventSourceMappingUUID", Value: "uuid"}),
+		// unmatched
+		newMetric(model.Dimension{Name: "FunctionName", Value: "deleted-1"}),
+		newMetric(model.Dimension{Name: "FunctionName", Value: "deleted-2"}),
+		newMetric(model.Dimension{Name: "FunctionName", Value: "deleted-3"}, model.Dimension{Name: "Resource", Value: "deleted-3:live"}),
+		newMetric(model.Dimension{Name: "Resource", Value: "deleted-4:live"}, model.Dimension{Name: "FunctionName", Value: "deleted-4"}),
+		newMetric(model.Dimension{Name: "FunctionName", Value: "deleted-5"}, model.Dimension{Name: "Resource", Value: "deleted-5"}, model.Dimension{Name: "ExecutedVersion", Value: "1"}),
+	}
+	dimensionRegexps := config.SupportedServices.GetService("AWS/Lambda").ToModelDimensionsRegexp()
+
+	t.Run("summary lists one example per set of dimensions names", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		associator := NewAssociator(logger, dimensionRegexps, lambdaResources, WithUnmatchedSummary(2))
+		for _, metric := range metrics {
+			associator.AssociateMetricToResource(metric)
+		}
+		associator.LogUnmatchedSummary("AWS/Lambda")
+
+		require.Contains(t, buf.String(), `msg="metrics skipped as unmatched by associator" namespace=AWS/Lambda count=5`)
+		require.Contains(t, buf.String(), `examples="Invocations{FunctionName=deleted-1}; Invocations{FunctionName=deleted-3,Resource=deleted-3:live}"`)
+	})
+
+	t.Run("no summary without the option", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+		associator := NewAssociator(logger, dimensionRegexps, lambdaResources)
+		for _, metric := range metrics {
+			associator.AssociateMetricToResource(metric)
+		}
+		associator.LogUnmatchedSummary("AWS/Lambda")
+
+		require.NotContains(t, buf.String(), "unmatched by associator")
+	})
+}
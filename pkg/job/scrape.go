@@ -17,16 +17,73 @@ import (
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
 	emconfig "github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics/config"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job/getmetricdata"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
+// ScrapeOption configures optional behaviour of ScrapeAwsData.
+type ScrapeOption func(*scrapeOptions)
+
+type scrapeOptions struct {
+	discoveryRetries   int
+	discoveryBackoff   time.Duration
+	accountConcurrency int
+	jobConcurrency     int
+	namespaceJobShare  float64
+}
+
+// WithDiscoveryRetries retries a failed resource discovery up to retries times. The first retry
+// waits for backoff, which is doubled before every following retry.
+func WithDiscoveryRetries(retries int, backoff time.Duration) ScrapeOption {
+	return func(o *scrapeOptions) {
+		o.discoveryRetries = retries
+		o.discoveryBackoff = backoff
+	}
+}
+
+// WithAccountConcurrency bounds how many account and alias lookups, one per job, role and region, run at
+// the same time. Zero doesn't bound them.
+func WithAccountConcurrency(concurrency int) ScrapeOption {
+	return func(o *scrapeOptions) {
+		o.accountConcurrency = concurrency
+	}
+}
+
+// WithJobConcurrency bounds how many jobs, one per job, role and region, run at the same time, and the share
+// of them, between 0 and 1, the jobs of a single namespace can take. A namespace can always run at least one
+// job. Zero concurrency doesn't bound them.
+func WithJobConcurrency(concurrency int, namespaceShare float64) ScrapeOption {
+	return func(o *scrapeOptions) {
+		o.jobConcurrency = concurrency
+		o.namespaceJobShare = namespaceShare
+	}
+}
+
+// JobRuns counts the runs of the jobs of a scrape, one per job, role and region.
+type JobRuns struct {
+	// Succeeded is the number of runs which completed, even without finding resources or metrics.
+	Succeeded int
+	// Failed is the number of runs which failed, e.g. because the account couldn't be looked up or the
+	// discovery failed, or were skipped because the scrape was canceled.
+	Failed int
+}
+
+// Total returns the number of runs of the scrape.
+func (r JobRuns) Total() int {
+	return r.Succeeded + r.Failed
+}
+
 func ScrapeAwsData(
 	ctx context.Context,
 	logger *slog.Logger,
@@ -35,7 +92,30 @@ func ScrapeAwsData(
 	metricsPerQuery int,
 	cloudwatchConcurrency cloudwatch.ConcurrencyConfig,
 	taggingAPIConcurrency int,
-) ([]model.TaggedResourceResult, []model.CloudwatchMetricResult) {
+	scrapeMetrics *promutil.ScrapeMetrics,
+	opts ...ScrapeOption,
+) ([]model.TaggedResourceResult, []model.CloudwatchMetricResult, JobRuns) {
+	var options scrapeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var accountSem chan struct{}
+	if options.accountConcurrency > 0 {
+		accountSem = make(chan struct{}, options.accountConcurrency)
+	}
+	slots := newJobSlots(options.jobConcurrency, options.namespaceJobShare)
+
+	var succeededRuns, failedRuns atomic.Int64
+	// recordRun counts a run as succeeded when *succeeded is set once it returns
+	recordRun := func(succeeded *bool) {
+		if *succeeded {
+			succeededRuns.Add(1)
+		} else {
+			failedRuns.Add(1)
+		}
+	}
+
 	mux := &sync.Mutex{}
 	cwData := make([]model.CloudwatchMetricResult, 0)
 	awsInfoData := make([]model.TaggedResourceResult, 0)
@@ -44,6 +124,24 @@ func ScrapeAwsData(
 	var enhancedMetricsService *enhancedmetrics.Service
 	var enhancedMetricsInitFailed bool
 
+	partitionLabel := config.FlagsFromCtx(ctx).IsFeatureEnabled(config.PartitionLabel)
+	omitGlobalNameLabel := config.FlagsFromCtx(ctx).IsFeatureEnabled(config.OmitGlobalNameLabel)
+
+	// Shared by the discovery jobs, so that jobs discovering the same resources only call the tagging APIs once.
+	resourceCache := tagging.NewResourceCache()
+	for _, discoveryJob := range jobsCfg.DiscoveryJobs {
+		for _, role := range discoveryJob.Roles {
+			for _, region := range discoveryJob.Regions {
+				resourceCache.Expect(discoveryJob, region, role)
+			}
+		}
+	}
+
+	var gmdCache *getmetricdata.ResultCache
+	if config.FlagsFromCtx(ctx).IsFeatureEnabled(config.DedupeGetMetricDataQueries) {
+		gmdCache = getmetricdata.NewResultCache()
+	}
+
 	for _, discoveryJob := range jobsCfg.DiscoveryJobs {
 		// initialize enhanced metrics service only if:
 		// - the current discovery job has enhanced metrics configured
@@ -54,6 +152,7 @@ func ScrapeAwsData(
 				enhancedMetricsService = enhancedmetrics.NewService(
 					configProvider,
 					enhancedmetrics.DefaultEnhancedMetricServiceRegistry,
+					scrapeMetrics,
 				)
 			} else {
 				enhancedMetricsInitFailed = true
@@ -61,65 +160,105 @@ func ScrapeAwsData(
 			}
 		}
 
+		// Shared by all the regions and roles of the job, so that its enhanced metrics concurrency bounds them all.
+		jobEnhancedMetricsService := enhancedMetricsServiceForJob(discoveryJob, enhancedMetricsService)
+
+		// Every role and region of the job runs its discovery and CloudWatch queries in its own goroutine, so
+		// the queries of a region start as soon as its resources are discovered, while other regions still discover.
 		for _, role := range discoveryJob.Roles {
 			for _, region := range discoveryJob.Regions {
 				wg.Add(1)
 				go func(discoveryJob model.DiscoveryJob, region string, role model.Role) {
 					defer wg.Done()
+					succeeded := false
+					defer recordRun(&succeeded)
+					release, err := slots.acquire(ctx, discoveryJob.Namespace)
+					if err != nil {
+						logger.Debug("Scrape canceled, skipping the job", "namespace", discoveryJob.Namespace, "region", region, "err", err)
+						return
+					}
+					defer release()
+					start := time.Now()
+					defer func() {
+						scrapeMetrics.JobScrapeDurationHistogram.Observe(time.Since(start).Seconds(), discoveryJob.Namespace, region)
+					}()
 					jobLogger := logger.With("namespace", discoveryJob.Namespace, "region", region, "arn", role.RoleArn)
-					accountID, err := factory.GetAccountClient(region, role).GetAccount(ctx)
+					accountID, accountAlias, err := getAccount(ctx, jobLogger, factory.GetAccountClient(region, role), accountSem)
 					if err != nil {
 						jobLogger.Error("Couldn't get account Id", "err", err)
 						return
 					}
 					jobLogger = jobLogger.With("account", accountID)
-
-					accountAlias, err := factory.GetAccountClient(region, role).GetAccountAlias(ctx)
-					if err != nil {
-						jobLogger.Warn("Couldn't get account alias", "err", err)
+					if ctx.Err() != nil {
+						jobLogger.Debug("Scrape canceled, skipping the job", "err", ctx.Err())
+						return
 					}
 
 					cloudwatchClient := factory.GetCloudwatchClient(region, role, cloudwatchConcurrency)
-					gmdProcessor := getmetricdata.NewDefaultProcessor(logger, cloudwatchClient, metricsPerQuery, cloudwatchConcurrency.GetMetricData)
+					gmdProcessor := getmetricdata.NewDefaultProcessor(logger, cloudwatchClient, metricsPerQuery, cloudwatchConcurrency.GetMetricData).
+						WithDefaultPeriod(discoveryJob.DefaultPeriodOverride)
+					if gmdCache != nil {
+						gmdProcessor = gmdProcessor.WithResultCache(gmdCache, accountID+"/"+region)
+					}
 
-					resources, metrics := runDiscoveryJob(
+					resources, metrics, recentlyActive, err := runDiscoveryJob(
 						ctx,
 						jobLogger,
 						discoveryJob,
 						region,
-						factory.GetTaggingClient(region, role, taggingAPIConcurrency),
+						// Retried inside the cache, so that the jobs sharing a discovery wait for its retries
+						tagging.NewCachingClient(
+							newRetryingTaggingClient(factory.GetTaggingClient(region, role, taggingAPIConcurrency), jobLogger, options.discoveryRetries, options.discoveryBackoff),
+							resourceCache,
+							role,
+						),
 						cloudwatchClient,
 						gmdProcessor,
-						enhancedMetricsService,
+						jobEnhancedMetricsService,
 						role,
+						scrapeMetrics,
 					)
+					succeeded = err == nil
+					if err == nil {
+						// Finding no resources or metrics is still a successful run
+						scrapeMetrics.JobLastSuccessTimestampGauge.Set(float64(time.Now().UnixNano())/1e9, discoveryJob.Namespace, region, role.RoleArn)
+					}
 
 					addDataToOutput := len(metrics) != 0
 					if config.FlagsFromCtx(ctx).IsFeatureEnabled(config.AlwaysReturnInfoMetrics) {
 						addDataToOutput = addDataToOutput || len(resources) != 0
 					}
+					sc := &model.ScrapeContext{
+						Region:              region,
+						AccountID:           accountID,
+						AccountAlias:        accountAlias,
+						CustomTags:          discoveryJob.CustomTags,
+						LabelsSnakeCase:     discoveryJob.LabelsSnakeCase,
+						PartitionLabel:      partitionLabel,
+						OmitGlobalNameLabel: omitGlobalNameLabel,
+						MetricNameOverrides: discoveryJob.MetricNameOverrides,
+					}
 					if addDataToOutput {
-						sc := &model.ScrapeContext{
-							Region:       region,
-							AccountID:    accountID,
-							AccountAlias: accountAlias,
-							CustomTags:   discoveryJob.CustomTags,
-						}
 						metricResult := model.CloudwatchMetricResult{
 							Context: sc,
 							Data:    metrics,
 						}
-						resourceResult := model.TaggedResourceResult{
-							Data: resources,
-						}
-						if discoveryJob.IncludeContextOnInfoMetrics {
-							resourceResult.Context = sc
-						}
+						resourceResult := taggedResourceResult(discoveryJob, sc, resources)
+						resourceResult.RecentlyActiveARNs = recentlyActive
 
 						mux.Lock()
 						awsInfoData = append(awsInfoData, resourceResult)
 						cwData = append(cwData, metricResult)
 						mux.Unlock()
+					} else if recentlyActive != nil && len(resources) != 0 {
+						// Resources without recently active metrics are still reported as idle, without their info metrics
+						resourceResult := taggedResourceResult(discoveryJob, sc, resources)
+						resourceResult.RecentlyActiveARNs = recentlyActive
+						resourceResult.OnlyRecentlyActive = true
+
+						mux.Lock()
+						awsInfoData = append(awsInfoData, resourceResult)
+						mux.Unlock()
 					}
 				}(discoveryJob, region, role)
 			}
@@ -132,26 +271,40 @@ func ScrapeAwsData(
 				wg.Add(1)
 				go func(staticJob model.StaticJob, region string, role model.Role) {
 					defer wg.Done()
+					succeeded := false
+					defer recordRun(&succeeded)
+					release, err := slots.acquire(ctx, staticJob.Namespace)
+					if err != nil {
+						logger.Debug("Scrape canceled, skipping the job", "namespace", staticJob.Namespace, "region", region, "err", err)
+						return
+					}
+					defer release()
+					start := time.Now()
+					defer func() {
+						scrapeMetrics.JobScrapeDurationHistogram.Observe(time.Since(start).Seconds(), staticJob.Namespace, region)
+					}()
 					jobLogger := logger.With("static_job_name", staticJob.Name, "region", region, "arn", role.RoleArn)
-					accountID, err := factory.GetAccountClient(region, role).GetAccount(ctx)
+					accountID, accountAlias, err := getAccount(ctx, jobLogger, factory.GetAccountClient(region, role), accountSem)
 					if err != nil {
 						jobLogger.Error("Couldn't get account Id", "err", err)
 						return
 					}
 					jobLogger = jobLogger.With("account", accountID)
-
-					accountAlias, err := factory.GetAccountClient(region, role).GetAccountAlias(ctx)
-					if err != nil {
-						jobLogger.Warn("Couldn't get account alias", "err", err)
+					if ctx.Err() != nil {
+						jobLogger.Debug("Scrape canceled, skipping the job", "err", ctx.Err())
+						return
 					}
 
 					metrics := runStaticJob(ctx, jobLogger, staticJob, factory.GetCloudwatchClient(region, role, cloudwatchConcurrency))
+					succeeded = true
+					scrapeMetrics.JobLastSuccessTimestampGauge.Set(float64(time.Now().UnixNano())/1e9, staticJob.Namespace, region, role.RoleArn)
 					metricResult := model.CloudwatchMetricResult{
 						Context: &model.ScrapeContext{
-							Region:       region,
-							AccountID:    accountID,
-							AccountAlias: accountAlias,
-							CustomTags:   staticJob.CustomTags,
+							Region:          region,
+							AccountID:       accountID,
+							AccountAlias:    accountAlias,
+							CustomTags:      staticJob.CustomTags,
+							LabelsSnakeCase: staticJob.LabelsSnakeCase,
 						},
 						Data: metrics,
 					}
@@ -169,38 +322,119 @@ func ScrapeAwsData(
 				wg.Add(1)
 				go func(customNamespaceJob model.CustomNamespaceJob, region string, role model.Role) {
 					defer wg.Done()
+					succeeded := false
+					defer recordRun(&succeeded)
+					release, err := slots.acquire(ctx, customNamespaceJob.Namespace)
+					if err != nil {
+						logger.Debug("Scrape canceled, skipping the job", "namespace", customNamespaceJob.Namespace, "region", region, "err", err)
+						return
+					}
+					defer release()
+					start := time.Now()
+					defer func() {
+						scrapeMetrics.JobScrapeDurationHistogram.Observe(time.Since(start).Seconds(), customNamespaceJob.Namespace, region)
+					}()
 					jobLogger := logger.With("custom_metric_namespace", customNamespaceJob.Namespace, "region", region, "arn", role.RoleArn)
-					accountID, err := factory.GetAccountClient(region, role).GetAccount(ctx)
+					accountID, accountAlias, err := getAccount(ctx, jobLogger, factory.GetAccountClient(region, role), accountSem)
 					if err != nil {
 						jobLogger.Error("Couldn't get account Id", "err", err)
 						return
 					}
 					jobLogger = jobLogger.With("account", accountID)
-
-					accountAlias, err := factory.GetAccountClient(region, role).GetAccountAlias(ctx)
-					if err != nil {
-						jobLogger.Warn("Couldn't get account alias", "err", err)
+					if ctx.Err() != nil {
+						jobLogger.Debug("Scrape canceled, skipping the job", "err", ctx.Err())
+						return
 					}
 
 					cloudwatchClient := factory.GetCloudwatchClient(region, role, cloudwatchConcurrency)
 					gmdProcessor := getmetricdata.NewDefaultProcessor(logger, cloudwatchClient, metricsPerQuery, cloudwatchConcurrency.GetMetricData)
-					metrics := runCustomNamespaceJob(ctx, jobLogger, customNamespaceJob, cloudwatchClient, gmdProcessor)
-					metricResult := model.CloudwatchMetricResult{
-						Context: &model.ScrapeContext{
-							Region:       region,
-							AccountID:    accountID,
-							AccountAlias: accountAlias,
-							CustomTags:   customNamespaceJob.CustomTags,
-						},
-						Data: metrics,
+					if gmdCache != nil {
+						gmdProcessor = gmdProcessor.WithResultCache(gmdCache, accountID+"/"+region)
+					}
+					metrics, err := runCustomNamespaceJob(ctx, jobLogger, customNamespaceJob, cloudwatchClient, gmdProcessor)
+					succeeded = err == nil
+					if err == nil {
+						scrapeMetrics.JobLastSuccessTimestampGauge.Set(float64(time.Now().UnixNano())/1e9, customNamespaceJob.Namespace, region, role.RoleArn)
 					}
+					sc := &model.ScrapeContext{
+						Region:              region,
+						AccountID:           accountID,
+						AccountAlias:        accountAlias,
+						CustomTags:          customNamespaceJob.CustomTags,
+						LabelsSnakeCase:     customNamespaceJob.LabelsSnakeCase,
+						MetricNameOverrides: customNamespaceJob.MetricNameOverrides,
+					}
+					metricResults := splitBySourceAccount(sc, metrics)
 					mux.Lock()
-					cwData = append(cwData, metricResult)
+					cwData = append(cwData, metricResults...)
 					mux.Unlock()
 				}(customNamespaceJob, region, role)
 			}
 		}
 	}
 	wg.Wait()
-	return awsInfoData, cwData
+	return awsInfoData, cwData, JobRuns{Succeeded: int(succeededRuns.Load()), Failed: int(failedRuns.Load())}
+}
+
+// getAccount looks up the account ID and alias of a role in a region, waiting for a free slot of accountSem
+// when it's set. Failing to get the alias is only logged, the alias is then empty.
+func getAccount(ctx context.Context, logger *slog.Logger, client account.Client, accountSem chan struct{}) (string, string, error) {
+	if accountSem != nil {
+		select {
+		case accountSem <- struct{}{}:
+			defer func() { <-accountSem }()
+		case <-ctx.Done():
+			return "", "", ctx.Err()
+		}
+	}
+
+	accountID, err := client.GetAccount(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	accountAlias, err := client.GetAccountAlias(ctx)
+	if err != nil {
+		logger.Warn("Couldn't get account alias", "err", err, "account", accountID)
+	}
+	return accountID, accountAlias, nil
+}
+
+// taggedResourceResult builds the result used for the info metrics of a discovery job. The scrape
+// context is only attached when the job is configured with IncludeContextOnInfoMetrics, so the info
+// metrics of a job carry the same labels whether or not CloudWatch returned metrics for it.
+func taggedResourceResult(job model.DiscoveryJob, sc *model.ScrapeContext, resources []*model.TaggedResource) model.TaggedResourceResult {
+	result := model.TaggedResourceResult{
+		Data:                    resources,
+		ResourceCountGroupByTag: job.ResourceCountGroupByTag,
+		DedupeAcrossRegions:     job.DedupeInfoMetricsAcrossRegions,
+	}
+	if job.IncludeContextOnInfoMetrics {
+		result.Context = sc
+	}
+	return result
+}
+
+// splitBySourceAccount groups the metrics queried from linked accounts into results of their own, whose
+// scrape context carries the source account instead of the monitoring account. Linked accounts share the
+// region of the monitoring account, and the alias of the monitoring account doesn't apply to them.
+func splitBySourceAccount(sc *model.ScrapeContext, metrics []*model.CloudwatchData) []model.CloudwatchMetricResult {
+	results := []model.CloudwatchMetricResult{{Context: sc}}
+	bySourceAccount := map[string]int{sc.AccountID: 0}
+	for _, metric := range metrics {
+		accountID := metric.SourceAccountID
+		if accountID == "" {
+			accountID = sc.AccountID
+		}
+		idx, ok := bySourceAccount[accountID]
+		if !ok {
+			sourceContext := *sc
+			sourceContext.AccountID = accountID
+			sourceContext.AccountAlias = ""
+			idx = len(results)
+			bySourceAccount[accountID] = idx
+			results = append(results, model.CloudwatchMetricResult{Context: &sourceContext})
+		}
+		results[idx].Data = append(results[idx].Data, metric)
+	}
+	return results
 }
@@ -0,0 +1,657 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/account"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/tagging"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job/maxdimassociator"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+func TestSplitBySourceAccount_LabelsSeriesWithSourceAccount(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newData := func(sourceAccountID, service string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:      "Requests",
+			ResourceName:    "my-app",
+			Namespace:       "MyApp",
+			Dimensions:      []model.Dimension{{Name: "Service", Value: service}},
+			SourceAccountID: sourceAccountID,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}
+	}
+
+	sc := &model.ScrapeContext{
+		Region:       "eu-west-1",
+		AccountID:    "111111111111",
+		AccountAlias: "monitoring",
+		CustomTags:   []model.Tag{{Key: "team", Value: "platform"}},
+	}
+	results := splitBySourceAccount(sc, []*model.CloudwatchData{
+		newData("", "api"),
+		newData("222222222222", "api"),
+		newData("111111111111", "web"),
+		newData("222222222222", "web"),
+	})
+
+	require.Len(t, results, 2)
+	require.Same(t, sc, results[0].Context)
+	require.Len(t, results[0].Data, 2)
+	require.Equal(t, "222222222222", results[1].Context.AccountID)
+	require.Equal(t, "eu-west-1", results[1].Context.Region)
+	require.Empty(t, results[1].Context.AccountAlias)
+	require.Equal(t, sc.CustomTags, results[1].Context.CustomTags)
+	require.Len(t, results[1].Data, 2)
+
+	metrics, _, err := promutil.BuildMetrics(results, promutil.BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	accounts := make(map[string]string)
+	for _, metric := range metrics {
+		accounts[metric.Labels["dimension_Service"]+"/"+metric.Labels["account_id"]] = metric.Labels["region"]
+	}
+	require.Equal(t, map[string]string{
+		"api/111111111111": "eu-west-1",
+		"web/111111111111": "eu-west-1",
+		"api/222222222222": "eu-west-1",
+		"web/222222222222": "eu-west-1",
+	}, accounts)
+}
+
+func TestSplitBySourceAccount_KeepsEmptyResult(t *testing.T) {
+	sc := &model.ScrapeContext{Region: "eu-west-1", AccountID: "111111111111"}
+	results := splitBySourceAccount(sc, nil)
+	require.Equal(t, []model.CloudwatchMetricResult{{Context: sc}}, results)
+}
+
+// countingFactory is a clients.Factory whose clients count the calls to the CloudWatch and tagging APIs.
+type countingFactory struct {
+	cloudwatchCalls atomic.Int64
+	taggingCalls    atomic.Int64
+}
+
+func (f *countingFactory) GetCloudwatchClient(string, model.Role, cloudwatch.ConcurrencyConfig) cloudwatch.Client {
+	return countingCloudwatchClient{calls: &f.cloudwatchCalls}
+}
+
+func (f *countingFactory) GetTaggingClient(string, model.Role, int) tagging.Client {
+	return countingTaggingClient{calls: &f.taggingCalls}
+}
+
+func (f *countingFactory) GetAccountClient(string, model.Role) account.Client {
+	return staticAccountClient{}
+}
+
+type countingCloudwatchClient struct {
+	calls *atomic.Int64
+}
+
+func (c countingCloudwatchClient) ListMetrics(context.Context, string, *model.MetricConfig, bool, bool, func(page []*model.Metric)) error {
+	c.calls.Add(1)
+	return nil
+}
+
+func (c countingCloudwatchClient) GetMetricData(context.Context, []*model.CloudwatchData, string, time.Time, time.Time) []cloudwatch.MetricDataResult {
+	c.calls.Add(1)
+	return nil
+}
+
+func (c countingCloudwatchClient) GetMetricStatistics(context.Context, *slog.Logger, []model.Dimension, string, *model.MetricConfig) []*model.MetricStatisticsResult {
+	c.calls.Add(1)
+	return nil
+}
+
+type countingTaggingClient struct {
+	calls *atomic.Int64
+}
+
+func (c countingTaggingClient) GetResources(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+	c.calls.Add(1)
+	return nil, nil
+}
+
+type staticAccountClient struct{}
+
+func (staticAccountClient) GetAccount(context.Context) (string, error) {
+	return "123456789012", nil
+}
+
+func (staticAccountClient) GetAccountAlias(context.Context) (string, error) {
+	return "", nil
+}
+
+// flakyTaggingFactory is a countingFactory whose tagging client fails the first failures calls.
+type flakyTaggingFactory struct {
+	countingFactory
+	failures int64
+}
+
+func (f *flakyTaggingFactory) GetTaggingClient(string, model.Role, int) tagging.Client {
+	return flakyTaggingClient{calls: &f.taggingCalls, failures: f.failures}
+}
+
+type flakyTaggingClient struct {
+	calls    *atomic.Int64
+	failures int64
+}
+
+func (c flakyTaggingClient) GetResources(_ context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	if c.calls.Add(1) <= c.failures {
+		return nil, errors.New("throttled")
+	}
+	return []*model.TaggedResource{{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: job.Namespace, Region: region}}, nil
+}
+
+// alwaysReturnInfoMetrics enables the AlwaysReturnInfoMetrics feature flag, so that discovered resources are
+// returned without metrics.
+type alwaysReturnInfoMetrics struct{}
+
+func (alwaysReturnInfoMetrics) IsFeatureEnabled(flag string) bool {
+	return flag == config.AlwaysReturnInfoMetrics
+}
+
+func TestScrapeAwsData_DiscoveryRetries(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{}},
+		}},
+	}
+	ctx := config.CtxWithFlags(context.Background(), alwaysReturnInfoMetrics{})
+
+	t.Run("succeeds after a transient failure", func(t *testing.T) {
+		factory := &flakyTaggingFactory{failures: 1}
+		resources, _, _ := ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard, WithDiscoveryRetries(2, time.Millisecond))
+
+		require.Equal(t, int64(2), factory.taggingCalls.Load())
+		require.Len(t, resources, 1)
+		require.Len(t, resources[0].Data, 1)
+	})
+
+	t.Run("gives up once retries are exhausted", func(t *testing.T) {
+		factory := &flakyTaggingFactory{failures: 10}
+		resources, _, _ := ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard, WithDiscoveryRetries(2, time.Millisecond))
+
+		require.Equal(t, int64(3), factory.taggingCalls.Load())
+		require.Empty(t, resources)
+	})
+
+	t.Run("does not retry without retries", func(t *testing.T) {
+		factory := &flakyTaggingFactory{failures: 1}
+		resources, _, _ := ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+
+		require.Equal(t, int64(1), factory.taggingCalls.Load())
+		require.Empty(t, resources)
+	})
+}
+
+func TestScrapeAwsData_ExportRecentlyActive(t *testing.T) {
+	newJobsCfg := func(exportRecentlyActive bool) model.JobsConfig {
+		return model.JobsConfig{
+			DiscoveryJobs: []model.DiscoveryJob{{
+				Namespace:            "AWS/EC2",
+				Regions:              []string{"us-east-1"},
+				Roles:                []model.Role{{}},
+				Metrics:              []*model.MetricConfig{{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300}},
+				RecentlyActiveOnly:   true,
+				ExportRecentlyActive: exportRecentlyActive,
+			}},
+		}
+	}
+
+	// No metric is recently active, so the discovered resource is idle
+	t.Run("reports idle resources without their info metrics", func(t *testing.T) {
+		resources, data, _ := ScrapeAwsData(context.Background(), promslog.NewNopLogger(), newJobsCfg(true), &flakyTaggingFactory{}, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+
+		require.Len(t, resources, 1)
+		require.True(t, resources[0].OnlyRecentlyActive)
+		require.Empty(t, resources[0].RecentlyActiveARNs)
+		require.Empty(t, data)
+	})
+
+	t.Run("reports nothing unless enabled", func(t *testing.T) {
+		resources, data, _ := ScrapeAwsData(context.Background(), promslog.NewNopLogger(), newJobsCfg(false), &flakyTaggingFactory{}, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+
+		require.Empty(t, resources)
+		require.Empty(t, data)
+	})
+
+	t.Run("reports the info metrics with always-return-info-metrics", func(t *testing.T) {
+		ctx := config.CtxWithFlags(context.Background(), alwaysReturnInfoMetrics{})
+		resources, _, _ := ScrapeAwsData(ctx, promslog.NewNopLogger(), newJobsCfg(true), &flakyTaggingFactory{}, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+
+		require.Len(t, resources, 1)
+		require.False(t, resources[0].OnlyRecentlyActive)
+		require.NotNil(t, resources[0].RecentlyActiveARNs)
+	})
+}
+
+func TestScrapeAwsData_RecordsLastSuccessfulRun(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{}},
+		}},
+		CustomNamespaceJobs: []model.CustomNamespaceJob{{
+			Name:      "custom",
+			Namespace: "MyApp",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{RoleArn: "arn:aws:iam::123456789012:role/yace"}},
+			Metrics:   []*model.MetricConfig{{Name: "Requests", Statistics: []string{"Sum"}, Period: 300, Length: 300}},
+		}},
+		StaticJobs: []model.StaticJob{{
+			Name:      "static",
+			Namespace: "AWS/AmazonMQ",
+			Regions:   []string{"eu-west-1"},
+			Roles:     []model.Role{{RoleArn: "arn:aws:iam::123456789012:role/static"}},
+			Metrics:   []*model.MetricConfig{{Name: "CpuUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300}},
+		}},
+	}
+
+	// The discovery of the EC2 job fails, the custom namespace and static jobs succeed without finding any metric
+	factory := &flakyTaggingFactory{failures: 1}
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	before := float64(time.Now().Unix())
+	_, _, runs := ScrapeAwsData(context.Background(), promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, scrapeMetrics)
+	require.Equal(t, JobRuns{Succeeded: 2, Failed: 1}, runs)
+
+	gauge := scrapeMetrics.JobLastSuccessTimestampGauge.Raw()
+	require.Equal(t, 2, testutil.CollectAndCount(gauge))
+	require.GreaterOrEqual(t, testutil.ToFloat64(gauge.WithLabelValues("MyApp", "us-east-1", "arn:aws:iam::123456789012:role/yace")), before)
+	require.GreaterOrEqual(t, testutil.ToFloat64(gauge.WithLabelValues("AWS/AmazonMQ", "eu-west-1", "arn:aws:iam::123456789012:role/static")), before)
+}
+
+// slowAccountFactory is a countingFactory whose account lookups take a while and record how many of them
+// run at the same time.
+type slowAccountFactory struct {
+	countingFactory
+	calls       atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (f *slowAccountFactory) GetAccountClient(string, model.Role) account.Client {
+	return slowAccountClient{factory: f}
+}
+
+type slowAccountClient struct {
+	factory *slowAccountFactory
+}
+
+func (c slowAccountClient) GetAccount(context.Context) (string, error) {
+	c.factory.calls.Add(1)
+	current := c.factory.inFlight.Add(1)
+	defer c.factory.inFlight.Add(-1)
+	for {
+		observed := c.factory.maxInFlight.Load()
+		if current <= observed || c.factory.maxInFlight.CompareAndSwap(observed, current) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return "123456789012", nil
+}
+
+func (slowAccountClient) GetAccountAlias(context.Context) (string, error) {
+	return "", nil
+}
+
+func TestScrapeAwsData_BoundsAccountConcurrency(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1", "us-east-2", "eu-west-1"},
+			Roles:     []model.Role{{}, {RoleArn: "arn:aws:iam::123456789012:role/yace"}},
+		}},
+	}
+
+	factory := &slowAccountFactory{}
+	ScrapeAwsData(context.Background(), promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard, WithAccountConcurrency(2))
+
+	require.Equal(t, int32(6), factory.calls.Load())
+	require.LessOrEqual(t, factory.maxInFlight.Load(), int32(2))
+	require.Equal(t, int64(6), factory.taggingCalls.Load())
+}
+
+// starvingTaggingFactory is a countingFactory whose discoveries of the high-volume namespace hold their job
+// until the low-volume namespace has been discovered, and record how many of them run at the same time.
+type starvingTaggingFactory struct {
+	countingFactory
+	lowVolumeDone chan struct{}
+	inFlight      atomic.Int32
+	maxInFlight   atomic.Int32
+}
+
+func (f *starvingTaggingFactory) GetTaggingClient(string, model.Role, int) tagging.Client {
+	return starvingTaggingClient{factory: f}
+}
+
+type starvingTaggingClient struct {
+	factory *starvingTaggingFactory
+}
+
+func (c starvingTaggingClient) GetResources(ctx context.Context, job model.DiscoveryJob, _ string) ([]*model.TaggedResource, error) {
+	if job.Namespace == "AWS/RDS" {
+		close(c.factory.lowVolumeDone)
+		return nil, nil
+	}
+
+	current := c.factory.inFlight.Add(1)
+	defer c.factory.inFlight.Add(-1)
+	for {
+		observed := c.factory.maxInFlight.Load()
+		if current <= observed || c.factory.maxInFlight.CompareAndSwap(observed, current) {
+			break
+		}
+	}
+	select {
+	case <-c.factory.lowVolumeDone:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestScrapeAwsData_NamespaceJobShare(t *testing.T) {
+	regions := make([]string, 0, 20)
+	for i := range 20 {
+		regions = append(regions, fmt.Sprintf("region-%d", i))
+	}
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{
+			{
+				Namespace: "AWS/EC2",
+				Regions:   regions,
+				Roles:     []model.Role{{}},
+			},
+			{
+				Namespace: "AWS/RDS",
+				Regions:   []string{"us-east-1"},
+				Roles:     []model.Role{{}},
+			},
+		},
+	}
+
+	// The EC2 jobs alone would fill the pool of 4 jobs, and never finish before the RDS job has run
+	factory := &starvingTaggingFactory{lowVolumeDone: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard, WithJobConcurrency(4, 0.5))
+
+	require.NoError(t, ctx.Err(), "the low-volume namespace didn't progress")
+	select {
+	case <-factory.lowVolumeDone:
+	default:
+		require.Fail(t, "the low-volume namespace wasn't discovered")
+	}
+	require.LessOrEqual(t, factory.maxInFlight.Load(), int32(2))
+}
+
+func TestJobSlots_NamespaceShare(t *testing.T) {
+	// fill acquires the slots of the high-volume namespace until it can't get more
+	fill := func(slots *jobSlots) int {
+		acquired := 0
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			_, err := slots.acquire(ctx, "AWS/EC2")
+			cancel()
+			if err != nil {
+				return acquired
+			}
+			acquired++
+		}
+	}
+
+	t.Run("without a share a namespace starves the others", func(t *testing.T) {
+		slots := newJobSlots(4, 1)
+		require.Equal(t, 4, fill(slots))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := slots.acquire(ctx, "AWS/RDS")
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("with a share the other namespaces progress", func(t *testing.T) {
+		slots := newJobSlots(4, 0.5)
+		require.Equal(t, 2, fill(slots))
+
+		release, err := slots.acquire(context.Background(), "AWS/RDS")
+		require.NoError(t, err)
+		release()
+	})
+}
+
+func TestNewJobSlots(t *testing.T) {
+	require.Nil(t, newJobSlots(0, 0.5))
+	require.Equal(t, int64(5), newJobSlots(10, 0.5).namespaceLimit)
+	require.Equal(t, int64(10), newJobSlots(10, 1).namespaceLimit)
+	// A namespace can always run a job
+	require.Equal(t, int64(1), newJobSlots(3, 0.1).namespaceLimit)
+}
+
+// pipelinedFactory is a countingFactory whose discovery of slowRegion waits until CloudWatch has been queried
+// in another region.
+type pipelinedFactory struct {
+	countingFactory
+	slowRegion        string
+	cloudwatchStarted chan struct{}
+	once              sync.Once
+}
+
+func (f *pipelinedFactory) GetTaggingClient(region string, _ model.Role, _ int) tagging.Client {
+	return pipelinedTaggingClient{factory: f, region: region}
+}
+
+func (f *pipelinedFactory) GetCloudwatchClient(region string, _ model.Role, _ cloudwatch.ConcurrencyConfig) cloudwatch.Client {
+	return pipelinedCloudwatchClient{countingCloudwatchClient: countingCloudwatchClient{calls: &f.cloudwatchCalls}, factory: f, region: region}
+}
+
+type pipelinedTaggingClient struct {
+	factory *pipelinedFactory
+	region  string
+}
+
+func (c pipelinedTaggingClient) GetResources(ctx context.Context, job model.DiscoveryJob, region string) ([]*model.TaggedResource, error) {
+	if c.region == c.factory.slowRegion {
+		select {
+		case <-c.factory.cloudwatchStarted:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return []*model.TaggedResource{{ARN: "arn:aws:ec2:" + region + ":123456789012:instance/i-abc123", Namespace: job.Namespace, Region: region}}, nil
+}
+
+type pipelinedCloudwatchClient struct {
+	countingCloudwatchClient
+	factory *pipelinedFactory
+	region  string
+}
+
+func (c pipelinedCloudwatchClient) ListMetrics(ctx context.Context, namespace string, metric *model.MetricConfig, recentlyActiveOnly bool, includeLinkedAccounts bool, fn func(page []*model.Metric)) error {
+	if c.region != c.factory.slowRegion {
+		c.factory.once.Do(func() { close(c.factory.cloudwatchStarted) })
+	}
+	return c.countingCloudwatchClient.ListMetrics(ctx, namespace, metric, recentlyActiveOnly, includeLinkedAccounts, fn)
+}
+
+func TestScrapeAwsData_QueriesCloudwatchWhileOtherRegionsDiscover(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1", "eu-west-1"},
+			Roles:     []model.Role{{}},
+			Metrics:   []*model.MetricConfig{{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300}},
+		}},
+	}
+
+	// The discovery of eu-west-1 only completes once us-east-1 has started querying CloudWatch
+	factory := &pipelinedFactory{slowRegion: "eu-west-1", cloudwatchStarted: make(chan struct{})}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, _, runs := ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+
+	require.NoError(t, ctx.Err())
+	require.Equal(t, JobRuns{Succeeded: 2}, runs)
+}
+
+func TestScrapeAwsData_RecordsJobScrapeDuration(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1", "us-east-2"},
+			Roles:     []model.Role{{}},
+		}},
+		CustomNamespaceJobs: []model.CustomNamespaceJob{{
+			Name:      "custom",
+			Namespace: "MyApp",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{}},
+			Metrics:   []*model.MetricConfig{{Name: "Requests", Statistics: []string{"Sum"}, Period: 300, Length: 300}},
+		}},
+	}
+
+	// One of the discoveries fails, failed runs are timed as well
+	factory := &flakyTaggingFactory{failures: 1}
+	scrapeMetrics := promutil.NewScrapeMetrics(prometheus.NewRegistry())
+	ScrapeAwsData(context.Background(), promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, scrapeMetrics)
+
+	histogram := scrapeMetrics.JobScrapeDurationHistogram.Raw()
+	require.Equal(t, 3, testutil.CollectAndCount(histogram))
+	for _, labels := range [][]string{{"AWS/EC2", "us-east-1"}, {"AWS/EC2", "us-east-2"}, {"MyApp", "us-east-1"}} {
+		var m dto.Metric
+		require.NoError(t, histogram.WithLabelValues(labels...).(prometheus.Metric).Write(&m))
+		require.Equal(t, uint64(1), m.GetHistogram().GetSampleCount(), labels)
+	}
+}
+
+func TestRetryingTaggingClient_StopsWhenTheContextIsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int64
+	client := newRetryingTaggingClient(cancelingTaggingClient{calls: &calls, cancel: cancel}, promslog.NewNopLogger(), 5, time.Hour)
+
+	_, err := client.GetResources(ctx, model.DiscoveryJob{Namespace: "AWS/EC2"}, "us-east-1")
+
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, int64(1), calls.Load())
+}
+
+func TestRetryingTaggingClient_StartsPagesOver(t *testing.T) {
+	page := func(id string) []*model.TaggedResource {
+		return []*model.TaggedResource{{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/" + id, Namespace: "AWS/EC2"}}
+	}
+	client := newRetryingTaggingClient(&failingPagesTaggingClient{pages: [][]*model.TaggedResource{page("i-1"), page("i-2")}, failures: 1}, promslog.NewNopLogger(), 1, time.Millisecond)
+
+	discovered := newDiscoveredResources(promslog.NewNopLogger(), model.DiscoveryJob{Namespace: "AWS/EC2"}, "us-east-1", func() *maxdimassociator.Builder { return nil })
+	require.NoError(t, tagging.GetResourcePages(context.Background(), client, model.DiscoveryJob{Namespace: "AWS/EC2"}, "us-east-1", discovered))
+
+	// The first page handed over by the failed attempt is discarded
+	require.Equal(t, slices.Concat(page("i-1"), page("i-2")), discovered.resources)
+}
+
+// failingPagesTaggingClient fails its first attempts after handing over their first page.
+type failingPagesTaggingClient struct {
+	pages    [][]*model.TaggedResource
+	failures int
+}
+
+func (c *failingPagesTaggingClient) GetResources(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+	return nil, errors.New("not paged")
+}
+
+func (c *failingPagesTaggingClient) GetResourcePages(_ context.Context, _ model.DiscoveryJob, _ string, handler tagging.PageHandler) error {
+	for i, page := range c.pages {
+		if i == 1 && c.failures > 0 {
+			c.failures--
+			return errors.New("throttled")
+		}
+		handler.HandlePage(page)
+	}
+	return nil
+}
+
+// cancelingTaggingClient fails after canceling the context of the scrape.
+type cancelingTaggingClient struct {
+	calls  *atomic.Int64
+	cancel context.CancelFunc
+}
+
+func (c cancelingTaggingClient) GetResources(context.Context, model.DiscoveryJob, string) ([]*model.TaggedResource, error) {
+	c.calls.Add(1)
+	c.cancel()
+	return nil, errors.New("throttled")
+}
+
+func TestScrapeAwsData_CanceledContext(t *testing.T) {
+	metrics := []*model.MetricConfig{{Name: "CPUUtilization", Statistics: []string{"Average"}, Period: 300, Length: 300}}
+	jobsCfg := model.JobsConfig{
+		DiscoveryJobs: []model.DiscoveryJob{{
+			Namespace: "AWS/EC2",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{}},
+			Metrics:   metrics,
+		}},
+		StaticJobs: []model.StaticJob{{
+			Name:       "static",
+			Namespace:  "AWS/EC2",
+			Regions:    []string{"us-east-1"},
+			Roles:      []model.Role{{}},
+			Dimensions: []model.Dimension{{Name: "InstanceId", Value: "i-abc123"}},
+			Metrics:    metrics,
+		}},
+		CustomNamespaceJobs: []model.CustomNamespaceJob{{
+			Name:      "custom",
+			Namespace: "MyApp",
+			Regions:   []string{"us-east-1"},
+			Roles:     []model.Role{{}},
+			Metrics:   metrics,
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	factory := &countingFactory{}
+	resources, data, _ := ScrapeAwsData(ctx, promslog.NewNopLogger(), jobsCfg, factory, 500, cloudwatch.ConcurrencyConfig{SingleLimit: 1}, 1, promutil.Discard)
+	require.Empty(t, resources)
+	require.Empty(t, data)
+	require.Zero(t, factory.cloudwatchCalls.Load())
+	require.Zero(t, factory.taggingCalls.Load())
+}
@@ -145,10 +145,7 @@ func (s Scraper) Scrape(ctx context.Context) ([]model.TaggedResourceResult, []mo
 						return
 					}
 					if len(resources) > 0 {
-						result := model.TaggedResourceResult{
-							Context: jobContext.ToScrapeContext(job.CustomTags),
-							Data:    resources,
-						}
+						result := taggedResourceResult(job, jobContext.ToScrapeContext(job.CustomTags), resources)
 						mux.Lock()
 						resourceResults = append(resourceResults, result)
 						mux.Unlock()
@@ -186,14 +183,12 @@ func (s Scraper) Scrape(ctx context.Context) ([]model.TaggedResourceResult, []mo
 
 			jobLogger.Debug("Job run finished", "number_of_metrics", len(metricResult))
 
-			result := model.CloudwatchMetricResult{
-				Context: jobContext.ToScrapeContext(jobToRun.CustomTags()),
-				Data:    metricResult,
-			}
+			// Metrics queried from linked accounts are labelled with their source account
+			results := splitBySourceAccount(jobContext.ToScrapeContext(jobToRun.CustomTags()), metricResult)
 
 			mux.Lock()
 			defer mux.Unlock()
-			metricResults = append(metricResults, result)
+			metricResults = append(metricResults, results...)
 		}()
 	})
 	wg.Wait()
@@ -29,6 +29,7 @@ import (
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job/cloudwatchrunner"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
 )
 
 type testRunnerFactory struct {
@@ -96,8 +97,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -202,8 +204,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -295,8 +298,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -326,8 +330,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -384,8 +389,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -453,8 +459,9 @@ func TestScrapeRunner_Run(t *testing.T) {
 			jobsCfg: model.JobsConfig{
 				DiscoveryJobs: []model.DiscoveryJob{
 					{
-						Regions:   []string{"us-east-1"},
-						Namespace: "aws-namespace",
+						Regions:                     []string{"us-east-1"},
+						Namespace:                   "aws-namespace",
+						IncludeContextOnInfoMetrics: true,
 						Roles: []model.Role{
 							{RoleArn: "aws-arn-1", ExternalID: "external-id-1"},
 						},
@@ -563,3 +570,41 @@ func TestScrapeRunner_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestScrapeRunner_LabelsLinkedAccountMetricsWithSourceAccount(t *testing.T) {
+	jobsCfg := model.JobsConfig{
+		CustomNamespaceJobs: []model.CustomNamespaceJob{
+			{Regions: []string{"us-east-1"}, Namespace: "MyApp", Roles: []model.Role{{}}, IncludeLinkedAccounts: true},
+		},
+	}
+	newData := func(sourceAccountID string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:          "Requests",
+			ResourceName:        "my-app",
+			Namespace:           "MyApp",
+			Dimensions:          []model.Dimension{{Name: "Service", Value: "api"}},
+			SourceAccountID:     sourceAccountID,
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Sum", DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: time.Now()}}},
+		}
+	}
+	rf := testRunnerFactory{
+		GetAccountFunc:      func() (string, error) { return "111111111111", nil },
+		GetAccountAliasFunc: func() (string, error) { return "monitoring", nil },
+		CloudwatchRunFunc: func(context.Context, cloudwatchrunner.Job) ([]*model.CloudwatchData, error) {
+			return []*model.CloudwatchData{newData(""), newData("222222222222")}, nil
+		},
+	}
+
+	sr := job.NewScraper(promslog.NewNopLogger(), jobsCfg, &rf)
+	_, metricResults, errs := sr.Scrape(context.Background())
+	assert.Empty(t, errs)
+
+	metrics, _, err := promutil.BuildMetrics(metricResults, promutil.BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	assert.NoError(t, err)
+	accounts := make(map[string]string)
+	for _, metric := range metrics {
+		accounts[metric.Labels["account_id"]] = metric.Labels["account_alias"]
+	}
+	// The alias of the monitoring account doesn't apply to the linked account
+	assert.Equal(t, map[string]string{"111111111111": "monitoring", "222222222222": ""}, accounts)
+}
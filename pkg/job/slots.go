@@ -0,0 +1,76 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package job
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// jobSlots bounds how many jobs, one per job, role and region, run at the same time, and how many of
+// them the jobs of a single namespace can take, so that a namespace with many jobs cannot starve the others.
+type jobSlots struct {
+	pool           *semaphore.Weighted
+	namespaceLimit int64
+
+	mu         sync.Mutex
+	namespaces map[string]*semaphore.Weighted
+}
+
+// newJobSlots returns the jobSlots of a pool of concurrency jobs, of which a namespace can take namespaceShare
+// (at least one). It returns nil, which doesn't bound the jobs, when concurrency isn't positive.
+func newJobSlots(concurrency int, namespaceShare float64) *jobSlots {
+	if concurrency <= 0 {
+		return nil
+	}
+	namespaceLimit := int64(concurrency)
+	if namespaceShare > 0 && namespaceShare < 1 {
+		namespaceLimit = max(int64(float64(concurrency)*namespaceShare), 1)
+	}
+	return &jobSlots{
+		pool:           semaphore.NewWeighted(int64(concurrency)),
+		namespaceLimit: namespaceLimit,
+		namespaces:     map[string]*semaphore.Weighted{},
+	}
+}
+
+// acquire waits for a slot of the pool and of the share of namespace, and returns the function releasing them.
+// The share is acquired first, so that the jobs of a namespace over its share don't hold slots of the pool
+// while waiting.
+func (s *jobSlots) acquire(ctx context.Context, namespace string) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	s.mu.Lock()
+	namespaceSem, ok := s.namespaces[namespace]
+	if !ok {
+		namespaceSem = semaphore.NewWeighted(s.namespaceLimit)
+		s.namespaces[namespace] = namespaceSem
+	}
+	s.mu.Unlock()
+
+	if err := namespaceSem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	if err := s.pool.Acquire(ctx, 1); err != nil {
+		namespaceSem.Release(1)
+		return nil, err
+	}
+	return func() {
+		s.pool.Release(1)
+		namespaceSem.Release(1)
+	}, nil
+}
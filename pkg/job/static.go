@@ -45,6 +45,9 @@ func runStaticJob(
 				MetricMigrationParams: model.MetricMigrationParams{
 					NilToZero:              metric.NilToZero,
 					AddCloudwatchTimestamp: metric.AddCloudwatchTimestamp,
+					StatisticNames:         metric.StatisticNames,
+					NilToZeroStatistics:    metric.NilToZeroStatistics,
+					MetricType:             metric.MetricType,
 				},
 				Tags:                          nil,
 				GetMetricDataProcessingParams: nil,
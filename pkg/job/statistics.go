@@ -0,0 +1,79 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package job
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+// splitGetMetricStatisticsData separates the requests of metrics configured to use GetMetricStatistics
+// from the ones to be processed with GetMetricData.
+func splitGetMetricStatisticsData(data []*model.CloudwatchData) ([]*model.CloudwatchData, []*model.CloudwatchData) {
+	getMetricData := make([]*model.CloudwatchData, 0, len(data))
+	var getMetricStatistics []*model.CloudwatchData
+	for _, d := range data {
+		if d.GetMetricStatisticsProcessingParams != nil {
+			getMetricStatistics = append(getMetricStatistics, d)
+		} else {
+			getMetricData = append(getMetricData, d)
+		}
+	}
+	return getMetricData, getMetricStatistics
+}
+
+// runGetMetricStatistics calls GetMetricStatistics for every request, the same way static jobs do.
+// Requests for which no results were returned are dropped.
+func runGetMetricStatistics(ctx context.Context, logger *slog.Logger, clientCloudwatch cloudwatch.Client, requests []*model.CloudwatchData) []*model.CloudwatchData {
+	cw := make([]*model.CloudwatchData, 0, len(requests))
+	mux := &sync.Mutex{}
+	var wg sync.WaitGroup
+
+	for _, data := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			params := data.GetMetricStatisticsProcessingParams
+			metric := &model.MetricConfig{
+				Name:       data.MetricName,
+				Statistics: params.Statistics,
+				Period:     params.Period,
+				Length:     params.Length,
+				Delay:      params.Delay,
+			}
+
+			results := clientCloudwatch.GetMetricStatistics(ctx, logger, data.Dimensions, data.Namespace, metric)
+			if results == nil {
+				return
+			}
+
+			data.GetMetricStatisticsResult = &model.GetMetricStatisticsResult{
+				Results:    results,
+				Statistics: params.Statistics,
+			}
+			// All GetMetricStatistics processing is done clear the params
+			data.GetMetricStatisticsProcessingParams = nil
+
+			mux.Lock()
+			cw = append(cw, data)
+			mux.Unlock()
+		}()
+	}
+	wg.Wait()
+	return cw
+}
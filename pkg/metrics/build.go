@@ -13,12 +13,20 @@
 package metrics
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	prom_model "github.com/prometheus/common/model"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/clients/cloudwatch"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/internal/enhancedmetrics"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/job"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
@@ -31,6 +39,9 @@ type Scraper struct {
 	jobsCfg       model.JobsConfig
 	factory       clients.Factory
 	scrapeMetrics *promutil.ScrapeMetrics
+	staleMarkers  *staleMarkers
+	grace         *emptyResultGrace
+	metadata      *promutil.MetricsMetadata
 }
 
 // NewScraper creates a scraper with its own scrape instrumentation collectors.
@@ -46,20 +57,53 @@ func NewScraper(
 		scrapeMetrics = promutil.Discard
 	}
 	cfg.FeatureFlags = append([]string(nil), cfg.FeatureFlags...)
+
+	var metadata *promutil.MetricsMetadata
+	if cfg.MetricsMetadataFile != "" {
+		var err error
+		metadata, err = promutil.LoadMetricsMetadata(cfg.MetricsMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read metrics metadata file %s: %w", cfg.MetricsMetadataFile, err)
+		}
+	}
+
+	setEnhancedMetricsEnabled(scrapeMetrics, jobsCfg)
+
 	return &Scraper{
 		logger:        logger,
 		scrapeMetrics: scrapeMetrics,
 		cfg:           cfg,
 		jobsCfg:       jobsCfg,
 		factory:       factory,
+		staleMarkers:  &staleMarkers{},
+		grace:         &emptyResultGrace{},
+		metadata:      metadata,
 	}, nil
 }
 
+// setEnhancedMetricsEnabled sets yace_enhanced_metrics_enabled for the namespaces of the discovery jobs with
+// enhanced metrics which have an enhanced metrics service. The namespaces of a previous configuration are removed.
+func setEnhancedMetricsEnabled(scrapeMetrics *promutil.ScrapeMetrics, jobsCfg model.JobsConfig) {
+	scrapeMetrics.EnhancedMetricsEnabledGauge.Reset()
+	for _, discoveryJob := range jobsCfg.DiscoveryJobs {
+		if !discoveryJob.HasEnhancedMetrics() {
+			continue
+		}
+		if _, err := enhancedmetrics.DefaultEnhancedMetricServiceRegistry.GetEnhancedMetricsService(discoveryJob.Namespace); err != nil {
+			continue
+		}
+		scrapeMetrics.EnhancedMetricsEnabledGauge.Set(1, discoveryJob.Namespace)
+	}
+}
+
 // Scrape performs one CloudWatch scrape and converts the result into Prometheus metrics.
 func (s *Scraper) Scrape(ctx context.Context) ([]*promutil.PrometheusMetric, error) {
 	ctx = config.CtxWithFlags(ctx, featureFlagsMapFromSlice(s.cfg.FeatureFlags))
 
-	tagsData, cloudwatchData := job.ScrapeAwsData(
+	start := time.Now()
+	apiRequestsBefore, apiErrorsBefore := s.scrapeMetrics.APIRequests(), s.scrapeMetrics.APIErrors()
+
+	tagsData, cloudwatchData, jobRuns := job.ScrapeAwsData(
 		ctx,
 		s.logger,
 		s.jobsCfg,
@@ -67,16 +111,101 @@ func (s *Scraper) Scrape(ctx context.Context) ([]*promutil.PrometheusMetric, err
 		s.cfg.MetricsPerQuery,
 		toCloudWatchConcurrency(s.cfg.CloudwatchConcurrency),
 		s.cfg.TaggingAPIConcurrency,
+		s.scrapeMetrics,
+		job.WithDiscoveryRetries(s.cfg.DiscoveryRetries, s.cfg.DiscoveryRetryBackoff),
+		job.WithAccountConcurrency(s.cfg.AccountConcurrency),
+		job.WithJobConcurrency(s.cfg.JobConcurrency, s.cfg.NamespaceJobShare),
 	)
 
-	metrics, observedMetricLabels, err := promutil.BuildMetrics(cloudwatchData, s.cfg.LabelsSnakeCase, s.logger)
+	s.grace.apply(cloudwatchData)
+	cloudwatchData = promutil.CapSeriesPerMetric(s.scrapeMetrics, cloudwatchData, s.logger)
+
+	buildOpts := promutil.BuildOptions{
+		LabelsSnakeCase:        s.cfg.LabelsSnakeCase,
+		CustomTagsLabelPrefix:  s.cfg.CustomTagsLabelPrefix,
+		ARNLabelName:           s.cfg.ARNLabelName,
+		InvalidLabelNameAction: s.cfg.InvalidLabelNameAction,
+		Metadata:               s.metadata,
+	}
+	metrics, observedMetricLabels, err := promutil.BuildMetricsConcurrently(cloudwatchData, s.cfg.BuildMetricsConcurrency, buildOpts, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	if s.cfg.ClampFutureTimestamps {
+		metrics = promutil.ClampFutureTimestamps(metrics, time.Now())
+	}
+	metrics, observedMetricLabels, err = promutil.BuildNamespaceInfoMetrics(tagsData, metrics, observedMetricLabels, buildOpts, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	metrics, observedMetricLabels, err = promutil.BuildResourceCountMetrics(tagsData, metrics, observedMetricLabels, buildOpts, s.logger)
 	if err != nil {
 		return nil, err
 	}
-	metrics, observedMetricLabels = promutil.BuildNamespaceInfoMetrics(tagsData, metrics, observedMetricLabels, s.cfg.LabelsSnakeCase, s.logger)
-	metrics = promutil.EnsureLabelConsistencyAndRemoveDuplicates(s.scrapeMetrics, metrics, observedMetricLabels)
+	metrics, observedMetricLabels = promutil.BuildRecentlyActiveMetrics(tagsData, metrics, observedMetricLabels, buildOpts, s.logger)
+	if config.FlagsFromCtx(ctx).IsFeatureEnabled(config.StaleResourceMarkers) {
+		metrics, observedMetricLabels = s.staleMarkers.inject(tagsData, metrics, observedMetricLabels, s.cfg.ARNLabelName)
+	}
+	var duplicatesLogger *slog.Logger
+	if s.cfg.LogDuplicateMetrics {
+		duplicatesLogger = s.logger
+	}
+	metrics = promutil.EnsureLabelConsistencyAndRemoveDuplicatesWithLogger(s.scrapeMetrics, metrics, observedMetricLabels, duplicatesLogger)
+	metrics, err = s.enforceSeriesLimit(metrics)
+
+	resources := 0
+	for _, result := range tagsData {
+		resources += len(result.Data)
+	}
+	s.logger.Info("Scrape summary",
+		"jobs", jobRuns.Total(),
+		"failed_jobs", jobRuns.Failed,
+		"resources", resources,
+		"metrics", len(metrics),
+		"api_requests", s.scrapeMetrics.APIRequests()-apiRequestsBefore,
+		"api_errors", s.scrapeMetrics.APIErrors()-apiErrorsBefore,
+		"duration_seconds", time.Since(start).Seconds(),
+		"success", err == nil,
+	)
+
+	return metrics, err
+}
+
+// enforceSeriesLimit applies the configured MaxSeries limit to the metrics built by a scrape.
+func (s *Scraper) enforceSeriesLimit(metrics []*promutil.PrometheusMetric) ([]*promutil.PrometheusMetric, error) {
+	if s.cfg.MaxSeries <= 0 || len(metrics) <= s.cfg.MaxSeries {
+		return metrics, nil
+	}
 
-	return metrics, nil
+	s.scrapeMetrics.SeriesLimitExceededCounter.Inc()
+	s.logger.Error("Scrape exceeded the series limit", "series", len(metrics), "limit", s.cfg.MaxSeries, "action", s.cfg.SeriesLimitAction)
+
+	if s.cfg.SeriesLimitAction == config.SeriesLimitActionFail {
+		return nil, fmt.Errorf("scrape produced %d series, exceeding the limit of %d", len(metrics), s.cfg.MaxSeries)
+	}
+	return truncateSeries(metrics, s.cfg.MaxSeries), nil
+}
+
+// truncateSeries keeps limit of the metrics. The metrics are sorted by name and labels first, as their order depends
+// on the completion order of the jobs, so that the same series are kept on every scrape.
+func truncateSeries(metrics []*promutil.PrometheusMetric, limit int) []*promutil.PrometheusMetric {
+	type series struct {
+		metric    *promutil.PrometheusMetric
+		signature uint64
+	}
+	sorted := make([]series, 0, len(metrics))
+	for _, metric := range metrics {
+		sorted = append(sorted, series{metric: metric, signature: prom_model.LabelsToSignature(metric.Labels)})
+	}
+	slices.SortStableFunc(sorted, func(a, b series) int {
+		return cmp.Or(strings.Compare(a.metric.Name, b.metric.Name), cmp.Compare(a.signature, b.signature))
+	})
+
+	truncated := make([]*promutil.PrometheusMetric, 0, limit)
+	for _, s := range sorted[:limit] {
+		truncated = append(truncated, s.metric)
+	}
+	return truncated
 }
 
 type featureFlagsMap map[string]struct{}
@@ -0,0 +1,87 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metrics
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/config"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+func TestNewScraper_EnhancedMetricsEnabled(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	scrapeMetrics := promutil.NewScrapeMetrics(registry)
+
+	jobsCfg := model.JobsConfig{DiscoveryJobs: []model.DiscoveryJob{
+		{Namespace: "AWS/RDS", EnhancedMetrics: []*model.EnhancedMetricConfig{{Name: "AllocatedStorage"}}},
+		// No enhanced metrics configured
+		{Namespace: "AWS/Lambda"},
+		// No enhanced metrics service
+		{Namespace: "AWS/S3", EnhancedMetrics: []*model.EnhancedMetricConfig{{Name: "BucketCount"}}},
+	}}
+	_, err := NewScraper(promslog.NewNopLogger(), scrapeMetrics, config.DefaultConfig(), jobsCfg, nil)
+	require.NoError(t, err)
+
+	expected := `
+# HELP yace_enhanced_metrics_enabled Set to 1 for the namespaces with enhanced metrics configured and supported
+# TYPE yace_enhanced_metrics_enabled gauge
+yace_enhanced_metrics_enabled{namespace="AWS/RDS"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "yace_enhanced_metrics_enabled"))
+
+	// A scraper for a new configuration only reports its own namespaces.
+	jobsCfg = model.JobsConfig{DiscoveryJobs: []model.DiscoveryJob{
+		{Namespace: "AWS/Lambda", EnhancedMetrics: []*model.EnhancedMetricConfig{{Name: "Timeout"}}},
+	}}
+	_, err = NewScraper(promslog.NewNopLogger(), scrapeMetrics, config.DefaultConfig(), jobsCfg, nil)
+	require.NoError(t, err)
+
+	expected = `
+# HELP yace_enhanced_metrics_enabled Set to 1 for the namespaces with enhanced metrics configured and supported
+# TYPE yace_enhanced_metrics_enabled gauge
+yace_enhanced_metrics_enabled{namespace="AWS/Lambda"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "yace_enhanced_metrics_enabled"))
+}
+
+func TestTruncateSeries(t *testing.T) {
+	var metrics []*promutil.PrometheusMetric
+	for _, name := range []string{"aws_ec2_cpuutilization_average", "aws_ec2_info", "aws_rds_cpuutilization_average"} {
+		for _, region := range []string{"us-east-1", "us-west-2", "eu-west-1"} {
+			metrics = append(metrics, &promutil.PrometheusMetric{Name: name, Labels: map[string]string{"region": region}})
+		}
+	}
+
+	expected := truncateSeries(metrics, 4)
+	require.Len(t, expected, 4)
+	for i := 0; i < 10; i++ {
+		shuffled := slices.Clone(metrics)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		// The same series are kept whatever the order the jobs produced them in
+		require.Equal(t, expected, truncateSeries(shuffled, 4))
+	}
+	// Whole metric families are kept before the next ones
+	for _, metric := range expected[:3] {
+		require.Equal(t, "aws_ec2_cpuutilization_average", metric.Name)
+	}
+}
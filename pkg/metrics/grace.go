@@ -0,0 +1,104 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+type lastResult struct {
+	dataPoints []model.DataPoint
+	// remaining is the number of further empty scrapes for which dataPoints may be reused.
+	remaining int
+}
+
+// emptyResultGrace remembers the last non-empty GetMetricData result of every metric configured with
+// an EmptyResultGrace, so that a flapping metric keeps its last value for a few empty scrapes.
+type emptyResultGrace struct {
+	mu       sync.Mutex
+	previous map[string]*lastResult
+}
+
+// apply replaces empty GetMetricData results with the last non-empty result of the same metric, for
+// at most EmptyResultGrace consecutive scrapes. Metrics missing from the current scrape are forgotten.
+func (g *emptyResultGrace) apply(results []model.CloudwatchMetricResult) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	current := make(map[string]*lastResult)
+	for _, result := range results {
+		for _, data := range result.Data {
+			if data.MetricMigrationParams.EmptyResultGrace <= 0 || data.GetMetricDataResult == nil {
+				continue
+			}
+
+			key := graceKey(result.Context, data)
+			if hasDataPoint(data.GetMetricDataResult.DataPoints) {
+				current[key] = &lastResult{
+					dataPoints: data.GetMetricDataResult.DataPoints,
+					remaining:  data.MetricMigrationParams.EmptyResultGrace,
+				}
+				continue
+			}
+
+			last, ok := g.previous[key]
+			if !ok || last.remaining <= 0 {
+				continue
+			}
+			data.GetMetricDataResult.DataPoints = last.dataPoints
+			current[key] = &lastResult{dataPoints: last.dataPoints, remaining: last.remaining - 1}
+		}
+	}
+
+	g.previous = current
+}
+
+func hasDataPoint(dataPoints []model.DataPoint) bool {
+	for _, dataPoint := range dataPoints {
+		if dataPoint.Value != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// graceKey identifies a metric by the account and region it's queried from, which for the metrics of
+// linked accounts is their source account in the region of the monitoring account.
+func graceKey(ctx *model.ScrapeContext, data *model.CloudwatchData) string {
+	sb := strings.Builder{}
+	if ctx != nil {
+		sb.WriteString(ctx.AccountID)
+		sb.WriteString("|")
+		sb.WriteString(ctx.Region)
+		sb.WriteString("|")
+	}
+	sb.WriteString(data.SourceAccountID)
+	sb.WriteString("|")
+	sb.WriteString(data.Namespace)
+	sb.WriteString("|")
+	sb.WriteString(data.MetricName)
+	sb.WriteString("|")
+	sb.WriteString(data.GetMetricDataResult.Statistic)
+	sb.WriteString("|")
+	sb.WriteString(data.ResourceName)
+	for _, dimension := range data.Dimensions {
+		sb.WriteString("|")
+		sb.WriteString(dimension.Name)
+		sb.WriteString("=")
+		sb.WriteString(dimension.Value)
+	}
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package metrics
+
+import (
+	"maps"
+	"math"
+	"sync"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/promutil"
+)
+
+type resourceKey struct {
+	namespace string
+	arn       string
+}
+
+// staleMarkers remembers the series exported for every discovered resource, so that the series of a
+// resource which is no longer discovered can be exported with a NaN value for exactly one scrape.
+type staleMarkers struct {
+	mu       sync.Mutex
+	previous map[resourceKey][]*promutil.PrometheusMetric
+}
+
+// inject appends a NaN copy of every series exported in the previous scrape for resources that have vanished since,
+// and remembers the series exported for the resources discovered in the current scrape. The series are matched with
+// their resource by the arnLabelName label.
+func (s *staleMarkers) inject(
+	tagsData []model.TaggedResourceResult,
+	metrics []*promutil.PrometheusMetric,
+	observedMetricLabels map[string]model.LabelSet,
+	arnLabelName string,
+) ([]*promutil.PrometheusMetric, map[string]model.LabelSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if arnLabelName == "" {
+		arnLabelName = promutil.DefaultARNLabelName
+	}
+
+	arnToKey := make(map[string]resourceKey)
+	for _, result := range tagsData {
+		for _, resource := range result.Data {
+			arnToKey[resource.ARN] = resourceKey{namespace: resource.Namespace, arn: resource.ARN}
+		}
+	}
+
+	current := make(map[resourceKey][]*promutil.PrometheusMetric, len(arnToKey))
+	for _, metric := range metrics {
+		if key, ok := arnToKey[metric.Labels[arnLabelName]]; ok {
+			current[key] = append(current[key], metric)
+		}
+	}
+
+	for key, previousMetrics := range s.previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		for _, previous := range previousMetrics {
+			metrics = append(metrics, &promutil.PrometheusMetric{
+				Name:   previous.Name,
+				Labels: maps.Clone(previous.Labels),
+				Value:  math.NaN(),
+			})
+
+			if _, ok := observedMetricLabels[previous.Name]; !ok {
+				observedMetricLabels[previous.Name] = make(model.LabelSet, len(previous.Labels))
+			}
+			for label := range previous.Labels {
+				observedMetricLabels[previous.Name][label] = struct{}{}
+			}
+		}
+	}
+
+	s.previous = current
+	return metrics, observedMetricLabels
+}
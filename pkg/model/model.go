@@ -13,23 +13,63 @@
 package model
 
 import (
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/grafana/regexp"
 )
 
 const (
 	DefaultPeriodSeconds = int64(300)
 	DefaultLengthSeconds = int64(300)
+
+	// MaxGetMetricDataDataPoints is the maximum number of data points a GetMetricData call returns.
+	MaxGetMetricDataDataPoints = int64(100800)
+)
+
+const (
+	// MetricTypeGauge exports the metric as a gauge, the default.
+	MetricTypeGauge = "gauge"
+	// MetricTypeCounter exports the metric as a counter, e.g. for the Sum of a monotonic metric.
+	MetricTypeCounter = "counter"
+	// MetricTypeUntyped exports the metric without a type.
+	MetricTypeUntyped = "untyped"
 )
 
 type JobsConfig struct {
-	StsRegion           string
+	StsRegion string
+	// Endpoints overrides the endpoint of AWS services, keyed by one of EndpointServices.
+	Endpoints           map[string]string
 	DiscoveryJobs       []DiscoveryJob
 	StaticJobs          []StaticJob
 	CustomNamespaceJobs []CustomNamespaceJob
 }
 
+// EndpointServices lists the AWS services whose endpoint can be overridden in JobsConfig.Endpoints.
+var EndpointServices = []string{
+	"amp",
+	"apigateway",
+	"apigatewayv2",
+	"autoscaling",
+	"cloudwatch",
+	// cloudwatch.list overrides the endpoint of the ListMetrics requests only, and cloudwatch.data the endpoint
+	// of the GetMetricData and GetMetricStatistics requests only.
+	"cloudwatch.list",
+	"cloudwatch.data",
+	"dms",
+	"ec2",
+	"iam",
+	"organizations",
+	"shield",
+	"storagegateway",
+	"sts",
+	"tagging",
+}
+
 type DiscoveryJob struct {
 	Regions                     []string
 	Namespace                   string
@@ -42,10 +82,47 @@ type DiscoveryJob struct {
 	RecentlyActiveOnly          bool
 	ExportedTagsOnMetrics       []string
 	IncludeContextOnInfoMetrics bool
+	SkipZeroDimensionMetrics    bool
+	DirectQuery                 bool
+	ResourceCountGroupByTag     string
 	DimensionsRegexps           []DimensionsRegexp
 
+	// DedupeInfoMetricsAcrossRegions exports the info metric of a resource discovered in several regions only once.
+	DedupeInfoMetricsAcrossRegions bool
+	// ExportRecentlyActive reports, for jobs with RecentlyActiveOnly, which resources had recently active metrics.
+	ExportRecentlyActive bool
+	// AllowMultipleResourceMappings lets a resource be associated through every dimensions regexp matching its ARN.
+	AllowMultipleResourceMappings bool
+	// LogUnmatchedMetrics logs a summary of the metrics skipped for not matching any resource.
+	LogUnmatchedMetrics bool
+	// DimensionGranularity picks, among the metrics of the same name associated with the same resource
+	// through different sets of dimensions, the ones to query: most-specific, most-aggregate, or all when empty.
+	DimensionGranularity string
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job when set.
+	LabelsSnakeCase *bool
+	// MetricNameOverrides maps CloudWatch metric names to the name they are exported with, after the namespace prefix.
+	MetricNameOverrides map[string]string
+
 	// EnhancedMetrics holds configuration for enhanced metrics in discovery jobs. It contains a configuration for the non-CloudWatch metrics to collect.
 	EnhancedMetrics []*EnhancedMetricConfig
+	// FailOnEnhancedMetricsError drops all the metrics of the job when the enhanced metrics fail,
+	// instead of exporting the CloudWatch metrics without them.
+	FailOnEnhancedMetricsError bool
+	// EnhancedMetricsConcurrency bounds how many regions and roles of the job describe resources for the
+	// enhanced metrics at the same time. Zero doesn't bound them.
+	EnhancedMetricsConcurrency int
+
+	// RequiredTags are the tags, with their exact value, a resource must carry for its metrics to be exported.
+	// Metrics which aren't associated with such a resource are dropped.
+	RequiredTags []Tag
+
+	// KeepCrossRegionResources keeps the discovered resources whose ARN is in another region than the queried one,
+	// e.g. for global services. They are dropped otherwise.
+	KeepCrossRegionResources bool
+
+	// DefaultPeriodOverride is the period of the metrics of the job which don't set their own, i.e. whose
+	// Period is 0. Zero when the job doesn't set a period, in which case all the metrics have theirs.
+	DefaultPeriodOverride int64
 }
 
 func (d *DiscoveryJob) HasEnhancedMetrics() bool {
@@ -64,6 +141,8 @@ type StaticJob struct {
 	CustomTags []Tag
 	Dimensions []Dimension
 	Metrics    []*MetricConfig
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job when set.
+	LabelsSnakeCase *bool
 }
 
 type CustomNamespaceJob struct {
@@ -76,11 +155,85 @@ type CustomNamespaceJob struct {
 	Metrics                   []*MetricConfig
 	CustomTags                []Tag
 	DimensionNameRequirements []string
+	// LabelsSnakeCase overrides the global labels snake case setting for the metrics of this job when set.
+	LabelsSnakeCase *bool
+	// MetricNameOverrides maps CloudWatch metric names to the name they are exported with, after the namespace prefix.
+	MetricNameOverrides map[string]string
+	// IncludeLinkedAccounts also lists and queries the metrics of the accounts linked to the monitoring account.
+	IncludeLinkedAccounts bool
+	// Expressions are the metric math expressions of the job, each exported as a single series.
+	Expressions []*ExpressionConfig
+}
+
+type ExpressionConfig struct {
+	// Name is the name the result of the expression is exported with, after the namespace prefix.
+	Name       string
+	Expression string
+	Period     int64
+	Length     int64
+	Delay      int64
+	// Labels are static labels added to the exported metric.
+	Labels map[string]string
 }
 
 type Role struct {
 	RoleArn    string
 	ExternalID string
+	// ExternalIDByRegion overrides ExternalID when assuming RoleArn in some regions, e.g. when the trust policy
+	// of the role was configured differently per region.
+	ExternalIDByRegion RegionalExternalIDs
+	// CredentialProcess is an optional external command, in the same format as the AWS CLI
+	// `credential_process` setting, used to source the credentials for this role.
+	CredentialProcess string
+	// UseCurrentCredentialsForSameAccount skips assuming RoleArn when it belongs to the
+	// account of the current credentials, which are then used as-is.
+	UseCurrentCredentialsForSameAccount bool
+	// SourceRoleArn is an optional intermediate role, assumed first and then used to assume RoleArn.
+	SourceRoleArn string
+	// TaggingAPIConcurrency overrides the tagging API concurrency limit for this role. Zero uses the global limit.
+	TaggingAPIConcurrency int
+	// TaggingAPIRateLimit is the maximum number of resource discovery calls per second for this role. Zero disables it.
+	TaggingAPIRateLimit float64
+	// UseFIPSEndpoint overrides the global FIPS setting for the clients of the jobs using this role. It is set
+	// from the job, so that a role used by FIPS and non-FIPS jobs gets distinct clients.
+	UseFIPSEndpoint aws.FIPSEndpointState
+}
+
+// ExternalIDForRegion returns the external ID assuming the role in region: the one of ExternalIDByRegion for the
+// region, or ExternalID.
+func (r Role) ExternalIDForRegion(region string) string {
+	if externalID, ok := r.ExternalIDByRegion.Get(region); ok {
+		return externalID
+	}
+	return r.ExternalID
+}
+
+// RegionalExternalIDs maps regions to an external ID. It is encoded as a string, so that Role stays comparable and
+// can key the client caches.
+type RegionalExternalIDs string
+
+// NewRegionalExternalIDs encodes the external IDs of the regions. The encoding doesn't depend on the order of the map.
+func NewRegionalExternalIDs(externalIDs map[string]string) RegionalExternalIDs {
+	var sb strings.Builder
+	for _, region := range slices.Sorted(maps.Keys(externalIDs)) {
+		// Region names never contain '=', and external IDs never contain a newline.
+		sb.WriteString(region)
+		sb.WriteByte('=')
+		sb.WriteString(externalIDs[region])
+		sb.WriteByte('\n')
+	}
+	return RegionalExternalIDs(sb.String())
+}
+
+// Get returns the external ID of the region, and whether there is one.
+func (e RegionalExternalIDs) Get(region string) (string, bool) {
+	for line := range strings.Lines(string(e)) {
+		lineRegion, externalID, _ := strings.Cut(strings.TrimSuffix(line, "\n"), "=")
+		if lineRegion == region {
+			return externalID, true
+		}
+	}
+	return "", false
 }
 
 type MetricConfig struct {
@@ -92,6 +245,19 @@ type MetricConfig struct {
 	NilToZero              bool
 	AddCloudwatchTimestamp bool
 	ExportAllDataPoints    bool
+	KeepLastN              int
+	EmptyResultGrace       int
+	StatisticNames         map[string]string
+	NilToZeroStatistics    map[string]bool
+	UseGetMetricStatistics bool
+	MaxSeriesPerMetric     int
+	SampleCappedSeries     bool
+	// ExportedTags overrides the ExportedTagsOnMetrics of the job for this metric when not nil.
+	ExportedTags []string
+	// MetricType is the type the metric is exported with, one of the MetricType constants.
+	MetricType string
+	// SumWithoutDimensions are the dimensions summed away from the series of the metric.
+	SumWithoutDimensions []string
 }
 
 type DimensionsRegexp struct {
@@ -121,6 +287,8 @@ type Metric struct {
 	Dimensions []Dimension
 	MetricName string
 	Namespace  string
+	// SourceAccountID is the account owning the metric, set when it's listed including linked accounts.
+	SourceAccountID string
 }
 
 type CloudwatchMetricResult struct {
@@ -131,6 +299,16 @@ type CloudwatchMetricResult struct {
 type TaggedResourceResult struct {
 	Context *ScrapeContext
 	Data    []*TaggedResource
+	// ResourceCountGroupByTag is the tag key used to group the resource count metric, empty when it's disabled.
+	ResourceCountGroupByTag string
+	// RecentlyActiveARNs are the ARNs of the resources with recently active metrics, nil unless the job uses
+	// RecentlyActiveOnly and ExportRecentlyActive.
+	RecentlyActiveARNs map[string]struct{}
+	// OnlyRecentlyActive is set when the resources are only reported for RecentlyActiveARNs, and don't get info
+	// or resource count metrics.
+	OnlyRecentlyActive bool
+	// DedupeAcrossRegions exports the info metric of a resource discovered in several regions only once.
+	DedupeAcrossRegions bool
 }
 
 type ScrapeContext struct {
@@ -138,6 +316,14 @@ type ScrapeContext struct {
 	AccountID    string
 	AccountAlias string
 	CustomTags   []Tag
+	// LabelsSnakeCase is the labels snake case override of the job, nil when it uses the global setting.
+	LabelsSnakeCase *bool
+	// PartitionLabel adds a partition label, parsed from the resource ARN, to the metrics.
+	PartitionLabel bool
+	// OmitGlobalNameLabel drops the name label of the metrics which aren't associated with a resource.
+	OmitGlobalNameLabel bool
+	// MetricNameOverrides are the metric name overrides of the job.
+	MetricNameOverrides map[string]string
 }
 
 // CloudwatchData is an internal representation of a CloudWatch
@@ -152,8 +338,14 @@ type CloudwatchData struct {
 	Namespace    string
 	Tags         []Tag
 	Dimensions   []Dimension
+	// SourceAccountID is the linked account the metric is queried from with cross-account observability.
+	// It is empty for the metrics of the account of the scrape context.
+	SourceAccountID string
 	// GetMetricDataProcessingParams includes necessary fields to run GetMetricData
 	GetMetricDataProcessingParams *GetMetricDataProcessingParams
+	// GetMetricStatisticsProcessingParams is set instead of GetMetricDataProcessingParams for metrics
+	// of discovery and custom namespace jobs which are configured to use GetMetricStatistics
+	GetMetricStatisticsProcessingParams *GetMetricStatisticsProcessingParams
 
 	// MetricMigrationParams holds configuration values necessary when migrating the resulting metrics
 	MetricMigrationParams MetricMigrationParams
@@ -194,12 +386,24 @@ type MetricStatisticsResult struct {
 	Timestamp *time.Time
 }
 
+type GetMetricStatisticsProcessingParams struct {
+	// The statistics to be used to call GetMetricStatistics
+	Statistics []string
+
+	// Fields which impact the start and endtime for
+	Period int64
+	Length int64
+	Delay  int64
+}
+
 type GetMetricDataProcessingParams struct {
 	// QueryID is a value internal to processing used for mapping results from GetMetricData their original request
 	QueryID string
 
 	// The statistic to be used to call GetMetricData
 	Statistic string
+	// Expression is the metric math expression queried instead of the metric, when set. It has no statistic.
+	Expression string
 
 	// Fields which impact the start and endtime for
 	Period int64
@@ -211,11 +415,41 @@ type MetricMigrationParams struct {
 	NilToZero              bool
 	AddCloudwatchTimestamp bool
 	ExportAllDataPoints    bool
+	// KeepLastN is the number of most recent data points to export, when ExportAllDataPoints is disabled.
+	KeepLastN int
+	// EmptyResultGrace is the number of consecutive scrapes for which the last
+	// GetMetricData value is kept when CloudWatch returns no data points.
+	EmptyResultGrace int
+	// StatisticNames maps statistics to the name used for them in the suffix of the exported metric names.
+	StatisticNames map[string]string
+	// NilToZeroStatistics overrides NilToZero for the statistics it contains.
+	NilToZeroStatistics map[string]bool
+	// MaxSeriesPerMetric caps the number of series exported per metric name. Zero disables the cap.
+	MaxSeriesPerMetric int
+	// SampleCappedSeries keeps a deterministic sample of the series over MaxSeriesPerMetric
+	// instead of the first ones.
+	SampleCappedSeries bool
+	// MetricType is the type the metric is exported with, one of the MetricType constants. Empty exports a gauge.
+	MetricType string
+	// SumWithoutDimensions are the dimensions summed away when building the metric: the series which only differ
+	// by them are exported as a single series, without them.
+	SumWithoutDimensions []string
+	// Labels are static labels added to the metric, e.g. the ones of an expression.
+	Labels map[string]string
 }
 
 type GetMetricDataResult struct {
 	Statistic  string
 	DataPoints []DataPoint
+	// Window is the query window of the GetMetricData call, only set when the scrape-window-labels feature flag is enabled.
+	Window *QueryWindow
+}
+
+// QueryWindow is the time range and period a metric was queried with.
+type QueryWindow struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Period    int64
 }
 
 type DataPoint struct {
@@ -263,6 +497,16 @@ func (r TaggedResource) FilterThroughTags(filterTags []SearchTag) bool {
 	return tagFilterMatches == len(filterTags)
 }
 
+// HasTags returns true if the TaggedResource carries all the given tags, with the same values.
+func (r TaggedResource) HasTags(tags []Tag) bool {
+	for _, tag := range tags {
+		if !slices.Contains(r.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
 // MetricTags returns a list of tags built from the tags of
 // TaggedResource, if exportedTags is not empty.
 //
@@ -291,3 +535,25 @@ func (r TaggedResource) MetricTags(exportedTags []string) []Tag {
 	}
 	return tags
 }
+
+// BackfillRegion sets Region from the ARN when it is empty. Resources of
+// global services (e.g. CloudFront or Route53) have no region in their ARN
+// and are left untouched.
+func (r *TaggedResource) BackfillRegion() {
+	if r.Region != "" {
+		return
+	}
+	if region, ok := RegionFromARN(r.ARN); ok {
+		r.Region = region
+	}
+}
+
+// RegionFromARN returns the region segment of an ARN. It returns ok=false for
+// malformed ARNs and for ARNs of global services, which have an empty region.
+func RegionFromARN(resourceARN string) (string, bool) {
+	parsed, err := arn.Parse(resourceARN)
+	if err != nil || parsed.Region == "" {
+		return "", false
+	}
+	return parsed.Region, true
+}
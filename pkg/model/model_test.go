@@ -263,3 +263,90 @@ func Test_MetricTags(t *testing.T) {
 		})
 	}
 }
+
+func Test_BackfillRegion(t *testing.T) {
+	testCases := []struct {
+		testName       string
+		arn            string
+		region         string
+		expectedRegion string
+	}{
+		{
+			testName:       "regional arn with empty region",
+			arn:            "arn:aws:ec2:eu-west-1:123456789012:instance/i-1",
+			expectedRegion: "eu-west-1",
+		},
+		{
+			testName:       "region already set",
+			arn:            "arn:aws:ec2:eu-west-1:123456789012:instance/i-1",
+			region:         "us-east-1",
+			expectedRegion: "us-east-1",
+		},
+		{
+			testName:       "global service arn",
+			arn:            "arn:aws:cloudfront::123456789012:distribution/EDFDVBD6EXAMPLE",
+			expectedRegion: "",
+		},
+		{
+			testName:       "global service arn without account",
+			arn:            "arn:aws:route53:::hostedzone/Z1D633PJN98FT9",
+			expectedRegion: "",
+		},
+		{
+			testName:       "malformed arn",
+			arn:            "not-an-arn",
+			expectedRegion: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			res := TaggedResource{
+				ARN:       tc.arn,
+				Namespace: "AWS/Service",
+				Region:    tc.region,
+			}
+			res.BackfillRegion()
+
+			require.Equal(t, tc.expectedRegion, res.Region)
+		})
+	}
+}
+
+func Test_RegionalExternalIDs(t *testing.T) {
+	externalIDs := NewRegionalExternalIDs(map[string]string{
+		"us-east-1": "external-us",
+		// External IDs may contain '='
+		"eu-west-1": "a=b",
+	})
+	// The encoding doesn't depend on the order of the map, so that equal roles share their clients.
+	require.Equal(t, externalIDs, NewRegionalExternalIDs(map[string]string{"eu-west-1": "a=b", "us-east-1": "external-us"}))
+
+	externalID, ok := externalIDs.Get("us-east-1")
+	require.True(t, ok)
+	require.Equal(t, "external-us", externalID)
+
+	externalID, ok = externalIDs.Get("eu-west-1")
+	require.True(t, ok)
+	require.Equal(t, "a=b", externalID)
+
+	_, ok = externalIDs.Get("ap-south-1")
+	require.False(t, ok)
+
+	_, ok = NewRegionalExternalIDs(nil).Get("us-east-1")
+	require.False(t, ok)
+
+	role := Role{RoleArn: "arn:aws:iam::123456789012:role/Prometheus", ExternalID: "shared", ExternalIDByRegion: externalIDs}
+	require.Equal(t, "a=b", role.ExternalIDForRegion("eu-west-1"))
+	require.Equal(t, "shared", role.ExternalIDForRegion("ap-south-1"))
+}
+
+func Test_HasTags(t *testing.T) {
+	resource := TaggedResource{Tags: []Tag{{Key: "monitored", Value: "true"}, {Key: "team", Value: "payments"}}}
+
+	require.True(t, resource.HasTags(nil))
+	require.True(t, resource.HasTags([]Tag{{Key: "monitored", Value: "true"}}))
+	require.True(t, resource.HasTags([]Tag{{Key: "team", Value: "payments"}, {Key: "monitored", Value: "true"}}))
+	require.False(t, resource.HasTags([]Tag{{Key: "monitored", Value: "false"}}))
+	require.False(t, resource.HasTags([]Tag{{Key: "monitored", Value: "true"}, {Key: "env", Value: "production"}}))
+}
@@ -0,0 +1,118 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v2"
+)
+
+const defaultHelp = "Help is not implemented yet."
+
+// MetricMetadata is one entry of a metrics metadata file. When Namespace is set,
+// Name is a CloudWatch metric name and the entry applies to every statistic
+// exported for it. Otherwise Name is an exported Prometheus metric name.
+type MetricMetadata struct {
+	Namespace string `yaml:"namespace"`
+	Name      string `yaml:"name"`
+	Help      string `yaml:"help"`
+	Unit      string `yaml:"unit"`
+}
+
+type metricsMetadataFile struct {
+	Metrics []MetricMetadata `yaml:"metrics"`
+}
+
+type cloudwatchMetricKey struct {
+	namespace  string
+	metricName string
+}
+
+// MetricsMetadata holds curated help texts for exported metrics.
+// A nil *MetricsMetadata is valid and provides no help texts.
+type MetricsMetadata struct {
+	byPrometheusName map[string]string
+	byCloudwatchName map[cloudwatchMetricKey]string
+}
+
+// LoadMetricsMetadata reads a metrics metadata file.
+func LoadMetricsMetadata(path string) (*MetricsMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := metricsMetadataFile{}
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return nil, err
+	}
+
+	m := &MetricsMetadata{
+		byPrometheusName: make(map[string]string),
+		byCloudwatchName: make(map[cloudwatchMetricKey]string),
+	}
+	for i, entry := range file.Metrics {
+		if entry.Name == "" {
+			return nil, fmt.Errorf("metrics[%d]: name should not be empty", i)
+		}
+		if entry.Help == "" && entry.Unit == "" {
+			return nil, fmt.Errorf("metrics[%d]: %s should have a help or unit", i, entry.Name)
+		}
+
+		if entry.Namespace == "" {
+			if _, ok := m.byPrometheusName[entry.Name]; ok {
+				return nil, fmt.Errorf("metrics[%d]: duplicate entry for %s", i, entry.Name)
+			}
+			m.byPrometheusName[entry.Name] = entry.helpText()
+			continue
+		}
+
+		key := cloudwatchMetricKey{namespace: entry.Namespace, metricName: entry.Name}
+		if _, ok := m.byCloudwatchName[key]; ok {
+			return nil, fmt.Errorf("metrics[%d]: duplicate entry for %s %s", i, entry.Namespace, entry.Name)
+		}
+		m.byCloudwatchName[key] = entry.helpText()
+	}
+
+	if len(m.byPrometheusName) == 0 && len(m.byCloudwatchName) == 0 {
+		return nil, errors.New("metrics metadata file has no entries")
+	}
+
+	return m, nil
+}
+
+// help returns the help text of an exported metric, preferring an entry for
+// its Prometheus name over one for the CloudWatch metric it was built from.
+func (m *MetricsMetadata) help(namespace, metricName, promName string) string {
+	if m == nil {
+		return ""
+	}
+	if help, ok := m.byPrometheusName[promName]; ok {
+		return help
+	}
+	return m.byCloudwatchName[cloudwatchMetricKey{namespace: namespace, metricName: metricName}]
+}
+
+func (m MetricMetadata) helpText() string {
+	switch {
+	case m.Unit == "":
+		return m.Help
+	case m.Help == "":
+		return fmt.Sprintf("Unit: %s.", m.Unit)
+	default:
+		return fmt.Sprintf("%s Unit: %s.", m.Help, m.Unit)
+	}
+}
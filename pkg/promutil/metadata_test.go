@@ -0,0 +1,151 @@
+// Copyright The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package promutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/promslog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
+)
+
+func TestLoadMetricsMetadata_AppliesHelpToDesc(t *testing.T) {
+	metadata, err := LoadMetricsMetadata(filepath.Join("metadata", "aws.yml"))
+	require.NoError(t, err)
+
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newData := func(namespace, metricName, statistic string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: metricName,
+			Namespace:  namespace,
+			Dimensions: []model.Dimension{
+				{Name: "InstanceId", Value: "i-abc123"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  statistic,
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+			ResourceName: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+		}
+	}
+
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("AWS/EC2", "CPUUtilization", "Average"),
+			newData("AWS/EC2", "CPUUtilization", "Maximum"),
+			newData("AWS/RDS", "CPUUtilization", "Average"),
+			newData("AWS/EC2", "DiskReadOps", "Sum"),
+		},
+	}}
+
+	metrics, _, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", Metadata: metadata}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	ec2CPUHelp := "The percentage of allocated EC2 compute units that are currently in use on the instance. Unit: Percent."
+	expected := map[string]string{
+		"aws_ec2_cpuutilization_average": ec2CPUHelp,
+		"aws_ec2_cpuutilization_maximum": ec2CPUHelp,
+		"aws_rds_cpuutilization_average": "The percentage of CPU utilization of the DB instance. Unit: Percent.",
+		"aws_ec2_disk_read_ops_sum":      defaultHelp,
+	}
+
+	ch := make(chan prometheus.Metric, len(metrics))
+	NewPrometheusCollector(metrics).Collect(ch)
+	close(ch)
+
+	seen := make(map[string]struct{})
+	for metric := range ch {
+		desc := metric.Desc().String()
+		for name, help := range expected {
+			if strings.Contains(desc, fmt.Sprintf("fqName: %q", name)) {
+				seen[name] = struct{}{}
+				require.Contains(t, desc, fmt.Sprintf("help: %q", help), name)
+			}
+		}
+	}
+	require.Len(t, seen, len(expected))
+}
+
+func TestLoadMetricsMetadata_PrometheusNameTakesPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metadata.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+metrics:
+  - namespace: AWS/EC2
+    name: CPUUtilization
+    help: CPU utilization.
+  - name: aws_ec2_cpuutilization_maximum
+    help: Peak CPU utilization.
+    unit: Percent
+`), 0o600))
+
+	metadata, err := LoadMetricsMetadata(path)
+	require.NoError(t, err)
+	require.Equal(t, "CPU utilization.", metadata.help("AWS/EC2", "CPUUtilization", "aws_ec2_cpuutilization_average"))
+	require.Equal(t, "Peak CPU utilization. Unit: Percent.", metadata.help("AWS/EC2", "CPUUtilization", "aws_ec2_cpuutilization_maximum"))
+	require.Empty(t, metadata.help("AWS/EC2", "NetworkIn", "aws_ec2_network_in_sum"))
+
+	var nilMetadata *MetricsMetadata
+	require.Empty(t, nilMetadata.help("AWS/EC2", "CPUUtilization", "aws_ec2_cpuutilization_average"))
+}
+
+func TestLoadMetricsMetadata_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{
+			name:        "missing name",
+			content:     "metrics:\n  - namespace: AWS/EC2\n    help: CPU utilization.\n",
+			expectedErr: "name should not be empty",
+		},
+		{
+			name:        "missing help and unit",
+			content:     "metrics:\n  - namespace: AWS/EC2\n    name: CPUUtilization\n",
+			expectedErr: "should have a help or unit",
+		},
+		{
+			name:        "duplicate entry",
+			content:     "metrics:\n  - name: aws_ec2_info\n    help: a\n  - name: aws_ec2_info\n    help: b\n",
+			expectedErr: "duplicate entry for aws_ec2_info",
+		},
+		{
+			name:        "unknown field",
+			content:     "metrics:\n  - name: aws_ec2_info\n    description: a\n",
+			expectedErr: "field description not found",
+		},
+		{
+			name:        "no entries",
+			content:     "metrics: []\n",
+			expectedErr: "has no entries",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "metadata.yml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+
+			_, err := LoadMetricsMetadata(path)
+			require.ErrorContains(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -14,22 +14,59 @@ package promutil
 
 import (
 	"fmt"
+	"hash/fnv"
+	"iter"
 	"log/slog"
 	"maps"
 	"math"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/grafana/regexp"
+	"github.com/prometheus/client_golang/prometheus"
 	prom_model "github.com/prometheus/common/model"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/prometheus-community/yet-another-cloudwatch-exporter/pkg/model"
 )
 
 var Percentile = regexp.MustCompile(`^p(\d{1,2}(\.\d{0,2})?|100)$`)
 
+// InvalidLabelNameAction controls what happens to the dimensions and tags whose name isn't a valid label name.
+type InvalidLabelNameAction string
+
+const (
+	// InvalidLabelNameActionSkip logs a warning and drops the dimension or tag. It's the default.
+	InvalidLabelNameActionSkip InvalidLabelNameAction = "skip"
+	// InvalidLabelNameActionSanitize replaces the characters of the name which aren't valid in a label name
+	// with underscores, and keeps the dimension or tag.
+	InvalidLabelNameActionSanitize InvalidLabelNameAction = "sanitize"
+	// InvalidLabelNameActionFail fails the scrape.
+	InvalidLabelNameActionFail InvalidLabelNameAction = "fail"
+)
+
+// DefaultARNLabelName is the name of the label holding the ARN of the resource of the info and data metrics.
+const DefaultARNLabelName = "name"
+
+// arnLabelNameOrDefault returns arnLabelName, or DefaultARNLabelName when it's empty.
+func arnLabelNameOrDefault(arnLabelName string) string {
+	if arnLabelName == "" {
+		return DefaultARNLabelName
+	}
+	return arnLabelName
+}
+
 func BuildMetricName(namespace, metricName, statistic string) string {
+	return buildMetricName(namespace, metricName, statistic, nil)
+}
+
+// buildMetricName is BuildMetricName exporting the metrics listed in overrides with the given name after the
+// namespace prefix, instead of the one derived from their CloudWatch metric name.
+func buildMetricName(namespace, metricName, statistic string, overrides map[string]string) string {
 	sb := strings.Builder{}
 
 	// Some namespaces have a leading forward slash like
@@ -48,6 +85,15 @@ func BuildMetricName(namespace, metricName, statistic string) string {
 
 	sb.WriteString("_")
 
+	if override, ok := overrides[metricName]; ok {
+		sb.WriteString(PromString(override))
+		if statistic != "" {
+			sb.WriteString("_")
+			PromStringToBuilder(statistic, &sb)
+		}
+		return sb.String()
+	}
+
 	promMetricName := PromString(metricName)
 	// Some metric names duplicate parts of the namespace as a prefix,
 	// For example, the `Glue` namespace metrics have names prefixed also by `glue``
@@ -67,18 +113,40 @@ func BuildMetricName(namespace, metricName, statistic string) string {
 	return sb.String()
 }
 
-func BuildNamespaceInfoMetrics(tagData []model.TaggedResourceResult, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet, labelsSnakeCase bool, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet) {
+// BuildNamespaceInfoMetrics adds a <namespace>_info metric for every discovered resource, labelled with its tags.
+// Tags whose name isn't a valid label name are handled according to opts.InvalidLabelNameAction.
+func BuildNamespaceInfoMetrics(tagData []model.TaggedResourceResult, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet, opts BuildOptions, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+	arnLabelName := arnLabelNameOrDefault(opts.ARNLabelName)
+	snakeCase := resolveLabelsSnakeCase(taggedResourceNamespaces(tagData), opts.LabelsSnakeCase, logger)
+	dedupeRegions := infoMetricRegions(tagData)
+	deduped := make(map[string]struct{}, len(dedupeRegions))
 	for _, tagResult := range tagData {
-		contextLabels := contextToLabels(tagResult.Context, labelsSnakeCase, logger)
+		if tagResult.OnlyRecentlyActive {
+			continue
+		}
+
+		contextLabelsBySetting := make(map[bool]map[string]string, 1)
 		for _, d := range tagResult.Data {
+			if tagResult.DedupeAcrossRegions {
+				if _, ok := deduped[d.ARN]; ok || d.Region != dedupeRegions[d.ARN] {
+					continue
+				}
+				deduped[d.ARN] = struct{}{}
+			}
+
 			metricName := BuildMetricName(d.Namespace, "info", "")
+			resourceSnakeCase := snakeCase[d.Namespace]
+			contextLabels := contextLabelsFor(contextLabelsBySetting, tagResult.Context, resourceSnakeCase, opts.CustomTagsLabelPrefix, logger)
 
 			promLabels := make(map[string]string, len(d.Tags)+len(contextLabels)+1)
 			maps.Copy(promLabels, contextLabels)
-			promLabels["name"] = d.ARN
+			promLabels[arnLabelName] = d.ARN
 			for _, tag := range d.Tags {
-				ok, promTag := PromStringTag(tag.Key, labelsSnakeCase)
+				ok, promTag := promLabelName(tag.Key, resourceSnakeCase, opts.InvalidLabelNameAction)
 				if !ok {
+					if opts.InvalidLabelNameAction == InvalidLabelNameActionFail {
+						return nil, nil, fmt.Errorf("tag name %q of resource %s is an invalid prometheus label name", tag.Key, d.ARN)
+					}
 					logger.Warn("tag name is an invalid prometheus label name", "tag", tag.Key)
 					continue
 				}
@@ -96,79 +164,610 @@ func BuildNamespaceInfoMetrics(tagData []model.TaggedResourceResult, metrics []*
 		}
 	}
 
+	return metrics, observedMetricLabels, nil
+}
+
+// infoMetricRegions returns the region whose info metric is exported for the resources of the results deduplicated
+// across regions. It's the first one in lexical order, so that the exported series doesn't change between scrapes.
+func infoMetricRegions(tagData []model.TaggedResourceResult) map[string]string {
+	regions := make(map[string]string)
+	for _, tagResult := range tagData {
+		if !tagResult.DedupeAcrossRegions || tagResult.OnlyRecentlyActive {
+			continue
+		}
+		for _, d := range tagResult.Data {
+			if region, ok := regions[d.ARN]; !ok || d.Region < region {
+				regions[d.ARN] = d.Region
+			}
+		}
+	}
+	return regions
+}
+
+// BuildResourceCountMetrics adds a yace_<namespace>_resource_count metric counting the discovered resources of every
+// namespace configured with a ResourceCountGroupByTag, grouped by the value of that tag. A tag whose name isn't a
+// valid label name is handled according to opts.InvalidLabelNameAction.
+func BuildResourceCountMetrics(tagData []model.TaggedResourceResult, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet, opts BuildOptions, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+	counts := make(map[string]*PrometheusMetric)
+	keys := make([]string, 0)
+	snakeCase := resolveLabelsSnakeCase(taggedResourceNamespaces(tagData), opts.LabelsSnakeCase, logger)
+	for _, tagResult := range tagData {
+		if tagResult.ResourceCountGroupByTag == "" || tagResult.OnlyRecentlyActive {
+			continue
+		}
+
+		contextLabelsBySetting := make(map[bool]map[string]string, 1)
+		for _, d := range tagResult.Data {
+			metricName := "yace_" + BuildMetricName(d.Namespace, "resource_count", "")
+			resourceSnakeCase := snakeCase[d.Namespace]
+			ok, promTag := promLabelName(tagResult.ResourceCountGroupByTag, resourceSnakeCase, opts.InvalidLabelNameAction)
+			if !ok {
+				if opts.InvalidLabelNameAction == InvalidLabelNameActionFail {
+					return nil, nil, fmt.Errorf("resource count tag name %q of namespace %s is an invalid prometheus label name", tagResult.ResourceCountGroupByTag, d.Namespace)
+				}
+				logger.Warn("resource count tag name is an invalid prometheus label name", "tag", tagResult.ResourceCountGroupByTag)
+				break
+			}
+			labelName := "tag_" + promTag
+			contextLabels := contextLabelsFor(contextLabelsBySetting, tagResult.Context, resourceSnakeCase, opts.CustomTagsLabelPrefix, logger)
+
+			promLabels := make(map[string]string, len(contextLabels)+1)
+			maps.Copy(promLabels, contextLabels)
+			promLabels[labelName] = ""
+			for _, tag := range d.Tags {
+				if tag.Key == tagResult.ResourceCountGroupByTag {
+					promLabels[labelName] = tag.Value
+					break
+				}
+			}
+
+			key := fmt.Sprintf("%s-%d", metricName, prom_model.LabelsToSignature(promLabels))
+			if metric, ok := counts[key]; ok {
+				metric.Value++
+				continue
+			}
+
+			observedMetricLabels = recordLabelsForMetric(metricName, promLabels, observedMetricLabels)
+			counts[key] = &PrometheusMetric{
+				Name:   metricName,
+				Labels: promLabels,
+				Value:  1,
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range keys {
+		metrics = append(metrics, counts[key])
+	}
+
+	return metrics, observedMetricLabels, nil
+}
+
+// BuildRecentlyActiveMetrics adds a yace_<namespace>_recently_active metric for every discovered resource of the jobs
+// using RecentlyActiveOnly and ExportRecentlyActive, set to 1 when the resource had metrics in the recently active list and 0 otherwise, so
+// that idle resources can be spotted.
+func BuildRecentlyActiveMetrics(tagData []model.TaggedResourceResult, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet, opts BuildOptions, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet) {
+	arnLabelName := arnLabelNameOrDefault(opts.ARNLabelName)
+	snakeCase := resolveLabelsSnakeCase(taggedResourceNamespaces(tagData), opts.LabelsSnakeCase, logger)
+	for _, tagResult := range tagData {
+		if tagResult.RecentlyActiveARNs == nil {
+			continue
+		}
+
+		contextLabelsBySetting := make(map[bool]map[string]string, 1)
+		for _, d := range tagResult.Data {
+			metricName := "yace_" + BuildMetricName(d.Namespace, "recently_active", "")
+			contextLabels := contextLabelsFor(contextLabelsBySetting, tagResult.Context, snakeCase[d.Namespace], opts.CustomTagsLabelPrefix, logger)
+
+			promLabels := make(map[string]string, len(contextLabels)+1)
+			maps.Copy(promLabels, contextLabels)
+			promLabels[arnLabelName] = d.ARN
+
+			value := 0.0
+			if _, ok := tagResult.RecentlyActiveARNs[d.ARN]; ok {
+				value = 1
+			}
+
+			observedMetricLabels = recordLabelsForMetric(metricName, promLabels, observedMetricLabels)
+			metrics = append(metrics, &PrometheusMetric{
+				Name:   metricName,
+				Labels: promLabels,
+				Value:  value,
+			})
+		}
+	}
+
 	return metrics, observedMetricLabels
 }
 
-func BuildMetrics(results []model.CloudwatchMetricResult, labelsSnakeCase bool, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+// CapSeriesPerMetric drops the series of metrics exceeding their MaxSeriesPerMetric, before they
+// are built into Prometheus metrics. Every CloudwatchData is one series of each statistic it holds,
+// so the cap applies per exported metric name. Dropped series are counted in SeriesCappedCounter.
+func CapSeriesPerMetric(scrapeMetrics *ScrapeMetrics, results []model.CloudwatchMetricResult, logger *slog.Logger) []model.CloudwatchMetricResult {
+	type series struct {
+		data *model.CloudwatchData
+		key  string
+		hash uint64
+	}
+	groups := make(map[string][]series)
+	keys := make([]string, 0)
+	for _, result := range results {
+		for _, data := range result.Data {
+			if data.MetricMigrationParams.MaxSeriesPerMetric <= 0 {
+				continue
+			}
+			key := fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%t",
+				data.Namespace, data.MetricName, strings.Join(statisticsInCloudwatchData(data), ","),
+				data.MetricMigrationParams.MaxSeriesPerMetric, data.MetricMigrationParams.SampleCappedSeries)
+			if _, ok := groups[key]; !ok {
+				keys = append(keys, key)
+			}
+			labels := seriesLabels(result.Context, data)
+			groups[key] = append(groups[key], series{data: data, key: labels, hash: fnvHash(labels)})
+		}
+	}
+
+	dropped := make(map[*model.CloudwatchData]struct{})
+	for _, key := range keys {
+		group := groups[key]
+		first := group[0].data
+		limit := first.MetricMigrationParams.MaxSeriesPerMetric
+		if len(group) <= limit {
+			continue
+		}
+
+		// Both actions sort the group, so that the same series are kept on every scrape regardless of
+		// the order in which CloudWatch returned them. Sampling by hash spreads the kept series over
+		// all the accounts, regions and dimension values instead of keeping the first ones.
+		if first.MetricMigrationParams.SampleCappedSeries {
+			sort.SliceStable(group, func(i, j int) bool { return group[i].hash < group[j].hash })
+		} else {
+			sort.SliceStable(group, func(i, j int) bool { return group[i].key < group[j].key })
+		}
+		for _, s := range group[limit:] {
+			dropped[s.data] = struct{}{}
+		}
+
+		scrapeMetrics.SeriesCappedCounter.Add(float64(len(group)-limit), first.Namespace)
+		logger.Warn("Metric exceeds maxSeriesPerMetric, dropping series",
+			"namespace", first.Namespace,
+			"metric_name", first.MetricName,
+			"series", len(group),
+			"limit", limit,
+			"sampled", first.MetricMigrationParams.SampleCappedSeries,
+		)
+	}
+
+	if len(dropped) == 0 {
+		return results
+	}
+
+	capped := make([]model.CloudwatchMetricResult, 0, len(results))
+	for _, result := range results {
+		data := make([]*model.CloudwatchData, 0, len(result.Data))
+		for _, d := range result.Data {
+			if _, ok := dropped[d]; !ok {
+				data = append(data, d)
+			}
+		}
+		capped = append(capped, model.CloudwatchMetricResult{Context: result.Context, Data: data})
+	}
+	return capped
+}
+
+// seriesLabels identifies a series by its account, region and dimensions.
+func seriesLabels(ctx *model.ScrapeContext, data *model.CloudwatchData) string {
+	var sb strings.Builder
+	if ctx != nil {
+		sb.WriteString(ctx.AccountID)
+		sb.WriteByte(0)
+		sb.WriteString(ctx.Region)
+		sb.WriteByte(0)
+	}
+	dimensions := slices.Clone(data.Dimensions)
+	sort.Slice(dimensions, func(i, j int) bool { return dimensions[i].Name < dimensions[j].Name })
+	for _, dimension := range dimensions {
+		sb.WriteString(dimension.Name)
+		sb.WriteByte(0)
+		sb.WriteString(dimension.Value)
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+func fnvHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// sumWithoutDimensions sums the data of every result whose metrics are configured with SumWithoutDimensions and only
+// differ by these dimensions, so that they are built into a single series without them. As every other label of the
+// series, e.g. the ARN of the resource and the tags, must be the same, it is only useful for the dimensions which
+// don't identify the resource, e.g. the AvailabilityZone of the metrics of a load balancer.
+func sumWithoutDimensions(results []model.CloudwatchMetricResult) []model.CloudwatchMetricResult {
+	summable := func(d *model.CloudwatchData) bool {
+		return len(d.MetricMigrationParams.SumWithoutDimensions) > 0 && d.GetMetricDataResult != nil
+	}
+	if !slices.ContainsFunc(results, func(result model.CloudwatchMetricResult) bool {
+		return slices.ContainsFunc(result.Data, summable)
+	}) {
+		return results
+	}
+
+	summed := make([]model.CloudwatchMetricResult, 0, len(results))
+	for _, result := range results {
+		data := make([]*model.CloudwatchData, 0, len(result.Data))
+		// sums holds the index in data of the sum of every group of series
+		sums := make(map[string]int)
+		for _, d := range result.Data {
+			if !summable(d) {
+				data = append(data, d)
+				continue
+			}
+
+			dimensions := slices.DeleteFunc(slices.Clone(d.Dimensions), func(dimension model.Dimension) bool {
+				return slices.Contains(d.MetricMigrationParams.SumWithoutDimensions, dimension.Name)
+			})
+			key := summedSeriesKey(d, dimensions)
+			if i, ok := sums[key]; ok {
+				data[i].GetMetricDataResult.DataPoints = sumDataPoints(data[i].GetMetricDataResult.DataPoints, d.GetMetricDataResult.DataPoints)
+				continue
+			}
+
+			// The data is copied, as it can be shared with e.g. the cache of the empty result grace.
+			sum := *d
+			sum.Dimensions = dimensions
+			getMetricDataResult := *d.GetMetricDataResult
+			sum.GetMetricDataResult = &getMetricDataResult
+			sums[key] = len(data)
+			data = append(data, &sum)
+		}
+		summed = append(summed, model.CloudwatchMetricResult{Context: result.Context, Data: data})
+	}
+	return summed
+}
+
+// summedSeriesKey identifies the series data is summed into, whose dimensions are the ones of data which aren't summed away.
+func summedSeriesKey(data *model.CloudwatchData, dimensions []model.Dimension) string {
+	var b strings.Builder
+	for _, s := range []string{data.Namespace, data.MetricName, data.GetMetricDataResult.Statistic, data.ResourceName, data.SourceAccountID, strings.Join(data.MetricMigrationParams.SumWithoutDimensions, ",")} {
+		b.WriteString(s)
+		b.WriteByte(0)
+	}
+	dimensions = slices.Clone(dimensions)
+	sort.Slice(dimensions, func(i, j int) bool { return dimensions[i].Name < dimensions[j].Name })
+	for _, dimension := range dimensions {
+		fmt.Fprintf(&b, "d%s=%s\x00", dimension.Name, dimension.Value)
+	}
+	tags := slices.Clone(data.Tags)
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	for _, tag := range tags {
+		fmt.Fprintf(&b, "t%s=%s\x00", tag.Key, tag.Value)
+	}
+	return b.String()
+}
+
+// sumDataPoints adds the values of the data points of b to the ones of a with the same timestamp, and adds the other
+// data points of b. Missing values are ignored, so that the sum is only missing when all the values are. The sums are
+// ordered most recent first, as the data points returned by GetMetricData.
+func sumDataPoints(a, b []model.DataPoint) []model.DataPoint {
+	sums := slices.Clone(a)
+	for _, dataPoint := range b {
+		i := slices.IndexFunc(sums, func(sum model.DataPoint) bool { return sum.Timestamp.Equal(dataPoint.Timestamp) })
+		if i < 0 {
+			sums = append(sums, dataPoint)
+			continue
+		}
+		if dataPoint.Value == nil {
+			continue
+		}
+		// The values are never updated in place, as they belong to the data which is summed.
+		sum := *dataPoint.Value
+		if sums[i].Value != nil {
+			sum += *sums[i].Value
+		}
+		sums[i].Value = &sum
+	}
+	sort.SliceStable(sums, func(i, j int) bool { return sums[i].Timestamp.After(sums[j].Timestamp) })
+	return sums
+}
+
+// BuildOptions configures how BuildMetrics converts the CloudWatch results to Prometheus metrics, and how the info,
+// resource count and recently active metrics of the discovered resources are labelled.
+type BuildOptions struct {
+	// LabelsSnakeCase converts the dimension and tag label names to snake case, unless a job overrides it.
+	LabelsSnakeCase bool
+	// CustomTagsLabelPrefix is the prefix of the labels of the custom tags of the jobs.
+	CustomTagsLabelPrefix string
+	// ARNLabelName is the name of the label holding the ARN of the resource of the metrics, DefaultARNLabelName
+	// when empty. It should be the same as the one of the info metrics.
+	ARNLabelName string
+	// InvalidLabelNameAction is applied to the dimensions and tags which aren't valid label names, skipping them
+	// when empty.
+	InvalidLabelNameAction InvalidLabelNameAction
+	// Metadata sets the help and unit of the metrics, when not nil.
+	Metadata *MetricsMetadata
+}
+
+// BuildMetrics converts the CloudWatch results to Prometheus metrics.
+func BuildMetrics(results []model.CloudwatchMetricResult, opts BuildOptions, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+	return BuildMetricsConcurrently(results, 1, opts, logger)
+}
+
+// ClampFutureTimestamps sets the timestamp of the metrics exported with a timestamp after now to now, as
+// some backends reject samples in the future. CloudWatch timestamps can be slightly ahead of the clock of
+// the exporter because of clock skew. When a series has several data points in the future, e.g. with
+// ExportAllDataPoints, only the newest one is kept, as they would all be exported at the same time.
+func ClampFutureTimestamps(metrics []*PrometheusMetric, now time.Time) []*PrometheusMetric {
+	newest := make(map[string]*PrometheusMetric)
+	for _, metric := range metrics {
+		if !metric.IncludeTimestamp || !metric.Timestamp.After(now) {
+			continue
+		}
+		key := fmt.Sprintf("%s-%d", metric.Name, prom_model.LabelsToSignature(metric.Labels))
+		if current, ok := newest[key]; !ok || metric.Timestamp.After(current.Timestamp) {
+			newest[key] = metric
+		}
+	}
+	if len(newest) == 0 {
+		return metrics
+	}
+
+	clamped := make([]*PrometheusMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.IncludeTimestamp && metric.Timestamp.After(now) {
+			key := fmt.Sprintf("%s-%d", metric.Name, prom_model.LabelsToSignature(metric.Labels))
+			if newest[key] != metric {
+				continue
+			}
+			metric.Timestamp = now
+		}
+		clamped = append(clamped, metric)
+	}
+	return clamped
+}
+
+// BuildMetricsConcurrently is BuildMetrics sharding the results across up to concurrency workers, for scrapes
+// producing many results. The shards are merged in order, so the output is the same as the one of BuildMetrics.
+func BuildMetricsConcurrently(results []model.CloudwatchMetricResult, concurrency int, opts BuildOptions, logger *slog.Logger) ([]*PrometheusMetric, map[string]model.LabelSet, error) {
+	concurrency = max(concurrency, 1)
+	arnLabelName := arnLabelNameOrDefault(opts.ARNLabelName)
+	results = sumWithoutDimensions(results)
+	snakeCase := resolveLabelsSnakeCase(cloudwatchMetricNamespaces(results), opts.LabelsSnakeCase, logger)
+	shards := shardResults(results, concurrency, snakeCase, opts.CustomTagsLabelPrefix, logger)
+
+	builtShards := make([]builtMetricsShard, len(shards))
+	errs := make([]error, len(shards))
+	if len(shards) == 1 {
+		builtShards[0], errs[0] = shards[0].build(snakeCase, arnLabelName, opts.InvalidLabelNameAction, opts.Metadata, logger)
+	} else {
+		var g errgroup.Group
+		g.SetLimit(concurrency)
+		for i, shard := range shards {
+			g.Go(func() error {
+				builtShards[i], errs[i] = shard.build(snakeCase, arnLabelName, opts.InvalidLabelNameAction, opts.Metadata, logger)
+				return nil
+			})
+		}
+		_ = g.Wait()
+	}
+
 	output := make([]*PrometheusMetric, 0)
 	observedMetricLabels := make(map[string]model.LabelSet)
+	// outputNamespaces holds the CloudWatch namespace of each entry in output.
+	outputNamespaces := make([]string, 0)
+	for i, built := range builtShards {
+		// Return the error of the first failing shard, as building serially would
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		output = append(output, built.output...)
+		outputNamespaces = append(outputNamespaces, built.outputNamespaces...)
+		for name, labels := range built.observedMetricLabels {
+			if _, ok := observedMetricLabels[name]; !ok {
+				observedMetricLabels[name] = labels
+				continue
+			}
+			maps.Copy(observedMetricLabels[name], labels)
+		}
+	}
+
+	disambiguateNamespaceCollisions(output, outputNamespaces, observedMetricLabels, logger)
+
+	return output, observedMetricLabels, nil
+}
+
+// valueType returns the PrometheusMetric.ValueType of one of the model.MetricType constants, zero for gauges.
+func valueType(metricType string) prometheus.ValueType {
+	switch metricType {
+	case model.MetricTypeCounter:
+		return prometheus.CounterValue
+	case model.MetricTypeUntyped:
+		return prometheus.UntypedValue
+	default:
+		return 0
+	}
+}
+
+// metricsShard is a contiguous part of the data of a result, built into metrics by a single worker.
+type metricsShard struct {
+	context *model.ScrapeContext
+	// contextLabels holds the context labels of the result by labels snake case setting. It is shared by
+	// the shards of the result and only read while building them.
+	contextLabels map[bool]map[string]string
+	data          []*model.CloudwatchData
+}
 
+type builtMetricsShard struct {
+	output               []*PrometheusMetric
+	outputNamespaces     []string
+	observedMetricLabels map[string]model.LabelSet
+}
+
+// shardResults splits the data of the results into shards of similar sizes, at most concurrency of them for
+// every result. A single shard per result is used when concurrency is 1.
+func shardResults(results []model.CloudwatchMetricResult, concurrency int, snakeCase map[string]bool, customTagsLabelPrefix string, logger *slog.Logger) []metricsShard {
+	total := 0
+	for _, result := range results {
+		total += len(result.Data)
+	}
+	shardSize := max((total+concurrency-1)/concurrency, 1)
+
+	shards := make([]metricsShard, 0, len(results))
 	for _, result := range results {
-		contextLabels := contextToLabels(result.Context, labelsSnakeCase, logger)
+		// Resolved before building the shards, so that they don't have to synchronize on it, and that the
+		// conflicts of the context labels are logged once.
+		contextLabels := make(map[bool]map[string]string, 1)
 		for _, metric := range result.Data {
-			// This should not be possible but check just in case
-			if metric.GetMetricStatisticsResult == nil && metric.GetMetricDataResult == nil {
-				logger.Warn("Attempted to migrate metric with no result", "namespace", metric.Namespace, "metric_name", metric.MetricName, "resource_name", metric.ResourceName)
-			}
+			contextLabelsFor(contextLabels, result.Context, snakeCase[metric.Namespace], customTagsLabelPrefix, logger)
+		}
 
-			for _, statistic := range statisticsInCloudwatchData(metric) {
-				dataPoints, err := getDataPoints(metric, statistic)
-				for _, dataPoint := range dataPoints {
-					ts := dataPoint.Timestamp
-					dataPoint := dataPoint.Value
-					if err != nil {
-						return nil, nil, err
-					}
-					var exportedDatapoint float64
-					if dataPoint == nil && metric.MetricMigrationParams.AddCloudwatchTimestamp {
-						// If we did not get a datapoint then the timestamp is a default value making it unusable in the
-						// exported metric. Attempting to put a fake timestamp on the metric will likely conflict with
-						// future CloudWatch timestamps which are always in the past.
-						if metric.MetricMigrationParams.ExportAllDataPoints {
-							// If we're exporting all data points, we can skip this one and check for a historical datapoint
-							continue
-						}
-						// If we are not exporting all data points, we better have nothing exported
-						break
-					}
-					if dataPoint == nil {
-						exportedDatapoint = math.NaN()
-					} else {
-						exportedDatapoint = *dataPoint
-					}
+		for data := range slices.Chunk(result.Data, shardSize) {
+			shards = append(shards, metricsShard{context: result.Context, contextLabels: contextLabels, data: data})
+		}
+	}
+	return shards
+}
 
-					if metric.MetricMigrationParams.NilToZero && math.IsNaN(exportedDatapoint) {
-						exportedDatapoint = 0
-					}
+func (s metricsShard) build(snakeCase map[string]bool, arnLabelName string, invalidLabelNameAction InvalidLabelNameAction, metadata *MetricsMetadata, logger *slog.Logger) (builtMetricsShard, error) {
+	shard := builtMetricsShard{
+		output:               make([]*PrometheusMetric, 0, len(s.data)),
+		outputNamespaces:     make([]string, 0, len(s.data)),
+		observedMetricLabels: make(map[string]model.LabelSet),
+	}
 
-					name := BuildMetricName(metric.Namespace, metric.MetricName, statistic)
+	partitionLabel := s.context != nil && s.context.PartitionLabel
+	omitGlobalNameLabel := s.context != nil && s.context.OmitGlobalNameLabel
+	var metricNameOverrides map[string]string
+	if s.context != nil {
+		metricNameOverrides = s.context.MetricNameOverrides
+	}
+	for _, metric := range s.data {
+		metricSnakeCase := snakeCase[metric.Namespace]
+		contextLabels := s.contextLabels[metricSnakeCase]
 
-					promLabels := createPrometheusLabels(metric, labelsSnakeCase, contextLabels, logger)
-					observedMetricLabels = recordLabelsForMetric(name, promLabels, observedMetricLabels)
+		// This should not be possible but check just in case
+		if metric.GetMetricStatisticsResult == nil && metric.GetMetricDataResult == nil {
+			logger.Warn("Attempted to migrate metric with no result", "namespace", metric.Namespace, "metric_name", metric.MetricName, "resource_name", metric.ResourceName)
+		}
 
-					if !metric.MetricMigrationParams.AddCloudwatchTimestamp {
-						// if we're not adding the original timestamp, we have to zero it so we can validate the data in the exporter via EnsureLabelConsistencyAndRemoveDuplicates
-						ts = time.Time{}
+		for _, statistic := range statisticsInCloudwatchData(metric) {
+			dataPoints, err := getDataPoints(metric, statistic)
+			// Data points are ordered most recent first, so keeping the first KeepLastN
+			// exports the most recent ones.
+			exportedDataPoints := 0
+			maxDataPoints := max(metric.MetricMigrationParams.KeepLastN, 1)
+			for _, dataPoint := range dataPoints {
+				ts := dataPoint.Timestamp
+				dataPoint := dataPoint.Value
+				if err != nil {
+					return shard, err
+				}
+				var exportedDatapoint float64
+				if dataPoint == nil && metric.MetricMigrationParams.AddCloudwatchTimestamp {
+					// If we did not get a datapoint then the timestamp is a default value making it unusable in the
+					// exported metric. Attempting to put a fake timestamp on the metric will likely conflict with
+					// future CloudWatch timestamps which are always in the past.
+					if metric.MetricMigrationParams.ExportAllDataPoints || metric.MetricMigrationParams.KeepLastN > 1 {
+						// If we're exporting more than one data point, we can skip this one and check for a historical datapoint
+						continue
 					}
+					// If we are not exporting all data points, we better have nothing exported
+					break
+				}
+				if dataPoint == nil {
+					exportedDatapoint = math.NaN()
+				} else {
+					exportedDatapoint = *dataPoint
+				}
 
-					output = append(output, &PrometheusMetric{
-						Name:             name,
-						Labels:           promLabels,
-						Value:            exportedDatapoint,
-						Timestamp:        ts,
-						IncludeTimestamp: metric.MetricMigrationParams.AddCloudwatchTimestamp,
-					})
-
-					if !metric.MetricMigrationParams.ExportAllDataPoints {
-						// If we're not exporting all data points, we can skip the rest of the data points for this metric
-						break
-					}
+				if nilToZero(metric, statistic) && math.IsNaN(exportedDatapoint) {
+					exportedDatapoint = 0
+				}
+
+				name := buildMetricName(metric.Namespace, metric.MetricName, statisticName(metric, statistic), metricNameOverrides)
+
+				promLabels, err := createPrometheusLabels(metric, metricSnakeCase, contextLabels, partitionLabel, omitGlobalNameLabel, arnLabelName, invalidLabelNameAction, logger)
+				if err != nil {
+					return shard, err
+				}
+				shard.observedMetricLabels = recordLabelsForMetric(name, promLabels, shard.observedMetricLabels)
+
+				if !metric.MetricMigrationParams.AddCloudwatchTimestamp {
+					// if we're not adding the original timestamp, we have to zero it so we can validate the data in the exporter via EnsureLabelConsistencyAndRemoveDuplicates
+					ts = time.Time{}
+				}
+
+				shard.output = append(shard.output, &PrometheusMetric{
+					Name:             name,
+					Labels:           promLabels,
+					Value:            exportedDatapoint,
+					Timestamp:        ts,
+					IncludeTimestamp: metric.MetricMigrationParams.AddCloudwatchTimestamp,
+					Help:             metadata.help(metric.Namespace, metric.MetricName, name),
+					ValueType:        valueType(metric.MetricMigrationParams.MetricType),
+				})
+				shard.outputNamespaces = append(shard.outputNamespaces, metric.Namespace)
+
+				exportedDataPoints++
+				if !metric.MetricMigrationParams.ExportAllDataPoints && exportedDataPoints >= maxDataPoints {
+					// If we're not exporting all data points, we can skip the rest of the data points for this metric
+					break
 				}
 			}
 		}
 	}
 
-	return output, observedMetricLabels, nil
+	return shard, nil
+}
+
+// disambiguateNamespaceCollisions handles metric names that are produced by
+// more than one CloudWatch namespace, e.g. the custom namespace "EC2" and
+// "AWS/EC2" both map to the "aws_ec2_" prefix. Every metric with a colliding
+// name is qualified with a "namespace" label holding its original namespace so
+// that series from different namespaces never share the same label set. When
+// a dimension or tag of the metric already uses the "namespace" label, the
+// "cloudwatch_namespace" label is used instead, so that it isn't overwritten.
+func disambiguateNamespaceCollisions(metrics []*PrometheusMetric, namespaces []string, observedMetricLabels map[string]model.LabelSet, logger *slog.Logger) {
+	namespacesByName := make(map[string]map[string]struct{})
+	for i, metric := range metrics {
+		if _, ok := namespacesByName[metric.Name]; !ok {
+			namespacesByName[metric.Name] = make(map[string]struct{})
+		}
+		namespacesByName[metric.Name][namespaces[i]] = struct{}{}
+	}
+
+	// labelNames holds the label of the namespace of every colliding metric name
+	labelNames := make(map[string]string)
+	for name, names := range namespacesByName {
+		if len(names) < 2 {
+			continue
+		}
+		labelName := ""
+		for _, candidate := range []string{"namespace", "cloudwatch_namespace"} {
+			if _, ok := observedMetricLabels[name][candidate]; !ok {
+				labelName = candidate
+				break
+			}
+		}
+		if labelName == "" {
+			logger.Warn("Metric name is produced by multiple namespaces, but its labels already use the namespace label names", "metric_name", name, "namespaces", len(names))
+			continue
+		}
+		logger.Warn("Metric name is produced by multiple namespaces, adding namespace label", "metric_name", name, "namespaces", len(names), "label", labelName)
+		labelNames[name] = labelName
+	}
+
+	for i, metric := range metrics {
+		labelName, ok := labelNames[metric.Name]
+		if !ok {
+			continue
+		}
+		metric.Labels[labelName] = namespaces[i]
+		observedMetricLabels[metric.Name][labelName] = struct{}{}
+	}
 }
 
 func statisticsInCloudwatchData(d *model.CloudwatchData) []string {
@@ -258,14 +857,24 @@ func sortByTimestamp(dataPoints []*model.MetricStatisticsResult) []*model.Metric
 	return dataPoints
 }
 
-func createPrometheusLabels(cwd *model.CloudwatchData, labelsSnakeCase bool, contextLabels map[string]string, logger *slog.Logger) map[string]string {
-	labels := make(map[string]string, len(cwd.Dimensions)+len(cwd.Tags)+len(contextLabels))
-	labels["name"] = cwd.ResourceName
+// createPrometheusLabels returns the labels of a metric. When omitGlobalNameLabel is set, metrics which aren't associated
+// with a resource have no arnLabelName label. EnsureLabelConsistencyAndRemoveDuplicates still adds an empty one to them when
+// other metrics of the same name have a resource.
+func createPrometheusLabels(cwd *model.CloudwatchData, labelsSnakeCase bool, contextLabels map[string]string, partitionLabel bool, omitGlobalNameLabel bool, arnLabelName string, invalidLabelNameAction InvalidLabelNameAction, logger *slog.Logger) (map[string]string, error) {
+	labels := make(map[string]string, len(cwd.MetricMigrationParams.Labels)+len(cwd.Dimensions)+len(cwd.Tags)+len(contextLabels))
+	// The labels set by the exporter below win over the static ones
+	maps.Copy(labels, cwd.MetricMigrationParams.Labels)
+	if !omitGlobalNameLabel || cwd.ResourceName != "global" {
+		labels[arnLabelName] = cwd.ResourceName
+	}
 
 	// Inject the sfn name back as a label
 	for _, dimension := range cwd.Dimensions {
-		ok, promTag := PromStringTag(dimension.Name, labelsSnakeCase)
+		ok, promTag := promLabelName(dimension.Name, labelsSnakeCase, invalidLabelNameAction)
 		if !ok {
+			if invalidLabelNameAction == InvalidLabelNameActionFail {
+				return nil, fmt.Errorf("dimension name %q of metric %s is an invalid prometheus label name", dimension.Name, cwd.MetricName)
+			}
 			logger.Warn("dimension name is an invalid prometheus label name", "dimension", dimension.Name)
 			continue
 		}
@@ -273,8 +882,11 @@ func createPrometheusLabels(cwd *model.CloudwatchData, labelsSnakeCase bool, con
 	}
 
 	for _, tag := range cwd.Tags {
-		ok, promTag := PromStringTag(tag.Key, labelsSnakeCase)
+		ok, promTag := promLabelName(tag.Key, labelsSnakeCase, invalidLabelNameAction)
 		if !ok {
+			if invalidLabelNameAction == InvalidLabelNameActionFail {
+				return nil, fmt.Errorf("tag name %q of metric %s is an invalid prometheus label name", tag.Key, cwd.MetricName)
+			}
 			logger.Warn("metric tag name is an invalid prometheus label name", "tag", tag.Key)
 			continue
 		}
@@ -283,10 +895,127 @@ func createPrometheusLabels(cwd *model.CloudwatchData, labelsSnakeCase bool, con
 
 	maps.Copy(labels, contextLabels)
 
+	if cwd.GetMetricDataResult != nil && cwd.GetMetricDataResult.Window != nil {
+		window := cwd.GetMetricDataResult.Window
+		labels["window_start"] = window.StartTime.UTC().Format(time.RFC3339)
+		labels["window_end"] = window.EndTime.UTC().Format(time.RFC3339)
+		labels["window_period"] = strconv.FormatInt(window.Period, 10)
+	}
+
+	// Metrics which aren't associated with a resource, e.g. "global", have no ARN to take the partition from.
+	if partitionLabel {
+		if resourceARN, err := arn.Parse(cwd.ResourceName); err == nil {
+			labels["partition"] = resourceARN.Partition
+		}
+	}
+
+	return labels, nil
+}
+
+// promLabelName converts a dimension or tag name to a label name like PromStringTag. With the sanitize action,
+// the characters PromStringTag leaves in place but aren't valid in a label name, e.g. '#' or non-ASCII letters,
+// are replaced with underscores.
+func promLabelName(name string, labelsSnakeCase bool, invalidLabelNameAction InvalidLabelNameAction) (bool, string) {
+	ok, promTag := PromStringTag(name, labelsSnakeCase)
+	if ok || invalidLabelNameAction != InvalidLabelNameActionSanitize {
+		return ok, promTag
+	}
+
+	sanitized := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, promTag)
+	if sanitized != "" && sanitized[0] >= '0' && sanitized[0] <= '9' {
+		sanitized = "_" + sanitized
+	}
+	return prom_model.LegacyValidation.IsValidLabelName(sanitized), sanitized
+}
+
+// statisticName returns the name of the statistic used in the suffix of the exported metric name,
+// which is the statistic itself unless the metric is configured to rename it.
+func statisticName(cwd *model.CloudwatchData, statistic string) string {
+	if name, ok := cwd.MetricMigrationParams.StatisticNames[statistic]; ok {
+		return name
+	}
+	return statistic
+}
+
+// resolveLabelsSnakeCase returns the labelsSnakeCase setting of every namespace produced by jobs, which
+// yields the namespace and scrape context of every metric or resource. Jobs can override the global setting,
+// but metrics of a namespace share their names: when jobs of the same namespace disagree the global setting
+// is used for it, so that the label names of a metric stay consistent.
+func resolveLabelsSnakeCase(jobs iter.Seq2[string, *model.ScrapeContext], labelsSnakeCase bool, logger *slog.Logger) map[string]bool {
+	settings := make(map[string]map[bool]struct{})
+	for namespace, context := range jobs {
+		setting := labelsSnakeCase
+		if context != nil && context.LabelsSnakeCase != nil {
+			setting = *context.LabelsSnakeCase
+		}
+		if _, ok := settings[namespace]; !ok {
+			settings[namespace] = make(map[bool]struct{}, 1)
+		}
+		settings[namespace][setting] = struct{}{}
+	}
+
+	resolved := make(map[string]bool, len(settings))
+	for namespace, values := range settings {
+		if len(values) > 1 {
+			logger.Warn("Jobs of the same namespace set different labelsSnakeCase, using the global setting", "namespace", namespace, "labels_snake_case", labelsSnakeCase)
+			resolved[namespace] = labelsSnakeCase
+			continue
+		}
+		for value := range values {
+			resolved[namespace] = value
+		}
+	}
+	return resolved
+}
+
+func cloudwatchMetricNamespaces(results []model.CloudwatchMetricResult) iter.Seq2[string, *model.ScrapeContext] {
+	return func(yield func(string, *model.ScrapeContext) bool) {
+		for _, result := range results {
+			for _, metric := range result.Data {
+				if !yield(metric.Namespace, result.Context) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func taggedResourceNamespaces(tagData []model.TaggedResourceResult) iter.Seq2[string, *model.ScrapeContext] {
+	return func(yield func(string, *model.ScrapeContext) bool) {
+		for _, tagResult := range tagData {
+			for _, resource := range tagResult.Data {
+				if !yield(resource.Namespace, tagResult.Context) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// contextLabelsFor returns the labels of context for a labelsSnakeCase setting, computing them once per setting.
+func contextLabelsFor(bySetting map[bool]map[string]string, context *model.ScrapeContext, labelsSnakeCase bool, customTagsLabelPrefix string, logger *slog.Logger) map[string]string {
+	if labels, ok := bySetting[labelsSnakeCase]; ok {
+		return labels
+	}
+	labels := contextToLabels(context, labelsSnakeCase, customTagsLabelPrefix, logger)
+	bySetting[labelsSnakeCase] = labels
 	return labels
 }
 
-func contextToLabels(context *model.ScrapeContext, labelsSnakeCase bool, logger *slog.Logger) map[string]string {
+// nilToZero returns whether missing values of the statistic are exported as 0 instead of NaN.
+func nilToZero(cwd *model.CloudwatchData, statistic string) bool {
+	if value, ok := cwd.MetricMigrationParams.NilToZeroStatistics[statistic]; ok {
+		return value
+	}
+	return cwd.MetricMigrationParams.NilToZero
+}
+
+func contextToLabels(context *model.ScrapeContext, labelsSnakeCase bool, customTagsLabelPrefix string, logger *slog.Logger) map[string]string {
 	if context == nil {
 		return map[string]string{}
 	}
@@ -305,7 +1034,13 @@ func contextToLabels(context *model.ScrapeContext, labelsSnakeCase bool, logger
 			logger.Warn("custom tag name is an invalid prometheus label name", "tag", label.Key)
 			continue
 		}
-		labels["custom_tag_"+promTag] = label.Value
+		labelName := customTagsLabelPrefix + promTag
+		// With a short or empty prefix, a custom tag could otherwise overwrite the context labels.
+		if labelName == "region" || labelName == "account_id" || labelName == "account_alias" {
+			logger.Warn("custom tag label name conflicts with a context label", "tag", label.Key, "label", labelName)
+			continue
+		}
+		labels[labelName] = label.Value
 	}
 
 	return labels
@@ -327,12 +1062,25 @@ func recordLabelsForMetric(metricName string, promLabels map[string]string, obse
 }
 
 // EnsureLabelConsistencyAndRemoveDuplicates aligns the label set of every
-// metric with the same name and drops duplicates.
+// metric with the same name and drops duplicates. It also records the number
+// of observed labels of every metric name in MetricLabelCardinalityGauge.
 func EnsureLabelConsistencyAndRemoveDuplicates(scrapeMetrics *ScrapeMetrics, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet) []*PrometheusMetric {
+	return EnsureLabelConsistencyAndRemoveDuplicatesWithLogger(scrapeMetrics, metrics, observedMetricLabels, nil)
+}
+
+// EnsureLabelConsistencyAndRemoveDuplicatesWithLogger is EnsureLabelConsistencyAndRemoveDuplicates
+// which also logs the labels of every dropped duplicate at debug level. A nil logger logs nothing.
+func EnsureLabelConsistencyAndRemoveDuplicatesWithLogger(scrapeMetrics *ScrapeMetrics, metrics []*PrometheusMetric, observedMetricLabels map[string]model.LabelSet, logger *slog.Logger) []*PrometheusMetric {
 	if scrapeMetrics == nil {
 		scrapeMetrics = Discard
 	}
 
+	// Metric names which are no longer exported should not keep their last value.
+	scrapeMetrics.MetricLabelCardinalityGauge.Reset()
+	for name, labels := range observedMetricLabels {
+		scrapeMetrics.MetricLabelCardinalityGauge.Set(float64(len(labels)), name)
+	}
+
 	metricKeys := make(map[string]struct{}, len(metrics))
 	output := make([]*PrometheusMetric, 0, len(metrics))
 
@@ -346,12 +1094,17 @@ func EnsureLabelConsistencyAndRemoveDuplicates(scrapeMetrics *ScrapeMetrics, met
 		// We are including the timestamp in the metric key to ensure that we don't have duplicate metrics
 		// if we have AddCloudwatchTimestamp enabled its the real timestamp, otherwise its a zero value
 		// the timestamp is needed to ensure valid date created by ExportAllDataPoints
-		metricKey := fmt.Sprintf("%s-%d-%d", metric.Name, prom_model.LabelsToSignature(metric.Labels), metric.Timestamp.Unix())
+		signature := prom_model.LabelsToSignature(metric.Labels)
+		metricKey := fmt.Sprintf("%s-%d-%d", metric.Name, signature, metric.Timestamp.Unix())
 		if _, exists := metricKeys[metricKey]; !exists {
 			metricKeys[metricKey] = struct{}{}
 			output = append(output, metric)
 		} else {
 			scrapeMetrics.DuplicateMetricsFilteredCounter.Inc()
+			scrapeMetrics.DuplicateMetricsFilteredByNameCounter.Inc(metric.Name)
+			if logger != nil {
+				logger.Debug("dropping duplicate metric", "metric", metric.Name, "labels", metric.Labels, "signature", signature, "timestamp", metric.Timestamp)
+			}
 		}
 	}
 
@@ -13,11 +13,20 @@
 package promutil
 
 import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"maps"
 	"math"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/promslog"
 	"github.com/stretchr/testify/require"
 
@@ -278,13 +287,64 @@ func TestBuildNamespaceInfoMetrics(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			metrics, labels := BuildNamespaceInfoMetrics(tc.resources, tc.metrics, tc.observedMetricLabels, tc.labelsSnakeCase, promslog.NewNopLogger())
+			metrics, labels, err := BuildNamespaceInfoMetrics(tc.resources, tc.metrics, tc.observedMetricLabels, BuildOptions{LabelsSnakeCase: tc.labelsSnakeCase, CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+			require.NoError(t, err)
 			require.Equal(t, tc.expectedMetrics, metrics)
 			require.Equal(t, tc.expectedLabels, labels)
 		})
 	}
 }
 
+func TestBuildResourceCountMetrics(t *testing.T) {
+	rdsInstance := func(name, engine string) *model.TaggedResource {
+		resource := &model.TaggedResource{
+			ARN:       "arn:aws:rds:us-east-1:123456789012:db:" + name,
+			Namespace: "AWS/RDS",
+			Region:    "us-east-1",
+		}
+		if engine != "" {
+			resource.Tags = []model.Tag{{Key: "Engine", Value: engine}}
+		}
+		return resource
+	}
+
+	resources := []model.TaggedResourceResult{
+		{
+			ResourceCountGroupByTag: "Engine",
+			Data: []*model.TaggedResource{
+				rdsInstance("db-1", "mysql"),
+				rdsInstance("db-2", "postgres"),
+				rdsInstance("db-3", "mysql"),
+				rdsInstance("db-4", ""),
+			},
+		},
+		{
+			ResourceCountGroupByTag: "Engine",
+			Data: []*model.TaggedResource{
+				rdsInstance("db-5", "mysql"),
+			},
+		},
+		{
+			// Resources of jobs without a group by tag are not counted
+			Data: []*model.TaggedResource{
+				rdsInstance("db-6", "mysql"),
+			},
+		},
+	}
+
+	metrics, labels, err := BuildResourceCountMetrics(resources, []*PrometheusMetric{}, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, []*PrometheusMetric{
+		{Name: "yace_aws_rds_resource_count", Labels: map[string]string{"tag_Engine": "mysql"}, Value: 3},
+		{Name: "yace_aws_rds_resource_count", Labels: map[string]string{"tag_Engine": "postgres"}, Value: 1},
+		{Name: "yace_aws_rds_resource_count", Labels: map[string]string{"tag_Engine": ""}, Value: 1},
+	}, metrics)
+	require.Equal(t, map[string]model.LabelSet{
+		"yace_aws_rds_resource_count": {"tag_Engine": {}},
+	}, labels)
+}
+
 func TestBuildMetrics(t *testing.T) {
 	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
 	nullTs := time.Time{}
@@ -1090,7 +1150,7 @@ func TestBuildMetrics(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			res, labels, err := BuildMetrics(tc.data, tc.labelsSnakeCase, promslog.NewNopLogger())
+			res, labels, err := BuildMetrics(tc.data, BuildOptions{LabelsSnakeCase: tc.labelsSnakeCase, CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
 			if tc.expectedErr != nil {
 				require.Equal(t, tc.expectedErr, err)
 			} else {
@@ -1102,6 +1162,359 @@ func TestBuildMetrics(t *testing.T) {
 	}
 }
 
+func TestBuildMetrics_NamespaceCollision(t *testing.T) {
+	newData := func(namespace string, value float64) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: "CPUUtilization",
+			Namespace:  namespace,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(value)}},
+			},
+			Dimensions: []model.Dimension{
+				{Name: "InstanceId", Value: "i-1"},
+			},
+			ResourceName: "arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+		}
+	}
+
+	data := []model.CloudwatchMetricResult{
+		{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data:    []*model.CloudwatchData{newData("AWS/EC2", 1), newData("EC2", 2), newData("AWS/EBS", 3)},
+		},
+	}
+
+	metrics, labels, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+
+	// "AWS/EC2" and "EC2" both map to aws_ec2_* and get a namespace label.
+	require.Equal(t, "aws_ec2_cpuutilization_average", metrics[0].Name)
+	require.Equal(t, "AWS/EC2", metrics[0].Labels["namespace"])
+	require.Equal(t, "aws_ec2_cpuutilization_average", metrics[1].Name)
+	require.Equal(t, "EC2", metrics[1].Labels["namespace"])
+	require.Contains(t, labels["aws_ec2_cpuutilization_average"], "namespace")
+
+	// Non-colliding names keep their per-namespace prefix and labels.
+	require.Equal(t, "aws_ebs_cpuutilization_average", metrics[2].Name)
+	require.NotContains(t, metrics[2].Labels, "namespace")
+	require.NotContains(t, labels["aws_ebs_cpuutilization_average"], "namespace")
+
+	deduped := EnsureLabelConsistencyAndRemoveDuplicates(NewScrapeMetrics(nil), metrics, labels)
+	require.Len(t, deduped, 3)
+}
+
+func TestBuildMetrics_NamespaceCollisionKeepsNamespaceTag(t *testing.T) {
+	newData := func(namespace string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: "CPUUtilization",
+			Namespace:  namespace,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1)}},
+			},
+			Dimensions:   []model.Dimension{{Name: "InstanceId", Value: "i-1"}},
+			ResourceName: "arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+		}
+	}
+
+	t.Run("custom tag named namespace", func(t *testing.T) {
+		// Without a prefix, the custom tag is exported in the namespace label
+		data := []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", CustomTags: []model.Tag{{Key: "namespace", Value: "team-a"}}},
+			Data:    []*model.CloudwatchData{newData("AWS/EC2"), newData("EC2")},
+		}}
+
+		metrics, labels, err := BuildMetrics(data, BuildOptions{}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 2)
+		for i, namespace := range []string{"AWS/EC2", "EC2"} {
+			require.Equal(t, "team-a", metrics[i].Labels["namespace"])
+			require.Equal(t, namespace, metrics[i].Labels["cloudwatch_namespace"])
+		}
+		require.Contains(t, labels["aws_ec2_cpuutilization_average"], "cloudwatch_namespace")
+	})
+
+	t.Run("both label names taken", func(t *testing.T) {
+		tags := []model.Tag{{Key: "namespace", Value: "team-a"}, {Key: "cloudwatch_namespace", Value: "team-b"}}
+		data := []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", CustomTags: tags},
+			Data:    []*model.CloudwatchData{newData("AWS/EC2"), newData("EC2")},
+		}}
+
+		metrics, _, err := BuildMetrics(data, BuildOptions{}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 2)
+		for _, metric := range metrics {
+			require.Equal(t, "team-a", metric.Labels["namespace"])
+			require.Equal(t, "team-b", metric.Labels["cloudwatch_namespace"])
+		}
+	})
+}
+
+func TestBuildMetrics_KeepLastN(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newData := func(metricName string, dataPoints []model.DataPoint) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: metricName,
+			MetricMigrationParams: model.MetricMigrationParams{
+				AddCloudwatchTimestamp: true,
+				KeepLastN:              2,
+			},
+			Namespace: "AWS/ElastiCache",
+			Dimensions: []model.Dimension{
+				{Name: "CacheClusterId", Value: "redis-cluster"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: dataPoints,
+			},
+			ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+		}
+	}
+
+	// Data points are ordered most recent first, as requested with ScanBy TimestampDescending.
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("NetworkPacketsIn", []model.DataPoint{
+				{Value: aws.Float64(4), Timestamp: ts},
+				{Value: aws.Float64(5), Timestamp: ts.Add(-1 * time.Minute)},
+				{Value: aws.Float64(6), Timestamp: ts.Add(-2 * time.Minute)},
+				{Value: aws.Float64(7), Timestamp: ts.Add(-3 * time.Minute)},
+			}),
+			newData("NetworkPacketsOut", []model.DataPoint{
+				{Value: nil, Timestamp: ts},
+				{Value: aws.Float64(5), Timestamp: ts.Add(-1 * time.Minute)},
+				{Value: aws.Float64(6), Timestamp: ts.Add(-2 * time.Minute)},
+				{Value: aws.Float64(7), Timestamp: ts.Add(-3 * time.Minute)},
+			}),
+		},
+	}}
+
+	metrics, _, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	type exported struct {
+		name      string
+		value     float64
+		timestamp time.Time
+	}
+	actual := make([]exported, 0, len(metrics))
+	for _, metric := range metrics {
+		require.True(t, metric.IncludeTimestamp)
+		actual = append(actual, exported{name: metric.Name, value: metric.Value, timestamp: metric.Timestamp})
+	}
+
+	require.Equal(t, []exported{
+		{name: "aws_elasticache_network_packets_in_average", value: 4, timestamp: ts},
+		{name: "aws_elasticache_network_packets_in_average", value: 5, timestamp: ts.Add(-1 * time.Minute)},
+		{name: "aws_elasticache_network_packets_out_average", value: 5, timestamp: ts.Add(-1 * time.Minute)},
+		{name: "aws_elasticache_network_packets_out_average", value: 6, timestamp: ts.Add(-2 * time.Minute)},
+	}, actual)
+}
+
+func TestClampFutureTimestamps(t *testing.T) {
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newData := func(metricName string, addCloudwatchTimestamp bool, ts time.Time) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: metricName,
+			MetricMigrationParams: model.MetricMigrationParams{
+				AddCloudwatchTimestamp: addCloudwatchTimestamp,
+			},
+			Namespace: "AWS/ElastiCache",
+			Dimensions: []model.Dimension{
+				{Name: "CacheClusterId", Value: "redis-cluster"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+			ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+		}
+	}
+
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("NetworkPacketsIn", true, now.Add(30*time.Second)),
+			newData("NetworkPacketsOut", true, now.Add(-time.Minute)),
+			newData("CPUUtilization", false, now.Add(30*time.Second)),
+		},
+	}}
+
+	metrics, _, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	metrics = ClampFutureTimestamps(metrics, now)
+
+	timestamps := make(map[string]time.Time, len(metrics))
+	for _, metric := range metrics {
+		timestamps[metric.Name] = metric.Timestamp
+	}
+	require.Equal(t, map[string]time.Time{
+		"aws_elasticache_network_packets_in_average":  now,
+		"aws_elasticache_network_packets_out_average": now.Add(-time.Minute),
+		// Exported without a timestamp
+		"aws_elasticache_cpuutilization_average": {},
+	}, timestamps)
+
+	t.Run("keeps the newest future data point of a series", func(t *testing.T) {
+		exportAll := newData("NetworkPacketsIn", true, now.Add(-time.Minute))
+		exportAll.MetricMigrationParams.ExportAllDataPoints = true
+		exportAll.GetMetricDataResult.DataPoints = []model.DataPoint{
+			{Value: aws.Float64(1), Timestamp: now.Add(30 * time.Second)},
+			{Value: aws.Float64(2), Timestamp: now.Add(90 * time.Second)},
+			{Value: aws.Float64(3), Timestamp: now.Add(60 * time.Second)},
+			{Value: aws.Float64(4), Timestamp: now.Add(-time.Minute)},
+		}
+		metrics, _, err := BuildMetrics([]model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data:    []*model.CloudwatchData{exportAll},
+		}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 4)
+
+		metrics = ClampFutureTimestamps(metrics, now)
+		values := make(map[time.Time]float64, len(metrics))
+		for _, metric := range metrics {
+			values[metric.Timestamp] = metric.Value
+		}
+		require.Equal(t, map[time.Time]float64{now: 2, now.Add(-time.Minute): 4}, values)
+	})
+}
+
+func TestBuildMetrics_CustomTagsLabelPrefix(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{
+			Region:    "us-east-1",
+			AccountID: "123456789012",
+			CustomTags: []model.Tag{
+				{Key: "Team", Value: "storage"},
+				{Key: "region", Value: "overridden"},
+			},
+		},
+		Data: []*model.CloudwatchData{{
+			MetricName: "NetworkPacketsIn",
+			Namespace:  "AWS/ElastiCache",
+			Dimensions: []model.Dimension{
+				{Name: "CacheClusterId", Value: "redis-cluster"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+			ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+		}},
+	}}
+
+	testCases := []struct {
+		name           string
+		prefix         string
+		expectedLabels map[string]string
+	}{
+		{
+			name:   "default prefix",
+			prefix: "custom_tag_",
+			expectedLabels: map[string]string{
+				"name":                     "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+				"region":                   "us-east-1",
+				"account_id":               "123456789012",
+				"dimension_CacheClusterId": "redis-cluster",
+				"custom_tag_Team":          "storage",
+				"custom_tag_region":        "overridden",
+			},
+		},
+		{
+			name:   "custom prefix",
+			prefix: "ct_",
+			expectedLabels: map[string]string{
+				"name":                     "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+				"region":                   "us-east-1",
+				"account_id":               "123456789012",
+				"dimension_CacheClusterId": "redis-cluster",
+				"ct_Team":                  "storage",
+				"ct_region":                "overridden",
+			},
+		},
+		{
+			name:   "empty prefix does not override context labels",
+			prefix: "",
+			expectedLabels: map[string]string{
+				"name":                     "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+				"region":                   "us-east-1",
+				"account_id":               "123456789012",
+				"dimension_CacheClusterId": "redis-cluster",
+				"Team":                     "storage",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics, observedLabels, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: tc.prefix}, promslog.NewNopLogger())
+			require.NoError(t, err)
+			require.Len(t, metrics, 1)
+			require.Equal(t, tc.expectedLabels, metrics[0].Labels)
+
+			for label := range tc.expectedLabels {
+				require.Contains(t, observedLabels[metrics[0].Name], label)
+			}
+		})
+	}
+}
+
+func TestBuildMetrics_StatisticNames(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	newData := func(statistic string, statisticNames map[string]string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: "NetworkPacketsIn",
+			MetricMigrationParams: model.MetricMigrationParams{
+				StatisticNames: statisticNames,
+			},
+			Namespace: "AWS/ElastiCache",
+			Dimensions: []model.Dimension{
+				{Name: "CacheClusterId", Value: "redis-cluster"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  statistic,
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+			ResourceName: "arn:aws:elasticache:us-east-1:123456789012:cluster:redis-cluster",
+		}
+	}
+
+	renamed := map[string]string{"Sum": "total", "Average": "avg"}
+	data := []model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("Sum", nil),
+			newData("Sum", renamed),
+			newData("Average", renamed),
+			newData("Maximum", renamed),
+		},
+	}}
+
+	metrics, observedLabels, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		names = append(names, metric.Name)
+	}
+	require.Equal(t, []string{
+		"aws_elasticache_network_packets_in_sum",
+		"aws_elasticache_network_packets_in_total",
+		"aws_elasticache_network_packets_in_avg",
+		"aws_elasticache_network_packets_in_maximum",
+	}, names)
+	require.Contains(t, observedLabels, "aws_elasticache_network_packets_in_total")
+}
+
 func Benchmark_BuildMetrics(b *testing.B) {
 	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
 
@@ -1213,7 +1626,7 @@ func Benchmark_BuildMetrics(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, labels, err = BuildMetrics(data, false, promslog.NewNopLogger())
+		_, labels, err = BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
 	}
 
 	expectedLabels := map[string]model.LabelSet{
@@ -1604,3 +2017,982 @@ func Test_EnsureLabelConsistencyAndRemoveDuplicates(t *testing.T) {
 		})
 	})
 }
+
+func Test_EnsureLabelConsistencyAndRemoveDuplicatesWithLogger_CountsDuplicatesByName(t *testing.T) {
+	newMetrics := func() []*PrometheusMetric {
+		return []*PrometheusMetric{
+			{Name: "metric1", Labels: map[string]string{"label1": "value1"}, Value: 1.0},
+			{Name: "metric1", Labels: map[string]string{"label1": "value1"}, Value: 2.0},
+			{Name: "metric1", Labels: map[string]string{"label1": "value1"}, Value: 3.0},
+			{Name: "metric2", Labels: map[string]string{"label1": "value1"}, Value: 4.0},
+			{Name: "metric2", Labels: map[string]string{"label1": "value1"}, Value: 5.0},
+			{Name: "metric3", Labels: map[string]string{"label1": "value1"}, Value: 6.0},
+		}
+	}
+	observedLabels := map[string]model.LabelSet{
+		"metric1": {"label1": struct{}{}},
+		"metric2": {"label1": struct{}{}},
+		"metric3": {"label1": struct{}{}},
+	}
+
+	t.Run("counts the dropped duplicates by metric name", func(t *testing.T) {
+		sm := NewScrapeMetrics(prometheus.NewRegistry())
+
+		output := EnsureLabelConsistencyAndRemoveDuplicates(sm, newMetrics(), observedLabels)
+		require.Len(t, output, 3)
+		require.Equal(t, float64(3), testutil.ToFloat64(sm.DuplicateMetricsFilteredCounter.Raw()))
+		require.Equal(t, float64(2), testutil.ToFloat64(sm.DuplicateMetricsFilteredByNameCounter.Raw().WithLabelValues("metric1")))
+		require.Equal(t, float64(1), testutil.ToFloat64(sm.DuplicateMetricsFilteredByNameCounter.Raw().WithLabelValues("metric2")))
+		require.Equal(t, 2, testutil.CollectAndCount(sm.DuplicateMetricsFilteredByNameCounter.Raw()))
+	})
+
+	t.Run("logs the dropped duplicates", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		output := EnsureLabelConsistencyAndRemoveDuplicatesWithLogger(nil, newMetrics(), observedLabels, logger)
+		require.Len(t, output, 3)
+		require.Equal(t, 3, strings.Count(buf.String(), "dropping duplicate metric"))
+		require.Equal(t, 2, strings.Count(buf.String(), "metric=metric1 "))
+		require.Contains(t, buf.String(), "labels=map[label1:value1]")
+	})
+}
+
+func Test_EnsureLabelConsistencyAndRemoveDuplicates_RecordsLabelCardinality(t *testing.T) {
+	sm := NewScrapeMetrics(prometheus.NewRegistry())
+	observedLabels := map[string]model.LabelSet{
+		"metric1": {"label1": struct{}{}, "label2": struct{}{}, "label3": struct{}{}},
+		"metric2": {"label1": struct{}{}},
+	}
+	metrics := []*PrometheusMetric{
+		{Name: "metric1", Labels: map[string]string{"label1": "value1"}, Value: 1.0},
+		{Name: "metric1", Labels: map[string]string{"label2": "value2", "label3": "value3"}, Value: 2.0},
+		{Name: "metric2", Labels: map[string]string{"label1": "value1"}, Value: 3.0},
+	}
+
+	EnsureLabelConsistencyAndRemoveDuplicates(sm, metrics, observedLabels)
+	require.Equal(t, 2, testutil.CollectAndCount(sm.MetricLabelCardinalityGauge.Raw()))
+	require.Equal(t, float64(3), testutil.ToFloat64(sm.MetricLabelCardinalityGauge.Raw().WithLabelValues("metric1")))
+	require.Equal(t, float64(1), testutil.ToFloat64(sm.MetricLabelCardinalityGauge.Raw().WithLabelValues("metric2")))
+
+	// metric1 is no longer exported
+	EnsureLabelConsistencyAndRemoveDuplicates(sm, metrics[2:], map[string]model.LabelSet{
+		"metric2": {"label1": struct{}{}, "label2": struct{}{}},
+	})
+	require.Equal(t, 1, testutil.CollectAndCount(sm.MetricLabelCardinalityGauge.Raw()))
+	require.Equal(t, float64(2), testutil.ToFloat64(sm.MetricLabelCardinalityGauge.Raw().WithLabelValues("metric2")))
+}
+
+func TestCapSeriesPerMetric(t *testing.T) {
+	newData := func(metricName, functionName string, limit int, sample bool) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName: metricName,
+			Namespace:  "AWS/Lambda",
+			Dimensions: []model.Dimension{{Name: "FunctionName", Value: functionName}},
+			MetricMigrationParams: model.MetricMigrationParams{
+				MaxSeriesPerMetric: limit,
+				SampleCappedSeries: sample,
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: "Sum"},
+		}
+	}
+	functionNames := func(results []model.CloudwatchMetricResult, metricName string) []string {
+		names := make([]string, 0)
+		for _, result := range results {
+			for _, data := range result.Data {
+				if data.MetricName == metricName {
+					names = append(names, data.Dimensions[0].Value)
+				}
+			}
+		}
+		return names
+	}
+	newResults := func(functions []string, sample bool) []model.CloudwatchMetricResult {
+		data := make([]*model.CloudwatchData, 0, len(functions)+1)
+		for _, function := range functions {
+			data = append(data, newData("Invocations", function, 3, sample))
+		}
+		data = append(data, newData("Errors", "uncapped", 0, false))
+		return []model.CloudwatchMetricResult{
+			{Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"}, Data: data[:2]},
+			{Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"}, Data: data[2:]},
+		}
+	}
+	functions := []string{"f0", "f1", "f2", "f3", "f4"}
+
+	t.Run("drop keeps the first series regardless of order", func(t *testing.T) {
+		scrapeMetrics := NewScrapeMetrics(prometheus.NewRegistry())
+		results := CapSeriesPerMetric(scrapeMetrics, newResults(functions, false), promslog.NewNopLogger())
+
+		require.Equal(t, []string{"f0", "f1", "f2"}, functionNames(results, "Invocations"))
+		require.Equal(t, []string{"uncapped"}, functionNames(results, "Errors"))
+		require.Equal(t, float64(2), readCounterValue(t, scrapeMetrics.SeriesCappedCounter.Raw().WithLabelValues("AWS/Lambda")))
+
+		reversed := slices.Clone(functions)
+		slices.Reverse(reversed)
+		results = CapSeriesPerMetric(scrapeMetrics, newResults(reversed, false), promslog.NewNopLogger())
+		require.ElementsMatch(t, []string{"f0", "f1", "f2"}, functionNames(results, "Invocations"))
+	})
+
+	t.Run("sample keeps the same series regardless of order", func(t *testing.T) {
+		scrapeMetrics := NewScrapeMetrics(prometheus.NewRegistry())
+		results := CapSeriesPerMetric(scrapeMetrics, newResults(functions, true), promslog.NewNopLogger())
+		sampled := functionNames(results, "Invocations")
+		require.Len(t, sampled, 3)
+
+		reversed := slices.Clone(functions)
+		slices.Reverse(reversed)
+		results = CapSeriesPerMetric(scrapeMetrics, newResults(reversed, true), promslog.NewNopLogger())
+		require.ElementsMatch(t, sampled, functionNames(results, "Invocations"))
+		require.Equal(t, []string{"uncapped"}, functionNames(results, "Errors"))
+		require.Equal(t, float64(4), readCounterValue(t, scrapeMetrics.SeriesCappedCounter.Raw().WithLabelValues("AWS/Lambda")))
+	})
+
+	t.Run("metrics within the cap are untouched", func(t *testing.T) {
+		scrapeMetrics := NewScrapeMetrics(prometheus.NewRegistry())
+		results := CapSeriesPerMetric(scrapeMetrics, newResults(functions[:3], false), promslog.NewNopLogger())
+
+		require.Equal(t, []string{"f0", "f1", "f2"}, functionNames(results, "Invocations"))
+		require.Zero(t, readCounterValue(t, scrapeMetrics.SeriesCappedCounter.Raw().WithLabelValues("AWS/Lambda")))
+	})
+}
+
+func TestBuildMetrics_JobLabelsSnakeCase(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	newResult := func(labelsSnakeCase *bool, namespace, dimension, value string) model.CloudwatchMetricResult {
+		return model.CloudwatchMetricResult{
+			Context: &model.ScrapeContext{
+				Region:          "us-east-1",
+				AccountID:       "123456789012",
+				CustomTags:      []model.Tag{{Key: "CostCenter", Value: "team-a"}},
+				LabelsSnakeCase: labelsSnakeCase,
+			},
+			Data: []*model.CloudwatchData{{
+				MetricName: "Invocations",
+				Namespace:  namespace,
+				Dimensions: []model.Dimension{{Name: dimension, Value: value}},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic:  "Sum",
+					DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+				},
+				ResourceName: value,
+			}},
+		}
+	}
+	labelNames := func(metrics []*PrometheusMetric, name string) [][]string {
+		names := make([][]string, 0)
+		for _, metric := range metrics {
+			if metric.Name == name {
+				keys := slices.Sorted(maps.Keys(metric.Labels))
+				names = append(names, keys)
+			}
+		}
+		return names
+	}
+
+	data := []model.CloudwatchMetricResult{
+		// Lambda opts into snake case, with the global setting disabled.
+		newResult(aws.Bool(true), "AWS/Lambda", "FunctionName", "function-1"),
+		// Jobs of the same namespace disagree, so the global setting is used for all of them.
+		newResult(aws.Bool(true), "AWS/Events", "RuleName", "rule-1"),
+		newResult(nil, "AWS/Events", "RuleName", "rule-2"),
+	}
+
+	metrics, _, err := BuildMetrics(data, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"account_id", "custom_tag_cost_center", "dimension_function_name", "name", "region"},
+	}, labelNames(metrics, "aws_lambda_invocations_sum"))
+	require.Equal(t, [][]string{
+		{"account_id", "custom_tag_CostCenter", "dimension_RuleName", "name", "region"},
+		{"account_id", "custom_tag_CostCenter", "dimension_RuleName", "name", "region"},
+	}, labelNames(metrics, "aws_events_invocations_sum"))
+
+	// Opting out of the global setting works the same way.
+	metrics, _, err = BuildMetrics(data[:1], BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"account_id", "custom_tag_cost_center", "dimension_function_name", "name", "region"},
+	}, labelNames(metrics, "aws_lambda_invocations_sum"))
+	data[0].Context.LabelsSnakeCase = aws.Bool(false)
+	metrics, _, err = BuildMetrics(data[:1], BuildOptions{LabelsSnakeCase: true, CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"account_id", "custom_tag_CostCenter", "dimension_FunctionName", "name", "region"},
+	}, labelNames(metrics, "aws_lambda_invocations_sum"))
+}
+
+func TestBuildNamespaceInfoMetrics_JobLabelsSnakeCase(t *testing.T) {
+	resources := []model.TaggedResourceResult{
+		{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", LabelsSnakeCase: aws.Bool(true)},
+			Data: []*model.TaggedResource{{
+				ARN:       "arn:aws:lambda:us-east-1:123456789012:function:function-1",
+				Namespace: "AWS/Lambda",
+				Tags:      []model.Tag{{Key: "CostCenter", Value: "team-a"}},
+			}},
+		},
+		{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.TaggedResource{{
+				ARN:       "arn:aws:sqs:us-east-1:123456789012:queue-1",
+				Namespace: "AWS/SQS",
+				Tags:      []model.Tag{{Key: "CostCenter", Value: "team-a"}},
+			}},
+		},
+	}
+
+	metrics, _, err := BuildNamespaceInfoMetrics(resources, []*PrometheusMetric{}, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Contains(t, metrics[0].Labels, "tag_cost_center")
+	require.Contains(t, metrics[1].Labels, "tag_CostCenter")
+}
+
+func TestBuildMetrics_AllTagsOnInfoMetricOnly(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	resource := &model.TaggedResource{
+		ARN:       "arn:aws:lambda:us-east-1:123456789012:function:function-1",
+		Namespace: "AWS/Lambda",
+		Tags: []model.Tag{
+			{Key: "Team", Value: "payments"},
+			{Key: "Environment", Value: "production"},
+			{Key: "CostCenter", Value: "cc-42"},
+		},
+	}
+	sc := &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"}
+
+	metrics, observedMetricLabels, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: sc,
+		Data: []*model.CloudwatchData{{
+			MetricName:   "Invocations",
+			Namespace:    "AWS/Lambda",
+			ResourceName: resource.ARN,
+			Dimensions:   []model.Dimension{{Name: "FunctionName", Value: "function-1"}},
+			Tags:         resource.MetricTags([]string{"Team"}),
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}},
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	metrics, _, err = BuildNamespaceInfoMetrics([]model.TaggedResourceResult{{
+		Context: sc,
+		Data:    []*model.TaggedResource{resource},
+	}}, metrics, observedMetricLabels, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	require.Len(t, metrics, 2)
+	require.Equal(t, "aws_lambda_invocations_sum", metrics[0].Name)
+	require.Equal(t, map[string]string{
+		"account_id":             "123456789012",
+		"region":                 "us-east-1",
+		"name":                   resource.ARN,
+		"dimension_FunctionName": "function-1",
+		"tag_Team":               "payments",
+	}, metrics[0].Labels)
+	require.Equal(t, "aws_lambda_info", metrics[1].Name)
+	require.Equal(t, map[string]string{
+		"account_id":      "123456789012",
+		"region":          "us-east-1",
+		"name":            resource.ARN,
+		"tag_Team":        "payments",
+		"tag_Environment": "production",
+		"tag_CostCenter":  "cc-42",
+	}, metrics[1].Labels)
+}
+
+func TestBuildMetrics_ARNLabelName(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	resource := &model.TaggedResource{
+		ARN:       "arn:aws:lambda:us-east-1:123456789012:function:function-1",
+		Namespace: "AWS/Lambda",
+		Tags:      []model.Tag{{Key: "Team", Value: "payments"}},
+	}
+	sc := &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"}
+
+	metrics, observedMetricLabels, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: sc,
+		Data: []*model.CloudwatchData{{
+			MetricName:   "Invocations",
+			Namespace:    "AWS/Lambda",
+			ResourceName: resource.ARN,
+			Dimensions:   []model.Dimension{{Name: "FunctionName", Value: "function-1"}},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}},
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", ARNLabelName: "arn"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	metrics, observedMetricLabels, err = BuildNamespaceInfoMetrics([]model.TaggedResourceResult{{
+		Context: sc,
+		Data:    []*model.TaggedResource{resource},
+	}}, metrics, observedMetricLabels, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", ARNLabelName: "arn"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	metrics = EnsureLabelConsistencyAndRemoveDuplicates(Discard, metrics, observedMetricLabels)
+
+	require.Len(t, metrics, 2)
+	require.Equal(t, "aws_lambda_invocations_sum", metrics[0].Name)
+	require.Equal(t, map[string]string{
+		"account_id":             "123456789012",
+		"region":                 "us-east-1",
+		"arn":                    resource.ARN,
+		"dimension_FunctionName": "function-1",
+	}, metrics[0].Labels)
+	require.Equal(t, "aws_lambda_info", metrics[1].Name)
+	require.Equal(t, map[string]string{
+		"account_id": "123456789012",
+		"region":     "us-east-1",
+		"arn":        resource.ARN,
+		"tag_Team":   "payments",
+	}, metrics[1].Labels)
+}
+
+func TestBuildNamespaceInfoMetrics_DedupeAcrossRegions(t *testing.T) {
+	distributionARN := "arn:aws:cloudfront::123456789012:distribution/E1ABCDEF"
+	newResult := func(region string, dedupe bool) model.TaggedResourceResult {
+		return model.TaggedResourceResult{
+			Context: &model.ScrapeContext{Region: region, AccountID: "123456789012"},
+			Data: []*model.TaggedResource{{
+				ARN:       distributionARN,
+				Namespace: "AWS/CloudFront",
+				Region:    region,
+				Tags:      []model.Tag{{Key: "Team", Value: "payments"}},
+			}},
+			DedupeAcrossRegions: dedupe,
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		dedupe      bool
+		wantRegions []string
+	}{
+		{name: "keep per region", dedupe: false, wantRegions: []string{"us-east-1", "eu-west-1"}},
+		// The first region in lexical order is kept, whatever the order of the results
+		{name: "dedupe", dedupe: true, wantRegions: []string{"eu-west-1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tagData := []model.TaggedResourceResult{newResult("us-east-1", tc.dedupe), newResult("eu-west-1", tc.dedupe)}
+			metrics, observedMetricLabels, err := BuildNamespaceInfoMetrics(tagData, []*PrometheusMetric{}, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+			require.NoError(t, err)
+			metrics = EnsureLabelConsistencyAndRemoveDuplicates(Discard, metrics, observedMetricLabels)
+
+			regions := make([]string, 0, len(metrics))
+			for _, metric := range metrics {
+				require.Equal(t, "aws_cloudfront_info", metric.Name)
+				require.Equal(t, distributionARN, metric.Labels["name"])
+				regions = append(regions, metric.Labels["region"])
+			}
+			require.Equal(t, tc.wantRegions, regions)
+		})
+	}
+}
+
+func TestBuildMetrics_NilToZeroStatistics(t *testing.T) {
+	newData := func(statistic string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   "Invocations",
+			Namespace:    "AWS/Lambda",
+			ResourceName: "arn:aws:lambda:us-east-1:123456789012:function:function-1",
+			Dimensions:   []model.Dimension{{Name: "FunctionName", Value: "function-1"}},
+			MetricMigrationParams: model.MetricMigrationParams{
+				NilToZero:           false,
+				NilToZeroStatistics: map[string]bool{"Sum": true, "Maximum": false},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{Statistic: statistic},
+		}
+	}
+
+	metrics, _, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data:    []*model.CloudwatchData{newData("Sum"), newData("Average"), newData("Maximum")},
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	values := make(map[string]float64, len(metrics))
+	for _, metric := range metrics {
+		values[metric.Name] = metric.Value
+	}
+	require.Len(t, values, 3)
+	require.Equal(t, float64(0), values["aws_lambda_invocations_sum"])
+	require.True(t, math.IsNaN(values["aws_lambda_invocations_average"]))
+	require.True(t, math.IsNaN(values["aws_lambda_invocations_maximum"]))
+
+	// Statistics which aren't listed fall back to NilToZero.
+	data := []*model.CloudwatchData{newData("Sum"), newData("Average"), newData("Maximum")}
+	for _, d := range data {
+		d.MetricMigrationParams.NilToZero = true
+	}
+	metrics, _, err = BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data:    data,
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	for _, metric := range metrics {
+		values[metric.Name] = metric.Value
+	}
+	require.Equal(t, float64(0), values["aws_lambda_invocations_sum"])
+	require.Equal(t, float64(0), values["aws_lambda_invocations_average"])
+	require.True(t, math.IsNaN(values["aws_lambda_invocations_maximum"]))
+}
+
+func TestBuildMetrics_QueryWindowLabels(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newData := func(window *model.QueryWindow) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   "CPUUtilization",
+			Namespace:    "AWS/EC2",
+			ResourceName: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",
+			Dimensions:   []model.Dimension{{Name: "InstanceId", Value: "i-abc123"}},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+				Window:     window,
+			},
+		}
+	}
+	sc := &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"}
+
+	metrics, _, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: sc,
+		Data: []*model.CloudwatchData{newData(&model.QueryWindow{
+			StartTime: time.Date(2024, time.January, 1, 9, 53, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, time.January, 1, 10, 3, 0, 0, time.UTC),
+			Period:    300,
+		})},
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "2024-01-01T09:53:00Z", metrics[0].Labels["window_start"])
+	require.Equal(t, "2024-01-01T10:03:00Z", metrics[0].Labels["window_end"])
+	require.Equal(t, "300", metrics[0].Labels["window_period"])
+
+	metrics, _, err = BuildMetrics([]model.CloudwatchMetricResult{{Context: sc, Data: []*model.CloudwatchData{newData(nil)}}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.NotContains(t, metrics[0].Labels, "window_start")
+}
+
+func TestBuildMetrics_PartitionLabel(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newData := func(resourceName string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   "CPUUtilization",
+			Namespace:    "AWS/EC2",
+			ResourceName: resourceName,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}
+	}
+	data := []*model.CloudwatchData{
+		newData("arn:aws:ec2:us-east-1:123456789012:instance/i-commercial"),
+		newData("arn:aws-cn:ec2:cn-north-1:123456789012:instance/i-china"),
+		newData("arn:aws-us-gov:ec2:us-gov-west-1:123456789012:instance/i-govcloud"),
+		newData("global"),
+	}
+
+	metrics, observedMetricLabels, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", PartitionLabel: true},
+		Data:    data,
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 4)
+
+	partitions := make(map[string]string, len(metrics))
+	for _, metric := range metrics {
+		partition, ok := metric.Labels["partition"]
+		if ok {
+			partitions[metric.Labels["name"]] = partition
+		}
+	}
+	require.Equal(t, map[string]string{
+		"arn:aws:ec2:us-east-1:123456789012:instance/i-commercial":          "aws",
+		"arn:aws-cn:ec2:cn-north-1:123456789012:instance/i-china":           "aws-cn",
+		"arn:aws-us-gov:ec2:us-gov-west-1:123456789012:instance/i-govcloud": "aws-us-gov",
+	}, partitions)
+	require.Contains(t, observedMetricLabels["aws_ec2_cpuutilization_average"], "partition")
+
+	metrics, _, err = BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data:    data[:1],
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.NotContains(t, metrics[0].Labels, "partition")
+}
+
+func TestBuildMetrics_OmitGlobalNameLabel(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newData := func(metricName, resourceName string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   metricName,
+			Namespace:    "AWS/EC2",
+			ResourceName: resourceName,
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Average",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}
+	}
+	data := []*model.CloudwatchData{
+		newData("CPUUtilization", "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123"),
+		newData("CPUUtilization", "global"),
+		newData("NetworkIn", "global"),
+	}
+
+	build := func(omitGlobalNameLabel bool) map[string][]map[string]string {
+		metrics, observedMetricLabels, err := BuildMetrics([]model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", OmitGlobalNameLabel: omitGlobalNameLabel},
+			Data:    data,
+		}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		metrics = EnsureLabelConsistencyAndRemoveDuplicates(Discard, metrics, observedMetricLabels)
+
+		labels := make(map[string][]map[string]string)
+		for _, metric := range metrics {
+			labels[metric.Name] = append(labels[metric.Name], metric.Labels)
+		}
+		return labels
+	}
+
+	labels := build(false)
+	require.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", labels["aws_ec2_cpuutilization_average"][0]["name"])
+	require.Equal(t, "global", labels["aws_ec2_cpuutilization_average"][1]["name"])
+	require.Equal(t, "global", labels["aws_ec2_network_in_average"][0]["name"])
+
+	labels = build(true)
+	require.Equal(t, "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", labels["aws_ec2_cpuutilization_average"][0]["name"])
+	// Kept as an empty label, as the other series of the metric have a name
+	require.Contains(t, labels["aws_ec2_cpuutilization_average"][1], "name")
+	require.Empty(t, labels["aws_ec2_cpuutilization_average"][1]["name"])
+	require.NotContains(t, labels["aws_ec2_network_in_average"][0], "name")
+}
+
+func TestBuildRecentlyActiveMetrics(t *testing.T) {
+	active := &model.TaggedResource{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", Namespace: "AWS/EC2", Region: "us-east-1"}
+	idle := &model.TaggedResource{ARN: "arn:aws:ec2:us-east-1:123456789012:instance/i-def456", Namespace: "AWS/EC2", Region: "us-east-1"}
+	tagData := []model.TaggedResourceResult{
+		{
+			Data:               []*model.TaggedResource{active, idle},
+			RecentlyActiveARNs: map[string]struct{}{active.ARN: {}},
+		},
+		{
+			// Jobs without RecentlyActiveOnly export nothing
+			Data: []*model.TaggedResource{{ARN: "arn:aws:sqs:us-east-1:123456789012:queue", Namespace: "AWS/SQS", Region: "us-east-1"}},
+		},
+	}
+
+	metrics, observedMetricLabels := BuildRecentlyActiveMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+
+	require.Equal(t, []*PrometheusMetric{
+		{Name: "yace_aws_ec2_recently_active", Labels: map[string]string{"name": active.ARN}, Value: 1},
+		{Name: "yace_aws_ec2_recently_active", Labels: map[string]string{"name": idle.ARN}, Value: 0},
+	}, metrics)
+	require.Equal(t, map[string]model.LabelSet{"yace_aws_ec2_recently_active": {"name": {}}}, observedMetricLabels)
+
+	t.Run("resources only reported as recently active have no info metric", func(t *testing.T) {
+		tagData := []model.TaggedResourceResult{{
+			Data:                    []*model.TaggedResource{active, idle},
+			RecentlyActiveARNs:      map[string]struct{}{active.ARN: {}},
+			OnlyRecentlyActive:      true,
+			ResourceCountGroupByTag: "Team",
+		}}
+
+		metrics, _ := BuildRecentlyActiveMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.Len(t, metrics, 2)
+
+		metrics, _, err := BuildNamespaceInfoMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Empty(t, metrics)
+
+		metrics, _, err = BuildResourceCountMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Empty(t, metrics)
+	})
+}
+
+func TestBuildMetrics_MetricNameOverrides(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newData := func(metricName string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   metricName,
+			Namespace:    "Glue",
+			ResourceName: "jobs",
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}
+	}
+	data := []*model.CloudwatchData{newData("GlueErrors"), newData("Errors")}
+
+	build := func(overrides map[string]string) []string {
+		metrics, _, err := BuildMetrics([]model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012", MetricNameOverrides: overrides},
+			Data:    data,
+		}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+
+		names := make([]string, 0, len(metrics))
+		for _, metric := range metrics {
+			names = append(names, metric.Name)
+		}
+		return names
+	}
+
+	// The namespace prefix is stripped from GlueErrors, which then collides with Errors
+	require.Equal(t, []string{"aws_glue_errors_sum", "aws_glue_errors_sum"}, build(nil))
+	require.Equal(t, []string{"aws_glue_glue_errors_sum", "aws_glue_errors_sum"}, build(map[string]string{"GlueErrors": "glue_errors"}))
+}
+
+func TestBuildMetrics_MetricType(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newData := func(metricName string, metricType string) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:            metricName,
+			Namespace:             "AWS/SQS",
+			ResourceName:          "arn:aws:sqs:us-east-1:123456789012:queue",
+			MetricMigrationParams: model.MetricMigrationParams{MetricType: metricType},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+			},
+		}
+	}
+	metrics, _, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data: []*model.CloudwatchData{
+			newData("NumberOfMessagesSent", model.MetricTypeCounter),
+			newData("NumberOfMessagesReceived", model.MetricTypeUntyped),
+			newData("NumberOfMessagesDeleted", model.MetricTypeGauge),
+			newData("NumberOfEmptyReceives", ""),
+		},
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(NewPrometheusCollector(metrics)))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	types := map[string]dto.MetricType{}
+	for _, family := range families {
+		types[family.GetName()] = family.GetType()
+	}
+	require.Equal(t, map[string]dto.MetricType{
+		"aws_sqs_number_of_messages_sent_sum":     dto.MetricType_COUNTER,
+		"aws_sqs_number_of_messages_received_sum": dto.MetricType_UNTYPED,
+		"aws_sqs_number_of_messages_deleted_sum":  dto.MetricType_GAUGE,
+		"aws_sqs_number_of_empty_receives_sum":    dto.MetricType_GAUGE,
+	}, types)
+}
+
+func TestBuildMetrics_InvalidLabelNameAction(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC)
+	newResults := func(dimensions []model.Dimension, tags []model.Tag) []model.CloudwatchMetricResult {
+		return []model.CloudwatchMetricResult{{
+			Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+			Data: []*model.CloudwatchData{{
+				MetricName:   "NumberOfMessagesSent",
+				Namespace:    "AWS/SQS",
+				ResourceName: "arn:aws:sqs:us-east-1:123456789012:queue",
+				Dimensions:   dimensions,
+				Tags:         tags,
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic:  "Sum",
+					DataPoints: []model.DataPoint{{Value: aws.Float64(1), Timestamp: ts}},
+				},
+			}},
+		}}
+	}
+	dimensions := []model.Dimension{{Name: "QueueName", Value: "queue"}, {Name: "Queue#Tier", Value: "gold"}}
+	tags := []model.Tag{{Key: "team", Value: "platform"}, {Key: "cost#center", Value: "42"}}
+
+	t.Run("skip drops the invalid dimension and tag", func(t *testing.T) {
+		metrics, observedLabels, err := BuildMetrics(newResults(dimensions, tags), BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "queue", metrics[0].Labels["dimension_QueueName"])
+		require.Equal(t, "platform", metrics[0].Labels["tag_team"])
+		require.NotContains(t, metrics[0].Labels, "dimension_Queue_Tier")
+		require.NotContains(t, metrics[0].Labels, "tag_cost_center")
+		require.Len(t, observedLabels["aws_sqs_number_of_messages_sent_sum"], len(metrics[0].Labels))
+	})
+
+	t.Run("sanitize keeps the invalid dimension and tag", func(t *testing.T) {
+		metrics, observedLabels, err := BuildMetrics(newResults(dimensions, tags), BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionSanitize}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "gold", metrics[0].Labels["dimension_Queue_Tier"])
+		require.Equal(t, "42", metrics[0].Labels["tag_cost_center"])
+		require.Contains(t, observedLabels["aws_sqs_number_of_messages_sent_sum"], "dimension_Queue_Tier")
+		require.Contains(t, observedLabels["aws_sqs_number_of_messages_sent_sum"], "tag_cost_center")
+	})
+
+	t.Run("fail fails on an invalid dimension", func(t *testing.T) {
+		_, _, err := BuildMetrics(newResults(dimensions, nil), BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.ErrorContains(t, err, `dimension name "Queue#Tier" of metric NumberOfMessagesSent is an invalid prometheus label name`)
+	})
+
+	t.Run("fail fails on an invalid tag", func(t *testing.T) {
+		_, _, err := BuildMetrics(newResults(nil, tags), BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.ErrorContains(t, err, `tag name "cost#center" of metric NumberOfMessagesSent is an invalid prometheus label name`)
+	})
+
+	t.Run("valid names never fail", func(t *testing.T) {
+		_, _, err := BuildMetrics(newResults(dimensions[:1], tags[:1]), BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.NoError(t, err)
+	})
+}
+
+func TestBuildNamespaceInfoMetrics_InvalidLabelNameAction(t *testing.T) {
+	tagData := []model.TaggedResourceResult{{
+		ResourceCountGroupByTag: "cost#center",
+		Data: []*model.TaggedResource{{
+			ARN:       "arn:aws:sqs:us-east-1:123456789012:queue",
+			Namespace: "AWS/SQS",
+			Tags:      []model.Tag{{Key: "team", Value: "platform"}, {Key: "cost#center", Value: "42"}},
+		}},
+	}}
+
+	t.Run("skip drops the invalid tag", func(t *testing.T) {
+		metrics, _, err := BuildNamespaceInfoMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "platform", metrics[0].Labels["tag_team"])
+		require.NotContains(t, metrics[0].Labels, "tag_cost_center")
+
+		metrics, _, err = BuildResourceCountMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Empty(t, metrics)
+	})
+
+	t.Run("sanitize keeps the invalid tag", func(t *testing.T) {
+		metrics, observedLabels, err := BuildNamespaceInfoMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionSanitize}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "42", metrics[0].Labels["tag_cost_center"])
+		require.Contains(t, observedLabels["aws_sqs_info"], "tag_cost_center")
+
+		metrics, _, err = BuildResourceCountMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionSanitize}, promslog.NewNopLogger())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, map[string]string{"tag_cost_center": "42"}, metrics[0].Labels)
+	})
+
+	t.Run("fail fails on an invalid tag", func(t *testing.T) {
+		_, _, err := BuildNamespaceInfoMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.ErrorContains(t, err, `tag name "cost#center" of resource arn:aws:sqs:us-east-1:123456789012:queue is an invalid prometheus label name`)
+
+		_, _, err = BuildResourceCountMetrics(tagData, nil, map[string]model.LabelSet{}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.ErrorContains(t, err, `resource count tag name "cost#center" of namespace AWS/SQS is an invalid prometheus label name`)
+	})
+}
+
+func TestPromLabelName_Sanitize(t *testing.T) {
+	for _, tc := range []struct {
+		name            string
+		labelsSnakeCase bool
+		want            string
+	}{
+		{name: "cost#center", want: "cost_center"},
+		{name: "CostCenter#Id", labelsSnakeCase: true, want: "cost_center_id"},
+		{name: "Ünit", want: "_nit"},
+		{name: "1st#owner", want: "_1st_owner"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, got := promLabelName(tc.name, tc.labelsSnakeCase, InvalidLabelNameActionSanitize)
+			require.True(t, ok)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// manyCloudwatchMetricResults returns results of several scrape contexts, whose data have distinct tags and
+// include a namespace colliding with AWS/EC2, to exercise the merge of concurrently built shards.
+func manyCloudwatchMetricResults(resultsCount, dataPerResult int) []model.CloudwatchMetricResult {
+	ts := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	results := make([]model.CloudwatchMetricResult, 0, resultsCount)
+	for r := range resultsCount {
+		result := model.CloudwatchMetricResult{
+			Context: &model.ScrapeContext{
+				Region:         fmt.Sprintf("us-east-%d", r+1),
+				AccountID:      "123456789012",
+				PartitionLabel: r%2 == 0,
+			},
+			Data: make([]*model.CloudwatchData, 0, dataPerResult),
+		}
+		for i := range dataPerResult {
+			namespace := "AWS/EC2"
+			if i%7 == 0 {
+				namespace = "EC2"
+			}
+			instanceID := fmt.Sprintf("i-%d-%d", r, i)
+			result.Data = append(result.Data, &model.CloudwatchData{
+				MetricName: []string{"CPUUtilization", "NetworkIn", "NetworkOut"}[i%3],
+				Namespace:  namespace,
+				Dimensions: []model.Dimension{{Name: "InstanceId", Value: instanceID}},
+				Tags:       []model.Tag{{Key: fmt.Sprintf("tag%d", i%5), Value: "value"}},
+				MetricMigrationParams: model.MetricMigrationParams{
+					NilToZero: true,
+				},
+				GetMetricDataResult: &model.GetMetricDataResult{
+					Statistic:  "Average",
+					DataPoints: []model.DataPoint{{Value: aws.Float64(float64(i)), Timestamp: ts}},
+				},
+				ResourceName: "arn:aws:ec2:us-east-1:123456789012:instance/" + instanceID,
+			})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func TestBuildMetricsConcurrently_MatchesBuildMetrics(t *testing.T) {
+	results := manyCloudwatchMetricResults(3, 100)
+	expectedMetrics, expectedLabels, err := BuildMetrics(results, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+	require.Len(t, expectedMetrics, 300)
+
+	for _, concurrency := range []int{0, 1, 2, 7, 1000} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			metrics, labels, err := BuildMetricsConcurrently(results, concurrency, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+			require.NoError(t, err)
+			require.Equal(t, expectedMetrics, metrics)
+			require.Equal(t, expectedLabels, labels)
+		})
+	}
+
+	t.Run("first error", func(t *testing.T) {
+		results := manyCloudwatchMetricResults(2, 50)
+		results[0].Data[30].Dimensions = append(results[0].Data[30].Dimensions, model.Dimension{Name: "Instance#Tier", Value: "a"})
+		results[1].Data[10].Dimensions = append(results[1].Data[10].Dimensions, model.Dimension{Name: "Other#Tier", Value: "b"})
+
+		_, _, expectedErr := BuildMetrics(results, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.ErrorContains(t, expectedErr, "Instance#Tier")
+
+		_, _, err := BuildMetricsConcurrently(results, 8, BuildOptions{CustomTagsLabelPrefix: "custom_tag_", InvalidLabelNameAction: InvalidLabelNameActionFail}, promslog.NewNopLogger())
+		require.Equal(t, expectedErr, err)
+	})
+}
+
+func Benchmark_BuildMetricsConcurrently(b *testing.B) {
+	results := manyCloudwatchMetricResults(4, 25000)
+
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency %d", concurrency), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				_, _, err := BuildMetricsConcurrently(results, concurrency, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMetrics_SumWithoutDimensions(t *testing.T) {
+	ts := time.Date(2024, time.January, 1, 0, 5, 0, 0, time.UTC)
+	lbARN := "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/lb-1/0123456789abcdef"
+	otherLBARN := "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/lb-2/0123456789abcdef"
+	newData := func(resourceARN, loadBalancer, zone string, dataPoints ...model.DataPoint) *model.CloudwatchData {
+		return &model.CloudwatchData{
+			MetricName:   "RequestCount",
+			Namespace:    "AWS/ApplicationELB",
+			ResourceName: resourceARN,
+			Dimensions: []model.Dimension{
+				{Name: "LoadBalancer", Value: loadBalancer},
+				{Name: "AvailabilityZone", Value: zone},
+			},
+			MetricMigrationParams: model.MetricMigrationParams{
+				AddCloudwatchTimestamp: true,
+				ExportAllDataPoints:    true,
+				SumWithoutDimensions:   []string{"AvailabilityZone"},
+			},
+			GetMetricDataResult: &model.GetMetricDataResult{
+				Statistic:  "Sum",
+				DataPoints: dataPoints,
+			},
+		}
+	}
+	data := []*model.CloudwatchData{
+		newData(lbARN, "app/lb-1/0123456789abcdef", "us-east-1a",
+			model.DataPoint{Value: aws.Float64(10), Timestamp: ts},
+			model.DataPoint{Value: aws.Float64(8), Timestamp: ts.Add(-time.Minute)},
+		),
+		newData(lbARN, "app/lb-1/0123456789abcdef", "us-east-1b",
+			model.DataPoint{Value: aws.Float64(5), Timestamp: ts},
+			model.DataPoint{Value: aws.Float64(4), Timestamp: ts.Add(-2 * time.Minute)},
+		),
+		// Missing values don't make the sum missing
+		newData(lbARN, "app/lb-1/0123456789abcdef", "us-east-1c",
+			model.DataPoint{Value: nil, Timestamp: ts},
+		),
+		newData(otherLBARN, "app/lb-2/0123456789abcdef", "us-east-1a",
+			model.DataPoint{Value: aws.Float64(1), Timestamp: ts},
+		),
+	}
+	// Not configured with SumWithoutDimensions, so it keeps its AvailabilityZone
+	unsummed := newData(lbARN, "app/lb-1/0123456789abcdef", "us-east-1a", model.DataPoint{Value: aws.Float64(3), Timestamp: ts})
+	unsummed.MetricName = "HTTPCode_ELB_5XX_Count"
+	unsummed.MetricMigrationParams.SumWithoutDimensions = nil
+	data = append(data, unsummed)
+
+	metrics, observedMetricLabels, err := BuildMetrics([]model.CloudwatchMetricResult{{
+		Context: &model.ScrapeContext{Region: "us-east-1", AccountID: "123456789012"},
+		Data:    data,
+	}}, BuildOptions{CustomTagsLabelPrefix: "custom_tag_"}, promslog.NewNopLogger())
+	require.NoError(t, err)
+
+	type sample struct {
+		name      string
+		labels    map[string]string
+		value     float64
+		timestamp time.Time
+	}
+	var got []sample
+	for _, metric := range metrics {
+		got = append(got, sample{name: metric.Name, labels: metric.Labels, value: metric.Value, timestamp: metric.Timestamp})
+	}
+	lbLabels := func(resourceARN, loadBalancer string) map[string]string {
+		return map[string]string{
+			"account_id":             "123456789012",
+			"region":                 "us-east-1",
+			"name":                   resourceARN,
+			"dimension_LoadBalancer": loadBalancer,
+		}
+	}
+	require.Equal(t, []sample{
+		{name: "aws_applicationelb_request_count_sum", labels: lbLabels(lbARN, "app/lb-1/0123456789abcdef"), value: 15, timestamp: ts},
+		{name: "aws_applicationelb_request_count_sum", labels: lbLabels(lbARN, "app/lb-1/0123456789abcdef"), value: 8, timestamp: ts.Add(-time.Minute)},
+		{name: "aws_applicationelb_request_count_sum", labels: lbLabels(lbARN, "app/lb-1/0123456789abcdef"), value: 4, timestamp: ts.Add(-2 * time.Minute)},
+		{name: "aws_applicationelb_request_count_sum", labels: lbLabels(otherLBARN, "app/lb-2/0123456789abcdef"), value: 1, timestamp: ts},
+		{
+			name: "aws_applicationelb_httpcode_elb_5_xx_count_sum",
+			labels: map[string]string{
+				"account_id":                 "123456789012",
+				"region":                     "us-east-1",
+				"name":                       lbARN,
+				"dimension_LoadBalancer":     "app/lb-1/0123456789abcdef",
+				"dimension_AvailabilityZone": "us-east-1a",
+			},
+			value:     3,
+			timestamp: ts,
+		},
+	}, got)
+	require.NotContains(t, observedMetricLabels["aws_applicationelb_request_count_sum"], "dimension_AvailabilityZone")
+
+	// The scraped data isn't modified, as it can be kept across scrapes.
+	require.Len(t, data[0].Dimensions, 2)
+	require.Equal(t, 10.0, *data[0].GetMetricDataResult.DataPoints[0].Value)
+	require.Len(t, data[0].GetMetricDataResult.DataPoints, 2)
+}
@@ -13,12 +13,17 @@
 package promutil
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
 	"golang.org/x/exp/maps"
 )
@@ -49,6 +54,7 @@ type ScrapeMetrics struct {
 	CloudwatchAPICounter                     CounterVec // labels: api_name
 	CloudwatchGetMetricDataAPICounter        Counter
 	CloudwatchGetMetricDataAPIMetricsCounter Counter
+	CloudwatchGetMetricDataNamespaceCounter  CounterVec // labels: namespace
 	CloudwatchGetMetricStatisticsAPICounter  Counter
 	ResourceGroupTaggingAPICounter           Counter
 	AutoScalingAPICounter                    Counter
@@ -60,7 +66,23 @@ type ScrapeMetrics struct {
 	ManagedPrometheusAPICounter              Counter
 	StoragegatewayAPICounter                 Counter
 	DmsAPICounter                            Counter
+	TaggingAPIRateLimitWaitCounter           Counter
+	TaggingAPIRateLimitAllowedCounter        Counter
 	DuplicateMetricsFilteredCounter          Counter
+	DuplicateMetricsFilteredByNameCounter    CounterVec // labels: metric_name
+	SeriesLimitExceededCounter               Counter
+	ZeroDimensionMetricsSkippedCounter       Counter
+	SeriesCappedCounter                      CounterVec   // labels: namespace
+	AssociatorRegexNoMatchCounter            CounterVec   // labels: namespace, regex
+	EnhancedMetricsResourcesCoveredCounter   CounterVec   // labels: namespace
+	EnhancedMetricsResourcesMissingCounter   CounterVec   // labels: namespace
+	EnhancedMetricsUnsupportedCounter        CounterVec   // labels: namespace, metric
+	EnhancedMetricsEnabledGauge              GaugeVec     // labels: namespace
+	ClientSDKVersionGauge                    GaugeVec     // labels: sdk
+	MetricLabelCardinalityGauge              GaugeVec     // labels: metric_name
+	JobLastSuccessTimestampGauge             GaugeVec     // labels: namespace, region, role
+	APIPagesHistogram                        HistogramVec // labels: api
+	JobScrapeDurationHistogram               HistogramVec // labels: namespace, region
 }
 
 func NewScrapeMetrics(r prometheus.Registerer) *ScrapeMetrics {
@@ -86,6 +108,10 @@ func NewScrapeMetrics(r prometheus.Registerer) *ScrapeMetrics {
 			Name: "yace_cloudwatch_getmetricdata_metrics_requested_total",
 			Help: "Number of metrics requested from the CloudWatch GetMetricData API which is how AWS bills",
 		})},
+		CloudwatchGetMetricDataNamespaceCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_cloudwatch_getmetricdata_metrics_requested_by_namespace_total",
+			Help: "Number of metrics requested from the CloudWatch GetMetricData API, by namespace",
+		}, []string{"namespace"})},
 		CloudwatchGetMetricStatisticsAPICounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
 			Name: "yace_cloudwatch_getmetricstatistics_requests_total",
 			Help: "DEPRECATED: replaced by yace_cloudwatch_requests_total with api_name label",
@@ -128,10 +154,76 @@ func NewScrapeMetrics(r prometheus.Registerer) *ScrapeMetrics {
 			Name: "yace_cloudwatch_dmsapi_requests_total",
 			Help: "Help is not implemented yet.",
 		})},
+		TaggingAPIRateLimitWaitCounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
+			Name: "yace_tagging_api_rate_limit_waits_total",
+			Help: "Number of resource discovery calls delayed by the taggingAPIRateLimit of their role",
+		})},
+		TaggingAPIRateLimitAllowedCounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
+			Name: "yace_tagging_api_rate_limit_allowed_total",
+			Help: "Number of resource discovery calls started without delay under the taggingAPIRateLimit of their role",
+		})},
 		DuplicateMetricsFilteredCounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
 			Name: "yace_cloudwatch_duplicate_metrics_filtered",
 			Help: "Help is not implemented yet.",
 		})},
+		DuplicateMetricsFilteredByNameCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_cloudwatch_duplicate_metrics_filtered_by_name_total",
+			Help: "Number of duplicate series dropped from the exported metrics, by metric name",
+		}, []string{"metric_name"})},
+		SeriesLimitExceededCounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
+			Name: "yace_series_limit_exceeded_total",
+			Help: "Number of scrapes which produced more series than the configured limit",
+		})},
+		ZeroDimensionMetricsSkippedCounter: Counter{inner: f.NewCounter(prometheus.CounterOpts{
+			Name: "yace_cloudwatch_zero_dimension_metrics_skipped_total",
+			Help: "Number of metrics without dimensions skipped by discovery jobs with skipZeroDimensionMetrics enabled",
+		})},
+		SeriesCappedCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_cloudwatch_series_capped_total",
+			Help: "Number of series dropped because their metric exceeded maxSeriesPerMetric, by namespace",
+		}, []string{"namespace"})},
+		AssociatorRegexNoMatchCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_associator_regex_no_match_total",
+			Help: "Number of times a dimensions regex of a namespace matched none of the discovered resources, by namespace and regex",
+		}, []string{"namespace", "regex"})},
+		EnhancedMetricsResourcesCoveredCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_enhanced_metrics_resources_covered_total",
+			Help: "Number of discovered resources found in the describe output of the enhanced metrics, by namespace",
+		}, []string{"namespace"})},
+		EnhancedMetricsResourcesMissingCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_enhanced_metrics_resources_missing_total",
+			Help: "Number of discovered resources missing from the describe output of the enhanced metrics, by namespace",
+		}, []string{"namespace"})},
+		EnhancedMetricsUnsupportedCounter: CounterVec{inner: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "yace_enhanced_unsupported_metric_total",
+			Help: "Number of times an enhanced metric was skipped because its service doesn't support it, by namespace and metric",
+		}, []string{"namespace", "metric"})},
+		EnhancedMetricsEnabledGauge: GaugeVec{inner: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yace_enhanced_metrics_enabled",
+			Help: "Set to 1 for the namespaces with enhanced metrics configured and supported",
+		}, []string{"namespace"})},
+		ClientSDKVersionGauge: GaugeVec{inner: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yace_client_sdk_version",
+			Help: "Set to 1 for the AWS SDK version used by the active client factory",
+		}, []string{"sdk"})},
+		MetricLabelCardinalityGauge: GaugeVec{inner: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yace_metric_label_cardinality",
+			Help: "Number of distinct label names observed for an exported metric in the last scrape",
+		}, []string{"metric_name"})},
+		JobLastSuccessTimestampGauge: GaugeVec{inner: f.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "yace_job_last_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful run of a job, by namespace, region and role ARN",
+		}, []string{"namespace", "region", "role"})},
+		APIPagesHistogram: HistogramVec{inner: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "yace_api_pages",
+			Help:    "Number of pages consumed by a paginated AWS API call",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 11),
+		}, []string{"api"})},
+		JobScrapeDurationHistogram: HistogramVec{inner: f.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "yace_job_scrape_duration_seconds",
+			Help:    "Duration of the run of a job, from its account lookup to its CloudWatch queries, by namespace and region",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}, []string{"namespace", "region"})},
 	}
 }
 
@@ -145,6 +237,13 @@ func (m *ScrapeMetrics) Collectors() []prometheus.Collector {
 	vecs := []CounterVec{
 		m.CloudwatchAPIErrorCounter,
 		m.CloudwatchAPICounter,
+		m.CloudwatchGetMetricDataNamespaceCounter,
+		m.SeriesCappedCounter,
+		m.AssociatorRegexNoMatchCounter,
+		m.EnhancedMetricsResourcesCoveredCounter,
+		m.EnhancedMetricsResourcesMissingCounter,
+		m.EnhancedMetricsUnsupportedCounter,
+		m.DuplicateMetricsFilteredByNameCounter,
 	}
 	counters := []Counter{
 		m.CloudwatchGetMetricDataAPICounter,
@@ -160,9 +259,23 @@ func (m *ScrapeMetrics) Collectors() []prometheus.Collector {
 		m.ManagedPrometheusAPICounter,
 		m.StoragegatewayAPICounter,
 		m.DmsAPICounter,
+		m.TaggingAPIRateLimitWaitCounter,
+		m.TaggingAPIRateLimitAllowedCounter,
 		m.DuplicateMetricsFilteredCounter,
+		m.SeriesLimitExceededCounter,
+		m.ZeroDimensionMetricsSkippedCounter,
+	}
+	gauges := []GaugeVec{
+		m.EnhancedMetricsEnabledGauge,
+		m.ClientSDKVersionGauge,
+		m.MetricLabelCardinalityGauge,
+		m.JobLastSuccessTimestampGauge,
 	}
-	out := make([]prometheus.Collector, 0, len(vecs)+len(counters))
+	histograms := []HistogramVec{
+		m.APIPagesHistogram,
+		m.JobScrapeDurationHistogram,
+	}
+	out := make([]prometheus.Collector, 0, len(vecs)+len(counters)+len(gauges)+len(histograms))
 	for _, c := range vecs {
 		if c.inner != nil {
 			out = append(out, c.inner)
@@ -173,9 +286,78 @@ func (m *ScrapeMetrics) Collectors() []prometheus.Collector {
 			out = append(out, c.inner)
 		}
 	}
+	for _, g := range gauges {
+		if g.inner != nil {
+			out = append(out, g.inner)
+		}
+	}
+	for _, h := range histograms {
+		if h.inner != nil {
+			out = append(out, h.inner)
+		}
+	}
 	return out
 }
 
+// APIRequests returns the total number of AWS API requests counted so far.
+// Deprecated per-API CloudWatch counters are excluded as they are also
+// counted by CloudwatchAPICounter.
+func (m *ScrapeMetrics) APIRequests() float64 {
+	if m == nil {
+		return 0
+	}
+	total := collectorValue(m.CloudwatchAPICounter.inner)
+	for _, c := range []Counter{
+		m.ResourceGroupTaggingAPICounter,
+		m.AutoScalingAPICounter,
+		m.TargetGroupsAPICounter,
+		m.APIGatewayAPICounter,
+		m.APIGatewayAPIV2Counter,
+		m.Ec2APICounter,
+		m.ShieldAPICounter,
+		m.ManagedPrometheusAPICounter,
+		m.StoragegatewayAPICounter,
+		m.DmsAPICounter,
+	} {
+		total += collectorValue(c.inner)
+	}
+	return total
+}
+
+// APIErrors returns the total number of failed CloudWatch API requests counted so far.
+func (m *ScrapeMetrics) APIErrors() float64 {
+	if m == nil {
+		return 0
+	}
+	return collectorValue(m.CloudwatchAPIErrorCounter.inner)
+}
+
+// collectorValue sums the values of all counters exposed by a collector.
+func collectorValue(c prometheus.Collector) float64 {
+	if c == nil {
+		return 0
+	}
+	// A nil *prometheus.CounterVec stored in the interface is not nil itself.
+	if vec, ok := c.(*prometheus.CounterVec); ok && vec == nil {
+		return 0
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var total float64
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err == nil && m.Counter != nil {
+			total += m.Counter.GetValue()
+		}
+	}
+	return total
+}
+
 var replacer = strings.NewReplacer(
 	" ", "_",
 	",", "_",
@@ -201,6 +383,10 @@ type PrometheusMetric struct {
 	Value            float64
 	IncludeTimestamp bool
 	Timestamp        time.Time
+	// Help is the help text of the metric. The default help text is used when it is empty.
+	Help string
+	// ValueType is the type the metric is exported with. Zero exports a gauge.
+	ValueType prometheus.ValueType
 }
 
 type PrometheusCollector struct {
@@ -227,6 +413,23 @@ func (p *PrometheusCollector) Collect(metrics chan<- prometheus.Metric) {
 	}
 }
 
+// metricValueTypes returns the type each metric name is exported with, which is the type of its metrics (a gauge
+// when unset) or untyped when they disagree on it.
+func metricValueTypes(metrics []*PrometheusMetric) map[string]prometheus.ValueType {
+	valueTypes := map[string]prometheus.ValueType{}
+	for _, metric := range metrics {
+		valueType := metric.ValueType
+		if valueType == 0 {
+			valueType = prometheus.GaugeValue
+		}
+		if existing, ok := valueTypes[metric.Name]; ok && existing != valueType {
+			valueType = prometheus.UntypedValue
+		}
+		valueTypes[metric.Name] = valueType
+	}
+	return valueTypes
+}
+
 func toConstMetrics(metrics []*PrometheusMetric) []prometheus.Metric {
 	// We keep two fast lookup maps here one for the prometheus.Desc of a metric which can be reused for each metric with
 	// the same name and the expected label key order of a particular metric name.
@@ -235,13 +438,20 @@ func toConstMetrics(metrics []*PrometheusMetric) []prometheus.Metric {
 	// values and they must be provided in the exact same order as registered in the Desc.
 	metricToDesc := map[string]*prometheus.Desc{}
 	metricToExpectedLabelOrder := map[string][]string{}
+	// A metric family has a single type: the metrics of a name are exported as untyped when they disagree on it, so
+	// that the type does not depend on the order of the metrics.
+	metricToValueType := metricValueTypes(metrics)
 
 	result := make([]prometheus.Metric, 0, len(metrics))
 	for _, metric := range metrics {
 		metricName := metric.Name
 		if _, ok := metricToDesc[metricName]; !ok {
 			labelKeys := maps.Keys(metric.Labels)
-			metricToDesc[metricName] = prometheus.NewDesc(metricName, "Help is not implemented yet.", labelKeys, nil)
+			help := metric.Help
+			if help == "" {
+				help = defaultHelp
+			}
+			metricToDesc[metricName] = prometheus.NewDesc(metricName, help, labelKeys, nil)
 			metricToExpectedLabelOrder[metricName] = labelKeys
 		}
 		metricsDesc := metricToDesc[metricName]
@@ -252,7 +462,7 @@ func toConstMetrics(metrics []*PrometheusMetric) []prometheus.Metric {
 			labelValues = append(labelValues, metric.Labels[labelKey])
 		}
 
-		promMetric, err := prometheus.NewConstMetric(metricsDesc, prometheus.GaugeValue, metric.Value, labelValues...)
+		promMetric, err := prometheus.NewConstMetric(metricsDesc, metricToValueType[metricName], metric.Value, labelValues...)
 		if err != nil {
 			// If for whatever reason the metric or metricsDesc is considered invalid this will ensure the error is
 			// reported through the collector
@@ -267,13 +477,68 @@ func toConstMetrics(metrics []*PrometheusMetric) []prometheus.Metric {
 	return result
 }
 
+// WriteMetricsFile writes metrics to path in the Prometheus text exposition format.
+// The file is written atomically, so readers never observe a partially written file.
+func WriteMetricsFile(path string, metrics []*PrometheusMetric) error {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(NewPrometheusCollector(metrics)); err != nil {
+		return err
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	encoder := expfmt.NewEncoder(tmp, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := encoder.Encode(family); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to encode metric family %s: %w", family.GetName(), err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary metrics file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
 func PromString(text string) string {
+	if isPromString(text) {
+		return text
+	}
 	var buf strings.Builder
-	PromStringToBuilder(text, &buf)
+	promStringToBuilder(text, &buf)
 	return buf.String()
 }
 
 func PromStringToBuilder(text string, buf *strings.Builder) {
+	if isPromString(text) {
+		buf.WriteString(text)
+		return
+	}
+	promStringToBuilder(text, buf)
+}
+
+// isPromString reports whether text only contains lowercase letters, digits and underscores, which
+// PromString returns unchanged, e.g. most statistics and lowercased namespaces.
+func isPromString(text string) bool {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func promStringToBuilder(text string, buf *strings.Builder) {
 	buf.Grow(len(text))
 
 	var prev rune
@@ -362,3 +627,36 @@ func (c CounterVec) Add(v float64, labels ...string) {
 }
 
 func (c CounterVec) Raw() *prometheus.CounterVec { return c.inner }
+
+// GaugeVec wraps a *prometheus.GaugeVec so Set is a no-op when inner is nil.
+type GaugeVec struct {
+	inner *prometheus.GaugeVec
+}
+
+func (g GaugeVec) Set(v float64, labels ...string) {
+	if g.inner != nil {
+		g.inner.WithLabelValues(labels...).Set(v)
+	}
+}
+
+// Reset deletes all the label values of the gauge.
+func (g GaugeVec) Reset() {
+	if g.inner != nil {
+		g.inner.Reset()
+	}
+}
+
+func (g GaugeVec) Raw() *prometheus.GaugeVec { return g.inner }
+
+// HistogramVec wraps a *prometheus.HistogramVec so Observe is a no-op when inner is nil.
+type HistogramVec struct {
+	inner *prometheus.HistogramVec
+}
+
+func (h HistogramVec) Observe(v float64, labels ...string) {
+	if h.inner != nil {
+		h.inner.WithLabelValues(labels...).Observe(v)
+	}
+}
+
+func (h HistogramVec) Raw() *prometheus.HistogramVec { return h.inner }
@@ -13,6 +13,9 @@
 package promutil
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -139,6 +142,58 @@ func TestPromStringTag_IgnoresGlobalValidationScheme(t *testing.T) {
 	assert.Equal(t, "labelName", out)
 }
 
+func TestPromString_FastPathMatchesSlowPath(t *testing.T) {
+	corpus := []string{
+		"",
+		"cpuutilization",
+		"request_count",
+		"p99",
+		"aws_ec2",
+		"CPUUtilization",
+		"HTTPCode_Target_5XX_Count",
+		"glue.driver.aggregate.bytesRead",
+		"BurstBalance%",
+		"“quoted",
+		"ÉtéCount",
+	}
+	// Every string of up to 4 runes over an alphabet covering the fast path, the rewritten runes, the
+	// lower to upper transitions and non-ascii runes.
+	alphabet := []string{"a", "z", "Z", "0", "9", "_", ".", "%", "“", "é"}
+	prev := []string{""}
+	for length := 1; length <= 4; length++ {
+		var next []string
+		for _, prefix := range prev {
+			for _, c := range alphabet {
+				next = append(next, prefix+c)
+			}
+		}
+		corpus = append(corpus, next...)
+		prev = next
+	}
+
+	for _, text := range corpus {
+		var slow strings.Builder
+		promStringToBuilder(text, &slow)
+		require.Equal(t, slow.String(), PromString(text), "PromString(%q)", text)
+
+		var buf strings.Builder
+		buf.WriteString("prefix_")
+		PromStringToBuilder(text, &buf)
+		require.Equal(t, "prefix_"+slow.String(), buf.String(), "PromStringToBuilder(%q)", text)
+	}
+}
+
+func Benchmark_PromString(b *testing.B) {
+	for _, text := range []string{"cpuutilization", "request_count_sum", "CPUUtilization", "glue.driver.aggregate.bytesRead"} {
+		b.Run(text, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				PromString(text)
+			}
+		})
+	}
+}
+
 func TestNewPrometheusCollector_CanReportMetricsAndErrors(t *testing.T) {
 	originalValidationScheme := model.NameValidationScheme //nolint:staticcheck
 	model.NameValidationScheme = model.LegacyValidation    //nolint:staticcheck
@@ -256,6 +311,85 @@ func TestNewPrometheusCollector_CanReportMetrics(t *testing.T) {
 	assert.Equal(t, 1.0, *tsMetric.Gauge.Value)
 }
 
+func TestNewPrometheusCollector_MetricType(t *testing.T) {
+	counter := &PrometheusMetric{Name: "metric", Labels: map[string]string{"key": "1"}, Value: 1, ValueType: prometheus.CounterValue}
+	otherCounter := &PrometheusMetric{Name: "metric", Labels: map[string]string{"key": "2"}, Value: 2, ValueType: prometheus.CounterValue}
+	gauge := &PrometheusMetric{Name: "metric", Labels: map[string]string{"key": "2"}, Value: 2}
+
+	testCases := []struct {
+		name     string
+		metrics  []*PrometheusMetric
+		expected dto.MetricType
+	}{
+		{
+			name:     "metrics of the same type",
+			metrics:  []*PrometheusMetric{counter, otherCounter},
+			expected: dto.MetricType_COUNTER,
+		},
+		{
+			name:     "conflicting types",
+			metrics:  []*PrometheusMetric{counter, gauge},
+			expected: dto.MetricType_UNTYPED,
+		},
+		{
+			name:     "conflicting types in reverse order",
+			metrics:  []*PrometheusMetric{gauge, counter},
+			expected: dto.MetricType_UNTYPED,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry := prometheus.NewRegistry()
+			require.NoError(t, registry.Register(NewPrometheusCollector(tc.metrics)))
+			families, err := registry.Gather()
+			require.NoError(t, err)
+			require.Len(t, families, 1)
+			// A metric family has a single type, which must not depend on the order of its metrics
+			assert.Equal(t, tc.expected, families[0].GetType())
+			assert.Len(t, families[0].GetMetric(), 2)
+		})
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	metrics := []*PrometheusMetric{
+		{
+			Name:   "aws_ec2_cpuutilization_average",
+			Labels: map[string]string{"name": "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", "region": "us-east-1"},
+			Value:  42.5,
+		},
+		{
+			Name:   "aws_ec2_info",
+			Labels: map[string]string{"name": "arn:aws:ec2:us-east-1:123456789012:instance/i-abc123", "tag_Name": "instance-1"},
+			Value:  0,
+		},
+	}
+
+	require.NoError(t, WriteMetricsFile(path, metrics))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP aws_ec2_cpuutilization_average Help is not implemented yet.
+# TYPE aws_ec2_cpuutilization_average gauge
+aws_ec2_cpuutilization_average{name="arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",region="us-east-1"} 42.5
+# HELP aws_ec2_info Help is not implemented yet.
+# TYPE aws_ec2_info gauge
+aws_ec2_info{name="arn:aws:ec2:us-east-1:123456789012:instance/i-abc123",tag_Name="instance-1"} 0
+`, string(content))
+
+	// A later scrape replaces the whole file and leaves no temporary file behind.
+	require.NoError(t, WriteMetricsFile(path, metrics[1:]))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "aws_ec2_cpuutilization_average")
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
 func TestNewScrapeMetrics_DiscardingRegisterers(t *testing.T) {
 	for _, tc := range []struct {
 		name string
@@ -303,9 +437,29 @@ func TestScrapeMetrics_ZeroValueAndNilReceiver(t *testing.T) {
 	t.Run("nil receiver", func(t *testing.T) {
 		var sm *ScrapeMetrics
 		require.Nil(t, sm.Collectors())
+		require.Zero(t, sm.APIRequests())
+		require.Zero(t, sm.APIErrors())
 	})
 }
 
+func TestScrapeMetrics_APIRequestsAndErrors(t *testing.T) {
+	sm := NewScrapeMetrics(nil)
+
+	sm.CloudwatchAPICounter.Inc("ListMetrics")
+	sm.CloudwatchAPICounter.Add(2, "GetMetricData")
+	sm.CloudwatchGetMetricDataAPICounter.Add(2)
+	sm.ResourceGroupTaggingAPICounter.Inc()
+	sm.Ec2APICounter.Inc()
+	sm.CloudwatchAPIErrorCounter.Inc("GetMetricData")
+
+	require.Equal(t, float64(5), sm.APIRequests())
+	require.Equal(t, float64(1), sm.APIErrors())
+
+	var zero ScrapeMetrics
+	require.Zero(t, zero.APIRequests())
+	require.Zero(t, zero.APIErrors())
+}
+
 func exerciseCounters(sm *ScrapeMetrics) {
 	sm.CloudwatchAPICounter.Inc("ListMetrics")
 	sm.CloudwatchAPICounter.Add(2, "GetMetricData")